@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/handlers"
+)
+
+const adminUsage = `usage: server admin <command>
+
+  create <email> [name]   create an admin account; the password is read
+                          from the first line of standard input
+  grant <email>           make an existing user an admin
+
+Example:
+  echo 'a-long-password' | server admin create ops@example.com "Ops Team"
+`
+
+// runAdminCommand implements `server admin …` against db and returns the
+// process exit code.
+func runAdminCommand(ctx context.Context, db *sql.DB, args []string, in io.Reader, out io.Writer) int {
+	switch {
+	case len(args) >= 2 && len(args) <= 3 && args[0] == "create":
+		name := ""
+		if len(args) == 3 {
+			name = args[2]
+		}
+		password, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && err != io.EOF {
+			fmt.Fprintf(out, "error: read password: %v\n", err)
+			return 1
+		}
+		created, err := handlers.EnsureAdmin(ctx, db, args[1], name, strings.TrimRight(password, "\r\n"))
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			return 1
+		}
+		if created {
+			fmt.Fprintf(out, "created admin %s\n", args[1])
+		} else {
+			fmt.Fprintf(out, "%s already exists; it is now an admin (password unchanged)\n", args[1])
+		}
+		return 0
+
+	case len(args) == 2 && args[0] == "grant":
+		var exists int
+		if err := db.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM users WHERE email = ?`, strings.ToLower(strings.TrimSpace(args[1])),
+		).Scan(&exists); err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			return 1
+		}
+		if exists == 0 {
+			fmt.Fprintf(out, "error: no user with email %s\n", args[1])
+			return 1
+		}
+		if _, err := handlers.EnsureAdmin(ctx, db, args[1], "", ""); err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(out, "%s is now an admin\n", args[1])
+		return 0
+	}
+
+	fmt.Fprint(out, adminUsage)
+	return 2
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/handlers"
+)
+
+const checkUsage = `usage: server check [--repair]
+
+Verifies the database's invariants (seat counters, registrations behind
+attendances, attendances behind badges, skill links of events) and lists
+every violation.  With --repair the violations are fixed in one
+transaction.  Exits 1 if violations were found and not repaired.
+`
+
+// runCheckCommand implements `server check …` against db and returns the
+// process exit code.
+func runCheckCommand(ctx context.Context, db *sql.DB, args []string, out io.Writer) int {
+	repair := false
+	switch {
+	case len(args) == 0:
+	case len(args) == 1 && args[0] == "--repair":
+		repair = true
+	default:
+		fmt.Fprint(out, checkUsage)
+		return 2
+	}
+
+	report, err := handlers.CheckInvariants(ctx, db, repair)
+	if err != nil {
+		fmt.Fprintf(out, "error: %v\n", err)
+		return 1
+	}
+	if len(report.Violations) == 0 {
+		fmt.Fprintln(out, "no violations")
+		return 0
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "INVARIANT\tSUBJECT\tDETAIL")
+	for _, v := range report.Violations {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Invariant, v.Subject, v.Detail)
+	}
+	tw.Flush()
+	if report.Repaired {
+		fmt.Fprintf(out, "repaired %d violations\n", len(report.Violations))
+		return 0
+	}
+	fmt.Fprintf(out, "%d violations; run `server check --repair` to fix them\n", len(report.Violations))
+	return 1
+}
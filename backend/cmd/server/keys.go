@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/handlers"
+)
+
+const keysUsage = `usage: server keys <command>
+
+  list                    show every signing key and its status
+  add <session|checkin>   create a new key (active: verifies, does not sign)
+  promote <id>            make a key primary; the old primary stays active
+  retire <id>             stop a non-primary key from verifying tokens
+
+Rotation without downtime: add, wait for KEY_RELOAD_INTERVAL so every
+server knows the key, promote, then retire the old key once the tokens it
+signed have expired (15 minutes for sessions; the sync deadline of the
+last event it signed QR codes for, for check-ins).
+`
+
+// runKeysCommand implements `server keys …` against db and returns the
+// process exit code.  Running servers pick the change up on their next
+// keyring reload.
+func runKeysCommand(ctx context.Context, db *sql.DB, args []string, out io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(out, keysUsage)
+		return 2
+	}
+
+	var err error
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		err = listKeys(ctx, db, out)
+	case args[0] == "add" && len(args) == 2:
+		purpose := auth.KeyPurpose(args[1])
+		if purpose != auth.PurposeSession && purpose != auth.PurposeCheckIn {
+			fmt.Fprintf(out, "unknown purpose %q: want session or checkin\n", args[1])
+			return 2
+		}
+		var k auth.Key
+		if k, err = handlers.AddSigningKey(ctx, db, purpose); err == nil {
+			fmt.Fprintf(out, "added %s key %s (active)\n", k.Purpose, k.ID)
+		}
+	case args[0] == "promote" && len(args) == 2:
+		if err = handlers.PromoteSigningKey(ctx, db, args[1]); err == nil {
+			fmt.Fprintf(out, "promoted %s to primary\n", args[1])
+		}
+	case args[0] == "retire" && len(args) == 2:
+		if err = handlers.RetireSigningKey(ctx, db, args[1]); err == nil {
+			fmt.Fprintf(out, "retired %s\n", args[1])
+		}
+	default:
+		fmt.Fprint(out, keysUsage)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(out, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func listKeys(ctx context.Context, db *sql.DB, out io.Writer) error {
+	keys, err := handlers.ListSigningKeys(ctx, db)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tPURPOSE\tSTATUS\tCREATED")
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", k.ID, k.Purpose, k.Status, k.CreatedAt.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
@@ -24,23 +24,30 @@
 // listen for SIGINT / SIGTERM on a channel, trigger Shutdown in a
 // goroutine, and wait for it to finish before main() returns.  This
 // means the database deferred close always runs and no request is cut
-// off mid-flight.
+// off mid-flight.  The event status scheduler and the signing key reload
+// are stopped after the HTTP server, so neither touches a closed database.
 package main
 
 import (
 	"context"
 	"log/slog"
+	"math"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/lmittmann/tint"
 
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
 	"github.com/Elizabethomito/skillzone/backend/internal/db"
 	"github.com/Elizabethomito/skillzone/backend/internal/handlers"
+	"github.com/Elizabethomito/skillzone/backend/internal/mail"
 	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/Elizabethomito/skillzone/backend/internal/oidc"
 )
 
 func main() {
@@ -66,11 +73,70 @@ func main() {
 	//   _pragma=foreign_keys(1)  — enforce FK constraints on every connection
 	//   _pragma=journal_mode(WAL) — Write-Ahead Logging: readers don't block writers
 	//   _pragma=busy_timeout(5000) — wait up to 5 s instead of returning SQLITE_BUSY
+	//   _txlock=immediate — transactions take the write lock at BEGIN, so
+	//                       concurrent ones wait (above) rather than fail;
+	//                       db.Open adds it when DATABASE_URL leaves it out
 	dsn := getenv("DATABASE_URL",
-		"skillzone.db?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
-	jwtSecret := getenv("JWT_SECRET", "changeme-use-a-real-secret-in-production")
+		"skillzone.db?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate")
+	// Unset, the first session key of a fresh database is random.
+	jwtSecret := os.Getenv("JWT_SECRET")
 	addr := getenv("ADDR", ":8080")
 
+	// Offline check-in tolerances.  Unset (or invalid) values fall back to
+	// the handler defaults: 5m of clock skew, syncs up to 7 days after an
+	// event ends unless the event sets its own sync_deadline, and rotating
+	// codes accepted one time step either side of the scan time.
+	checkIn := handlers.CheckInPolicy{
+		ClockSkew:     getenvDuration("CHECKIN_CLOCK_SKEW"),
+		SyncGrace:     getenvDuration("CHECKIN_SYNC_GRACE"),
+		StepTolerance: getenvInt("CHECKIN_STEP_TOLERANCE"),
+	}
+
+	// Failed-login throttling.  Unset values use the handler defaults: an
+	// email is locked after 5 failures in a row and an IP after 50, for 30s
+	// doubling per further failure up to 15m.
+	login := handlers.LoginThrottlePolicy{
+		AccountThreshold: int(getenvInt("LOGIN_ACCOUNT_THRESHOLD")),
+		IPThreshold:      int(getenvInt("LOGIN_IP_THRESHOLD")),
+		BaseLockout:      getenvDuration("LOGIN_BASE_LOCKOUT"),
+		MaxLockout:       getenvDuration("LOGIN_MAX_LOCKOUT"),
+	}
+
+	// Password hashing.  New hashes use PASSWORD_HASH (argon2id by default,
+	// or bcrypt) with the costs below; unset costs use the auth package
+	// defaults.  Existing hashes keep working and are re-hashed with these
+	// settings at the user's next login.  The argon2id costs are checked
+	// against their field types first, so an out-of-range value stops the
+	// server instead of wrapping around.
+	for _, setting := range []struct {
+		env string
+		max int64
+	}{
+		{"ARGON2_MEMORY_KIB", math.MaxUint32},
+		{"ARGON2_ITERATIONS", math.MaxUint32},
+		{"ARGON2_PARALLELISM", math.MaxUint8},
+	} {
+		if os.Getenv(setting.env) == "" {
+			continue
+		}
+		if n := getenvInt(setting.env); n < 1 || n > setting.max {
+			slog.Error("password hashing", "err", "out of range", "env", setting.env,
+				"value", os.Getenv(setting.env), "min", 1, "max", setting.max)
+			os.Exit(1)
+		}
+	}
+	passwords := auth.PasswordHasher{
+		Algorithm:         os.Getenv("PASSWORD_HASH"),
+		Argon2Memory:      uint32(getenvInt("ARGON2_MEMORY_KIB")),
+		Argon2Iterations:  uint32(getenvInt("ARGON2_ITERATIONS")),
+		Argon2Parallelism: uint8(getenvInt("ARGON2_PARALLELISM")),
+		BcryptCost:        int(getenvInt("BCRYPT_COST")),
+	}
+	if err := passwords.Validate(); err != nil {
+		slog.Error("password hashing", "err", err)
+		os.Exit(1)
+	}
+
 	// ── Database ─────────────────────────────────────────────────────
 	// db.Open creates the file if it doesn't exist and runs all CREATE
 	// TABLE IF NOT EXISTS migrations automatically.
@@ -81,13 +147,137 @@ func main() {
 	}
 	defer database.Close()
 
+	// `server keys …` manages the signing keyring, `server admin …`
+	// creates admin accounts and `server check` verifies the database; all
+	// exit instead of serving — see keys.go, admin.go and check.go in this
+	// directory.
+	if len(os.Args) > 1 {
+		code := 2
+		switch os.Args[1] {
+		case "keys":
+			code = runKeysCommand(context.Background(), database, os.Args[2:], os.Stdout)
+		case "admin":
+			code = runAdminCommand(context.Background(), database, os.Args[2:], os.Stdin, os.Stdout)
+		case "check":
+			code = runCheckCommand(context.Background(), database, os.Args[2:], os.Stdout)
+		default:
+			slog.Error("unknown command", "command", os.Args[1], "want", "keys | admin | check")
+		}
+		database.Close()
+		os.Exit(code)
+	}
+
+	// ADMIN_EMAIL + ADMIN_PASSWORD bootstrap the first admin account on a
+	// fresh deployment (an existing user with that email is promoted).
+	if email := os.Getenv("ADMIN_EMAIL"); email != "" {
+		created, err := handlers.EnsureAdmin(context.Background(), database,
+			email, getenv("ADMIN_NAME", "Administrator"), os.Getenv("ADMIN_PASSWORD"))
+		if err != nil {
+			slog.Error("bootstrap admin", "email", email, "err", err)
+			os.Exit(1)
+		}
+		if created {
+			slog.Info("created admin account", "email", email)
+		}
+	}
+
+	// The keys that sign session and QR check-in tokens live in the
+	// database so tokens already issued survive a restart.  JWT_SECRET only
+	// seeds the first session key of a fresh database, and the published
+	// example value is refused.
+	keys, err := handlers.LoadKeyring(context.Background(), database, jwtSecret)
+	if err != nil {
+		slog.Error("load signing keys", "err", err)
+		os.Exit(1)
+	}
+
 	// ── Handlers ─────────────────────────────────────────────────────
-	// Server is a plain struct that holds the two shared dependencies
-	// (database handle and JWT secret). All handler methods live on it.
+	// Server is a plain struct that holds the shared dependencies
+	// (database handle and signing keyring). All handler methods live on it.
+	// AUTH_CACHE_TTL is how long a session/role/status lookup is reused
+	// (default 5s; a negative value such as -1s turns the cache off).
+	mailer, err := newMailer()
+	if err != nil {
+		slog.Error("set up mail", "err", err)
+		os.Exit(1)
+	}
+	// OIDC_PROVIDERS is a JSON array of single sign-on providers (see
+	// README).  Their callback defaults to API_URL/api/auth/oidc/<name>/callback,
+	// which must be registered with the provider.
+	var providers map[string]*oidc.Provider
+	if raw := os.Getenv("OIDC_PROVIDERS"); raw != "" {
+		cfgs, err := oidc.ParseConfigs([]byte(raw))
+		if err == nil {
+			providers, err = handlers.NewOIDCProviders(cfgs, getenv("API_URL", "http://localhost:8080"))
+		}
+		if err != nil {
+			slog.Error("configure OIDC", "err", err)
+			os.Exit(1)
+		}
+	}
 	srv := &handlers.Server{
-		DB:     database,
-		Secret: jwtSecret,
+		DB:               database,
+		Keys:             keys,
+		CheckIn:          checkIn,
+		LoginThrottle:    login,
+		AuthCacheTTL:     getenvDuration("AUTH_CACHE_TTL"),
+		Mailer:           mailer,
+		AppURL:           getenv("APP_URL", "http://localhost:5173"),
+		PasswordResetTTL: getenvDuration("PASSWORD_RESET_TTL"),
+		MagicLinkTTL:     getenvDuration("MAGIC_LINK_TTL"),
+		WaitlistOfferTTL: getenvDuration("WAITLIST_OFFER_TTL"),
+		OIDC:             providers,
+		Passwords:        passwords,
+		// EMAIL_VERIFICATION=optional lets unverified companies create
+		// events and lists unverified students in candidate search.
+		EmailVerification: handlers.EmailVerificationPolicy{
+			AllowUnverifiedHosts:   os.Getenv("EMAIL_VERIFICATION") == "optional",
+			ListUnverifiedStudents: os.Getenv("EMAIL_VERIFICATION") == "optional",
+		},
+	}
+
+	// Pick up keys added, promoted or retired with `server keys` without a
+	// restart.  A failed reload keeps the previous keyring.  Like the
+	// scheduler below, it is stopped after the HTTP server.
+	keyReload := getenvDuration("KEY_RELOAD_INTERVAL")
+	if keyReload <= 0 {
+		keyReload = time.Minute
 	}
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	reloadDone := make(chan struct{})
+	go func() {
+		defer close(reloadDone)
+		ticker := time.NewTicker(keyReload)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-reloadCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := srv.ReloadKeys(reloadCtx); err != nil && reloadCtx.Err() == nil {
+				slog.Error("reload signing keys", "err", err)
+			}
+		}
+	}()
+
+	// Move events to active at start_time and to completed once
+	// EVENT_COMPLETE_GRACE (default 1h) has passed after end_time, checking
+	// every EVENT_STATUS_INTERVAL (default 1m; negative turns it off), and
+	// hand the seats of expired waitlist offers on.  It is stopped after
+	// the HTTP server, before the database closes.
+	scheduler := &handlers.StatusScheduler{
+		DB:            database,
+		Interval:      getenvDuration("EVENT_STATUS_INTERVAL"),
+		CompleteGrace: getenvDuration("EVENT_COMPLETE_GRACE"),
+		Waitlist:      srv,
+	}
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		scheduler.Run(schedulerCtx)
+	}()
 
 	// ── Router ───────────────────────────────────────────────────────
 	// Go 1.22+ ServeMux supports method prefixes ("GET /path") and path
@@ -97,53 +287,134 @@ func main() {
 	// Public routes — no token required.
 	mux.HandleFunc("POST /api/auth/register", srv.Register)
 	mux.HandleFunc("POST /api/auth/login", srv.Login)
+	mux.HandleFunc("POST /api/auth/refresh", srv.Refresh)
+	mux.HandleFunc("POST /api/auth/password/forgot", srv.ForgotPassword)
+	mux.HandleFunc("POST /api/auth/password/reset", srv.ResetPassword)
+	mux.HandleFunc("POST /api/auth/email/confirm", srv.ConfirmEmail)
+	mux.HandleFunc("POST /api/auth/email/verify", srv.VerifyEmail)
+	mux.HandleFunc("POST /api/auth/magic-link", srv.RequestMagicLink)
+	mux.HandleFunc("POST /api/auth/magic-link/verify", srv.VerifyMagicLink)
+	// The second login step authenticates with the challenge token instead.
+	mux.HandleFunc("POST /api/auth/login/2fa", srv.LoginTwoFactor)
+	mux.HandleFunc("POST /api/auth/login/2fa/enroll", srv.LoginTwoFactorEnroll)
+	// Single sign-on — see handlers/oidc.go for the order these are called in.
+	mux.HandleFunc("GET /api/auth/oidc/providers", srv.ListOIDCProviders)
+	mux.HandleFunc("GET /api/auth/oidc/{provider}/start", srv.StartOIDCLogin)
+	mux.HandleFunc("GET /api/auth/oidc/{provider}/callback", srv.OIDCCallback)
+	mux.HandleFunc("POST /api/auth/oidc/exchange", srv.OIDCExchange)
+	mux.HandleFunc("GET /api/.well-known/jwks.json", srv.JWKS)
 	mux.HandleFunc("GET /api/events", srv.ListEvents)
 	mux.HandleFunc("GET /api/events/{id}", srv.GetEvent)
 	mux.HandleFunc("GET /api/skills", srv.ListSkills)
-	// Demo seed — loads all fixture data; safe to call multiple times (idempotent).
-	// Remove or gate behind an env flag before any real deployment.
-	mux.HandleFunc("POST /api/admin/seed", srv.SeedDemo)
+	mux.HandleFunc("GET /api/institutions", srv.ListInstitutions)
 
 	// ── Middleware helpers ────────────────────────────────────────────
 	// middleware.Authenticate returns a function that wraps any handler.
+	// srv is passed as the middleware.Store so every request checks that
+	// the token's session has not been revoked.
 	// middleware.RequireRole does the same but also checks the role claim.
 	// Chaining them: auth(onlyCompany(handler)) means:
 	//   1. Authenticate runs first  → sets user_id/role in context
 	//   2. RequireRole runs second  → allows or rejects based on role
 	//   3. handler runs last        → does the actual work
-	auth := middleware.Authenticate(jwtSecret)
+	auth := middleware.Authenticate(srv.Keys, srv)
 	onlyCompany := middleware.RequireRole("company")
 	onlyStudent := middleware.RequireRole("student")
+	onlyAdmin := middleware.RequireRole("admin")
+	// Routes an API key may call, and the scope it needs.  Every other
+	// authenticated route refuses API keys.
+	scope := middleware.RequireScope
 
 	// Authenticated — any logged-in user.
 	mux.Handle("GET /api/auth/me",
 		auth(http.HandlerFunc(srv.Me)))
+	mux.Handle("PATCH /api/auth/me",
+		auth(http.HandlerFunc(srv.UpdateMe)))
+	mux.Handle("POST /api/auth/me/password",
+		auth(http.HandlerFunc(srv.ChangePassword)))
+	mux.Handle("POST /api/auth/email/verify/resend",
+		auth(http.HandlerFunc(srv.ResendVerificationEmail)))
+	mux.Handle("POST /api/auth/logout",
+		auth(http.HandlerFunc(srv.Logout)))
+	mux.Handle("POST /api/auth/logout-all",
+		auth(http.HandlerFunc(srv.LogoutAll)))
+	mux.Handle("GET /api/auth/sessions",
+		auth(http.HandlerFunc(srv.ListSessions)))
+	mux.Handle("POST /api/auth/2fa/enroll",
+		auth(http.HandlerFunc(srv.EnrollTwoFactor)))
+	mux.Handle("POST /api/auth/2fa/confirm",
+		auth(http.HandlerFunc(srv.ConfirmTwoFactor)))
+	mux.Handle("POST /api/auth/2fa/disable",
+		auth(http.HandlerFunc(srv.DisableTwoFactor)))
+	mux.Handle("POST /api/auth/2fa/recovery-codes",
+		auth(http.HandlerFunc(srv.RegenerateRecoveryCodes)))
 
 	// Company-only routes.
 	mux.Handle("POST /api/events",
 		auth(onlyCompany(http.HandlerFunc(srv.CreateEvent))))
 	mux.Handle("PUT /api/events/{id}",
 		auth(onlyCompany(http.HandlerFunc(srv.UpdateEvent))))
+	mux.Handle("DELETE /api/events/{id}",
+		auth(onlyCompany(http.HandlerFunc(srv.DeleteEvent))))
+	// Not company-only: volunteers with an event role (scanner,
+	// coordinator; see eventroles.go) may be students.
 	mux.Handle("GET /api/events/{id}/checkin-code",
-		auth(onlyCompany(http.HandlerFunc(srv.GetEventCheckInCode))))
+		auth(http.HandlerFunc(srv.GetEventCheckInCode)))
+	mux.Handle("POST /api/events/{id}/checkin-code/rotate",
+		auth(onlyCompany(http.HandlerFunc(srv.RotateEventCheckInCode))))
 	mux.Handle("PATCH /api/events/{id}/status",
 		auth(onlyCompany(http.HandlerFunc(srv.UpdateEventStatus))))
+	mux.Handle("GET /api/events/hosted",
+		auth(scope(models.ScopeEventsRead)(onlyCompany(http.HandlerFunc(srv.ListHostedEvents)))))
+	// Not company-only either: coordinators may be students.
 	mux.Handle("GET /api/events/{id}/registrations",
-		auth(onlyCompany(http.HandlerFunc(srv.GetEventRegistrations))))
+		auth(scope(models.ScopeRegistrationsRead)(http.HandlerFunc(srv.GetEventRegistrations))))
 	mux.Handle("PATCH /api/events/{id}/registrations/{reg_id}",
 		auth(onlyCompany(http.HandlerFunc(srv.ResolveRegistrationConflict))))
 	mux.Handle("DELETE /api/events/{id}/registrations/{reg_id}",
 		auth(onlyCompany(http.HandlerFunc(srv.KickRegistration))))
+	mux.Handle("GET /api/events/{id}/roles",
+		auth(onlyCompany(http.HandlerFunc(srv.ListEventRoles))))
+	mux.Handle("POST /api/events/{id}/roles",
+		auth(onlyCompany(http.HandlerFunc(srv.GrantEventRole))))
+	mux.Handle("DELETE /api/events/{id}/roles/{user_id}",
+		auth(onlyCompany(http.HandlerFunc(srv.RevokeEventRole))))
 	mux.Handle("POST /api/skills",
 		auth(onlyCompany(http.HandlerFunc(srv.CreateSkill))))
 	mux.Handle("GET /api/users/students",
-		auth(onlyCompany(http.HandlerFunc(srv.SearchStudents))))
+		auth(scope(models.ScopeCandidatesRead)(onlyCompany(http.HandlerFunc(srv.SearchStudents)))))
+	mux.Handle("POST /api/api-keys",
+		auth(onlyCompany(http.HandlerFunc(srv.CreateAPIKey))))
+	mux.Handle("GET /api/api-keys",
+		auth(onlyCompany(http.HandlerFunc(srv.ListAPIKeys))))
+	mux.Handle("DELETE /api/api-keys/{id}",
+		auth(onlyCompany(http.HandlerFunc(srv.RevokeAPIKey))))
+	mux.Handle("POST /api/organisations",
+		auth(onlyCompany(http.HandlerFunc(srv.CreateOrganisation))))
+	mux.Handle("GET /api/organisations",
+		auth(onlyCompany(http.HandlerFunc(srv.ListOrganisations))))
+	mux.Handle("GET /api/organisations/{id}",
+		auth(onlyCompany(http.HandlerFunc(srv.GetOrganisation))))
+	mux.Handle("PATCH /api/organisations/{id}/members/{user_id}",
+		auth(onlyCompany(http.HandlerFunc(srv.UpdateMemberRole))))
+	mux.Handle("DELETE /api/organisations/{id}/members/{user_id}",
+		auth(onlyCompany(http.HandlerFunc(srv.RemoveMember))))
+	mux.Handle("POST /api/organisations/{id}/invitations",
+		auth(onlyCompany(http.HandlerFunc(srv.InviteMember))))
+	mux.Handle("GET /api/organisations/{id}/invitations",
+		auth(onlyCompany(http.HandlerFunc(srv.ListInvitations))))
+	mux.Handle("DELETE /api/organisations/{id}/invitations/{invitation_id}",
+		auth(onlyCompany(http.HandlerFunc(srv.RevokeInvitation))))
+	mux.Handle("POST /api/organisations/invitations/accept",
+		auth(onlyCompany(http.HandlerFunc(srv.AcceptInvitation))))
 
 	// Student-only routes.
 	mux.Handle("POST /api/events/{id}/register",
 		auth(onlyStudent(http.HandlerFunc(srv.RegisterForEvent))))
 	mux.Handle("DELETE /api/events/{id}/register",
 		auth(onlyStudent(http.HandlerFunc(srv.UnregisterFromEvent))))
+	mux.Handle("POST /api/events/{id}/register/accept",
+		auth(onlyStudent(http.HandlerFunc(srv.AcceptWaitlistOffer))))
 	// ↓ Core local-first sync endpoint — see handlers/sync.go
 	mux.Handle("POST /api/sync/attendance",
 		auth(onlyStudent(http.HandlerFunc(srv.SyncAttendance))))
@@ -152,6 +423,41 @@ func main() {
 	mux.Handle("GET /api/users/me/registrations",
 		auth(onlyStudent(http.HandlerFunc(srv.GetMyRegistrations))))
 
+	// Admin-only routes — everything under /api/admin/.
+	// Demo seed — loads all fixture data; safe to call multiple times (idempotent).
+	mux.Handle("POST /api/admin/seed",
+		auth(onlyAdmin(http.HandlerFunc(srv.SeedDemo))))
+	mux.Handle("GET /api/admin/users",
+		auth(onlyAdmin(http.HandlerFunc(srv.ListUsers))))
+	mux.Handle("POST /api/admin/users/{id}/suspend",
+		auth(onlyAdmin(http.HandlerFunc(srv.SuspendUser))))
+	mux.Handle("POST /api/admin/users/{id}/unsuspend",
+		auth(onlyAdmin(http.HandlerFunc(srv.UnsuspendUser))))
+	mux.Handle("PATCH /api/admin/users/{id}/role",
+		auth(onlyAdmin(http.HandlerFunc(srv.UpdateUserRole))))
+	mux.Handle("PATCH /api/admin/users/{id}/status",
+		auth(onlyAdmin(http.HandlerFunc(srv.UpdateUserStatus))))
+	mux.Handle("POST /api/admin/users/{id}/unlock",
+		auth(onlyAdmin(http.HandlerFunc(srv.UnlockUser))))
+	mux.Handle("GET /api/admin/login-attempts",
+		auth(onlyAdmin(http.HandlerFunc(srv.ListLoginAttempts))))
+	mux.Handle("DELETE /api/admin/users/{id}/2fa",
+		auth(onlyAdmin(http.HandlerFunc(srv.ResetUserTwoFactor))))
+	mux.Handle("GET /api/admin/two-factor-policy",
+		auth(onlyAdmin(http.HandlerFunc(srv.GetTwoFactorPolicy))))
+	mux.Handle("PUT /api/admin/two-factor-policy",
+		auth(onlyAdmin(http.HandlerFunc(srv.UpdateTwoFactorPolicy))))
+	mux.Handle("POST /api/admin/institutions",
+		auth(onlyAdmin(http.HandlerFunc(srv.CreateInstitution))))
+	mux.Handle("PUT /api/admin/institutions/{id}",
+		auth(onlyAdmin(http.HandlerFunc(srv.UpdateInstitution))))
+	mux.Handle("DELETE /api/admin/institutions/{id}",
+		auth(onlyAdmin(http.HandlerFunc(srv.DeleteInstitution))))
+	mux.Handle("GET /api/admin/check",
+		auth(onlyAdmin(http.HandlerFunc(srv.CheckDatabase))))
+	mux.Handle("POST /api/admin/check/repair",
+		auth(onlyAdmin(http.HandlerFunc(srv.RepairDatabase))))
+
 	// Wrap the entire mux in CORS and the request logger so every
 	// request is printed: method, path, status, latency.
 	handler := middleware.CORS(requestLogger(mux))
@@ -195,6 +501,11 @@ func main() {
 	} else {
 		slog.Info("server stopped cleanly")
 	}
+	// A sweep in progress rolls back when its context is cancelled.
+	stopScheduler()
+	stopReload()
+	<-schedulerDone
+	<-reloadDone
 }
 
 // getenv returns the value of the named environment variable, or fallback
@@ -206,6 +517,60 @@ func getenv(key, fallback string) string {
 	return fallback
 }
 
+// newMailer picks how emails leave the server.  SMTP_ADDR selects SMTP
+// (with SMTP_USERNAME/SMTP_PASSWORD if set); otherwise messages are appended
+// to MAIL_FILE, or printed to stderr when that is unset too — handy on the
+// demo laptop, where the reset link can be copied straight from the log.
+func newMailer() (mail.Mailer, error) {
+	from := getenv("MAIL_FROM", "Skillzone <noreply@skillzone.local>")
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		return mail.SMTPMailer{
+			Addr:     addr,
+			From:     from,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}, nil
+	}
+	if path := os.Getenv("MAIL_FILE"); path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		return &mail.LogMailer{From: from, W: f}, nil
+	}
+	return &mail.LogMailer{From: from, W: os.Stderr}, nil
+}
+
+// getenvDuration parses key as a time.Duration ("10m", "72h").  It returns
+// zero — meaning "use the default" — when the variable is unset or invalid.
+func getenvDuration(key string) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		slog.Warn("ignoring invalid duration", "env", key, "value", v, "err", err)
+		return 0
+	}
+	return d
+}
+
+// getenvInt parses key as a base-10 integer.  Like getenvDuration it returns
+// zero when the variable is unset or invalid.
+func getenvInt(key string) int64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		slog.Warn("ignoring invalid integer", "env", key, "value", v, "err", err)
+		return 0
+	}
+	return n
+}
+
 // isatty reports whether f is connected to an interactive terminal.
 // Used to decide whether to emit ANSI colour codes — we skip them when
 // stdout is a pipe or a file so logs stay clean for tools like grep.
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — why check-in tokens use Ed25519, not HMAC
+// ────────────────────────────────────────────────────────────────────
+// HMAC (HS256) is SYMMETRIC: the same secret both signs and verifies.  To
+// let the student's PWA check a QR code offline we would have to ship it
+// the secret — and then anyone could mint tokens.  It also means one leaked
+// JWT_SECRET forges session tokens AND check-in tokens.
+//
+// Ed25519 is ASYMMETRIC: the private key signs, the public key only
+// verifies.  The server keeps the private key and publishes the public key
+// as a JWK Set (GET /api/.well-known/jwks.json).  The PWA caches that set
+// and can tell a student right after scanning whether a QR is genuine,
+// without a network connection and without being able to forge one.
+//
+// Session tokens stay on HS256 with their own keys (see keyring.go): only
+// the server ever verifies them, so there is nothing to gain from a public
+// key there.
+
+// CheckInKey is an Ed25519 keypair that signs check-in tokens.  ID is sent
+// as the token's "kid" header and as the JWK "kid" so verifiers can pick
+// the right public key.
+type CheckInKey struct {
+	ID      string
+	Private ed25519.PrivateKey
+}
+
+// NewCheckInKey generates a fresh random keypair.
+func NewCheckInKey() (*CheckInKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate check-in key: %w", err)
+	}
+	return CheckInKeyFromSeed(priv.Seed())
+}
+
+// CheckInKeyFromSeed rebuilds a keypair from its 32-byte seed, which is
+// what gets persisted.  The ID is derived from the public key, so the same
+// seed always yields the same ID.
+func CheckInKeyFromSeed(seed []byte) (*CheckInKey, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, errors.New("check-in key seed must be 32 bytes")
+	}
+	priv := ed25519.NewKeyFromSeed(seed)
+	k := &CheckInKey{Private: priv}
+	k.ID = k.JWK().Thumbprint()
+	return k, nil
+}
+
+// Public returns the verification half of the keypair.
+func (k *CheckInKey) Public() ed25519.PublicKey {
+	return k.Private.Public().(ed25519.PublicKey)
+}
+
+// Seed returns the 32-byte private seed for storage.
+func (k *CheckInKey) Seed() []byte {
+	return k.Private.Seed()
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517 / RFC 8037).
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+}
+
+// JWKS is a JWK Set — the body of GET /api/.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK returns the public key of k in JWK format.
+func (k *CheckInKey) JWK() JWK {
+	return JWK{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		X:   base64.RawURLEncoding.EncodeToString(k.Public()),
+		Kid: k.ID,
+		Use: "sig",
+		Alg: "EdDSA",
+	}
+}
+
+// Thumbprint computes the RFC 7638 thumbprint of an OKP key: the SHA-256 of
+// its required members in lexicographic order, base64url-encoded.
+func (j JWK) Thumbprint() string {
+	canonical := fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, j.Crv, j.Kty, j.X)
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+)
+
+func TestCheckInKeyFromSeed_RoundTrip(t *testing.T) {
+	k, err := NewCheckInKey()
+	if err != nil {
+		t.Fatalf("NewCheckInKey: %v", err)
+	}
+	again, err := CheckInKeyFromSeed(k.Seed())
+	if err != nil {
+		t.Fatalf("CheckInKeyFromSeed: %v", err)
+	}
+	if again.ID != k.ID {
+		t.Errorf("ID changed across round trip: %q vs %q", again.ID, k.ID)
+	}
+	if !bytes.Equal(again.Public(), k.Public()) {
+		t.Error("public key changed across round trip")
+	}
+
+	if _, err := CheckInKeyFromSeed([]byte("short")); err == nil {
+		t.Error("expected error for a short seed")
+	}
+}
+
+func TestCheckInKey_JWK(t *testing.T) {
+	k, _ := NewCheckInKey()
+	j := k.JWK()
+	if j.Kty != "OKP" || j.Crv != "Ed25519" || j.Alg != "EdDSA" || j.Kid != k.ID {
+		t.Errorf("unexpected JWK header fields: %+v", j)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(j.X)
+	if err != nil {
+		t.Fatalf("decode x: %v", err)
+	}
+	if !ed25519.PublicKey(x).Equal(k.Public()) {
+		t.Error("JWK x does not match the public key")
+	}
+}
+
+// TestJWKThumbprint checks the RFC 8037 Appendix A.3 example.
+func TestJWKThumbprint(t *testing.T) {
+	j := JWK{Kty: "OKP", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}
+	if got, want := j.Thumbprint(), "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"; got != want {
+		t.Errorf("thumbprint: got %q, want %q", got, want)
+	}
+}
@@ -13,9 +13,12 @@
 // The PAYLOAD carries our custom claims (user_id, role) plus standard
 // ones (expiry, issued-at).
 // The SIGNATURE is an HMAC-SHA256 hash of HEADER+PAYLOAD using a secret
-// key only the server knows. Tampering with the payload invalidates the
+// key only the server knows.  Tampering with the payload invalidates the
 // signature, so the server can trust the claims without a database lookup
 // on every request.
+// The header also carries a "kid" (key ID) naming the Keyring key
+// (keyring.go) that signed the token, so keys can be rotated without
+// logging everyone out.
 //
 // Useful resource: https://jwt.io/introduction
 package auth
@@ -34,79 +37,143 @@ import (
 type Claims struct {
 	UserID string `json:"user_id"`
 	Role   string `json:"role"`
+	// SessionID ties the access token to a row in the sessions table so the
+	// server can revoke it before it expires (logout, stolen device).
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// tokenDuration is how long a user session token stays valid after being issued.
-// 72 hours means users stay logged in across the hackathon demo days
-// without needing to re-authenticate.
-const tokenDuration = 72 * time.Hour
+// AccessTokenDuration is how long a user access token stays valid after being
+// issued.  It is deliberately short: a stolen access token is only useful for
+// a few minutes, and the client silently swaps its refresh token for a new
+// one via POST /api/auth/refresh.
+const AccessTokenDuration = 15 * time.Minute
+
+// RefreshTokenDuration is the absolute lifetime of a login session.  Refresh
+// tokens rotate on every use, but the session they belong to still ends
+// RefreshTokenDuration after the user logged in.
+const RefreshTokenDuration = 30 * 24 * time.Hour
 
 // CheckInTokenDuration is the validity window of a QR check-in token.
 //
 // ────────────────────────────────────────────────────────────────────
-// LEARNING NOTE — why two different expiry windows?
+// LEARNING NOTE — scan window vs sync deadline
 // ────────────────────────────────────────────────────────────────────
 // The QR code on the projector screen represents the "live" event window.
 // We want only students who were physically present (i.e. who scanned
 // while the QR was active) to be able to check in.
 //
-// The trick: the QR token expires after 6 hours, so it cannot be
-// replayed from a previous session.  BUT the student's sync can happen
-// at any point in the future — the server never rejects a sync based on
-// when it arrived.  The only validity check is the JWT signature inside
-// the payload, which the student's device captured at scan time.
+// Two separate checks enforce that at sync time:
 //
-// Concretely:
-//   - Student scans at 10:00 → their device stores a signed token valid until 16:00.
-//   - They sync at 15:30 (still within window) → server verifies JWT: accepted.
-//   - They sync at 23:00 (same day, poor connectivity) → server verifies JWT: still accepted
-//     because the token was SCANNED (and thus signed) before it expired.
-//   - A student who never scanned tries to craft a fake payload at 23:00 → rejected,
-//     because they cannot produce a token whose exp is after the signing time.
+//   - SCAN WINDOW: the student's device records when it scanned the QR
+//     (CheckInPayload.Timestamp).  That time must fall between the token's
+//     iat and exp, widened by a small clock-skew allowance — see
+//     CheckScanTime.  A token photographed at 10:00 and "scanned" from the
+//     photo at 23:00 fails this check.
+//   - SYNC DEADLINE: the sync request itself must arrive before the
+//     event's sync deadline (default: some days after end_time).  This
+//     still gives students with poor connectivity plenty of time, but a
+//     screenshot can no longer be replayed forever.
 //
-// This gives students with poor connectivity unlimited time to sync,
-// while still ensuring the QR was live when they scanned it.
+// Concretely, for a 09:00–17:00 event with the default deadline:
+//   - Host shows a QR at 09:30 (valid until 15:30); student scans at 10:00 →
+//     scan time inside the window.
+//   - They sync at 23:00 (poor connectivity) → before the deadline: accepted.
+//   - They sync three weeks later → after the deadline: rejected.
+//   - Someone claims to have scanned at 20:00 → outside the window: rejected.
 const CheckInTokenDuration = 6 * time.Hour
 
+// Errors returned by CheckScanTime.  Callers map them to machine-readable
+// rejection reasons, so they are exported sentinels rather than strings.
+var (
+	ErrScanBeforeIssue  = errors.New("scan time is before the check-in token was issued")
+	ErrScanAfterExpiry  = errors.New("scan time is after the check-in token expired")
+	ErrNoScanWindow     = errors.New("check-in token has no validity window")
+	ErrScanStepMismatch = errors.New("check-in code was not on screen at the scan time")
+)
+
 // CheckInClaims are the claims embedded in a QR check-in token.
 // These are intentionally minimal — the token just proves the student
 // scanned a specific event's QR while it was live.
 type CheckInClaims struct {
 	EventID string `json:"event_id"`
 	HostSig string `json:"host_sig"` // the event's check_in_code (shared secret)
+	// Step and Period are set only on rotating codes: the token is valid
+	// for time step Step, i.e. [Step*Period, (Step+1)*Period) in Unix seconds.
+	Step   int64 `json:"step,omitempty"`
+	Period int64 `json:"period,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — rotating codes (TOTP-style time steps)
+// ────────────────────────────────────────────────────────────────────
+// A static token is live for CheckInTokenDuration, so one photo of the
+// projector can be forwarded to friends at home and "scanned" there at any
+// point in the next six hours.  A rotating code narrows that to seconds.
+//
+// Like TOTP (RFC 6238), time is cut into fixed-length steps:
+//
+//	step = floor(unix_seconds / period)
+//
+// The host screen fetches a fresh token whenever the step changes.  Each
+// token carries its step number, and at sync time the server recomputes the
+// step from the student's recorded scan time.  If they differ by more than a
+// small tolerance the code was not on screen when the student says it was
+// scanned.  A forwarded photo still works for a few seconds, which is the
+// best any QR-only scheme can do offline.
+
+// CheckInStep returns the time step t falls in for the given period.
+func CheckInStep(t time.Time, period time.Duration) int64 {
+	return t.Unix() / int64(period/time.Second)
+}
+
 // GenerateCheckInToken creates a short-lived signed JWT for a check-in QR code.
-// The token is signed with the same server secret as user tokens, but carries
-// event-specific claims rather than user claims.
+// The token is signed with the Ed25519 check-in key (see checkin_keys.go), not
+// with the session secret, and carries event-specific claims rather than user
+// claims.
 //
 // The host embeds this token as the "token" field in the QR code payload.
 // Its signature is the cryptographic proof that the QR came from a server that
 // knew the event's check_in_code at the time the QR was generated.
-func GenerateCheckInToken(eventID, hostSig, secret string) (string, error) {
+func GenerateCheckInToken(eventID, hostSig string, key *CheckInKey) (string, error) {
 	now := time.Now().UTC()
+	return GenerateCheckInTokenWithExpiry(eventID, hostSig, key, now, now.Add(CheckInTokenDuration))
+}
+
+// GenerateRotatingCheckInToken creates a check-in token that is only valid for
+// the time step containing now.  It also returns when that step ends, which is
+// when the host screen should fetch the next token.
+func GenerateRotatingCheckInToken(eventID, hostSig string, key *CheckInKey, period time.Duration, now time.Time) (string, time.Time, error) {
+	if period < time.Second {
+		return "", time.Time{}, errors.New("rotation period must be at least one second")
+	}
+	step := CheckInStep(now, period)
+	secs := int64(period / time.Second)
+	start := time.Unix(step*secs, 0).UTC()
+	end := start.Add(time.Duration(secs) * time.Second)
 	claims := CheckInClaims{
 		EventID: eventID,
 		HostSig: hostSig,
+		Step:    step,
+		Period:  secs,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(CheckInTokenDuration)),
-			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(end),
+			IssuedAt:  jwt.NewNumericDate(start),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString([]byte(secret))
+	signed, err := signCheckInClaims(claims, key)
 	if err != nil {
-		return "", fmt.Errorf("sign check-in token: %w", err)
+		return "", time.Time{}, err
 	}
-	return signed, nil
+	return signed, end, nil
 }
 
 // GenerateCheckInTokenWithExpiry creates a check-in token with explicit iat/exp values.
 // This is primarily used in tests to simulate tokens that were scanned in the
-// past (and whose exp has already elapsed) to verify that late syncs are accepted.
-func GenerateCheckInTokenWithExpiry(eventID, hostSig, secret string, iat, exp time.Time) (string, error) {
+// past (and whose exp has already elapsed) to exercise the scan-window and
+// sync-deadline checks.
+func GenerateCheckInTokenWithExpiry(eventID, hostSig string, key *CheckInKey, iat, exp time.Time) (string, error) {
 	claims := CheckInClaims{
 		EventID: eventID,
 		HostSig: hostSig,
@@ -115,37 +182,47 @@ func GenerateCheckInTokenWithExpiry(eventID, hostSig, secret string, iat, exp ti
 			IssuedAt:  jwt.NewNumericDate(iat),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString([]byte(secret))
+	return signCheckInClaims(claims, key)
+}
+
+func signCheckInClaims(claims CheckInClaims, key *CheckInKey) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.Private)
 	if err != nil {
 		return "", fmt.Errorf("sign check-in token: %w", err)
 	}
 	return signed, nil
 }
 
-// IMPORTANT: This function verifies the JWT SIGNATURE but deliberately skips
-// the expiry (exp) check.  This is the core of the "scan window vs sync window"
-// design:
+// ParseCheckInToken verifies the JWT SIGNATURE of a check-in token and returns
+// its claims.  It deliberately skips the exp check: at sync time "now" is
+// irrelevant to whether the token was live when the student scanned it.
+// The time policy is applied separately by the caller:
 //
-//   - The 6-hour exp is enforced at SCAN time by the student's PWA — if the
-//     host's QR page refreshes or the event ends, the new code is different.
-//   - Once a student has a valid signed token stored in IndexedDB, they can
-//     sync it at any point in the future.  Poor connectivity should never
-//     cause a legitimate check-in to be rejected.
+//   - CheckScanTime compares the device-reported scan time with iat/exp.
+//   - The sync handler compares the arrival time with the event's deadline.
 //
-// Security: a forged or tampered token is still rejected because the HMAC
-// signature will not verify without the server secret.
-func ParseCheckInToken(tokenStr, secret string) (*CheckInClaims, error) {
+// Security: a forged or tampered token is still rejected because the Ed25519
+// signature will not verify without the private key.  HS256 tokens are
+// refused outright, so a session token (or anything else signed with a
+// session key) can never pass as a check-in token.
+func ParseCheckInToken(tokenStr string, keys *Keyring) (*CheckInClaims, error) {
 	// jwt.WithoutClaimsValidation skips exp/nbf/iss checks — we only want
 	// the signature verification that happens unconditionally.
 	token, err := jwt.ParseWithClaims(
 		tokenStr,
 		&CheckInClaims{},
 		func(t *jwt.Token) (any, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			if _, ok := t.Method.(*jwt.SigningMethodEd25519); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 			}
-			return []byte(secret), nil
+			kid, _ := t.Header["kid"].(string)
+			key, err := keys.checkInVerifier(kid)
+			if err != nil {
+				return nil, err
+			}
+			return key.Public(), nil
 		},
 		jwt.WithoutClaimsValidation(),
 	)
@@ -159,20 +236,60 @@ func ParseCheckInToken(tokenStr, secret string) (*CheckInClaims, error) {
 	return claims, nil
 }
 
-// GenerateToken creates a signed JWT for the given user.
-// The token is signed with HS256 (HMAC-SHA256) using the server secret.
-// Anyone with the secret can verify the token — keep it out of git!
-func GenerateToken(userID, role, secret string) (string, error) {
+// CheckScanTime reports whether scannedAt lies inside the token's validity
+// window [iat, exp], widened by skew on both sides to absorb the difference
+// between the student's phone clock and the server clock.
+func (c *CheckInClaims) CheckScanTime(scannedAt time.Time, skew time.Duration) error {
+	if c.IssuedAt == nil || c.ExpiresAt == nil {
+		return ErrNoScanWindow
+	}
+	if scannedAt.Before(c.IssuedAt.Add(-skew)) {
+		return ErrScanBeforeIssue
+	}
+	if scannedAt.After(c.ExpiresAt.Add(skew)) {
+		return ErrScanAfterExpiry
+	}
+	return nil
+}
+
+// Rotating reports whether the token is a rotating (time-step) code.
+func (c *CheckInClaims) Rotating() bool {
+	return c.Period > 0
+}
+
+// CheckScanStep reports whether scannedAt falls within tolerance steps of the
+// token's own step.  Tolerance 1 accepts a scan recorded one step early or
+// late, which absorbs small clock differences and a code that changed while
+// the camera was focusing.
+func (c *CheckInClaims) CheckScanStep(scannedAt time.Time, tolerance int64) error {
+	if !c.Rotating() {
+		return ErrNoScanWindow
+	}
+	diff := CheckInStep(scannedAt, time.Duration(c.Period)*time.Second) - c.Step
+	if diff < -tolerance || diff > tolerance {
+		return ErrScanStepMismatch
+	}
+	return nil
+}
+
+// GenerateToken creates a signed access token for the given user and session.
+// The token is signed with HS256 (HMAC-SHA256) using the keyring's primary
+// session key, whose ID goes in the "kid" header.
+// Anyone with the key can verify the token — keep it out of git!
+func GenerateToken(userID, role, sessionID string, keys *Keyring) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Role:   role,
+		UserID:    userID,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenDuration)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
+	kid, secret := keys.sessionSigner()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString([]byte(secret))
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(secret)
 	if err != nil {
 		return "", fmt.Errorf("sign token: %w", err)
 	}
@@ -184,13 +301,15 @@ func GenerateToken(userID, role, secret string) (string, error) {
 //   - wrong or missing signature
 //   - expired tokens (ExpiresAt in the past)
 //   - unexpected signing algorithm (algorithm confusion attack prevention)
-func ParseToken(tokenStr, secret string) (*Claims, error) {
+//   - a kid that is missing, unknown or retired
+func ParseToken(tokenStr string, keys *Keyring) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (any, error) {
 		// Guard against "alg:none" or RS256 tokens being passed to an HS256 server.
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 		}
-		return []byte(secret), nil
+		kid, _ := t.Header["kid"].(string)
+		return keys.sessionVerifier(kid)
 	})
 	if err != nil {
 		return nil, fmt.Errorf("parse token: %w", err)
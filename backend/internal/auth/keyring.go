@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — key rotation with key IDs
+// ────────────────────────────────────────────────────────────────────
+// With a single signing secret, changing it invalidates every token in
+// circulation at once: everybody is logged out and every QR code already on
+// a student's phone stops verifying.  A KEYRING avoids that:
+//
+//   - Every token carries a "kid" (key ID) header naming the key that
+//     signed it.
+//   - Per purpose, exactly one key is PRIMARY: it signs new tokens.
+//   - Any number of keys are ACTIVE: they still verify tokens but no
+//     longer sign.  RETIRED keys do neither.
+//
+// A zero-downtime rotation is therefore:
+//
+//  1. add      — the new key starts as ACTIVE, so every server learns it
+//     before any token signed with it exists;
+//  2. promote  — the new key becomes PRIMARY, the old primary drops to
+//     ACTIVE and keeps verifying the tokens it already signed;
+//  3. retire   — once those tokens have expired, the old key is retired.
+
+// KeyPurpose says what a key signs.  Session and check-in keys are kept
+// apart so one leaked key cannot forge the other kind of token.
+type KeyPurpose string
+
+const (
+	// PurposeSession keys are HS256 secrets for access tokens.
+	PurposeSession KeyPurpose = "session"
+	// PurposeCheckIn keys are Ed25519 seeds for QR check-in tokens.
+	PurposeCheckIn KeyPurpose = "checkin"
+)
+
+// KeyStatus is a key's place in the rotation lifecycle.
+type KeyStatus string
+
+const (
+	KeyPrimary KeyStatus = "primary" // signs and verifies
+	KeyActive  KeyStatus = "active"  // verifies only
+	KeyRetired KeyStatus = "retired" // ignored
+)
+
+// Key is one entry of the keyring.  Material is the raw HMAC secret for
+// session keys and the 32-byte Ed25519 seed for check-in keys.
+type Key struct {
+	ID        string
+	Purpose   KeyPurpose
+	Status    KeyStatus
+	Material  []byte
+	CreatedAt time.Time
+}
+
+// Keyring holds the signing and verification keys for both token kinds.
+// It is safe for concurrent use; Replace swaps the whole set atomically so
+// a running server can pick up keys added by the admin CLI.
+type Keyring struct {
+	mu             sync.RWMutex
+	sessionPrimary string
+	session        map[string][]byte
+	checkInPrimary string
+	checkIn        map[string]*CheckInKey
+}
+
+// ErrUnknownKey is returned when a token's kid is not in the keyring (or
+// the key has been retired).
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// NewKeyring builds a keyring from keys.  It fails unless there is exactly
+// one primary key for each purpose.
+func NewKeyring(keys []Key) (*Keyring, error) {
+	kr := &Keyring{}
+	if err := kr.Replace(keys); err != nil {
+		return nil, err
+	}
+	return kr, nil
+}
+
+// Replace swaps the keyring's contents for keys, with the same validation
+// as NewKeyring.  On error the keyring is left unchanged.
+func (kr *Keyring) Replace(keys []Key) error {
+	session := map[string][]byte{}
+	checkIn := map[string]*CheckInKey{}
+	var sessionPrimary, checkInPrimary string
+
+	for _, k := range keys {
+		if k.Status == KeyRetired {
+			continue
+		}
+		switch k.Purpose {
+		case PurposeSession:
+			if len(k.Material) == 0 {
+				return fmt.Errorf("session key %q has no secret", k.ID)
+			}
+			session[k.ID] = k.Material
+			if k.Status == KeyPrimary {
+				if sessionPrimary != "" {
+					return errors.New("more than one primary session key")
+				}
+				sessionPrimary = k.ID
+			}
+		case PurposeCheckIn:
+			ck, err := CheckInKeyFromSeed(k.Material)
+			if err != nil {
+				return fmt.Errorf("check-in key %q: %w", k.ID, err)
+			}
+			ck.ID = k.ID
+			checkIn[k.ID] = ck
+			if k.Status == KeyPrimary {
+				if checkInPrimary != "" {
+					return errors.New("more than one primary check-in key")
+				}
+				checkInPrimary = k.ID
+			}
+		default:
+			return fmt.Errorf("key %q has unknown purpose %q", k.ID, k.Purpose)
+		}
+	}
+	if sessionPrimary == "" {
+		return errors.New("no primary session key")
+	}
+	if checkInPrimary == "" {
+		return errors.New("no primary check-in key")
+	}
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.session, kr.sessionPrimary = session, sessionPrimary
+	kr.checkIn, kr.checkInPrimary = checkIn, checkInPrimary
+	return nil
+}
+
+// sessionSigner returns the primary session key.
+func (kr *Keyring) sessionSigner() (kid string, secret []byte) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.sessionPrimary, kr.session[kr.sessionPrimary]
+}
+
+// sessionVerifier returns the session secret for kid.
+func (kr *Keyring) sessionVerifier(kid string) ([]byte, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	secret, ok := kr.session[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+	}
+	return secret, nil
+}
+
+// CheckInSigner returns the primary check-in key, which signs new QR tokens.
+func (kr *Keyring) CheckInSigner() *CheckInKey {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.checkIn[kr.checkInPrimary]
+}
+
+// checkInVerifier returns the check-in key for kid.
+func (kr *Keyring) checkInVerifier(kid string) (*CheckInKey, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	k, ok := kr.checkIn[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+	}
+	return k, nil
+}
+
+// JWKS returns the public halves of every non-retired check-in key, primary
+// first, so clients can verify tokens signed by any of them.
+func (kr *Keyring) JWKS() JWKS {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	set := JWKS{Keys: []JWK{kr.checkIn[kr.checkInPrimary].JWK()}}
+	ids := make([]string, 0, len(kr.checkIn))
+	for id := range kr.checkIn {
+		if id != kr.checkInPrimary {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		set.Keys = append(set.Keys, kr.checkIn[id].JWK())
+	}
+	return set
+}
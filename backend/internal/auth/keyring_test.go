@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TestKeyring_RotationKeepsOldTokensValid walks through add → promote →
+// retire and checks which tokens verify at each stage.
+func TestKeyring_RotationKeepsOldTokensValid(t *testing.T) {
+	oldCI, newCI := mustCheckInKey(), mustCheckInKey()
+	keys := []Key{
+		{ID: "s-old", Purpose: PurposeSession, Status: KeyPrimary, Material: []byte("old-secret")},
+		{ID: oldCI.ID, Purpose: PurposeCheckIn, Status: KeyPrimary, Material: oldCI.Seed()},
+	}
+	kr, err := NewKeyring(keys)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	oldSession, _ := GenerateToken("user-1", "student", "session-1", kr)
+	oldCheckIn, _ := GenerateCheckInToken("event-1", "sig", kr.CheckInSigner())
+
+	// add + promote: new keys sign, old keys still verify.
+	keys[0].Status, keys[1].Status = KeyActive, KeyActive
+	keys = append(keys,
+		Key{ID: "s-new", Purpose: PurposeSession, Status: KeyPrimary, Material: []byte("new-secret")},
+		Key{ID: newCI.ID, Purpose: PurposeCheckIn, Status: KeyPrimary, Material: newCI.Seed()},
+	)
+	if err := kr.Replace(keys); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	newSession, _ := GenerateToken("user-1", "student", "session-1", kr)
+	if kid := headerKid(t, newSession); kid != "s-new" {
+		t.Errorf("new session token kid: got %q, want s-new", kid)
+	}
+	if kr.CheckInSigner().ID != newCI.ID {
+		t.Errorf("check-in signer: got %q, want %q", kr.CheckInSigner().ID, newCI.ID)
+	}
+	for name, tok := range map[string]string{"old": oldSession, "new": newSession} {
+		if _, err := ParseToken(tok, kr); err != nil {
+			t.Errorf("%s session token rejected after promote: %v", name, err)
+		}
+	}
+	if _, err := ParseCheckInToken(oldCheckIn, kr); err != nil {
+		t.Errorf("old check-in token rejected after promote: %v", err)
+	}
+
+	// retire: tokens signed by the old keys stop verifying.
+	keys[0].Status, keys[1].Status = KeyRetired, KeyRetired
+	if err := kr.Replace(keys); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	if _, err := ParseToken(oldSession, kr); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("old session token after retire: got %v, want ErrUnknownKey", err)
+	}
+	if _, err := ParseCheckInToken(oldCheckIn, kr); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("old check-in token after retire: got %v, want ErrUnknownKey", err)
+	}
+	if _, err := ParseToken(newSession, kr); err != nil {
+		t.Errorf("new session token rejected after retire: %v", err)
+	}
+}
+
+// TestKeyring_ReplaceValidates verifies that an invalid key set is refused
+// and leaves the keyring as it was.
+func TestKeyring_ReplaceValidates(t *testing.T) {
+	ci := mustCheckInKey()
+	kr := mustKeyring(testSecret, ci)
+
+	cases := map[string][]Key{
+		"no session primary": {
+			{ID: "s1", Purpose: PurposeSession, Status: KeyActive, Material: []byte("x")},
+			{ID: ci.ID, Purpose: PurposeCheckIn, Status: KeyPrimary, Material: ci.Seed()},
+		},
+		"two session primaries": {
+			{ID: "s1", Purpose: PurposeSession, Status: KeyPrimary, Material: []byte("x")},
+			{ID: "s2", Purpose: PurposeSession, Status: KeyPrimary, Material: []byte("y")},
+			{ID: ci.ID, Purpose: PurposeCheckIn, Status: KeyPrimary, Material: ci.Seed()},
+		},
+		"retired primary only": {
+			{ID: "s1", Purpose: PurposeSession, Status: KeyPrimary, Material: []byte("x")},
+			{ID: ci.ID, Purpose: PurposeCheckIn, Status: KeyRetired, Material: ci.Seed()},
+		},
+	}
+	for name, keys := range cases {
+		if err := kr.Replace(keys); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+
+	token, _ := GenerateToken("user-1", "student", "session-1", kr)
+	if kid := headerKid(t, token); kid != "session-1" {
+		t.Errorf("keyring changed by a failed Replace: signer kid %q", kid)
+	}
+}
+
+// TestParseToken_RejectsMissingKid verifies that a token without a kid
+// header — e.g. one issued before key rotation existed — is rejected.
+func TestParseToken_RejectsMissingKid(t *testing.T) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UserID: "user-1"})
+	signed, err := token.SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if _, err := ParseToken(signed, testKeys); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("got %v, want ErrUnknownKey", err)
+	}
+}
+
+// TestKeyring_JWKS verifies that the JWK Set lists the primary check-in key
+// first, includes active keys and leaves out retired ones.
+func TestKeyring_JWKS(t *testing.T) {
+	primary, active, retired := mustCheckInKey(), mustCheckInKey(), mustCheckInKey()
+	kr, err := NewKeyring([]Key{
+		{ID: "s1", Purpose: PurposeSession, Status: KeyPrimary, Material: []byte("x")},
+		{ID: active.ID, Purpose: PurposeCheckIn, Status: KeyActive, Material: active.Seed()},
+		{ID: retired.ID, Purpose: PurposeCheckIn, Status: KeyRetired, Material: retired.Seed()},
+		{ID: primary.ID, Purpose: PurposeCheckIn, Status: KeyPrimary, Material: primary.Seed()},
+	})
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	set := kr.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("keys: got %d, want 2", len(set.Keys))
+	}
+	if set.Keys[0].Kid != primary.ID || set.Keys[1].Kid != active.ID {
+		t.Errorf("kids: got [%s %s], want [%s %s]", set.Keys[0].Kid, set.Keys[1].Kid, primary.ID, active.ID)
+	}
+}
+
+func headerKid(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified: %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — password hashes that can change over time
+// ────────────────────────────────────────────────────────────────────
+// A password hash has to stay slow as hardware gets faster, so the
+// algorithm and its cost must be able to change without asking every user
+// to pick a new password.  That works because each stored hash says how it
+// was made:
+//
+//	$2a$10$…                                bcrypt, cost 10 (the original format)
+//	$argon2id$v=19$m=19456,t=2,p=1$salt$key argon2id, version 19, 19 MiB, 2 passes, 1 lane
+//
+// CheckPassword reads the scheme and parameters from the hash itself, so
+// every hash ever stored keeps verifying.  NeedsRehash compares them with
+// the current PasswordHasher settings; after a successful login the server
+// re-hashes the password it was just given (the only moment it knows it)
+// and stores the new hash.  Raising a cost therefore upgrades each account
+// the next time its owner logs in.
+//
+// argon2id is the default: unlike bcrypt it is memory-hard, so guessing
+// passwords on GPUs costs far more, and it has no 72-byte password limit.
+
+// Password hash algorithms.
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// Defaults for a zero PasswordHasher: the OWASP argon2id baseline
+// (19 MiB, 2 iterations, 1 lane) and bcrypt's own default cost.
+const (
+	defaultArgon2Memory      = 19 * 1024 // KiB
+	defaultArgon2Iterations  = 2
+	defaultArgon2Parallelism = 1
+	defaultBcryptCost        = bcrypt.DefaultCost
+
+	// Upper bounds, so a typo in the configuration cannot make every
+	// login allocate gigabytes or spin for seconds.
+	maxArgon2Memory     = 1 << 20 // 1 GiB in KiB
+	maxArgon2Iterations = 64
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// ErrPasswordMismatch is returned by CheckPassword when the password is
+// wrong or the stored hash is not one it can read (e.g. the empty hash of a
+// single sign-on account).
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// PasswordHasher hashes new passwords with Algorithm and its cost
+// parameters.  The zero value hashes with argon2id at the defaults; zero
+// fields fall back to their defaults too.
+type PasswordHasher struct {
+	// Algorithm is AlgorithmArgon2id or AlgorithmBcrypt.
+	Algorithm string
+	// Argon2Memory is the argon2id memory cost in KiB.
+	Argon2Memory uint32
+	// Argon2Iterations is the argon2id time cost (passes over memory).
+	Argon2Iterations uint32
+	// Argon2Parallelism is the number of argon2id lanes.
+	Argon2Parallelism uint8
+	// BcryptCost is the bcrypt log2 work factor.
+	BcryptCost int
+}
+
+func (h PasswordHasher) algorithm() string {
+	if h.Algorithm == "" {
+		return AlgorithmArgon2id
+	}
+	return h.Algorithm
+}
+
+func (h PasswordHasher) argon2Params() argon2Params {
+	p := argon2Params{
+		memory:      h.Argon2Memory,
+		iterations:  h.Argon2Iterations,
+		parallelism: h.Argon2Parallelism,
+	}
+	if p.memory == 0 {
+		p.memory = defaultArgon2Memory
+	}
+	if p.iterations == 0 {
+		p.iterations = defaultArgon2Iterations
+	}
+	if p.parallelism == 0 {
+		p.parallelism = defaultArgon2Parallelism
+	}
+	return p
+}
+
+func (h PasswordHasher) bcryptCost() int {
+	if h.BcryptCost == 0 {
+		return defaultBcryptCost
+	}
+	return h.BcryptCost
+}
+
+// Validate reports settings that Hash would reject, so a bad configuration
+// fails at start-up rather than at the first registration.
+func (h PasswordHasher) Validate() error {
+	switch h.algorithm() {
+	case AlgorithmArgon2id:
+		p := h.argon2Params()
+		if p.memory < 8*uint32(p.parallelism) || p.memory > maxArgon2Memory {
+			return fmt.Errorf("argon2id memory must be at least 8 KiB per lane and at most %d KiB", maxArgon2Memory)
+		}
+		if p.iterations > maxArgon2Iterations {
+			return fmt.Errorf("argon2id iterations must be at most %d", maxArgon2Iterations)
+		}
+	case AlgorithmBcrypt:
+		if c := h.bcryptCost(); c < bcrypt.MinCost || c > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return fmt.Errorf("unknown password hash algorithm %q (want %s or %s)",
+			h.Algorithm, AlgorithmArgon2id, AlgorithmBcrypt)
+	}
+	return nil
+}
+
+// Hash returns the encoded hash of password, to store in users.password_hash.
+func (h PasswordHasher) Hash(password string) (string, error) {
+	if err := h.Validate(); err != nil {
+		return "", err
+	}
+	if h.algorithm() == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost())
+		return string(hash), err
+	}
+
+	p := h.argon2Params()
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CheckPassword reports whether password matches encoded, whichever
+// supported scheme encoded uses.  It returns nil on a match and
+// ErrPasswordMismatch otherwise.
+func CheckPassword(encoded, password string) error {
+	if isBcrypt(encoded) {
+		if bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) != nil {
+			return ErrPasswordMismatch
+		}
+		return nil
+	}
+	p, salt, key, err := parseArgon2id(encoded)
+	if err != nil {
+		return ErrPasswordMismatch
+	}
+	got := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(got, key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// NeedsRehash reports whether encoded was made with another algorithm or
+// other cost parameters than h would use now.  Hashes it cannot read are
+// reported as not needing a rehash: there is no password to upgrade.
+func (h PasswordHasher) NeedsRehash(encoded string) bool {
+	if isBcrypt(encoded) {
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err == nil && (h.algorithm() != AlgorithmBcrypt || cost != h.bcryptCost())
+	}
+	p, _, key, err := parseArgon2id(encoded)
+	if err != nil {
+		return false
+	}
+	return h.algorithm() != AlgorithmArgon2id || p != h.argon2Params() || len(key) != argon2KeyLength
+}
+
+// argon2Params are argon2id's cost parameters.
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+// parseArgon2id decodes "$argon2id$v=19$m=…,t=…,p=…$salt$key".
+func parseArgon2id(encoded string) (p argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != AlgorithmArgon2id {
+		return p, nil, nil, errors.New("not an argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errors.New("unsupported argon2 version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil ||
+		p.iterations == 0 || p.parallelism == 0 {
+		return p, nil, nil, errors.New("bad argon2id parameters")
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, err
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return p, nil, nil, errors.New("bad argon2id key")
+	}
+	return p, salt, key, nil
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap keeps the tests fast; the parameters are still encoded and checked.
+var cheap = PasswordHasher{Argon2Memory: 64, Argon2Iterations: 1}
+
+func TestPasswordHasher_Argon2id(t *testing.T) {
+	hash, err := cheap.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("unexpected encoding %s", hash)
+	}
+	if err := CheckPassword(hash, "correct horse"); err != nil {
+		t.Errorf("right password rejected: %v", err)
+	}
+	if err := CheckPassword(hash, "battery staple"); err != ErrPasswordMismatch {
+		t.Errorf("wrong password: got %v", err)
+	}
+	if again, _ := cheap.Hash("correct horse"); again == hash {
+		t.Error("two hashes of one password are equal: salt not random")
+	}
+
+	if cheap.NeedsRehash(hash) {
+		t.Error("fresh hash needs a rehash")
+	}
+	stronger := cheap
+	stronger.Argon2Iterations = 2
+	if !stronger.NeedsRehash(hash) {
+		t.Error("raised iterations not detected")
+	}
+	if !(PasswordHasher{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}).NeedsRehash(hash) {
+		t.Error("algorithm change not detected")
+	}
+}
+
+func TestPasswordHasher_Bcrypt(t *testing.T) {
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err := CheckPassword(string(legacy), "correct horse"); err != nil {
+		t.Errorf("existing bcrypt hash rejected: %v", err)
+	}
+	if err := CheckPassword(string(legacy), "nope"); err != ErrPasswordMismatch {
+		t.Errorf("wrong password: got %v", err)
+	}
+	if !cheap.NeedsRehash(string(legacy)) {
+		t.Error("bcrypt hash not upgraded to argon2id")
+	}
+
+	h := PasswordHasher{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}
+	if h.NeedsRehash(string(legacy)) {
+		t.Error("bcrypt hash at the configured cost needs a rehash")
+	}
+	if !(PasswordHasher{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost + 1}).NeedsRehash(string(legacy)) {
+		t.Error("raised bcrypt cost not detected")
+	}
+}
+
+func TestCheckPassword_Unreadable(t *testing.T) {
+	for _, hash := range []string{
+		"", // single sign-on accounts have no password
+		"plaintext",
+		"$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA$",
+	} {
+		if err := CheckPassword(hash, ""); err != ErrPasswordMismatch {
+			t.Errorf("%q: got %v", hash, err)
+		}
+		if cheap.NeedsRehash(hash) {
+			t.Errorf("%q: unreadable hash reported as needing a rehash", hash)
+		}
+	}
+	if err := (PasswordHasher{Algorithm: "md5"}).Validate(); err == nil {
+		t.Error("unknown algorithm accepted")
+	}
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key from RFC 6238 Appendix B.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; a 6-digit code is their last six digits.
+	for _, tc := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	} {
+		got, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(tc.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode: %v", err)
+		}
+		if got != tc.want {
+			t.Errorf("T=%d: got %s, want %s", tc.unix, got, tc.want)
+		}
+	}
+}
+
+func TestVerifyTOTP_Skew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	prev, _ := TOTPCode(rfcSecret, TOTPStep(now)-1)
+	old, _ := TOTPCode(rfcSecret, TOTPStep(now)-3)
+
+	if step, ok := VerifyTOTP(rfcSecret, prev[:3]+" "+prev[3:], now, 1); !ok || step != TOTPStep(now)-1 {
+		t.Errorf("previous step: ok=%v step=%d", ok, step)
+	}
+	if _, ok := VerifyTOTP(rfcSecret, old, now, 1); ok {
+		t.Error("code three steps old accepted")
+	}
+	if _, ok := VerifyTOTP(rfcSecret, "12345", now, 1); ok {
+		t.Error("short code accepted")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := TOTPURI("Skillzone", "host@techcorp.test", secret)
+	for _, want := range []string{"otpauth://totp/Skillzone:host@techcorp.test?", "secret=" + secret, "issuer=Skillzone", "digits=6", "period=30"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("URI %q missing %q", uri, want)
+		}
+	}
+}
@@ -19,6 +19,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -32,9 +33,19 @@ import (
 // Open opens (or creates) the SQLite database at dsn and runs all migrations.
 //
 // Recommended DSN formats for modernc.org/sqlite:
-//   - Production file: "skillzone.db?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+//   - Production file: "skillzone.db?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate"
 //   - Tests:           "file:testXYZ?mode=memory&cache=shared&_foreign_keys=on"
 //
+// A DSN without _txlock gets _txlock=immediate (see withImmediateTxLock).
+//
+// LEARNING NOTE — timestamps are compared in Go, not in SQL
+// The driver writes a time.Time as text in Go's own layout ("2006-01-02
+// 15:04:05.999999999 -0700 MST"), with as many fractional digits as the
+// value happens to have.  Two such strings do not sort in time order, so
+// `WHERE expires_at > ?` or `ORDER BY created_at` can be silently wrong.
+// Queries therefore select the timestamp and compare (or sort) the scanned
+// time.Time values in Go; rowid gives insertion order where that is needed.
+//
 // LEARNING NOTE — DSN (Data Source Name)
 // A DSN is just a connection string. For SQLite it's the file path plus
 // optional URI query parameters that configure pragma settings. Using
@@ -45,6 +56,7 @@ func Open(dsn string) (*sql.DB, error) {
 	// sql.Open does NOT open a real connection yet — it just validates
 	// the driver name and stores the DSN. The first real connection is
 	// made lazily on the first query (or explicitly via db.Ping()).
+	dsn = withImmediateTxLock(dsn)
 	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("open db: %w", err)
@@ -59,6 +71,34 @@ func Open(dsn string) (*sql.DB, error) {
 	return db, nil
 }
 
+// withImmediateTxLock adds _txlock=immediate to dsn unless it already sets
+// _txlock.
+//
+// LEARNING NOTE — why every transaction takes the write lock at BEGIN
+// By default SQLite starts a transaction as a reader and upgrades it at the
+// first write.  If another connection wrote in between, the upgrade fails at
+// once with SQLITE_BUSY — busy_timeout does not help, since waiting could
+// never succeed.  Handlers such as claimSeat read and then write inside one
+// transaction, so under load they would fail instead of queueing.  Taking
+// the lock at BEGIN makes concurrent transactions wait their turn.  It is
+// added here, not left to the DSN, so a hand-written DATABASE_URL cannot
+// quietly drop it.
+func withImmediateTxLock(dsn string) string {
+	_, query, _ := strings.Cut(dsn, "?")
+	for _, param := range strings.Split(query, "&") {
+		if strings.HasPrefix(param, "_txlock=") {
+			return dsn
+		}
+	}
+	if query == "" && !strings.HasSuffix(dsn, "?") {
+		return dsn + "?_txlock=immediate"
+	}
+	if query == "" {
+		return dsn + "_txlock=immediate"
+	}
+	return dsn + "&_txlock=immediate"
+}
+
 // migrate runs each DDL statement in the schema individually.
 //
 // LEARNING NOTE — why not one big Exec(schema)?
@@ -77,28 +117,238 @@ func migrate(db *sql.DB) error {
 			return fmt.Errorf("migration statement failed: %w\nstatement: %s", err, stmt)
 		}
 	}
+	if err := addColumns(db); err != nil {
+		return err
+	}
+	if err := rewriteConstraints(db); err != nil {
+		return err
+	}
+	return AssignEventOrganisations(context.Background(), db)
+}
+
+// AssignEventOrganisations gives every event without an organisation the
+// personal organisation of its host: "org-<host id>", named after the host,
+// with the host as its owner.  Events from before organisations existed
+// get one this way at start-up; the demo seeder uses it too.  A no-op once
+// every event has an organisation.
+func AssignEventOrganisations(ctx context.Context, db *sql.DB) error {
+	for _, stmt := range []string{
+		`INSERT OR IGNORE INTO organisations (id, name, created_at)
+		 SELECT 'org-' || id, name, created_at FROM users
+		 WHERE id IN (SELECT host_id FROM events WHERE organisation_id IS NULL)`,
+		`INSERT OR IGNORE INTO organisation_members (organisation_id, user_id, role, created_at)
+		 SELECT 'org-' || id, id, 'owner', created_at FROM users
+		 WHERE id IN (SELECT host_id FROM events WHERE organisation_id IS NULL)`,
+		`UPDATE events SET organisation_id = 'org-' || host_id WHERE organisation_id IS NULL`,
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("assign event organisations: %w", err)
+		}
+	}
+	return nil
+}
+
+// addedColumns lists columns introduced after their table first shipped.
+//
+// LEARNING NOTE — why a second list?
+// CREATE TABLE IF NOT EXISTS does nothing when the table already exists, so
+// a database file created by an older build would never gain a new column.
+// Every column here is ALSO in the CREATE TABLE statement below (so fresh
+// databases get it in one go); addColumns only runs ALTER TABLE for files
+// where it is missing.  SQLite's ALTER TABLE ADD COLUMN cannot add UNIQUE
+// columns or non-constant defaults, so keep definitions simple.
+var addedColumns = []struct{ table, column, definition string }{
+	{"events", "sync_deadline", "DATETIME"},
+	{"events", "checkin_rotation_seconds", "INTEGER"},
+	{"signing_keys", "status", "TEXT NOT NULL DEFAULT 'active'"},
+	{"signing_keys", "retired_at", "DATETIME"},
+	{"users", "suspended_at", "DATETIME"},
+	{"users", "status", "TEXT NOT NULL DEFAULT 'active' CHECK(status IN ('active','suspended','deleted'))"},
+	{"users", "totp_secret", "TEXT"},
+	{"users", "totp_enabled_at", "DATETIME"},
+	{"users", "totp_last_step", "INTEGER NOT NULL DEFAULT 0"},
+	{"users", "email_verified_at", "DATETIME"},
+	{"users", "institution_id", "TEXT REFERENCES institutions(id) ON DELETE SET NULL"},
+	{"events", "organisation_id", "TEXT REFERENCES organisations(id)"},
+	{"registrations", "offer_expires_at", "DATETIME"},
+	{"events", "cancellation_reason", "TEXT NOT NULL DEFAULT ''"},
+	{"events", "cancelled_at", "DATETIME"},
+	{"events", "deleted_at", "DATETIME"},
+	{"events", "manual_status", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// columnBackfills holds a statement to run right after a column from
+// addedColumns is added to an existing table ("table.column" → SQL), for
+// columns whose initial value derives from older data.
+var columnBackfills = map[string]string{
+	// Before users.status existed, suspended_at alone marked a suspension.
+	"users.status": `UPDATE users SET status = 'suspended' WHERE suspended_at IS NOT NULL`,
+	// Accounts from before email verification keep working as they did.
+	"users.email_verified_at": `UPDATE users SET email_verified_at = created_at`,
+}
+
+// addColumns applies addedColumns, skipping any that already exist.
+func addColumns(db *sql.DB) error {
+	for _, c := range addedColumns {
+		var n int
+		err := db.QueryRow(
+			`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, c.table, c.column,
+		).Scan(&n)
+		if err != nil {
+			return fmt.Errorf("inspect %s.%s: %w", c.table, c.column, err)
+		}
+		if n > 0 {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition)
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("migration statement failed: %w\nstatement: %s", err, stmt)
+		}
+		if backfill, ok := columnBackfills[c.table+"."+c.column]; ok {
+			if _, err := db.Exec(backfill); err != nil {
+				return fmt.Errorf("migration statement failed: %w\nstatement: %s", err, backfill)
+			}
+		}
+	}
+	return nil
+}
+
+// rewrittenConstraints lists CHECK constraints changed after their table
+// first shipped: in table, the text from becomes to.
+//
+// LEARNING NOTE — changing a constraint in SQLite
+// SQLite's ALTER TABLE cannot touch constraints, and addColumns only helps
+// with new columns.  The documented way out is to REBUILD the table: create
+// a copy with the new definition, copy the rows over, drop the original and
+// rename the copy.  Foreign keys must be off while the original is dropped,
+// otherwise ON DELETE CASCADE would empty every table that references it.
+var rewrittenConstraints = []struct{ table, from, to string }{
+	{"users", "CHECK(role IN ('student','company'))", "CHECK(role IN ('student','company','admin'))"},
+	{"registrations", "CHECK(status IN ('confirmed','conflict_pending','waitlisted'))",
+		"CHECK(status IN ('confirmed','conflict_pending','waitlisted','offered'))"},
+	{"events", "CHECK(status IN ('upcoming','active','completed'))",
+		"CHECK(status IN ('upcoming','active','completed','cancelled'))"},
+}
+
+// rewriteConstraints applies rewrittenConstraints to tables whose stored
+// definition still contains the old text.
+func rewriteConstraints(db *sql.DB) error {
+	for _, c := range rewrittenConstraints {
+		var ddl string
+		err := db.QueryRow(
+			`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, c.table,
+		).Scan(&ddl)
+		if err != nil {
+			return fmt.Errorf("inspect %s: %w", c.table, err)
+		}
+		if !strings.Contains(ddl, c.from) {
+			continue
+		}
+		if err := rebuildTable(db, c.table, strings.Replace(ddl, c.from, c.to, 1)); err != nil {
+			return fmt.Errorf("rebuild %s: %w", c.table, err)
+		}
+	}
 	return nil
 }
 
+// rebuildTable replaces table with one created by ddl (a CREATE TABLE
+// statement for the same table and columns), keeping every row.
+func rebuildTable(db *sql.DB, table, ddl string) error {
+	ctx := context.Background()
+	// PRAGMA foreign_keys is per connection and ignored inside a
+	// transaction, so pin one connection for the whole rebuild.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`) //nolint:errcheck
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	tmp := table + "_rebuild"
+	// sqlite_master stores the statement as "CREATE TABLE <name> (...)",
+	// possibly with the name quoted; swap in the temporary name.
+	open := strings.Index(ddl, "(")
+	if open < 0 {
+		return fmt.Errorf("unexpected definition: %s", ddl)
+	}
+	stmts := []string{
+		"CREATE TABLE " + tmp + " " + ddl[open:],
+		"INSERT INTO " + tmp + " SELECT * FROM " + table,
+		"DROP TABLE " + table,
+		"ALTER TABLE " + tmp + " RENAME TO " + table,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%w\nstatement: %s", err, stmt)
+		}
+	}
+	return tx.Commit()
+}
+
 // schema contains every CREATE TABLE statement for the application.
 //
 // LEARNING NOTE — schema design choices
 //
-//	users          — single table for both students and companies; the
+//	users          — single table for students, companies and admins; the
 //	                 "role" column distinguishes them. Simpler than two
-//	                 separate tables for a project this size.
+//	                 separate tables for a project this size. status is
+//	                 active | suspended | deleted (soft delete) and only
+//	                 changed by admins; suspended_at records when the
+//	                 current suspension began. totp_secret is set while
+//	                 2FA is being set up or on; totp_enabled_at marks it
+//	                 on, and totp_last_step stops a code being reused.
+//	                 institution_id is a student's affiliation, derived
+//	                 from their verified email (see institutions).
+//
+//	institutions   — universities and colleges. institution_domains holds
+//	                 the email domains an admin has checked belong to each
+//	                 one; a domain also covers its subdomains.
 //
 //	skills         — a global catalogue of skill badges. Companies pick
 //	                 from here when creating events.
 //
+//	organisations  — the companies that host events. organisation_members
+//	                 says who works for each one and as what: owner (also
+//	                 manages owners), admin (manages events and staff) or
+//	                 staff (runs events: check-in, registrations).
+//	                 organisation_invitations are emailed invites to join,
+//	                 with the same token handling as password_resets.
+//
 //	events         — hosted by a company. Stores a check_in_code (a
 //	                 random UUID) that is the shared secret embedded in
-//	                 the QR code shown at check-in.
+//	                 the QR code shown at check-in. sync_deadline (NULL =
+//	                 server default after end_time) closes offline sync.
+//	                 checkin_rotation_seconds (NULL = static code) turns on
+//	                 rotating QR codes that change every N seconds.
+//	                 organisation_id is who may manage the event; host_id
+//	                 is only the member who created it.  A cancelled
+//	                 event keeps its row, with cancellation_reason and
+//	                 cancelled_at; deleted_at hides it everywhere but its
+//	                 registrants' own lists (soft delete).  Unless
+//	                 manual_status is set, the status scheduler moves it
+//	                 to active and completed on time.
 //
 //	event_skills   — many-to-many join: one event can award many skills.
 //
+//	event_roles    — per-event jobs the organisation hands to someone
+//	                 outside it, e.g. a volunteer: scanner (shows the
+//	                 check-in QR) or coordinator (also sees registrations).
+//
 //	registrations  — a student's intent to attend an event. Created
 //	                 online; the UNIQUE constraint prevents duplicates.
+//	                 When a seat frees up the longest-waiting waitlisted
+//	                 or conflict_pending one moves up, to confirmed or to
+//	                 offered: the seat is held until offer_expires_at.
 //
 //	attendances    — the offline check-in proof. The student stores this
 //	                 locally and syncs it later. UNIQUE(event_id,student_id)
@@ -106,17 +356,90 @@ func migrate(db *sql.DB) error {
 //
 //	user_skills    — the awarded badge. Written when attendance is verified.
 //	                 UNIQUE(user_id,skill_id,event_id) makes award idempotent.
+//
+//	sessions       — one row per login (device). Holds the SHA-256 of the
+//	                 current refresh token; revoked_at set on logout.
+//
+//	password_resets — one row per "forgot password" email. Holds only the
+//	                 SHA-256 of the emailed token; used_at makes it
+//	                 single-use and expires_at bounds how long it works.
+//
+//	magic_links    — emailed one-click student logins. Same token handling
+//	                 as password_resets, plus the email the link was sent
+//	                 to, which must still be the account's when it is used.
+//
+//	email_verifications — emailed "is this your address?" links sent at
+//	                 sign-up. Same token handling as magic_links; opening
+//	                 one sets users.email_verified_at.
+//
+//	email_changes  — a requested new address, applied only when the link
+//	                 sent to it is opened. Same token handling as
+//	                 password_resets.
+//
+//	login_attempts — audit trail of failed logins (email as typed, IP,
+//	                 reason). user_id is not a foreign key: unknown
+//	                 emails are recorded too.
+//
+//	login_throttle — failure counters and lockouts, keyed "account:<email>"
+//	                 or "ip:<address>".
+//
+//	recovery_codes — one-time 2FA fallback codes, stored as SHA-256.
+//
+//	login_challenges — the half-finished login between password and 2FA
+//	                 code: a hashed token, an expiry and an attempt count.
+//
+//	settings       — small key/value store for admin-editable policy
+//	                 (e.g. which roles must use 2FA).
+//
+//	user_identities — single sign-on links: which account an identity
+//	                 provider's (provider, subject) pair logs in to.
+//
+//	oidc_logins    — one row per SSO attempt: the state, nonce and PKCE
+//	                 verifier from the redirect to the provider, then the
+//	                 short-lived handoff token the frontend swaps for a
+//	                 session.
+//
+//	api_keys       — long-lived credentials companies give their scripts.
+//	                 Holds the SHA-256 of the key, a short display prefix,
+//	                 the space-separated scopes, and when and from where
+//	                 it was last used. revoked_at set on revoke.
+//
+//	signing_keys   — the keyring: secrets the server signs tokens with.
+//	                 purpose 'session' rows hold HS256 secrets, 'checkin'
+//	                 rows the 32-byte Ed25519 seed for QR tokens. status is
+//	                 primary (signs) | active (verifies) | retired. Treat a
+//	                 copy of the database file as a copy of these keys.
 const schema = `
 CREATE TABLE IF NOT EXISTS users (
     id            TEXT PRIMARY KEY,
     email         TEXT NOT NULL UNIQUE,
     password_hash TEXT NOT NULL,
     name          TEXT NOT NULL,
-    role          TEXT NOT NULL CHECK(role IN ('student','company')),
+    role          TEXT NOT NULL CHECK(role IN ('student','company','admin')),
     created_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    updated_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+    updated_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    suspended_at  DATETIME,
+    status        TEXT NOT NULL DEFAULT 'active' CHECK(status IN ('active','suspended','deleted')),
+    totp_secret     TEXT,
+    totp_enabled_at DATETIME,
+    totp_last_step  INTEGER NOT NULL DEFAULT 0,
+    email_verified_at DATETIME,
+    institution_id  TEXT REFERENCES institutions(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS institutions (
+    id         TEXT PRIMARY KEY,
+    name       TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS institution_domains (
+    domain         TEXT PRIMARY KEY,
+    institution_id TEXT NOT NULL REFERENCES institutions(id) ON DELETE CASCADE
 );
 
+CREATE INDEX IF NOT EXISTS idx_institution_domains_institution ON institution_domains(institution_id);
+
 CREATE TABLE IF NOT EXISTS skills (
     id          TEXT PRIMARY KEY,
     name        TEXT NOT NULL UNIQUE,
@@ -124,6 +447,37 @@ CREATE TABLE IF NOT EXISTS skills (
     created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE TABLE IF NOT EXISTS organisations (
+    id         TEXT PRIMARY KEY,
+    name       TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS organisation_members (
+    organisation_id TEXT NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    user_id         TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role            TEXT NOT NULL CHECK(role IN ('owner','admin','staff')),
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organisation_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organisation_members_user ON organisation_members(user_id);
+
+CREATE TABLE IF NOT EXISTS organisation_invitations (
+    id              TEXT PRIMARY KEY,
+    organisation_id TEXT NOT NULL REFERENCES organisations(id) ON DELETE CASCADE,
+    email           TEXT NOT NULL,
+    role            TEXT NOT NULL CHECK(role IN ('owner','admin','staff')),
+    invited_by      TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash      TEXT NOT NULL UNIQUE,
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at      DATETIME NOT NULL,
+    accepted_at     DATETIME,
+    revoked_at      DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_organisation_invitations_org ON organisation_invitations(organisation_id);
+
 CREATE TABLE IF NOT EXISTS events (
     id            TEXT PRIMARY KEY,
     host_id       TEXT NOT NULL REFERENCES users(id),
@@ -133,12 +487,19 @@ CREATE TABLE IF NOT EXISTS events (
     start_time    DATETIME NOT NULL,
     end_time      DATETIME NOT NULL,
     status        TEXT NOT NULL DEFAULT 'upcoming'
-                      CHECK(status IN ('upcoming','active','completed')),
+                      CHECK(status IN ('upcoming','active','completed','cancelled')),
     check_in_code TEXT NOT NULL DEFAULT '',
     capacity         INTEGER,
     slots_remaining  INTEGER,
+    sync_deadline    DATETIME,
+    checkin_rotation_seconds INTEGER,
     created_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    updated_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+    updated_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    organisation_id TEXT REFERENCES organisations(id),
+    cancellation_reason TEXT NOT NULL DEFAULT '',
+    cancelled_at  DATETIME,
+    deleted_at    DATETIME,
+    manual_status INTEGER NOT NULL DEFAULT 0
 );
 
 CREATE TABLE IF NOT EXISTS event_skills (
@@ -147,13 +508,25 @@ CREATE TABLE IF NOT EXISTS event_skills (
     PRIMARY KEY (event_id, skill_id)
 );
 
+CREATE TABLE IF NOT EXISTS event_roles (
+    event_id   TEXT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role       TEXT NOT NULL CHECK(role IN ('scanner','coordinator')),
+    granted_by TEXT NOT NULL REFERENCES users(id),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (event_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_roles_user ON event_roles(user_id);
+
 CREATE TABLE IF NOT EXISTS registrations (
     id            TEXT PRIMARY KEY,
     event_id      TEXT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
     student_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     registered_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     status        TEXT NOT NULL DEFAULT 'confirmed'
-                      CHECK(status IN ('confirmed','conflict_pending','waitlisted')),
+                      CHECK(status IN ('confirmed','conflict_pending','waitlisted','offered')),
+    offer_expires_at DATETIME,
     UNIQUE (event_id, student_id)
 );
 
@@ -177,4 +550,163 @@ CREATE TABLE IF NOT EXISTS user_skills (
     awarded_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     UNIQUE (user_id, skill_id, event_id)
 );
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id           TEXT PRIMARY KEY,
+    user_id      TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash   TEXT NOT NULL,
+    user_agent   TEXT NOT NULL DEFAULT '',
+    ip           TEXT NOT NULL DEFAULT '',
+    created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at   DATETIME NOT NULL,
+    revoked_at   DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    used_at    DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_resets_user ON password_resets(user_id);
+
+CREATE TABLE IF NOT EXISTS magic_links (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email      TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    used_at    DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_magic_links_user ON magic_links(user_id);
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email      TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    used_at    DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user ON email_verifications(user_id);
+
+CREATE TABLE IF NOT EXISTS email_changes (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    new_email  TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    used_at    DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_changes_user ON email_changes(user_id);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id         TEXT PRIMARY KEY,
+    email      TEXT NOT NULL,
+    user_id    TEXT,
+    ip         TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    reason     TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_email ON login_attempts(email);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip ON login_attempts(ip);
+
+CREATE TABLE IF NOT EXISTS login_throttle (
+    key             TEXT PRIMARY KEY,
+    failures        INTEGER NOT NULL DEFAULT 0,
+    last_failure_at DATETIME NOT NULL,
+    locked_until    DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash  TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at    DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user ON recovery_codes(user_id);
+
+CREATE TABLE IF NOT EXISTS login_challenges (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    attempts   INTEGER NOT NULL DEFAULT 0,
+    used_at    DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS settings (
+    key        TEXT PRIMARY KEY,
+    value      TEXT NOT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id            TEXT PRIMARY KEY,
+    user_id       TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider      TEXT NOT NULL,
+    subject       TEXT NOT NULL,
+    email         TEXT NOT NULL DEFAULT '',
+    created_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login_at DATETIME,
+    UNIQUE(provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities(user_id);
+
+CREATE TABLE IF NOT EXISTS oidc_logins (
+    id            TEXT PRIMARY KEY,
+    provider      TEXT NOT NULL,
+    state_hash    TEXT NOT NULL UNIQUE,
+    nonce         TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    created_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at    DATETIME NOT NULL,
+    callback_at   DATETIME,
+    user_id       TEXT REFERENCES users(id) ON DELETE CASCADE,
+    handoff_hash  TEXT UNIQUE,
+    used_at       DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           TEXT PRIMARY KEY,
+    user_id      TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name         TEXT NOT NULL,
+    prefix       TEXT NOT NULL,
+    key_hash     TEXT NOT NULL UNIQUE,
+    scopes       TEXT NOT NULL,
+    created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at   DATETIME,
+    last_used_at DATETIME,
+    last_used_ip TEXT NOT NULL DEFAULT '',
+    revoked_at   DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id);
+
+CREATE TABLE IF NOT EXISTS signing_keys (
+    id          TEXT PRIMARY KEY,
+    purpose     TEXT NOT NULL,
+    private_key BLOB NOT NULL,
+    status      TEXT NOT NULL DEFAULT 'active' CHECK(status IN ('primary','active','retired')),
+    created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    retired_at  DATETIME
+);
 `
@@ -58,3 +58,216 @@ func TestOpenInMemory(t *testing.T) {
 		t.Fatal("expected non-nil db")
 	}
 }
+
+// TestOpen_AddsColumnsToOlderFile verifies that a database file created
+// before a column existed picks it up on the next Open.
+func TestOpen_AddsColumnsToOlderFile(t *testing.T) {
+	path := t.TempDir() + "/old.db"
+
+	old, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	// An events table as shipped before sync_deadline was added.
+	if _, err := old.Exec(`CREATE TABLE events (id TEXT PRIMARY KEY, host_id TEXT NOT NULL, title TEXT NOT NULL)`); err != nil {
+		t.Fatalf("create old table: %v", err)
+	}
+	old.Close()
+
+	d, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer d.Close()
+
+	for _, c := range addedColumns {
+		if c.table != "events" {
+			continue
+		}
+		var n int
+		d.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('events') WHERE name = ?`, c.column).Scan(&n)
+		if n != 1 {
+			t.Errorf("column events.%s missing after Open", c.column)
+		}
+	}
+}
+
+// TestOpen_RebuildsOldRoleCheck verifies that a users table created before
+// the admin role existed is rebuilt to accept it, without losing its rows
+// or cascading into tables that reference it.
+func TestOpen_RebuildsOldRoleCheck(t *testing.T) {
+	path := t.TempDir() + "/old.db"
+
+	old, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE users (
+		    id            TEXT PRIMARY KEY,
+		    email         TEXT NOT NULL UNIQUE,
+		    password_hash TEXT NOT NULL,
+		    name          TEXT NOT NULL,
+		    role          TEXT NOT NULL CHECK(role IN ('student','company')),
+		    created_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    updated_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE sessions (
+		    id         TEXT PRIMARY KEY,
+		    user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		    token_hash TEXT NOT NULL,
+		    expires_at DATETIME NOT NULL
+		)`,
+		`INSERT INTO users (id, email, password_hash, name, role) VALUES ('u1', 'a@b.c', 'x', 'A', 'student')`,
+		`INSERT INTO sessions (id, user_id, token_hash, expires_at) VALUES ('s1', 'u1', 'h', '2030-01-01')`,
+	} {
+		if _, err := old.Exec(stmt); err != nil {
+			t.Fatalf("prepare old file: %v", err)
+		}
+	}
+	old.Close()
+
+	d, err := Open(path + "?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer d.Close()
+
+	if _, err := d.Exec(`INSERT INTO users (id, email, password_hash, name, role) VALUES ('u2', 'admin@b.c', 'x', 'Admin', 'admin')`); err != nil {
+		t.Errorf("admin role still rejected: %v", err)
+	}
+	var users, sessions int
+	d.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&users)
+	d.QueryRow(`SELECT COUNT(*) FROM sessions`).Scan(&sessions)
+	if users != 2 || sessions != 1 {
+		t.Errorf("rows after rebuild: users=%d sessions=%d, want 2 and 1", users, sessions)
+	}
+	if _, err := d.Exec(`INSERT INTO sessions (id, user_id, token_hash, expires_at) VALUES ('s2', 'nobody', 'h', '2030-01-01')`); err == nil {
+		t.Error("foreign keys not enforced after rebuild")
+	}
+}
+
+// TestOpen_BackfillsUserStatus verifies that accounts suspended before the
+// status column existed come out of the migration as 'suspended', and that
+// existing accounts are treated as having a verified email.
+func TestOpen_BackfillsUserStatus(t *testing.T) {
+	path := t.TempDir() + "/old.db"
+
+	old, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE users (
+		    id            TEXT PRIMARY KEY,
+		    email         TEXT NOT NULL UNIQUE,
+		    password_hash TEXT NOT NULL,
+		    name          TEXT NOT NULL,
+		    role          TEXT NOT NULL CHECK(role IN ('student','company','admin')),
+		    created_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    updated_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    suspended_at  DATETIME
+		)`,
+		`INSERT INTO users (id, email, password_hash, name, role) VALUES ('u1', 'a@b.c', 'x', 'A', 'student')`,
+		`INSERT INTO users (id, email, password_hash, name, role, suspended_at) VALUES ('u2', 'b@b.c', 'x', 'B', 'student', '2025-01-01')`,
+	} {
+		if _, err := old.Exec(stmt); err != nil {
+			t.Fatalf("prepare old file: %v", err)
+		}
+	}
+	old.Close()
+
+	d, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer d.Close()
+
+	for id, want := range map[string]string{"u1": "active", "u2": "suspended"} {
+		var got string
+		if err := d.QueryRow(`SELECT status FROM users WHERE id = ?`, id).Scan(&got); err != nil {
+			t.Fatalf("read status: %v", err)
+		}
+		if got != want {
+			t.Errorf("%s: status %q, want %q", id, got, want)
+		}
+	}
+	// Accounts from before email verification count as verified.
+	var unverified int
+	if err := d.QueryRow(`SELECT COUNT(*) FROM users WHERE email_verified_at IS NULL`).Scan(&unverified); err != nil {
+		t.Fatalf("read email_verified_at: %v", err)
+	}
+	if unverified != 0 {
+		t.Errorf("%d existing accounts left unverified", unverified)
+	}
+}
+
+// TestOpen_AssignsEventOrganisations verifies that events from before
+// organisations existed end up in their host's own organisation, owned by
+// the host, and that running the assignment again changes nothing.
+func TestOpen_AssignsEventOrganisations(t *testing.T) {
+	d, err := Open(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer d.Close()
+
+	for _, stmt := range []string{
+		`INSERT INTO users (id, email, password_hash, name, role) VALUES ('c1', 'hr@acme.test', 'x', 'Acme', 'company')`,
+		`INSERT INTO events (id, host_id, title, start_time, end_time, check_in_code)
+		 VALUES ('e1', 'c1', 'Fair', '2025-01-01', '2025-01-02', 'x'), ('e2', 'c1', 'Expo', '2025-01-01', '2025-01-02', 'y')`,
+	} {
+		if _, err := d.Exec(stmt); err != nil {
+			t.Fatalf("prepare: %v", err)
+		}
+	}
+	for range 2 {
+		if err := AssignEventOrganisations(t.Context(), d); err != nil {
+			t.Fatalf("AssignEventOrganisations: %v", err)
+		}
+	}
+
+	var orgs, assigned int
+	var name, role string
+	d.QueryRow(`SELECT COUNT(*) FROM organisations`).Scan(&orgs)
+	d.QueryRow(`SELECT COUNT(*) FROM events WHERE organisation_id = 'org-c1'`).Scan(&assigned)
+	d.QueryRow(`SELECT o.name, m.role FROM organisations o
+	            JOIN organisation_members m ON m.organisation_id = o.id AND m.user_id = 'c1'`).Scan(&name, &role)
+	if orgs != 1 || assigned != 2 || name != "Acme" || role != "owner" {
+		t.Errorf("got %d organisations, %d events assigned, %q owned by c1 as %q", orgs, assigned, name, role)
+	}
+}
+
+// TestOpen_TransactionsTakeWriteLock opens a DSN without _txlock and checks
+// that a transaction which has not written yet already keeps other writers
+// out, as it only does when BEGIN is IMMEDIATE.
+func TestOpen_TransactionsTakeWriteLock(t *testing.T) {
+	db, err := Open(t.TempDir() + "/test.db?_pragma=journal_mode(WAL)")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+	if _, err := db.Exec(`INSERT INTO skills (id, name) VALUES ('s1', 'Go')`); err == nil {
+		t.Error("another connection wrote while a transaction was open: BEGIN was not IMMEDIATE")
+	}
+}
+
+func TestWithImmediateTxLock(t *testing.T) {
+	for dsn, want := range map[string]string{
+		"skillzone.db":  "skillzone.db?_txlock=immediate",
+		"skillzone.db?": "skillzone.db?_txlock=immediate",
+		"skillzone.db?_pragma=busy_timeout(5000)":     "skillzone.db?_pragma=busy_timeout(5000)&_txlock=immediate",
+		"skillzone.db?_txlock=immediate":              "skillzone.db?_txlock=immediate",
+		"skillzone.db?_pragma=x(1)&_txlock=exclusive": "skillzone.db?_pragma=x(1)&_txlock=exclusive",
+	} {
+		if got := withImmediateTxLock(dsn); got != want {
+			t.Errorf("withImmediateTxLock(%q) = %q, want %q", dsn, got, want)
+		}
+	}
+}
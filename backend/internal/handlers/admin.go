@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — the admin API
+// ────────────────────────────────────────────────────────────────────
+// Everything under /api/admin/ is mounted behind RequireRole("admin") in
+// main.go, so the handlers here never check the role themselves.  Nobody
+// can register as an admin: the first one is created from the command line
+// (`server admin create`) or from ADMIN_EMAIL/ADMIN_PASSWORD at start-up,
+// and further admins are promoted by an existing one.
+//
+// Role and status changes take effect on the user's very next request:
+// Authenticate reads both from the database (through a short cache that
+// these handlers evict, see authcache.go) instead of trusting the token.
+
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
+)
+
+// ListUsers handles GET /api/admin/users  (admin only)
+//
+// Query parameters, all optional:
+//
+//	role=student|company|admin        filter by role
+//	status=active|suspended|deleted   filter by account status
+//	q=text                            substring match on email or name
+//	limit=N, offset=N            paging (default 50, max 200)
+func (s *Server) ListUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	where := []string{"1 = 1"}
+	var args []any
+	if role := q.Get("role"); role != "" {
+		if !validRole(models.UserRole(role)) {
+			respondError(w, http.StatusBadRequest, "role must be 'student', 'company' or 'admin'")
+			return
+		}
+		where = append(where, "role = ?")
+		args = append(args, role)
+	}
+	if status := q.Get("status"); status != "" {
+		if !validStatus(models.UserStatus(status)) {
+			respondError(w, http.StatusBadRequest, "status must be 'active', 'suspended' or 'deleted'")
+			return
+		}
+		where = append(where, "status = ?")
+		args = append(args, status)
+	}
+	if text := strings.TrimSpace(q.Get("q")); text != "" {
+		where = append(where, "(email LIKE ? OR name LIKE ?)")
+		like := "%" + text + "%"
+		args = append(args, like, like)
+	}
+	limit, offset, ok := pageParams(q.Get("limit"), q.Get("offset"))
+	if !ok {
+		respondError(w, http.StatusBadRequest, "limit and offset must be non-negative integers")
+		return
+	}
+	cond := strings.Join(where, " AND ")
+
+	var list models.UserList
+	if err := s.DB.QueryRowContext(r.Context(),
+		`SELECT COUNT(*) FROM users WHERE `+cond, args...,
+	).Scan(&list.Total); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	rows, err := s.DB.QueryContext(r.Context(),
+		`SELECT id, email, name, role, status, created_at, updated_at, suspended_at, email_verified_at
+		 FROM users WHERE `+cond+`
+		 ORDER BY created_at DESC, id
+		 LIMIT ? OFFSET ?`,
+		append(args, limit, offset)...,
+	)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	list.Users = []models.User{}
+	for rows.Next() {
+		var u models.User
+		var suspendedAt, verifiedAt sql.NullTime
+		if err := rows.Scan(&u.ID, &u.Email, &u.Name, &u.Role, &u.Status, &u.CreatedAt, &u.UpdatedAt,
+			&suspendedAt, &verifiedAt); err != nil {
+			respondError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		if suspendedAt.Valid {
+			u.SuspendedAt = &suspendedAt.Time
+		}
+		if verifiedAt.Valid {
+			u.EmailVerifiedAt = &verifiedAt.Time
+		}
+		list.Users = append(list.Users, u)
+	}
+	if err := rows.Err(); err != nil {
+		respondError(w, http.StatusInternalServerError, "rows error")
+		return
+	}
+
+	respond(w, http.StatusOK, list)
+}
+
+// pageParams parses limit/offset query values, applying the defaults.
+func pageParams(limitStr, offsetStr string) (limit, offset int, ok bool) {
+	limit = defaultUserPageSize
+	if limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n < 0 {
+			return 0, 0, false
+		}
+		limit = min(n, maxUserPageSize)
+	}
+	if offsetStr != "" {
+		n, err := strconv.Atoi(offsetStr)
+		if err != nil || n < 0 {
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return limit, offset, true
+}
+
+// UpdateUserStatus handles PATCH /api/admin/users/{id}/status  (admin only)
+//
+// Body: {"status": "active" | "suspended" | "deleted"}.
+//
+//   - suspended blocks login and every authenticated request (403) but keeps
+//     the user's sessions, so setting the account back to active lets their
+//     devices carry on where they left off.
+//   - deleted is a soft delete: the row stays so events, badges and
+//     attendance keep their references, but every session is revoked.
+func (s *Server) UpdateUserStatus(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateUserStatusRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	s.setUserStatus(w, r, req.Status)
+}
+
+// SuspendUser handles POST /api/admin/users/{id}/suspend  (admin only)
+// Shorthand for PATCH …/status with {"status": "suspended"}.
+func (s *Server) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	s.setUserStatus(w, r, models.UserSuspended)
+}
+
+// UnsuspendUser handles POST /api/admin/users/{id}/unsuspend  (admin only)
+// Shorthand for PATCH …/status with {"status": "active"}.
+func (s *Server) UnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	s.setUserStatus(w, r, models.UserActive)
+}
+
+func (s *Server) setUserStatus(w http.ResponseWriter, r *http.Request, status models.UserStatus) {
+	id := r.PathValue("id")
+	if !validStatus(status) {
+		respondError(w, http.StatusBadRequest, "status must be 'active', 'suspended' or 'deleted'")
+		return
+	}
+	if id == middleware.GetUserID(r.Context()) {
+		respondError(w, http.StatusBadRequest, "you cannot change the status of your own account")
+		return
+	}
+
+	// suspended_at keeps the start of the current suspension, so setting
+	// "suspended" twice does not move it.
+	now := time.Now().UTC()
+	result, err := s.DB.ExecContext(r.Context(),
+		`UPDATE users
+		 SET status = ?,
+		     suspended_at = CASE WHEN ? = 'suspended' THEN COALESCE(suspended_at, ?) END,
+		     updated_at = ?
+		 WHERE id = ?`,
+		status, status, now, now, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not update user")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	if status == models.UserDeleted {
+		if err := s.revokeUserSessions(r.Context(), id); err != nil {
+			respondError(w, http.StatusInternalServerError, "could not revoke sessions")
+			return
+		}
+	}
+	s.authCache.forgetUser(id)
+
+	s.respondAdminUser(w, r, id)
+}
+
+// UpdateUserRole handles PATCH /api/admin/users/{id}/role  (admin only)
+//
+// Body: {"role": "student" | "company" | "admin"}.  The new role applies
+// from the user's next request; they stay logged in.
+func (s *Server) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == middleware.GetUserID(r.Context()) {
+		respondError(w, http.StatusBadRequest, "you cannot change your own role")
+		return
+	}
+
+	var req models.UpdateUserRoleRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if !validRole(req.Role) {
+		respondError(w, http.StatusBadRequest, "role must be 'student', 'company' or 'admin'")
+		return
+	}
+
+	_, err := s.DB.ExecContext(r.Context(),
+		`UPDATE users SET role = ?, updated_at = ? WHERE id = ? AND role != ?`,
+		req.Role, time.Now().UTC(), id, req.Role)
+	if err == nil {
+		// Only students are affiliated with an institution.
+		err = updateAffiliation(r.Context(), s.DB, id)
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not update user")
+		return
+	}
+	s.authCache.forgetUser(id)
+
+	s.respondAdminUser(w, r, id)
+}
+
+// respondAdminUser writes the user with the given ID, or 404.
+func (s *Server) respondAdminUser(w http.ResponseWriter, r *http.Request, id string) {
+	var u models.User
+	var suspendedAt, verifiedAt sql.NullTime
+	err := s.DB.QueryRowContext(r.Context(),
+		`SELECT id, email, name, role, status, created_at, updated_at, suspended_at, email_verified_at
+		 FROM users WHERE id = ?`, id,
+	).Scan(&u.ID, &u.Email, &u.Name, &u.Role, &u.Status, &u.CreatedAt, &u.UpdatedAt, &suspendedAt, &verifiedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if suspendedAt.Valid {
+		u.SuspendedAt = &suspendedAt.Time
+	}
+	if verifiedAt.Valid {
+		u.EmailVerifiedAt = &verifiedAt.Time
+	}
+	respond(w, http.StatusOK, u)
+}
+
+func validRole(role models.UserRole) bool {
+	return role == models.RoleStudent || role == models.RoleCompany || role == models.RoleAdmin
+}
+
+func validStatus(status models.UserStatus) bool {
+	return status == models.UserActive || status == models.UserSuspended || status == models.UserDeleted
+}
+
+// EnsureAdmin makes sure an admin account with email exists.  An existing
+// user with that email is promoted (its password is left alone); otherwise
+// a new admin is created with name and password.  It reports whether a new
+// account was created.  Used by `server admin` and the ADMIN_EMAIL
+// bootstrap in main.
+func EnsureAdmin(ctx context.Context, db *sql.DB, email, name, password string) (created bool, err error) {
+	email = strings.TrimSpace(strings.ToLower(email))
+	if email == "" {
+		return false, errors.New("email is required")
+	}
+
+	now := time.Now().UTC()
+	result, err := db.ExecContext(ctx,
+		`UPDATE users SET role = 'admin', updated_at = ? WHERE email = ? AND role != 'admin'`, now, email)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return false, nil
+	}
+	var exists int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE email = ?`, email).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists > 0 {
+		return false, nil
+	}
+
+	if len(password) < 8 {
+		return false, errors.New("password must be at least 8 characters")
+	}
+	if name = strings.TrimSpace(name); name == "" {
+		name = "Administrator"
+	}
+	// Default hash settings: a server configured otherwise upgrades the
+	// hash at the admin's first login.
+	hash, err := auth.PasswordHasher{}.Hash(password)
+	if err != nil {
+		return false, err
+	}
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO users (id, email, password_hash, name, role, created_at, updated_at, email_verified_at)
+		 VALUES (?, ?, ?, ?, 'admin', ?, ?, ?)`,
+		uuid.NewString(), email, hash, name, now, now, now,
+	)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+)
+
+// seedAdminUser creates an admin through EnsureAdmin and returns its ID.
+func seedAdminUser(t *testing.T, srv *Server) string {
+	t.Helper()
+	if _, err := EnsureAdmin(context.Background(), srv.DB, "admin@example.com", "Admin", "adminpass123"); err != nil {
+		t.Fatalf("seedAdminUser: %v", err)
+	}
+	var id string
+	srv.DB.QueryRow(`SELECT id FROM users WHERE email = 'admin@example.com'`).Scan(&id)
+	return id
+}
+
+// loginStatus attempts a login and returns the HTTP status.
+func loginStatus(t *testing.T, srv *Server, email, password string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login",
+		jsonBody(t, models.LoginRequest{Email: email, Password: password}))
+	rec := httptest.NewRecorder()
+	srv.Login(rec, req)
+	return rec.Code
+}
+
+func TestListUsers_FiltersAndPages(t *testing.T) {
+	srv := newTestServer(t)
+	adminID := seedAdminUser(t, srv)
+	seedStudentUser(t, srv)
+	seedStudentUser(t, srv)
+	seedCompanyUser(t, srv)
+
+	list := func(query string) models.UserList {
+		t.Helper()
+		req := ctxWithUser(httptest.NewRequest(http.MethodGet, "/api/admin/users?"+query, nil), adminID, "admin")
+		rec := httptest.NewRecorder()
+		srv.ListUsers(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("ListUsers(%q): expected 200, got %d: %s", query, rec.Code, rec.Body.String())
+		}
+		var out models.UserList
+		json.NewDecoder(rec.Body).Decode(&out)
+		return out
+	}
+
+	if got := list(""); got.Total != 4 || len(got.Users) != 4 {
+		t.Errorf("all users: total=%d len=%d, want 4", got.Total, len(got.Users))
+	}
+	if got := list("role=student"); got.Total != 2 {
+		t.Errorf("students: total=%d, want 2", got.Total)
+	}
+	if got := list("q=admin@"); got.Total != 1 || got.Users[0].ID != adminID {
+		t.Errorf("search: got %+v", got)
+	}
+	if got := list("limit=1&offset=1"); got.Total != 4 || len(got.Users) != 1 {
+		t.Errorf("paged: total=%d len=%d, want 4 and 1", got.Total, len(got.Users))
+	}
+
+	req := ctxWithUser(httptest.NewRequest(http.MethodGet, "/api/admin/users?role=root", nil), adminID, "admin")
+	rec := httptest.NewRecorder()
+	srv.ListUsers(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("bad role filter: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestSuspendUser_BlocksLoginAndKeepsSessions(t *testing.T) {
+	srv := newTestServer(t)
+	adminID := seedAdminUser(t, srv)
+	login := registerUser(t, srv, "abuser@example.com", models.RoleCompany)
+	claims, _ := auth.ParseToken(login.Token, testKeys)
+
+	req := ctxWithUser(httptest.NewRequest(http.MethodPost, "/", nil), adminID, "admin")
+	req.SetPathValue("id", login.User.ID)
+	rec := httptest.NewRecorder()
+	srv.SuspendUser(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("suspend: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var u models.User
+	json.NewDecoder(rec.Body).Decode(&u)
+	if u.SuspendedAt == nil || u.Status != models.UserSuspended {
+		t.Errorf("response: status=%q suspended_at=%v", u.Status, u.SuspendedAt)
+	}
+
+	state, _ := srv.SessionState(context.Background(), claims.SessionID)
+	if !state.Active || state.Status != string(models.UserSuspended) {
+		t.Errorf("session state after suspension: %+v", state)
+	}
+	if code := loginStatus(t, srv, "abuser@example.com", "password123"); code != http.StatusForbidden {
+		t.Errorf("login while suspended: expected 403, got %d", code)
+	}
+	// A wrong password must not reveal that the account is suspended.
+	if code := loginStatus(t, srv, "abuser@example.com", "wrong-password"); code != http.StatusUnauthorized {
+		t.Errorf("wrong password while suspended: expected 401, got %d", code)
+	}
+	if rec := refresh(t, srv, login.RefreshToken); rec.Code != http.StatusForbidden {
+		t.Errorf("refresh while suspended: expected 403, got %d", rec.Code)
+	}
+
+	req = ctxWithUser(httptest.NewRequest(http.MethodPost, "/", nil), adminID, "admin")
+	req.SetPathValue("id", login.User.ID)
+	rec = httptest.NewRecorder()
+	srv.UnsuspendUser(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unsuspend: expected 200, got %d", rec.Code)
+	}
+	if state, _ := srv.SessionState(context.Background(), claims.SessionID); state.Status != string(models.UserActive) {
+		t.Errorf("status after unsuspend: got %q, want active", state.Status)
+	}
+	if code := loginStatus(t, srv, "abuser@example.com", "password123"); code != http.StatusOK {
+		t.Errorf("login after unsuspend: expected 200, got %d", code)
+	}
+}
+
+func TestSuspendUser_Guards(t *testing.T) {
+	srv := newTestServer(t)
+	adminID := seedAdminUser(t, srv)
+
+	cases := []struct {
+		name, id string
+		want     int
+	}{
+		{"self", adminID, http.StatusBadRequest},
+		{"unknown", "no-such-user", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		req := ctxWithUser(httptest.NewRequest(http.MethodPost, "/", nil), adminID, "admin")
+		req.SetPathValue("id", tc.id)
+		rec := httptest.NewRecorder()
+		srv.SuspendUser(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	srv := newTestServer(t)
+	adminID := seedAdminUser(t, srv)
+	login := registerUser(t, srv, "promote-me@example.com", models.RoleStudent)
+
+	patch := func(id string, role models.UserRole) *httptest.ResponseRecorder {
+		req := ctxWithUser(httptest.NewRequest(http.MethodPatch, "/",
+			jsonBody(t, models.UpdateUserRoleRequest{Role: role})), adminID, "admin")
+		req.SetPathValue("id", id)
+		rec := httptest.NewRecorder()
+		srv.UpdateUserRole(rec, req)
+		return rec
+	}
+
+	rec := patch(login.User.ID, models.RoleCompany)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var u models.User
+	json.NewDecoder(rec.Body).Decode(&u)
+	if u.Role != models.RoleCompany {
+		t.Errorf("role: got %q, want company", u.Role)
+	}
+	// The user stays logged in and the new role applies at once.
+	claims, _ := auth.ParseToken(login.Token, testKeys)
+	if state, _ := srv.SessionState(context.Background(), claims.SessionID); !state.Active || state.Role != "company" {
+		t.Errorf("session state after role change: %+v", state)
+	}
+
+	if rec := patch(login.User.ID, "superuser"); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid role: expected 400, got %d", rec.Code)
+	}
+	if rec := patch(adminID, models.RoleStudent); rec.Code != http.StatusBadRequest {
+		t.Errorf("own role: expected 400, got %d", rec.Code)
+	}
+	if rec := patch("no-such-user", models.RoleAdmin); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown user: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestEnsureAdmin(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	if _, err := EnsureAdmin(ctx, srv.DB, "new-admin@example.com", "", "short"); err == nil {
+		t.Error("expected error for a short password")
+	}
+	created, err := EnsureAdmin(ctx, srv.DB, " New-Admin@Example.com ", "", "longenough")
+	if err != nil || !created {
+		t.Fatalf("create: created=%v err=%v", created, err)
+	}
+	if code := loginStatus(t, srv, "new-admin@example.com", "longenough"); code != http.StatusOK {
+		t.Errorf("login as bootstrapped admin: expected 200, got %d", code)
+	}
+
+	// An existing user is promoted, and its password is left alone.
+	registerUser(t, srv, "existing@example.com", models.RoleCompany)
+	created, err = EnsureAdmin(ctx, srv.DB, "existing@example.com", "", "ignored-password")
+	if err != nil || created {
+		t.Fatalf("promote: created=%v err=%v", created, err)
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM users WHERE email = 'existing@example.com' AND role = 'admin'`); n != 1 {
+		t.Error("existing user not promoted")
+	}
+	if code := loginStatus(t, srv, "existing@example.com", "password123"); code != http.StatusOK {
+		t.Errorf("promoted user's password changed: login got %d", code)
+	}
+}
+
+func TestRegister_CannotChooseAdmin(t *testing.T) {
+	srv := newTestServer(t)
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/register", jsonBody(t, models.RegisterRequest{
+		Email: "sneaky@example.com", Password: "password123", Name: "Sneaky", Role: models.RoleAdmin,
+	}))
+	rec := httptest.NewRecorder()
+	srv.Register(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestUpdateUserStatus_Delete(t *testing.T) {
+	srv := newTestServer(t)
+	adminID := seedAdminUser(t, srv)
+	login := registerUser(t, srv, "leaving@example.com", models.RoleStudent)
+
+	patch := func(status models.UserStatus) *httptest.ResponseRecorder {
+		req := ctxWithUser(httptest.NewRequest(http.MethodPatch, "/",
+			jsonBody(t, models.UpdateUserStatusRequest{Status: status})), adminID, "admin")
+		req.SetPathValue("id", login.User.ID)
+		rec := httptest.NewRecorder()
+		srv.UpdateUserStatus(rec, req)
+		return rec
+	}
+
+	if rec := patch("banned"); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid status: expected 400, got %d", rec.Code)
+	}
+	rec := patch(models.UserDeleted)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var u models.User
+	json.NewDecoder(rec.Body).Decode(&u)
+	if u.Status != models.UserDeleted || u.SuspendedAt != nil {
+		t.Errorf("response: status=%q suspended_at=%v", u.Status, u.SuspendedAt)
+	}
+
+	claims, _ := auth.ParseToken(login.Token, testKeys)
+	if state, _ := srv.SessionState(context.Background(), claims.SessionID); state.Active {
+		t.Error("session still active after delete")
+	}
+	// A deleted account is indistinguishable from an unknown one.
+	if code := loginStatus(t, srv, "leaving@example.com", "password123"); code != http.StatusUnauthorized {
+		t.Errorf("login after delete: expected 401, got %d", code)
+	}
+}
+
+// TestAuthenticate_SeesChangesDespiteCache drives the real middleware with
+// the server as its store: changes made through the admin handlers must
+// apply to the very next request even though the lookup is cached.
+func TestAuthenticate_SeesChangesDespiteCache(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AuthCacheTTL = time.Hour
+	adminID := seedAdminUser(t, srv)
+	login := registerUser(t, srv, "cached@example.com", models.RoleStudent)
+
+	handler := middleware.Authenticate(testKeys, srv)(
+		middleware.RequireRole("company")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})),
+	)
+	call := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+login.Token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	admin := func(h http.HandlerFunc, body any) {
+		t.Helper()
+		req := ctxWithUser(httptest.NewRequest(http.MethodPost, "/", jsonBody(t, body)), adminID, "admin")
+		req.SetPathValue("id", login.User.ID)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("admin call: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	if code := call(); code != http.StatusForbidden {
+		t.Fatalf("student on company route: expected 403, got %d", code)
+	}
+	admin(srv.UpdateUserRole, models.UpdateUserRoleRequest{Role: models.RoleCompany})
+	if code := call(); code != http.StatusOK {
+		t.Errorf("after promotion: expected 200, got %d", code)
+	}
+	admin(srv.UpdateUserStatus, models.UpdateUserStatusRequest{Status: models.UserSuspended})
+	if code := call(); code != http.StatusForbidden {
+		t.Errorf("after suspension: expected 403, got %d", code)
+	}
+	admin(srv.UpdateUserStatus, models.UpdateUserStatusRequest{Status: models.UserActive})
+	if code := call(); code != http.StatusOK {
+		t.Errorf("after reinstatement: expected 200, got %d", code)
+	}
+}
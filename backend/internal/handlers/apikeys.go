@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — API keys
+// ────────────────────────────────────────────────────────────────────
+// Sessions suit people: they expire, and logging in again is cheap.  A
+// script that runs every night needs a credential that keeps working, so
+// companies can create API keys.  To keep that safe:
+//
+//   - The key is "szk_" plus 128 random bits and is shown once.  Only its
+//     SHA-256 is stored, like a refresh token: a copy of the database does
+//     not hand out working keys.  Because the key is random (not a
+//     password) a fast hash is enough.
+//   - Each key has scopes, and only routes wrapped in
+//     middleware.RequireScope accept keys at all.  A key cannot manage keys,
+//     change the account or do anything else a session can.
+//   - Keys act as their owner, so suspending the account stops them too.
+//   - last_used_at and last_used_ip show whether a key is still in use
+//     before it is revoked, and where from.  They are written at most once
+//     per apiKeyUsageInterval per key, so a busy script does not turn every
+//     read into a write.
+
+const (
+	// maxAPIKeysPerUser bounds how many unrevoked keys a company can hold.
+	maxAPIKeysPerUser = 25
+	// apiKeyUsageInterval is how stale last_used_at may get before a use
+	// updates it.
+	apiKeyUsageInterval = time.Minute
+	// apiKeyPrefixLength is how much of the key is kept in clear, to tell
+	// keys apart in the list.
+	apiKeyPrefixLength = len(middleware.APIKeyPrefix) + 6
+)
+
+// apiKeyScopes are the scopes a key can be given.
+var apiKeyScopes = []string{
+	models.ScopeEventsRead,
+	models.ScopeRegistrationsRead,
+	models.ScopeCandidatesRead,
+}
+
+// APIKeyState implements middleware.Store.
+//
+// A key is no way around the 2FA mandate: while its owner's role requires
+// 2FA and the owner has not enabled it, the key is refused.
+func (s *Server) APIKeyState(ctx context.Context, keyHash, ip string) (middleware.APIKeyState, error) {
+	var state middleware.APIKeyState
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	var lastIP string
+	var twoFactor bool
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT k.id, k.user_id, k.scopes, k.expires_at, k.last_used_at, k.last_used_ip, k.revoked_at,
+		        u.role, u.status, u.totp_enabled_at IS NOT NULL
+		 FROM api_keys k
+		 JOIN users u ON u.id = k.user_id
+		 WHERE k.key_hash = ?`, keyHash,
+	).Scan(&state.KeyID, &state.UserID, &scopes, &expiresAt, &lastUsedAt, &lastIP, &revokedAt,
+		&state.Role, &state.Status, &twoFactor)
+	if errors.Is(err, sql.ErrNoRows) {
+		return middleware.APIKeyState{}, nil
+	}
+	if err != nil {
+		return middleware.APIKeyState{}, err
+	}
+
+	now := time.Now().UTC()
+	state.Active = !revokedAt.Valid && (!expiresAt.Valid || !isExpired(expiresAt.Time, now))
+	if !state.Active {
+		return middleware.APIKeyState{}, nil
+	}
+	state.Scopes = strings.Fields(scopes)
+	if !twoFactor {
+		required, err := s.roleRequiresTwoFactor(ctx, models.UserRole(state.Role))
+		if err != nil {
+			return middleware.APIKeyState{}, err
+		}
+		state.TwoFactorMissing = required
+	}
+
+	if !lastUsedAt.Valid || now.Sub(lastUsedAt.Time) >= apiKeyUsageInterval || lastIP != ip {
+		if _, err := s.DB.ExecContext(ctx,
+			`UPDATE api_keys SET last_used_at = ?, last_used_ip = ? WHERE id = ?`,
+			now, truncate(ip, 64), state.KeyID,
+		); err != nil {
+			return middleware.APIKeyState{}, err
+		}
+	}
+	return state, nil
+}
+
+// revokeUserAPIKeys revokes every live API key of the user, e.g. when their
+// password changes: a key taken along with the old password must not
+// outlive it.
+func (s *Server) revokeUserAPIKeys(ctx context.Context, userID string) error {
+	_, err := s.DB.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`,
+		time.Now().UTC(), userID,
+	)
+	return err
+}
+
+// CreateAPIKey handles POST /api/api-keys  (company only)
+//
+// Body: {"name": "ATS sync", "scopes": ["registrations:read"], "expires_at": optional}.
+// The response carries the key; it is never shown again.
+func (s *Server) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	var req models.CreateAPIKeyRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		respondError(w, http.StatusBadRequest, "name is required (at most 100 characters)")
+		return
+	}
+	if len(req.Scopes) == 0 {
+		respondError(w, http.StatusBadRequest, "at least one scope is required")
+		return
+	}
+	var scopes []string
+	for _, scope := range req.Scopes {
+		if !slices.Contains(apiKeyScopes, scope) {
+			respondError(w, http.StatusBadRequest, "unknown scope "+scope+
+				"; valid scopes are "+strings.Join(apiKeyScopes, ", "))
+			return
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	now := time.Now().UTC()
+	var expiresAt sql.NullTime
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) {
+			respondError(w, http.StatusBadRequest, "expires_at must be in the future")
+			return
+		}
+		expiresAt = sql.NullTime{Time: req.ExpiresAt.UTC(), Valid: true}
+	}
+
+	var count int
+	if err := s.DB.QueryRowContext(r.Context(),
+		`SELECT COUNT(*) FROM api_keys WHERE user_id = ? AND revoked_at IS NULL`, userID,
+	).Scan(&count); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if count >= maxAPIKeysPerUser {
+		respondError(w, http.StatusConflict, "too many API keys; revoke one first")
+		return
+	}
+
+	key := middleware.APIKeyPrefix + rand.Text()
+	created := models.CreatedAPIKey{
+		APIKey: models.APIKey{
+			ID:        uuid.NewString(),
+			Name:      req.Name,
+			Prefix:    key[:apiKeyPrefixLength],
+			Scopes:    scopes,
+			CreatedAt: now,
+		},
+		Key: key,
+	}
+	if expiresAt.Valid {
+		created.ExpiresAt = &expiresAt.Time
+	}
+	if _, err := s.DB.ExecContext(r.Context(),
+		`INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		created.ID, userID, created.Name, created.Prefix, auth.HashOpaqueToken(key),
+		strings.Join(scopes, " "), now, expiresAt,
+	); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not create API key")
+		return
+	}
+
+	respond(w, http.StatusCreated, created)
+}
+
+// ListAPIKeys handles GET /api/api-keys  (company only)
+// Returns the caller's unrevoked keys, newest first, without the secrets.
+func (s *Server) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	rows, err := s.DB.QueryContext(r.Context(),
+		`SELECT id, name, prefix, scopes, created_at, expires_at, last_used_at, last_used_ip
+		 FROM api_keys
+		 WHERE user_id = ? AND revoked_at IS NULL
+		 ORDER BY rowid DESC`, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var k models.APIKey
+		var scopes string
+		var expiresAt, lastUsedAt sql.NullTime
+		if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, &scopes, &k.CreatedAt,
+			&expiresAt, &lastUsedAt, &k.LastUsedIP); err != nil {
+			respondError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		k.Scopes = strings.Fields(scopes)
+		if expiresAt.Valid {
+			k.ExpiresAt = &expiresAt.Time
+		}
+		if lastUsedAt.Valid {
+			k.LastUsedAt = &lastUsedAt.Time
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		respondError(w, http.StatusInternalServerError, "rows error")
+		return
+	}
+
+	respond(w, http.StatusOK, keys)
+}
+
+// RevokeAPIKey handles DELETE /api/api-keys/{id}  (company only)
+// The key stops working immediately.
+func (s *Server) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	result, err := s.DB.ExecContext(r.Context(),
+		`UPDATE api_keys SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`,
+		time.Now().UTC(), r.PathValue("id"), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+)
+
+// createAPIKey creates a key for companyID through the handler.
+func createAPIKey(t *testing.T, srv *Server, companyID string, req models.CreateAPIKeyRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/api/api-keys", jsonBody(t, req))
+	r = ctxWithUser(r, companyID, "company")
+	rec := httptest.NewRecorder()
+	srv.CreateAPIKey(rec, r)
+	return rec
+}
+
+// callWithKey calls GET /api/events/hosted the way main.go mounts it,
+// authenticating with key.
+func callWithKey(srv *Server, key string) int {
+	handler := middleware.Authenticate(srv.Keys, srv)(
+		middleware.RequireScope(models.ScopeEventsRead)(
+			middleware.RequireRole("company")(http.HandlerFunc(srv.ListHostedEvents))))
+	r := httptest.NewRequest(http.MethodGet, "/api/events/hosted", nil)
+	r.Header.Set("Authorization", "Bearer "+key)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	return rec.Code
+}
+
+func TestAPIKeys_CreateUseListRevoke(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	seedEvent(t, srv, companyID)
+	seedEvent(t, srv, seedCompanyUser(t, srv))
+
+	rec := createAPIKey(t, srv, companyID, models.CreateAPIKeyRequest{
+		Name: "ATS sync", Scopes: []string{models.ScopeEventsRead, models.ScopeEventsRead},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created models.CreatedAPIKey
+	json.NewDecoder(rec.Body).Decode(&created)
+	if !strings.HasPrefix(created.Key, middleware.APIKeyPrefix) || !strings.HasPrefix(created.Key, created.Prefix) ||
+		len(created.Scopes) != 1 {
+		t.Fatalf("unexpected key: %+v", created)
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM api_keys WHERE key_hash = ?`, created.Key); n != 0 {
+		t.Error("key stored in clear")
+	}
+
+	// The key reads the company's own events, and its use is recorded.
+	handler := middleware.Authenticate(srv.Keys, srv)(
+		middleware.RequireScope(models.ScopeEventsRead)(http.HandlerFunc(srv.ListHostedEvents)))
+	r := httptest.NewRequest(http.MethodGet, "/api/events/hosted", nil)
+	r.Header.Set("Authorization", "Bearer "+created.Key)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("use: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var events []models.Event
+	json.NewDecoder(rec.Body).Decode(&events)
+	if len(events) != 1 || events[0].HostID != companyID {
+		t.Errorf("expected the company's one event, got %+v", events)
+	}
+
+	r = ctxWithUser(httptest.NewRequest(http.MethodGet, "/api/api-keys", nil), companyID, "company")
+	rec = httptest.NewRecorder()
+	srv.ListAPIKeys(rec, r)
+	var keys []models.APIKey
+	json.NewDecoder(rec.Body).Decode(&keys)
+	if len(keys) != 1 || keys[0].LastUsedAt == nil || keys[0].LastUsedIP == "" {
+		t.Fatalf("expected one key with its last use, got %+v", keys)
+	}
+	if strings.Contains(rec.Body.String(), created.Key) {
+		t.Error("list exposes the key")
+	}
+
+	// Another company cannot revoke it; its owner can, and it stops working.
+	r = ctxWithUser(httptest.NewRequest(http.MethodDelete, "/api/api-keys/"+created.ID, nil), seedCompanyUser(t, srv), "company")
+	r.SetPathValue("id", created.ID)
+	rec = httptest.NewRecorder()
+	srv.RevokeAPIKey(rec, r)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("revoke by another company: expected 404, got %d", rec.Code)
+	}
+	r = ctxWithUser(httptest.NewRequest(http.MethodDelete, "/api/api-keys/"+created.ID, nil), companyID, "company")
+	r.SetPathValue("id", created.ID)
+	rec = httptest.NewRecorder()
+	srv.RevokeAPIKey(rec, r)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("revoke: expected 204, got %d", rec.Code)
+	}
+	if code := callWithKey(srv, created.Key); code != http.StatusUnauthorized {
+		t.Errorf("revoked key: expected 401, got %d", code)
+	}
+}
+
+func TestAPIKeys_ScopesExpiryAndOwner(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+
+	if rec := createAPIKey(t, srv, companyID, models.CreateAPIKeyRequest{
+		Name: "x", Scopes: []string{"events:write"},
+	}); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown scope: expected 400, got %d", rec.Code)
+	}
+	past := time.Now().Add(-time.Hour)
+	if rec := createAPIKey(t, srv, companyID, models.CreateAPIKeyRequest{
+		Name: "x", Scopes: []string{models.ScopeEventsRead}, ExpiresAt: &past,
+	}); rec.Code != http.StatusBadRequest {
+		t.Errorf("past expiry: expected 400, got %d", rec.Code)
+	}
+
+	var wrongScope, short models.CreatedAPIKey
+	rec := createAPIKey(t, srv, companyID, models.CreateAPIKeyRequest{
+		Name: "candidates", Scopes: []string{models.ScopeCandidatesRead},
+	})
+	json.NewDecoder(rec.Body).Decode(&wrongScope)
+	if code := callWithKey(srv, wrongScope.Key); code != http.StatusForbidden {
+		t.Errorf("key without events:read: expected 403, got %d", code)
+	}
+
+	soon := time.Now().Add(time.Hour)
+	rec = createAPIKey(t, srv, companyID, models.CreateAPIKeyRequest{
+		Name: "short-lived", Scopes: []string{models.ScopeEventsRead}, ExpiresAt: &soon,
+	})
+	json.NewDecoder(rec.Body).Decode(&short)
+	if code := callWithKey(srv, short.Key); code != http.StatusOK {
+		t.Fatalf("fresh key: expected 200, got %d", code)
+	}
+
+	// Keys act as their owner: suspending the account stops them.
+	srv.DB.Exec(`UPDATE users SET status = 'suspended' WHERE id = ?`, companyID)
+	if code := callWithKey(srv, short.Key); code != http.StatusForbidden {
+		t.Errorf("suspended owner: expected 403, got %d", code)
+	}
+	srv.DB.Exec(`UPDATE users SET status = 'active' WHERE id = ?`, companyID)
+
+	srv.DB.Exec(`UPDATE api_keys SET expires_at = ? WHERE id = ?`, time.Now().UTC().Add(-time.Second), short.ID)
+	if code := callWithKey(srv, short.Key); code != http.StatusUnauthorized {
+		t.Errorf("expired key: expected 401, got %d", code)
+	}
+}
+
+func TestAPIKeys_TwoFactorMandateAndPasswordChanges(t *testing.T) {
+	srv := newTestServer(t)
+	box := &outbox{}
+	srv.Mailer = box
+	login := registerUser(t, srv, "keys@example.com", models.RoleCompany)
+	newKey := func() string {
+		t.Helper()
+		rec := createAPIKey(t, srv, login.User.ID, models.CreateAPIKeyRequest{
+			Name: "ATS sync", Scopes: []string{models.ScopeEventsRead},
+		})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var created models.CreatedAPIKey
+		json.NewDecoder(rec.Body).Decode(&created)
+		return created.Key
+	}
+
+	// A key is no way around a 2FA mandate for its owner's role.
+	key := newKey()
+	if err := srv.putSetting(t.Context(), settingTwoFactorRoles, "company"); err != nil {
+		t.Fatal(err)
+	}
+	if code := callWithKey(srv, key); code != http.StatusForbidden {
+		t.Errorf("owner without required 2FA: expected 403, got %d", code)
+	}
+	srv.DB.Exec(`UPDATE users SET totp_enabled_at = ? WHERE id = ?`, time.Now().UTC(), login.User.ID)
+	if code := callWithKey(srv, key); code != http.StatusOK {
+		t.Errorf("owner with 2FA: expected 200, got %d", code)
+	}
+
+	// Changing the password revokes every key.
+	req := ctxWithSession(t, httptest.NewRequest(http.MethodPost, "/api/auth/me/password",
+		jsonBody(t, models.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "new-password-1"})), login.Token)
+	rec := httptest.NewRecorder()
+	srv.ChangePassword(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("change password: expected 204, got %d", rec.Code)
+	}
+	if code := callWithKey(srv, key); code != http.StatusUnauthorized {
+		t.Errorf("after password change: expected 401, got %d", code)
+	}
+
+	// So does resetting it.
+	key = newKey()
+	box.sent = nil
+	forgot(t, srv, "keys@example.com")
+	if code := reset(t, srv, box.lastResetToken(t), "brand-new-password"); code != http.StatusNoContent {
+		t.Fatalf("reset: expected 204, got %d", code)
+	}
+	if code := callWithKey(srv, key); code != http.StatusUnauthorized {
+		t.Errorf("after password reset: expected 401, got %d", code)
+	}
+}
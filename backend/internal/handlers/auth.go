@@ -1,25 +1,27 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
 	"github.com/Elizabethomito/skillzone/backend/internal/models"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // Register handles POST /api/auth/register
 //
 // Flow:
 //  1. Decode and validate the request body.
-//  2. Hash the password with bcrypt (slow by design — makes brute force hard).
+//  2. Hash the password with s.Passwords (slow by design — makes brute force hard).
 //  3. Insert the new user row.
-//  4. Generate a JWT and return it with the user object.
+//  4. Start a session and return its tokens with the user object.
 //
 // Returning the token immediately means the client can start making
 // authenticated requests without a separate login step.
@@ -43,15 +45,15 @@ func (s *Server) Register(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "role must be 'student' or 'company'")
 		return
 	}
-	if len(req.Password) < 8 {
+	if len(req.Password) < minPasswordLength {
 		respondError(w, http.StatusBadRequest, "password must be at least 8 characters")
 		return
 	}
 
-	// bcrypt.DefaultCost (10) means ~100 ms per hash on modern hardware —
-	// intentionally slow to resist offline brute-force attacks if the DB leaks.
+	// argon2id by default (see auth/password.go) — intentionally slow and
+	// memory-hungry to resist offline brute-force attacks if the DB leaks.
 	// We NEVER store the plain-text password.
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hash, err := s.Passwords.Hash(req.Password)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to hash password")
 		return
@@ -60,9 +62,10 @@ func (s *Server) Register(w http.ResponseWriter, r *http.Request) {
 	user := models.User{
 		ID:           uuid.NewString(), // random UUID v4 — globally unique without a sequence
 		Email:        req.Email,
-		PasswordHash: string(hash),
+		PasswordHash: hash,
 		Name:         req.Name,
 		Role:         req.Role,
+		Status:       models.UserActive,
 		CreatedAt:    time.Now().UTC(),
 		UpdatedAt:    time.Now().UTC(),
 	}
@@ -82,20 +85,49 @@ func (s *Server) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := auth.GenerateToken(user.ID, string(user.Role), s.Secret)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "could not generate token")
-		return
+	// The account works straight away, but some things wait until the
+	// address is verified (see verification.go).  A mail failure is not the
+	// user's problem here: they can ask for another link.
+	if err := s.sendVerificationEmail(r.Context(), user.ID); err != nil {
+		slog.Error("verification email", "user_id", user.ID, "err", err)
 	}
 
 	// 201 Created — a new resource was created.
-	respond(w, http.StatusCreated, models.LoginResponse{Token: token, User: user})
+	s.finishLogin(w, r, user, http.StatusCreated)
+}
+
+// dummyPasswordHash is compared against when the email is unknown, so that
+// case costs the same hashing time as a wrong password.  It is made with
+// s.Passwords, like every new hash.
+func (s *Server) dummyPasswordHash() string {
+	s.dummyHashOnce.Do(func() {
+		s.dummyHash, _ = s.Passwords.Hash("not-a-real-password")
+	})
+	return s.dummyHash
+}
+
+// upgradePasswordHash re-hashes password with the current s.Passwords
+// settings if the stored hash was made with older ones (see
+// auth/password.go).  It is called after password was checked against
+// oldHash; the compare-and-swap on oldHash means a password change racing
+// the login wins.
+func (s *Server) upgradePasswordHash(ctx context.Context, userID, oldHash, password string) error {
+	if !s.Passwords.NeedsRehash(oldHash) {
+		return nil
+	}
+	hash, err := s.Passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.ExecContext(ctx,
+		`UPDATE users SET password_hash = ? WHERE id = ? AND password_hash = ?`, hash, userID, oldHash)
+	return err
 }
 
 // Login handles POST /api/auth/login
 //
 // LEARNING NOTE — timing attacks
-// We always call bcrypt.CompareHashAndPassword even when the user is not
+// We always call auth.CheckPassword even when the user is not
 // found. Without this, an attacker could tell whether an email exists by
 // measuring response time (registered users take ~100 ms; unknown users
 // return instantly). Here we return the same error message and take the
@@ -109,15 +141,34 @@ func (s *Server) Login(w http.ResponseWriter, r *http.Request) {
 
 	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
 
+	// Refuse locked accounts and IPs before doing any hashing work, and
+	// count the attempt as a failure until the password proves right (see
+	// throttle.go).
+	wait, err := s.claimLoginAttempt(r, req.Email, "")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if wait > 0 {
+		respondTooManyAttempts(w, wait)
+		return
+	}
+
 	var user models.User
-	err := s.DB.QueryRowContext(r.Context(),
-		`SELECT id, email, password_hash, name, role, created_at, updated_at
+	err = s.DB.QueryRowContext(r.Context(),
+		`SELECT id, email, password_hash, name, role, status, created_at, updated_at
 		 FROM users WHERE email = ?`, req.Email,
-	).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.Role,
+	).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.Role, &user.Status,
 		&user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
+			// Burn the same hashing time a real account would cost.
+			auth.CheckPassword(s.dummyPasswordHash(), req.Password) //nolint:errcheck
 			// Return 401, not 404 — we don't want to confirm the email exists.
+			if err := s.recordLoginFailure(r, req.Email, "", attemptUnknownEmail); err != nil {
+				respondError(w, http.StatusInternalServerError, "database error")
+				return
+			}
 			respondError(w, http.StatusUnauthorized, "invalid credentials")
 			return
 		}
@@ -125,39 +176,55 @@ func (s *Server) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// CompareHashAndPassword runs the same bcrypt cost as registration.
-	// It returns an error if the password doesn't match.
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+	// CheckPassword reads the algorithm and cost from the stored hash, so
+	// accounts hashed with older settings still log in.  It returns an
+	// error if the password doesn't match.
+	// A deleted account looks exactly like an unknown email.
+	if err := auth.CheckPassword(user.PasswordHash, req.Password); err != nil ||
+		user.Status == models.UserDeleted {
+		reason := attemptBadPassword
+		if user.Status == models.UserDeleted {
+			reason = attemptUnknownEmail
+		}
+		if err := s.recordLoginFailure(r, req.Email, user.ID, reason); err != nil {
+			respondError(w, http.StatusInternalServerError, "database error")
+			return
+		}
 		respondError(w, http.StatusUnauthorized, "invalid credentials")
 		return
 	}
-
-	token, err := auth.GenerateToken(user.ID, string(user.Role), s.Secret)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "could not generate token")
+	if err := s.refundLoginAttempt(r.Context(), req.Email, clientIP(r)); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	// The password is known to be right: bring its hash up to the current
+	// settings.  A failure only delays the upgrade to the next login.
+	if err := s.upgradePasswordHash(r.Context(), user.ID, user.PasswordHash, req.Password); err != nil {
+		slog.Error("upgrade password hash", "user_id", user.ID, "err", err)
+	}
+	// Only reveal the suspension to someone who knows the password.
+	if user.Status == models.UserSuspended {
+		respondError(w, http.StatusForbidden, "account suspended")
 		return
 	}
 
-	respond(w, http.StatusOK, models.LoginResponse{Token: token, User: user})
+	// The failure counter is cleared by finishLogin, or by LoginTwoFactor if
+	// a second factor is still needed.
+	s.finishLogin(w, r, user, http.StatusOK)
 }
 
 // Me handles GET /api/auth/me
 // Returns the currently authenticated user's profile.
 // The Authenticate middleware has already validated the token and placed
 // the user_id in the context, so we just need to look up the full record.
+// The query in loadProfile (profile.go) never selects password_hash, so the
+// hash cannot leak even if the json:"-" tag were removed.
 func (s *Server) Me(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("user_id").(string)
+	// The context key is middleware.ContextUserID, a private type — a plain
+	// "user_id" string would never match it.
+	userID := middleware.GetUserID(r.Context())
 
-	var user models.User
-	err := s.DB.QueryRowContext(r.Context(),
-		// The empty string literal ('') is a placeholder for password_hash —
-		// we never want to return the hash over the wire, so we discard it
-		// here rather than scanning into the struct and hoping the json:"-"
-		// tag on PasswordHash prevents it from being serialised.
-		`SELECT id, email, '', name, role, created_at, updated_at
-		 FROM users WHERE id = ?`, userID,
-	).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.Role,
-		&user.CreatedAt, &user.UpdatedAt)
+	user, err := s.loadProfile(r.Context(), userID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			respondError(w, http.StatusNotFound, "user not found")
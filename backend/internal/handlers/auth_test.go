@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"testing"
 
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
 	"github.com/Elizabethomito/skillzone/backend/internal/db"
 	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
 	"github.com/Elizabethomito/skillzone/backend/internal/models"
@@ -17,6 +20,28 @@ import (
 
 const testSecret = "handler-test-secret"
 
+// testCheckInKey is the Ed25519 key every test server signs check-in tokens with.
+var testCheckInKey = func() *auth.CheckInKey {
+	k, err := auth.NewCheckInKey()
+	if err != nil {
+		panic(err)
+	}
+	return k
+}()
+
+// testKeys is the keyring every test server signs with: testSecret as the
+// primary session key and testCheckInKey as the primary check-in key.
+var testKeys = func() *auth.Keyring {
+	kr, err := auth.NewKeyring([]auth.Key{
+		{ID: "test-session-key", Purpose: auth.PurposeSession, Status: auth.KeyPrimary, Material: []byte(testSecret)},
+		{ID: testCheckInKey.ID, Purpose: auth.PurposeCheckIn, Status: auth.KeyPrimary, Material: testCheckInKey.Seed()},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return kr
+}()
+
 var testDBCounter uint64
 
 // newTestServer creates a Server backed by a unique in-memory SQLite database.
@@ -31,7 +56,22 @@ func newTestServer(t *testing.T) *Server {
 		t.Fatalf("newTestServer: open db: %v", err)
 	}
 	t.Cleanup(func() { testDB.Close() })
-	return &Server{DB: testDB, Secret: testSecret}
+	return &Server{DB: testDB, Keys: testKeys}
+}
+
+// newFileTestServer is newTestServer on a database file, for tests that run
+// requests concurrently: the shared in-memory database locks tables instead
+// of waiting on busy_timeout.  The DSN leaves out _txlock, which db.Open
+// must add.
+func newFileTestServer(t *testing.T) *Server {
+	t.Helper()
+	testDB, err := db.Open(filepath.Join(t.TempDir(), "skillzone.db") +
+		"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(10000)")
+	if err != nil {
+		t.Fatalf("newFileTestServer: open db: %v", err)
+	}
+	t.Cleanup(func() { testDB.Close() })
+	return &Server{DB: testDB, Keys: testKeys}
 }
 
 // jsonBody encodes v to JSON and returns a bytes.Buffer.
@@ -51,6 +91,25 @@ func ctxWithUser(r *http.Request, userID, role string) *http.Request {
 	return r.WithContext(ctx)
 }
 
+// registerUser registers an account through the Register handler and
+// returns the login response (tokens + user).
+func registerUser(t *testing.T, srv *Server, email string, role models.UserRole) models.LoginResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/register", jsonBody(t, models.RegisterRequest{
+		Email: email, Password: "password123", Name: "Test User", Role: role,
+	}))
+	rec := httptest.NewRecorder()
+	srv.Register(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("registerUser: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp models.LoginResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("registerUser: decode: %v", err)
+	}
+	return resp
+}
+
 // ---- Auth handler tests ----
 
 func TestRegister_Success(t *testing.T) {
@@ -75,6 +134,9 @@ func TestRegister_Success(t *testing.T) {
 	if resp.Token == "" {
 		t.Error("expected non-empty token")
 	}
+	if resp.RefreshToken == "" {
+		t.Error("expected non-empty refresh token")
+	}
 	if resp.User.Email != "alice@example.com" {
 		t.Errorf("email: got %q", resp.User.Email)
 	}
@@ -152,3 +214,38 @@ func TestLogin_WrongPassword(t *testing.T) {
 		t.Errorf("expected 401, got %d", rec.Code)
 	}
 }
+
+func TestLogin_UpgradesPasswordHash(t *testing.T) {
+	srv := newTestServer(t)
+	// An account from before argon2id: a bcrypt hash.
+	legacy, _ := auth.PasswordHasher{Algorithm: auth.AlgorithmBcrypt, BcryptCost: 4}.Hash("oldpassword")
+	srv.DB.Exec(`INSERT INTO users (id, email, password_hash, name, role) VALUES ('u-old', 'old@example.com', ?, 'Old', 'student')`, legacy)
+
+	if code := loginStatus(t, srv, "old@example.com", "wrongpassword"); code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: expected 401, got %d", code)
+	}
+	if got := dbString(t, srv, `SELECT password_hash FROM users WHERE id = 'u-old'`); got != legacy {
+		t.Error("hash changed after a failed login")
+	}
+
+	if code := loginStatus(t, srv, "old@example.com", "oldpassword"); code != http.StatusOK {
+		t.Fatalf("bcrypt account: expected 200, got %d", code)
+	}
+	upgraded := dbString(t, srv, `SELECT password_hash FROM users WHERE id = 'u-old'`)
+	if !strings.HasPrefix(upgraded, "$argon2id$") {
+		t.Fatalf("hash not upgraded: %s", upgraded)
+	}
+
+	// Raising the cost upgrades again at the next login, and the new hash
+	// still works.
+	srv.Passwords = auth.PasswordHasher{Argon2Iterations: 3}
+	if code := loginStatus(t, srv, "old@example.com", "oldpassword"); code != http.StatusOK {
+		t.Fatalf("argon2id account: expected 200, got %d", code)
+	}
+	if got := dbString(t, srv, `SELECT password_hash FROM users WHERE id = 'u-old'`); !strings.Contains(got, ",t=3,") {
+		t.Errorf("cost change not applied: %s", got)
+	}
+	if code := loginStatus(t, srv, "old@example.com", "oldpassword"); code != http.StatusOK {
+		t.Errorf("after second upgrade: expected 200, got %d", code)
+	}
+}
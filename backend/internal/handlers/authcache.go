@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — caching the per-request auth lookup
+// ────────────────────────────────────────────────────────────────────
+// Authenticate asks the database about every request: is the session still
+// live, is the account still active, what is the user's role NOW?  That is
+// one indexed lookup, but it sits in front of every authenticated call, so
+// we remember answers for a few seconds (Server.AuthCacheTTL).
+//
+// A cache is only safe if writes evict what they change.  Every handler on
+// this server that revokes a session or changes a user's role or status
+// calls forgetSession/forgetUser, so on a single server those changes are
+// still immediate.  With several servers behind a load balancer the others
+// notice within one TTL.
+
+// defaultAuthCacheTTL is used when Server.AuthCacheTTL is zero.
+const defaultAuthCacheTTL = 5 * time.Second
+
+// maxAuthCacheEntries bounds memory.  When full the cache is simply emptied:
+// the next requests refill it from the database.
+const maxAuthCacheEntries = 10_000
+
+type authCacheEntry struct {
+	state   middleware.SessionState
+	expires time.Time
+}
+
+// authCache maps session ID → SessionState.  The zero value is ready to use.
+type authCache struct {
+	mu      sync.Mutex
+	entries map[string]authCacheEntry
+}
+
+func (c *authCache) get(sessionID string, now time.Time) (middleware.SessionState, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[sessionID]
+	if !ok || now.After(e.expires) {
+		return middleware.SessionState{}, false
+	}
+	return e.state, true
+}
+
+func (c *authCache) put(sessionID string, state middleware.SessionState, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil || len(c.entries) >= maxAuthCacheEntries {
+		c.entries = make(map[string]authCacheEntry)
+	}
+	c.entries[sessionID] = authCacheEntry{state: state, expires: expires}
+}
+
+// forgetSession evicts one session.
+func (c *authCache) forgetSession(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, sessionID)
+}
+
+// forgetUser evicts every session of userID.
+func (c *authCache) forgetUser(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, e := range c.entries {
+		if e.state.UserID == userID {
+			delete(c.entries, id)
+		}
+	}
+}
+
+// forgetAll empties the cache, for changes that affect many users at once.
+func (c *authCache) forgetAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+// SessionState implements middleware.Store.  Answers are cached for
+// AuthCacheTTL; a negative TTL disables the cache.
+func (s *Server) SessionState(ctx context.Context, sessionID string) (middleware.SessionState, error) {
+	ttl := s.AuthCacheTTL
+	if ttl == 0 {
+		ttl = defaultAuthCacheTTL
+	}
+	now := time.Now()
+	if ttl > 0 {
+		if state, ok := s.authCache.get(sessionID, now); ok {
+			return state, nil
+		}
+	}
+
+	var state middleware.SessionState
+	var expiresAt time.Time
+	var revokedAt sql.NullTime
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT s.user_id, s.expires_at, s.revoked_at, u.role, u.status
+		 FROM sessions s
+		 JOIN users u ON u.id = s.user_id
+		 WHERE s.id = ?`, sessionID,
+	).Scan(&state.UserID, &expiresAt, &revokedAt, &state.Role, &state.Status)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return middleware.SessionState{}, err
+	}
+	state.Active = err == nil && !revokedAt.Valid && !isExpired(expiresAt, now)
+
+	if ttl > 0 {
+		s.authCache.put(sessionID, state, now.Add(ttl))
+	}
+	return state, nil
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — checking the database's invariants
+// ────────────────────────────────────────────────────────────────────
+// Some facts are stored twice, or implied by other rows, and nothing in the
+// schema keeps them in step: slots_remaining is a counter kept by every
+// handler that creates or removes a registration, and a skill badge is only
+// meant to exist because of a verified attendance.  A bug, a hand-edited row
+// or a crash between two statements of an older release leaves them out of
+// step without anyone noticing.  CheckInvariants looks for:
+//
+//	orphaned_event_skill            event_skills row whose event or skill is gone
+//	attendance_without_registration verified attendance with no registration
+//	skill_without_attendance        user_skills row without a verified attendance
+//	slots_remaining                 counter ≠ capacity − confirmed/offered seats
+//
+// Repairs, in that order: drop the orphaned links, register the attendee
+// (confirmed, at the time they checked in), withdraw the badge, recount the
+// seats.  They run in one write transaction.  A plain check only reads, in
+// a read-only transaction: db.Open's _txlock=immediate does not apply to
+// those, so checking never holds up sign-ups or syncs.  The report is the
+// same either way — the seat count treats an attendee the repair would
+// register as holding a seat already — so a check shows exactly what a
+// repair would do.  Seats a recount frees go to the waitlist the next time
+// the event's registrations change.
+//
+// `server check [--repair]` runs it from the command line, and admins can
+// run it over HTTP.
+
+// CheckInvariants reports every violation of the invariants above, and
+// fixes them if repair is set.
+func CheckInvariants(ctx context.Context, db *sql.DB, repair bool) (models.CheckReport, error) {
+	report := models.CheckReport{Violations: []models.InvariantViolation{}, CheckedAt: time.Now().UTC()}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: !repair})
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, check := range []func(context.Context, *sql.Tx, bool) ([]models.InvariantViolation, error){
+		checkEventSkills, checkAttendanceRegistrations, checkUnearnedSkills, checkSlots,
+	} {
+		found, err := check(ctx, tx, repair)
+		if err != nil {
+			return report, err
+		}
+		report.Violations = append(report.Violations, found...)
+	}
+
+	if repair {
+		if err := tx.Commit(); err != nil {
+			return report, err
+		}
+		report.Repaired = len(report.Violations) > 0
+	}
+	return report, nil
+}
+
+// checkEventSkills finds event_skills rows pointing at a missing event or
+// skill, and drops them if repair is set.
+func checkEventSkills(ctx context.Context, tx *sql.Tx, repair bool) ([]models.InvariantViolation, error) {
+	type link struct{ eventID, skillID, missing string }
+	var links []link
+	rows, err := tx.QueryContext(ctx,
+		`SELECT es.event_id, es.skill_id, CASE WHEN e.id IS NULL THEN 'event' ELSE 'skill' END
+		 FROM event_skills es
+		 LEFT JOIN events e ON e.id = es.event_id
+		 LEFT JOIN skills sk ON sk.id = es.skill_id
+		 WHERE e.id IS NULL OR sk.id IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var l link
+		if err := rows.Scan(&l.eventID, &l.skillID, &l.missing); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var found []models.InvariantViolation
+	for _, l := range links {
+		if repair {
+			if _, err := tx.ExecContext(ctx,
+				`DELETE FROM event_skills WHERE event_id = ? AND skill_id = ?`, l.eventID, l.skillID,
+			); err != nil {
+				return nil, err
+			}
+		}
+		found = append(found, models.InvariantViolation{
+			Invariant: models.InvariantOrphanedEventSkill,
+			Subject:   l.eventID + "/" + l.skillID,
+			Detail:    "its " + l.missing + " no longer exists",
+		})
+	}
+	return found, nil
+}
+
+// checkAttendanceRegistrations finds students whose attendance was verified
+// without a registration and, if repair is set, registers them as confirmed
+// from the time of the attendance.
+func checkAttendanceRegistrations(ctx context.Context, tx *sql.Tx, repair bool) ([]models.InvariantViolation, error) {
+	type attendance struct {
+		id, eventID, studentID string
+		at                     time.Time
+	}
+	var missing []attendance
+	rows, err := tx.QueryContext(ctx,
+		`SELECT a.id, a.event_id, a.student_id, a.created_at
+		 FROM attendances a
+		 LEFT JOIN registrations r ON r.event_id = a.event_id AND r.student_id = a.student_id
+		 WHERE a.status = 'verified' AND r.id IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var a attendance
+		if err := rows.Scan(&a.id, &a.eventID, &a.studentID, &a.at); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		missing = append(missing, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var found []models.InvariantViolation
+	for _, a := range missing {
+		if repair {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO registrations (id, event_id, student_id, registered_at, status) VALUES (?, ?, ?, ?, 'confirmed')`,
+				uuid.NewString(), a.eventID, a.studentID, a.at,
+			); err != nil {
+				return nil, err
+			}
+		}
+		found = append(found, models.InvariantViolation{
+			Invariant: models.InvariantAttendanceRegistration,
+			Subject:   a.id,
+			Detail:    fmt.Sprintf("student %s attended event %s without a registration", a.studentID, a.eventID),
+		})
+	}
+	return found, nil
+}
+
+// checkUnearnedSkills finds badges that no verified attendance earned, and
+// withdraws them if repair is set.
+func checkUnearnedSkills(ctx context.Context, tx *sql.Tx, repair bool) ([]models.InvariantViolation, error) {
+	var found []models.InvariantViolation
+	rows, err := tx.QueryContext(ctx,
+		`SELECT us.id, us.user_id, us.skill_id, us.event_id
+		 FROM user_skills us
+		 LEFT JOIN attendances a ON a.event_id = us.event_id AND a.student_id = us.user_id AND a.status = 'verified'
+		 WHERE a.id IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id, userID, skillID, eventID string
+		if err := rows.Scan(&id, &userID, &skillID, &eventID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		found = append(found, models.InvariantViolation{
+			Invariant: models.InvariantSkillAttendance,
+			Subject:   id,
+			Detail:    fmt.Sprintf("user %s holds skill %s from event %s without a verified attendance", userID, skillID, eventID),
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !repair {
+		return found, nil
+	}
+	for _, v := range found {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_skills WHERE id = ?`, v.Subject); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// checkSlots recounts slots_remaining: capacity less the confirmed and
+// offered registrations, never below zero (hosts may confirm past capacity
+// when resolving conflicts), and NULL for events without a capacity.  It
+// corrects the counter if repair is set.  Verified attendees without a
+// registration count as confirmed, as checkAttendanceRegistrations makes
+// them; after that repair there are none left.
+func checkSlots(ctx context.Context, tx *sql.Tx, repair bool) ([]models.InvariantViolation, error) {
+	type recount struct {
+		eventID string
+		slots   sql.NullInt64
+	}
+	var found []models.InvariantViolation
+	var fixes []recount
+	rows, err := tx.QueryContext(ctx,
+		`SELECT e.id, e.capacity, e.slots_remaining,
+		        (SELECT COUNT(*) FROM registrations r WHERE r.event_id = e.id AND r.status IN ('confirmed','offered')) +
+		        (SELECT COUNT(DISTINCT a.student_id) FROM attendances a
+		         LEFT JOIN registrations r ON r.event_id = a.event_id AND r.student_id = a.student_id
+		         WHERE a.event_id = e.id AND a.status = 'verified' AND r.id IS NULL)
+		 FROM events e`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id string
+		var capacity, slots sql.NullInt64
+		var held int64
+		if err := rows.Scan(&id, &capacity, &slots, &held); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		want := sql.NullInt64{}
+		if capacity.Valid {
+			want = sql.NullInt64{Int64: max(capacity.Int64-held, 0), Valid: true}
+		}
+		if want == slots {
+			continue
+		}
+		found = append(found, models.InvariantViolation{
+			Invariant: models.InvariantSlots,
+			Subject:   id,
+			Detail:    fmt.Sprintf("slots_remaining is %s, want %s", nullCount(slots), nullCount(want)),
+		})
+		fixes = append(fixes, recount{id, want})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !repair {
+		return found, nil
+	}
+	for _, f := range fixes {
+		if _, err := tx.ExecContext(ctx, `UPDATE events SET slots_remaining = ? WHERE id = ?`, f.slots, f.eventID); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+func nullCount(n sql.NullInt64) string {
+	if !n.Valid {
+		return "NULL"
+	}
+	return fmt.Sprint(n.Int64)
+}
+
+// CheckDatabase handles GET /api/admin/check  (admin only)
+//
+// Reports invariant violations without changing anything.
+func (s *Server) CheckDatabase(w http.ResponseWriter, r *http.Request) {
+	s.checkDatabase(w, r, false)
+}
+
+// RepairDatabase handles POST /api/admin/check/repair  (admin only)
+//
+// Reports invariant violations and fixes them in one transaction.
+func (s *Server) RepairDatabase(w http.ResponseWriter, r *http.Request) {
+	s.checkDatabase(w, r, true)
+}
+
+func (s *Server) checkDatabase(w http.ResponseWriter, r *http.Request, repair bool) {
+	report, err := CheckInvariants(r.Context(), s.DB, repair)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not check database")
+		return
+	}
+	respond(w, http.StatusOK, report)
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestCheckInvariants_SeedIsConsistent(t *testing.T) {
+	srv := newTestServer(t)
+	runSeed(t, srv)
+
+	report, err := CheckInvariants(context.Background(), srv.DB, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Violations) != 0 {
+		t.Errorf("seed data breaks invariants: %+v", report.Violations)
+	}
+}
+
+func TestCheckInvariants_ReportsThenRepairs(t *testing.T) {
+	srv := newTestServer(t)
+	host := seedCompanyUser(t, srv)
+	admin := seedCompanyUser(t, srv)
+	eventID, _ := seedEventWithCapacity(t, srv, host, 5)
+	attendee, badgeHolder := seedStudentUser(t, srv), seedStudentUser(t, srv)
+	asUser(t, srv.RegisterForEvent, http.MethodPost, badgeHolder, "student", nil, "id", eventID)
+
+	skillID := uuid.NewString()
+	now := time.Now().UTC()
+	for _, stmt := range []struct {
+		query string
+		args  []any
+	}{
+		{`INSERT INTO skills (id, name) VALUES (?, 'Welding')`, []any{skillID}},
+		{`INSERT INTO event_skills (event_id, skill_id) VALUES (?, ?)`, []any{eventID, skillID}},
+		{`INSERT INTO event_skills (event_id, skill_id) VALUES ('gone', ?)`, []any{skillID}},
+		{`INSERT INTO attendances (id, event_id, student_id, payload, status, created_at, updated_at)
+		  VALUES (?, ?, ?, '{}', 'verified', ?, ?)`, []any{uuid.NewString(), eventID, attendee, now, now}},
+		{`INSERT INTO user_skills (id, user_id, skill_id, event_id) VALUES (?, ?, ?, ?)`,
+			[]any{uuid.NewString(), badgeHolder, skillID, eventID}},
+		{`UPDATE events SET slots_remaining = 0 WHERE id = ?`, []any{eventID}},
+	} {
+		if _, err := srv.DB.Exec(stmt.query, stmt.args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[models.Invariant]bool{
+		models.InvariantOrphanedEventSkill:     true,
+		models.InvariantAttendanceRegistration: true,
+		models.InvariantSkillAttendance:        true,
+		models.InvariantSlots:                  true,
+	}
+	check := func(handler http.HandlerFunc, method string) models.CheckReport {
+		t.Helper()
+		rec := asUser(t, handler, method, admin, "admin", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var report models.CheckReport
+		json.NewDecoder(rec.Body).Decode(&report)
+		return report
+	}
+
+	report := check(srv.CheckDatabase, http.MethodGet)
+	if report.Repaired || len(report.Violations) != len(want) {
+		t.Fatalf("check: unexpected report %+v", report)
+	}
+	for _, v := range report.Violations {
+		if !want[v.Invariant] {
+			t.Errorf("unexpected violation %+v", v)
+		}
+	}
+	// Checking changes nothing.
+	if n := dbInt(t, srv, `SELECT slots_remaining FROM events WHERE id = ?`, eventID); n != 0 {
+		t.Errorf("check changed slots_remaining to %d", n)
+	}
+
+	repaired := check(srv.RepairDatabase, http.MethodPost)
+	if !repaired.Repaired || !reflect.DeepEqual(repaired.Violations, report.Violations) {
+		t.Fatalf("repair: expected the violations the check reported, got %+v", repaired)
+	}
+	// Two seats taken: the registration and the attendee's new one.
+	if n := dbInt(t, srv, `SELECT slots_remaining FROM events WHERE id = ?`, eventID); n != 3 {
+		t.Errorf("slots_remaining: expected 3, got %d", n)
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM registrations WHERE student_id = ? AND status = 'confirmed'`, attendee); n != 1 {
+		t.Errorf("attendee was not registered")
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM user_skills`); n != 0 {
+		t.Errorf("unearned badge kept")
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM event_skills`); n != 1 {
+		t.Errorf("expected only the valid skill link to remain, got %d", n)
+	}
+	if report := check(srv.CheckDatabase, http.MethodGet); len(report.Violations) != 0 {
+		t.Errorf("after repair: %+v", report.Violations)
+	}
+}
+
+// TestCheckInvariants_CheckDoesNotWaitForWriters runs a check while another
+// connection holds the write lock: a check only reads, so it must not queue
+// behind sign-ups and syncs.
+func TestCheckInvariants_CheckDoesNotWaitForWriters(t *testing.T) {
+	srv := newFileTestServer(t)
+	seedEventWithCapacity(t, srv, seedCompanyUser(t, srv), 5)
+
+	writer, err := srv.DB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Rollback() //nolint:errcheck
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := CheckInvariants(context.Background(), srv.DB, false)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the check waited for the write lock")
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — per-event roles
+// ────────────────────────────────────────────────────────────────────
+// At a big event the organisation needs extra hands at the door: student
+// volunteers who show the check-in QR or keep the attendee list.  Making
+// them organisation staff would let them run every event, so instead the
+// organisation's members grant a role on ONE event (event_roles):
+//
+//	scanner     — GET /checkin-code, to show the QR code
+//	coordinator — scanner, plus GET /registrations
+//
+// A grant never allows more: editing the event, rotating the check-in
+// secret, changing its status and removing or resolving registrations stay
+// with the organisation.  Each handler lists the roles it accepts in its
+// authorizeEvent call.  Any account can be granted a role, students
+// included, so those two routes are not company-only.
+
+// ListEventRoles handles GET /api/events/{id}/roles  (organisation members)
+func (s *Server) ListEventRoles(w http.ResponseWriter, r *http.Request) {
+	eventID := r.PathValue("id")
+	if !s.authorizeEvent(w, r, eventID, models.OrgStaff) {
+		return
+	}
+
+	rows, err := s.DB.QueryContext(r.Context(),
+		`SELECT g.user_id, u.name, u.email, g.role, g.granted_by, g.created_at
+		 FROM event_roles g
+		 JOIN users u ON u.id = g.user_id
+		 WHERE g.event_id = ?
+		 ORDER BY g.created_at, u.name`, eventID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	grants := []models.EventRoleGrant{}
+	for rows.Next() {
+		g := models.EventRoleGrant{EventID: eventID}
+		if err := rows.Scan(&g.UserID, &g.Name, &g.Email, &g.Role, &g.GrantedBy, &g.CreatedAt); err != nil {
+			respondError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		grants = append(grants, g)
+	}
+	if err := rows.Err(); err != nil {
+		respondError(w, http.StatusInternalServerError, "rows error")
+		return
+	}
+
+	respond(w, http.StatusOK, grants)
+}
+
+// GrantEventRole handles POST /api/events/{id}/roles  (organisation members)
+//
+// Body: {"email": "volunteer@uni.ac.ke", "role": "scanner" | "coordinator"}.
+// Staff may grant: the roles only allow things staff can do themselves.
+func (s *Server) GrantEventRole(w http.ResponseWriter, r *http.Request) {
+	eventID := r.PathValue("id")
+	if !s.authorizeEvent(w, r, eventID, models.OrgStaff) {
+		return
+	}
+
+	var req models.GrantEventRoleRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.Role != models.EventScanner && req.Role != models.EventCoordinator {
+		respondError(w, http.StatusBadRequest, "role must be 'scanner' or 'coordinator'")
+		return
+	}
+
+	g := models.EventRoleGrant{
+		EventID:   eventID,
+		Email:     strings.ToLower(strings.TrimSpace(req.Email)),
+		Role:      req.Role,
+		GrantedBy: middleware.GetUserID(r.Context()),
+		CreatedAt: time.Now().UTC(),
+	}
+	err := s.DB.QueryRowContext(r.Context(),
+		`SELECT id, name FROM users WHERE email = ? AND status != 'deleted'`, g.Email,
+	).Scan(&g.UserID, &g.Name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "no account with that email")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if _, err := s.DB.ExecContext(r.Context(),
+		`INSERT INTO event_roles (event_id, user_id, role, granted_by, created_at) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT (event_id, user_id) DO UPDATE SET role = excluded.role, granted_by = excluded.granted_by`,
+		eventID, g.UserID, g.Role, g.GrantedBy, g.CreatedAt,
+	); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not grant role")
+		return
+	}
+
+	respond(w, http.StatusCreated, g)
+}
+
+// RevokeEventRole handles DELETE /api/events/{id}/roles/{user_id}  (organisation members)
+func (s *Server) RevokeEventRole(w http.ResponseWriter, r *http.Request) {
+	eventID := r.PathValue("id")
+	if !s.authorizeEvent(w, r, eventID, models.OrgStaff) {
+		return
+	}
+
+	result, err := s.DB.ExecContext(r.Context(),
+		`DELETE FROM event_roles WHERE event_id = ? AND user_id = ?`, eventID, r.PathValue("user_id"))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusNotFound, "no role on this event")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
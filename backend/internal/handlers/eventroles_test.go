@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+)
+
+func TestEventRoles_VolunteersOnlyGetWhatIsGranted(t *testing.T) {
+	srv := newTestServer(t)
+	host := seedCompanyUser(t, srv)
+	outsider := seedCompanyUser(t, srv)
+	volunteer := seedStudentUser(t, srv)
+	volunteerEmail := dbString(t, srv, `SELECT email FROM users WHERE id = ?`, volunteer)
+	eventID, _ := seedEvent(t, srv, host)
+	attendee := seedStudentUser(t, srv)
+	asUser(t, srv.RegisterForEvent, http.MethodPost, attendee, "student", nil, "id", eventID)
+	regID := dbString(t, srv, `SELECT id FROM registrations WHERE event_id = ?`, eventID)
+
+	grant := func(actor string, role models.EventRole) int {
+		return asCompany(t, srv.GrantEventRole, http.MethodPost, actor,
+			models.GrantEventRoleRequest{Email: volunteerEmail, Role: role}, "id", eventID).Code
+	}
+	asVolunteer := func(handler http.HandlerFunc, method string, body any) int {
+		return asUser(t, handler, method, volunteer, "student", body, "id", eventID, "reg_id", regID).Code
+	}
+	checkInCode := func() int { return asVolunteer(srv.GetEventCheckInCode, http.MethodGet, nil) }
+	registrations := func() int { return asVolunteer(srv.GetEventRegistrations, http.MethodGet, nil) }
+
+	if code := checkInCode(); code != http.StatusForbidden {
+		t.Errorf("before any grant: expected 403, got %d", code)
+	}
+	if code := grant(outsider, models.EventScanner); code != http.StatusForbidden {
+		t.Errorf("grant by a non-member: expected 403, got %d", code)
+	}
+
+	if code := grant(host, models.EventScanner); code != http.StatusCreated {
+		t.Fatalf("grant scanner: expected 201, got %d", code)
+	}
+	if code := checkInCode(); code != http.StatusOK {
+		t.Errorf("scanner check-in code: expected 200, got %d", code)
+	}
+	if code := registrations(); code != http.StatusForbidden {
+		t.Errorf("scanner registrations: expected 403, got %d", code)
+	}
+
+	// A second grant replaces the first.
+	if code := grant(host, models.EventCoordinator); code != http.StatusCreated {
+		t.Fatalf("grant coordinator: expected 201, got %d", code)
+	}
+	if code := registrations(); code != http.StatusOK {
+		t.Errorf("coordinator registrations: expected 200, got %d", code)
+	}
+	if code := checkInCode(); code != http.StatusOK {
+		t.Errorf("coordinator check-in code: expected 200, got %d", code)
+	}
+	// Editing the event and removing people stay with the organisation.
+	if code := asVolunteer(srv.UpdateEvent, http.MethodPut, models.UpdateEventRequest{Title: "Mine"}); code != http.StatusForbidden {
+		t.Errorf("coordinator edit: expected 403, got %d", code)
+	}
+	if code := asVolunteer(srv.KickRegistration, http.MethodDelete, nil); code != http.StatusForbidden {
+		t.Errorf("coordinator kick: expected 403, got %d", code)
+	}
+	if code := asVolunteer(srv.RotateEventCheckInCode, http.MethodPost, nil); code != http.StatusForbidden {
+		t.Errorf("coordinator rotate: expected 403, got %d", code)
+	}
+
+	rec := asCompany(t, srv.ListEventRoles, http.MethodGet, host, nil, "id", eventID)
+	var grants []models.EventRoleGrant
+	json.NewDecoder(rec.Body).Decode(&grants)
+	if len(grants) != 1 || grants[0].UserID != volunteer || grants[0].Role != models.EventCoordinator {
+		t.Errorf("unexpected grants %+v", grants)
+	}
+
+	if rec := asCompany(t, srv.RevokeEventRole, http.MethodDelete, host, nil,
+		"id", eventID, "user_id", volunteer); rec.Code != http.StatusNoContent {
+		t.Fatalf("revoke: expected 204, got %d", rec.Code)
+	}
+	if code := checkInCode(); code != http.StatusForbidden {
+		t.Errorf("after revoke: expected 403, got %d", code)
+	}
+	if rec := asCompany(t, srv.GrantEventRole, http.MethodPost, host,
+		models.GrantEventRoleRequest{Email: "nobody@test.com", Role: models.EventScanner}, "id", eventID); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown email: expected 404, got %d", rec.Code)
+	}
+}
@@ -18,9 +18,26 @@ import (
 // LEARNING NOTE — database transactions
 // We use a transaction (tx) here because we need to INSERT two things
 // atomically: the event row AND the event_skills rows.
+//
+// The event belongs to req.OrganisationID, or to the one organisation the
+// caller manages (see hostingOrganisation in organisations.go).
 func (s *Server) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	hostID := middleware.GetUserID(r.Context())
 
+	// Only companies that proved their address may advertise events (see
+	// verification.go).
+	if !s.EmailVerification.AllowUnverifiedHosts {
+		verified, err := s.emailVerified(r.Context(), hostID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if !verified {
+			respondError(w, http.StatusForbidden, "verify your email address before creating events")
+			return
+		}
+	}
+
 	var req models.CreateEventRequest
 	if err := decode(r, &req); err != nil {
 		respondError(w, http.StatusBadRequest, "invalid JSON")
@@ -40,6 +57,14 @@ func (s *Server) CreateEvent(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "end_time must be after start_time")
 		return
 	}
+	if req.SyncDeadline != nil && !req.SyncDeadline.After(req.EndTime) {
+		respondError(w, http.StatusBadRequest, "sync_deadline must be after end_time")
+		return
+	}
+	if req.CheckInRotationSeconds != 0 && !validRotation(req.CheckInRotationSeconds) {
+		respondError(w, http.StatusBadRequest, rotationRangeMsg)
+		return
+	}
 
 	event := models.Event{
 		ID:          uuid.NewString(),
@@ -53,6 +78,16 @@ func (s *Server) CreateEvent(w http.ResponseWriter, r *http.Request) {
 		CheckInCode: uuid.NewString(),
 		CreatedAt:   time.Now().UTC(),
 		UpdatedAt:   time.Now().UTC(),
+
+		ManualStatus: req.ManualStatus,
+	}
+	if req.SyncDeadline != nil {
+		d := req.SyncDeadline.UTC()
+		event.SyncDeadline = &d
+	}
+	if req.CheckInRotationSeconds > 0 {
+		n := req.CheckInRotationSeconds
+		event.CheckInRotationSeconds = &n
 	}
 
 	// Set capacity if provided (> 0 means limited slots).
@@ -70,13 +105,26 @@ func (s *Server) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback() //nolint:errcheck
 
+	event.OrganisationID, err = hostingOrganisation(r.Context(), tx, hostID, req.OrganisationID)
+	switch {
+	case errors.Is(err, errNoHostingOrganisation):
+		respondError(w, http.StatusForbidden, err.Error())
+		return
+	case errors.Is(err, errSeveralOrganisations):
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
 	_, err = tx.ExecContext(r.Context(),
-		`INSERT INTO events (id, host_id, title, description, location, start_time, end_time, status, check_in_code, capacity, slots_remaining, created_at, updated_at)
- VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO events (id, host_id, title, description, location, start_time, end_time, status, check_in_code, capacity, slots_remaining, sync_deadline, checkin_rotation_seconds, created_at, updated_at, organisation_id, manual_status)
+ VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		event.ID, event.HostID, event.Title, event.Description, event.Location,
 		event.StartTime, event.EndTime, event.Status, event.CheckInCode,
-		event.Capacity, event.SlotsRemaining,
-		event.CreatedAt, event.UpdatedAt,
+		event.Capacity, event.SlotsRemaining, event.SyncDeadline, event.CheckInRotationSeconds,
+		event.CreatedAt, event.UpdatedAt, event.OrganisationID, event.ManualStatus,
 	)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "could not create event")
@@ -105,10 +153,28 @@ func (s *Server) CreateEvent(w http.ResponseWriter, r *http.Request) {
 
 // ListEvents handles GET /api/events (public)
 func (s *Server) ListEvents(w http.ResponseWriter, r *http.Request) {
-	rows, err := s.DB.QueryContext(r.Context(),
-		`SELECT id, host_id, title, description, location, start_time, end_time, status,
-        capacity, slots_remaining, created_at, updated_at
- FROM events ORDER BY start_time ASC`)
+	s.listEvents(w, r, `SELECT id, host_id, title, description, location, start_time, end_time, status,
+        capacity, slots_remaining, sync_deadline, checkin_rotation_seconds, created_at, updated_at, organisation_id,
+        cancellation_reason, cancelled_at, manual_status
+ FROM events WHERE deleted_at IS NULL ORDER BY start_time ASC`)
+}
+
+// ListHostedEvents handles GET /api/events/hosted  (company only)
+// Returns the events of every organisation the caller belongs to; API keys
+// need the events:read scope.
+func (s *Server) ListHostedEvents(w http.ResponseWriter, r *http.Request) {
+	s.listEvents(w, r, `SELECT id, host_id, title, description, location, start_time, end_time, status,
+        capacity, slots_remaining, sync_deadline, checkin_rotation_seconds, created_at, updated_at, organisation_id,
+        cancellation_reason, cancelled_at, manual_status
+ FROM events
+ WHERE organisation_id IN (SELECT organisation_id FROM organisation_members WHERE user_id = ?)
+   AND deleted_at IS NULL
+ ORDER BY start_time ASC`, middleware.GetUserID(r.Context()))
+}
+
+// listEvents responds with the events query selects, with their skills.
+func (s *Server) listEvents(w http.ResponseWriter, r *http.Request, query string, args ...any) {
+	rows, err := s.DB.QueryContext(r.Context(), query, args...)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "database error")
 		return
@@ -120,8 +186,8 @@ func (s *Server) ListEvents(w http.ResponseWriter, r *http.Request) {
 		var e models.Event
 		if err := rows.Scan(&e.ID, &e.HostID, &e.Title, &e.Description, &e.Location,
 			&e.StartTime, &e.EndTime, &e.Status,
-			&e.Capacity, &e.SlotsRemaining,
-			&e.CreatedAt, &e.UpdatedAt); err != nil {
+			&e.Capacity, &e.SlotsRemaining, &e.SyncDeadline, &e.CheckInRotationSeconds,
+			&e.CreatedAt, &e.UpdatedAt, &e.OrganisationID, &e.CancellationReason, &e.CancelledAt, &e.ManualStatus); err != nil {
 			respondError(w, http.StatusInternalServerError, "scan error")
 			return
 		}
@@ -143,12 +209,13 @@ func (s *Server) GetEvent(w http.ResponseWriter, r *http.Request) {
 	var e models.Event
 	err := s.DB.QueryRowContext(r.Context(),
 		`SELECT id, host_id, title, description, location, start_time, end_time, status,
-        capacity, slots_remaining, created_at, updated_at
- FROM events WHERE id = ?`, id,
+        capacity, slots_remaining, sync_deadline, checkin_rotation_seconds, created_at, updated_at, organisation_id,
+        cancellation_reason, cancelled_at, manual_status
+ FROM events WHERE id = ? AND deleted_at IS NULL`, id,
 	).Scan(&e.ID, &e.HostID, &e.Title, &e.Description, &e.Location,
 		&e.StartTime, &e.EndTime, &e.Status,
-		&e.Capacity, &e.SlotsRemaining,
-		&e.CreatedAt, &e.UpdatedAt)
+		&e.Capacity, &e.SlotsRemaining, &e.SyncDeadline, &e.CheckInRotationSeconds,
+		&e.CreatedAt, &e.UpdatedAt, &e.OrganisationID, &e.CancellationReason, &e.CancelledAt, &e.ManualStatus)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			respondError(w, http.StatusNotFound, "event not found")
@@ -162,66 +229,158 @@ func (s *Server) GetEvent(w http.ResponseWriter, r *http.Request) {
 	respond(w, http.StatusOK, e)
 }
 
-// GetEventCheckInCode handles GET /api/events/{id}/checkin-code  (host only)
+// GetEventCheckInCode handles GET /api/events/{id}/checkin-code  (organisation members, scanners, coordinators)
 //
 // Returns a short-lived signed JWT (valid for auth.CheckInTokenDuration, currently
 // 6 hours) that the host's PWA encodes into a QR code.
 //
-// The JWT carries event_id and host_sig claims and is signed with the server
-// secret.  Students scan the QR and store the raw token string in IndexedDB.
-// When they sync later the server verifies the signature to confirm the student
-// was physically present during the QR's live window, without requiring an
-// internet connection at scan time.
+// The JWT carries event_id and host_sig claims and is signed with the server's
+// Ed25519 check-in key.  Students scan the QR and store the raw token string in IndexedDB.
+// When they sync later the server verifies the signature and that host_sig is
+// still the event's check_in_code, to confirm the student was physically
+// present during the QR's live window, without requiring an internet
+// connection at scan time.
+//
+// For events with checkin_rotation_seconds set, the token is only valid for
+// the current time step (see auth.GenerateRotatingCheckInToken).  The response
+// then also carries rotation_seconds, and expires_in_seconds counts down to
+// the next step — the host screen re-fetches when it reaches zero.
 func (s *Server) GetEventCheckInCode(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
-	hostID := middleware.GetUserID(r.Context())
+	if !s.authorizeEvent(w, r, id, models.OrgStaff, models.EventScanner, models.EventCoordinator) {
+		return
+	}
 
-	var dbHostID, checkInCode string
+	var checkInCode string
+	var rotation sql.NullInt64
+	var status models.EventStatus
 	err := s.DB.QueryRowContext(r.Context(),
-		`SELECT host_id, check_in_code FROM events WHERE id = ?`, id,
-	).Scan(&dbHostID, &checkInCode)
+		`SELECT check_in_code, checkin_rotation_seconds, status FROM events WHERE id = ?`, id,
+	).Scan(&checkInCode, &rotation, &status)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			respondError(w, http.StatusNotFound, "event not found")
-			return
-		}
 		respondError(w, http.StatusInternalServerError, "database error")
 		return
 	}
+	// A QR code for a cancelled event could only produce rejected check-ins.
+	if status == models.EventStatusCancelled {
+		respondError(w, http.StatusConflict, "the event is cancelled")
+		return
+	}
 
-	if dbHostID != hostID {
-		respondError(w, http.StatusForbidden, "you are not the host of this event")
+	s.respondCheckInToken(w, id, checkInCode, rotation)
+}
+
+// RotateEventCheckInCode handles POST /api/events/{id}/checkin-code/rotate  (organisation members)
+//
+// Replaces the event's check_in_code with a fresh random secret.  Every token
+// minted with the old secret carries the old value as its host_sig claim, so
+// the sync handler rejects all of them from now on — use this when a QR code
+// has leaked (e.g. a photo of the projector was posted in a group chat).
+// Check-ins already verified are not affected.
+//
+// The response has the same shape as GET /checkin-code, with a token for the
+// new secret, so the host screen can redraw the QR immediately.
+func (s *Server) RotateEventCheckInCode(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !s.authorizeEvent(w, r, id, models.OrgStaff) {
+		return
+	}
+
+	var rotation sql.NullInt64
+	err := s.DB.QueryRowContext(r.Context(),
+		`SELECT checkin_rotation_seconds FROM events WHERE id = ?`, id,
+	).Scan(&rotation)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	checkInCode := uuid.NewString()
+	_, err = s.DB.ExecContext(r.Context(),
+		`UPDATE events SET check_in_code = ?, updated_at = ? WHERE id = ?`,
+		checkInCode, time.Now().UTC(), id,
+	)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not rotate check-in code")
+		return
+	}
+
+	s.respondCheckInToken(w, id, checkInCode, rotation)
+}
+
+// respondCheckInToken writes the check-in token response shared by
+// GetEventCheckInCode and RotateEventCheckInCode.
+func (s *Server) respondCheckInToken(w http.ResponseWriter, eventID, checkInCode string, rotation sql.NullInt64) {
+	if rotation.Valid {
+		period := time.Duration(rotation.Int64) * time.Second
+		now := time.Now()
+		token, stepEnd, err := auth.GenerateRotatingCheckInToken(eventID, checkInCode, s.Keys.CheckInSigner(), period, now)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "could not generate check-in token")
+			return
+		}
+		respond(w, http.StatusOK, map[string]any{
+			"event_id":           eventID,
+			"token":              token,
+			"expires_in_seconds": int(stepEnd.Sub(now).Round(time.Second).Seconds()),
+			"rotation_seconds":   rotation.Int64,
+		})
 		return
 	}
 
 	// Generate a signed check-in token that expires after CheckInTokenDuration.
-	// The token embeds event_id and host_sig so the sync handler can verify both
-	// without any additional database lookup.
-	token, err := auth.GenerateCheckInToken(id, checkInCode, s.Secret)
+	// The token embeds event_id and host_sig; the sync handler compares host_sig
+	// with the event's current check_in_code.
+	token, err := auth.GenerateCheckInToken(eventID, checkInCode, s.Keys.CheckInSigner())
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "could not generate check-in token")
 		return
 	}
 
 	respond(w, http.StatusOK, map[string]any{
-		"event_id":           id,
+		"event_id":           eventID,
 		"token":              token,
 		"expires_in_seconds": int(auth.CheckInTokenDuration.Seconds()),
 	})
 }
 
-// UpdateEventStatus handles PATCH /api/events/{id}/status  (host only)
+// Rotation periods outside this range are rejected: shorter ones give phone
+// cameras too little time to read the code, longer ones defeat the purpose.
+const (
+	minRotationSeconds = 10
+	maxRotationSeconds = 600
+)
+
+const rotationRangeMsg = "checkin_rotation_seconds must be 0 or between 10 and 600"
+
+func validRotation(n int) bool {
+	return n >= minRotationSeconds && n <= maxRotationSeconds
+}
+
+// maxCancellationReason bounds the reason given when cancelling an event.
+const maxCancellationReason = 500
+
+// deletedEventReason is the cancellation reason of an event deleted while
+// still upcoming or active.
+const deletedEventReason = "The organiser removed this event."
+
+// UpdateEventStatus handles PATCH /api/events/{id}/status  (organisation members)
 //
 // Allows the host to move an event through its lifecycle:
 //
 //	upcoming → active  (open check-in QR)
 //	active   → completed  (end-of-day / early close)
+//	upcoming / active → cancelled  (admins and owners; needs a reason)
 //
 // When an event is marked completed, the sync endpoint will still
-// accept attendance records already in flight (within the 24-h window).
+// accept attendance records already in flight until its sync deadline.
+// Unless the event has manual_status set, the status scheduler (see
+// scheduler.go) also makes these moves on time, and will redo one a host
+// undoes.
+// Cancelling is final: the event stays listed, with its reason, so its
+// registrants find out, but it takes no new registrations or check-ins.
 func (s *Server) UpdateEventStatus(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
-	hostID := middleware.GetUserID(r.Context())
 
 	var req models.UpdateEventStatusRequest
 	if err := decode(r, &req); err != nil {
@@ -229,73 +388,131 @@ func (s *Server) UpdateEventStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	allowed := req.Status == models.EventStatusActive ||
-		req.Status == models.EventStatusCompleted ||
-		req.Status == models.EventStatusUpcoming
-	if !allowed {
-		respondError(w, http.StatusBadRequest, "status must be upcoming, active, or completed")
+	need := models.OrgStaff
+	switch req.Status {
+	case models.EventStatusUpcoming, models.EventStatusActive, models.EventStatusCompleted:
+		req.Reason = ""
+	case models.EventStatusCancelled:
+		// Cancelling cannot be undone, so it is for those who may edit.
+		need = models.OrgAdmin
+		req.Reason = strings.TrimSpace(req.Reason)
+		if req.Reason == "" || len(req.Reason) > maxCancellationReason {
+			respondError(w, http.StatusBadRequest, "cancelling needs a reason of at most 500 characters")
+			return
+		}
+	default:
+		respondError(w, http.StatusBadRequest, "status must be upcoming, active, completed, or cancelled")
 		return
 	}
 
-	// Verify ownership.
-	var dbHostID string
-	err := s.DB.QueryRowContext(r.Context(),
-		`SELECT host_id FROM events WHERE id = ?`, id,
-	).Scan(&dbHostID)
+	if !s.authorizeEvent(w, r, id, need) {
+		return
+	}
+
+	now := time.Now().UTC()
+	query := `UPDATE events SET status = ?, updated_at = ? WHERE id = ? AND status != 'cancelled'`
+	args := []any{req.Status, now, id}
+	conflict := "the event is cancelled"
+	if req.Status == models.EventStatusCancelled {
+		query = `UPDATE events SET status = 'cancelled', cancellation_reason = ?, cancelled_at = ?, updated_at = ?
+		 WHERE id = ? AND status IN ('upcoming','active')`
+		args = []any{req.Reason, now, now, id}
+		conflict = "only upcoming or active events can be cancelled"
+	}
+	result, err := s.DB.ExecContext(r.Context(), query, args...)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			respondError(w, http.StatusNotFound, "event not found")
-			return
-		}
-		respondError(w, http.StatusInternalServerError, "database error")
+		respondError(w, http.StatusInternalServerError, "could not update status")
 		return
 	}
-	if dbHostID != hostID {
-		respondError(w, http.StatusForbidden, "you are not the host of this event")
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusConflict, conflict)
 		return
 	}
 
-	_, err = s.DB.ExecContext(r.Context(),
-		`UPDATE events SET status = ?, updated_at = ? WHERE id = ?`,
-		req.Status, time.Now().UTC(), id,
-	)
+	out := map[string]string{"event_id": id, "status": string(req.Status)}
+	if req.Reason != "" {
+		out["cancellation_reason"] = req.Reason
+	}
+	respond(w, http.StatusOK, out)
+}
+
+// DeleteEvent handles DELETE /api/events/{id}  (organisation admins and owners)
+//
+// A soft delete: the row stays, because registrations, attendances and
+// badges point at it, but deleted_at hides the event from every listing and
+// from GET /api/events/{id}, and every management route answers 404.  An
+// event that had not finished is cancelled too, so its registrants see why
+// in GET /api/users/me/registrations.  Event roles are revoked.
+func (s *Server) DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !s.authorizeEvent(w, r, id, models.OrgAdmin) {
+		return
+	}
+
+	now := time.Now().UTC()
+	tx, err := s.DB.BeginTx(r.Context(), nil)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "could not update status")
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.ExecContext(r.Context(),
+		`UPDATE events SET status = 'cancelled', cancellation_reason = ?, cancelled_at = ?
+		 WHERE id = ? AND status IN ('upcoming','active')`, deletedEventReason, now, id,
+	); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not delete event")
+		return
+	}
+	if _, err := tx.ExecContext(r.Context(),
+		`UPDATE events SET deleted_at = ?, updated_at = ? WHERE id = ?`, now, now, id,
+	); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not delete event")
+		return
+	}
+	if _, err := tx.ExecContext(r.Context(), `DELETE FROM event_roles WHERE event_id = ?`, id); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not delete event")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
 		return
 	}
 
-	respond(w, http.StatusOK, map[string]string{"event_id": id, "status": string(req.Status)})
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// GetEventRegistrations handles GET /api/events/{id}/registrations  (host only)
+// GetEventRegistrations handles GET /api/events/{id}/registrations  (organisation members, coordinators)
 //
 // Returns all registrations for the event, including student details and status.
 // The host uses this to see the attendee list and to spot conflict_pending entries
 // that need resolution (e.g. when two offline applicants both claim the last internship slot).
+// Expired waitlist offers are dropped first, so the list is current.
 func (s *Server) GetEventRegistrations(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
-	hostID := middleware.GetUserID(r.Context())
+	if !s.authorizeEvent(w, r, id, models.OrgStaff, models.EventCoordinator) {
+		return
+	}
 
-	// Ownership check.
-	var dbHostID string
-	err := s.DB.QueryRowContext(r.Context(),
-		`SELECT host_id FROM events WHERE id = ?`, id,
-	).Scan(&dbHostID)
+	tx, err := s.DB.BeginTx(r.Context(), nil)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			respondError(w, http.StatusNotFound, "event not found")
-			return
-		}
 		respondError(w, http.StatusInternalServerError, "database error")
 		return
 	}
-	if dbHostID != hostID {
-		respondError(w, http.StatusForbidden, "you are not the host of this event")
+	defer tx.Rollback() //nolint:errcheck
+	promoted, err := s.settleWaitlist(r.Context(), tx, id, time.Now().UTC())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not update waitlist")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
 		return
 	}
+	s.notifyPromotions(r.Context(), promoted)
 
 	rows, err := s.DB.QueryContext(r.Context(),
-		`SELECT r.id, r.event_id, r.student_id, r.registered_at, r.status,
+		`SELECT r.id, r.event_id, r.student_id, r.registered_at, r.status, r.offer_expires_at,
         u.name, u.email
  FROM registrations r
  JOIN users u ON u.id = r.student_id
@@ -316,13 +533,17 @@ func (s *Server) GetEventRegistrations(w http.ResponseWriter, r *http.Request) {
 	var regs []RegWithStudent
 	for rows.Next() {
 		var reg RegWithStudent
+		var offerExpiresAt sql.NullTime
 		if err := rows.Scan(
-			&reg.ID, &reg.EventID, &reg.StudentID, &reg.RegisteredAt, &reg.Status,
+			&reg.ID, &reg.EventID, &reg.StudentID, &reg.RegisteredAt, &reg.Status, &offerExpiresAt,
 			&reg.StudentName, &reg.StudentEmail,
 		); err != nil {
 			respondError(w, http.StatusInternalServerError, "scan error")
 			return
 		}
+		if offerExpiresAt.Valid {
+			reg.OfferExpiresAt = &offerExpiresAt.Time
+		}
 		regs = append(regs, reg)
 	}
 	if err := rows.Err(); err != nil {
@@ -336,7 +557,7 @@ func (s *Server) GetEventRegistrations(w http.ResponseWriter, r *http.Request) {
 	respond(w, http.StatusOK, regs)
 }
 
-// ResolveRegistrationConflict handles PATCH /api/events/{id}/registrations/{reg_id}  (host only)
+// ResolveRegistrationConflict handles PATCH /api/events/{id}/registrations/{reg_id}  (organisation members)
 //
 // This is the conflict-resolution endpoint for the internship demo scenario.
 //
@@ -353,7 +574,6 @@ func (s *Server) GetEventRegistrations(w http.ResponseWriter, r *http.Request) {
 func (s *Server) ResolveRegistrationConflict(w http.ResponseWriter, r *http.Request) {
 	eventID := r.PathValue("id")
 	regID := r.PathValue("reg_id")
-	hostID := middleware.GetUserID(r.Context())
 
 	var req models.ResolveConflictRequest
 	if err := decode(r, &req); err != nil {
@@ -365,27 +585,13 @@ func (s *Server) ResolveRegistrationConflict(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Verify host owns the event.
-	var dbHostID string
-	err := s.DB.QueryRowContext(r.Context(),
-		`SELECT host_id FROM events WHERE id = ?`, eventID,
-	).Scan(&dbHostID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			respondError(w, http.StatusNotFound, "event not found")
-			return
-		}
-		respondError(w, http.StatusInternalServerError, "database error")
-		return
-	}
-	if dbHostID != hostID {
-		respondError(w, http.StatusForbidden, "you are not the host of this event")
+	if !s.authorizeEvent(w, r, eventID, models.OrgStaff) {
 		return
 	}
 
 	// Check the registration exists and is conflict_pending.
 	var currentStatus models.RegistrationStatus
-	err = s.DB.QueryRowContext(r.Context(),
+	err := s.DB.QueryRowContext(r.Context(),
 		`SELECT status FROM registrations WHERE id = ? AND event_id = ?`, regID, eventID,
 	).Scan(&currentStatus)
 	if err != nil {
@@ -424,79 +630,58 @@ func (s *Server) ResolveRegistrationConflict(w http.ResponseWriter, r *http.Requ
 // RegisterForEvent handles POST /api/events/{id}/register  (student only)
 //
 // If the event has a capacity limit and slots are available, the registration
-// is confirmed and slots_remaining is decremented atomically.
+// is confirmed and slots_remaining is decremented atomically (see claimSeat).
 // If no slots remain, the registration is still recorded as conflict_pending
-// so the host can resolve it manually.
+// so the host can resolve it manually.  Registering twice returns the
+// existing registration.  Cancelled events refuse registrations with 409.
 func (s *Server) RegisterForEvent(w http.ResponseWriter, r *http.Request) {
 	eventID := r.PathValue("id")
 	studentID := middleware.GetUserID(r.Context())
+	now := time.Now().UTC()
 
-	// Load capacity info.
-	var capacity, slotsRemaining sql.NullInt64
-	var exists bool
-	err := s.DB.QueryRowContext(r.Context(),
-		`SELECT 1, capacity, slots_remaining FROM events WHERE id = ?`, eventID,
-	).Scan(&exists, &capacity, &slotsRemaining)
-	if err != nil || !exists {
-		respondError(w, http.StatusNotFound, "event not found")
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
 		return
 	}
+	defer tx.Rollback() //nolint:errcheck
 
-	// Determine registration status.
-	regStatus := models.RegistrationConfirmed
-	if capacity.Valid && capacity.Int64 > 0 && slotsRemaining.Valid && slotsRemaining.Int64 <= 0 {
-		regStatus = models.RegistrationConflictPending
+	var status models.EventStatus
+	if err := tx.QueryRowContext(r.Context(),
+		`SELECT status FROM events WHERE id = ? AND deleted_at IS NULL`, eventID,
+	).Scan(&status); err != nil {
+		respondError(w, http.StatusNotFound, "event not found")
+		return
 	}
-
-	reg := models.Registration{
-		ID:           uuid.NewString(),
-		EventID:      eventID,
-		StudentID:    studentID,
-		RegisteredAt: time.Now().UTC(),
-		Status:       regStatus,
+	if status == models.EventStatusCancelled {
+		respondError(w, http.StatusConflict, "the event is cancelled")
+		return
 	}
 
-	tx, err := s.DB.BeginTx(r.Context(), nil)
+	// Seats freed by expired offers go to those already waiting, not to
+	// whoever registers next.
+	promoted, err := s.settleWaitlist(r.Context(), tx, eventID, now)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "database error")
+		respondError(w, http.StatusInternalServerError, "could not update waitlist")
 		return
 	}
-	defer tx.Rollback() //nolint:errcheck
 
-	// INSERT OR IGNORE: if already registered, keep the existing record.
-	result, err := tx.ExecContext(r.Context(),
-		`INSERT OR IGNORE INTO registrations (id, event_id, student_id, registered_at, status)
- VALUES (?, ?, ?, ?, ?)`,
-		reg.ID, reg.EventID, reg.StudentID, reg.RegisteredAt, reg.Status,
-	)
+	reg, _, err := s.claimSeat(r.Context(), tx, eventID, studentID, now)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "could not register")
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected > 0 && regStatus == models.RegistrationConfirmed && capacity.Valid {
-		// Decrement slot counter only when a new confirmed registration was inserted.
-		_, err = tx.ExecContext(r.Context(),
-			`UPDATE events SET slots_remaining = slots_remaining - 1, updated_at = ?
- WHERE id = ? AND slots_remaining > 0`,
-			time.Now().UTC(), eventID,
-		)
-		if err != nil {
-			respondError(w, http.StatusInternalServerError, "could not update slots")
-			return
-		}
-	}
-
 	if err := tx.Commit(); err != nil {
 		respondError(w, http.StatusInternalServerError, "database error")
 		return
 	}
+	s.notifyPromotions(r.Context(), promoted)
 
 	respond(w, http.StatusCreated, reg)
 }
 
-// UpdateEvent handles PUT /api/events/{id}  (host only)
+// UpdateEvent handles PUT /api/events/{id}  (organisation admins and owners)
 //
 // Allows the event host to adjust title, description, location, start/end
 // times, capacity, and linked skills. Partial updates are supported — omitted
@@ -504,37 +689,44 @@ func (s *Server) RegisterForEvent(w http.ResponseWriter, r *http.Request) {
 // decreased below the number of confirmed registrations.
 func (s *Server) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
-	hostID := middleware.GetUserID(r.Context())
 
-	// Verify ownership and fetch current values.
+	// Editing is for the organisation's admins and owners; staff only run
+	// the event.
+	if !s.authorizeEvent(w, r, id, models.OrgAdmin) {
+		return
+	}
+
+	// Decode partial update request.
+	var req models.UpdateEventRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	// Fetch current values in the transaction that writes them back, so
+	// seats taken meanwhile are counted.
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
 	var e models.Event
 	var cap, slots sql.NullInt64
-	err := s.DB.QueryRowContext(r.Context(),
+	err = tx.QueryRowContext(r.Context(),
 		`SELECT id, host_id, title, description, location, start_time, end_time,
-		        status, check_in_code, capacity, slots_remaining, created_at, updated_at
+		        status, check_in_code, capacity, slots_remaining, sync_deadline, checkin_rotation_seconds, created_at, updated_at,
+		        manual_status
 		 FROM events WHERE id = ?`, id,
 	).Scan(&e.ID, &e.HostID, &e.Title, &e.Description, &e.Location,
 		&e.StartTime, &e.EndTime, &e.Status, &e.CheckInCode,
-		&cap, &slots, &e.CreatedAt, &e.UpdatedAt)
+		&cap, &slots, &e.SyncDeadline, &e.CheckInRotationSeconds, &e.CreatedAt, &e.UpdatedAt,
+		&e.ManualStatus)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			respondError(w, http.StatusNotFound, "event not found")
-			return
-		}
 		respondError(w, http.StatusInternalServerError, "database error")
 		return
 	}
-	if e.HostID != hostID {
-		respondError(w, http.StatusForbidden, "you are not the host of this event")
-		return
-	}
-
-	// Decode partial update request.
-	var req models.UpdateEventRequest
-	if err := decode(r, &req); err != nil {
-		respondError(w, http.StatusBadRequest, "invalid JSON")
-		return
-	}
 
 	// Apply patch: only update fields that are provided.
 	if t := strings.TrimSpace(req.Title); t != "" {
@@ -556,6 +748,28 @@ func (s *Server) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "end_time must be after start_time")
 		return
 	}
+	if req.SyncDeadline != nil {
+		d := req.SyncDeadline.UTC()
+		e.SyncDeadline = &d
+	}
+	if e.SyncDeadline != nil && !e.SyncDeadline.After(e.EndTime) {
+		respondError(w, http.StatusBadRequest, "sync_deadline must be after end_time")
+		return
+	}
+	if req.CheckInRotationSeconds != nil {
+		switch n := *req.CheckInRotationSeconds; {
+		case n == 0:
+			e.CheckInRotationSeconds = nil
+		case validRotation(n):
+			e.CheckInRotationSeconds = &n
+		default:
+			respondError(w, http.StatusBadRequest, rotationRangeMsg)
+			return
+		}
+	}
+	if req.ManualStatus != nil {
+		e.ManualStatus = *req.ManualStatus
+	}
 	now := time.Now().UTC()
 	e.UpdatedAt = now
 
@@ -568,42 +782,32 @@ func (s *Server) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 			newCap = sql.NullInt64{}
 			newSlots = sql.NullInt64{}
 		} else {
-			// Count confirmed registrations to prevent shrinking below current count.
+			// Count confirmed registrations (and held offers) to prevent
+			// shrinking below current count.
 			var confirmed int64
-			_ = s.DB.QueryRowContext(r.Context(),
-				`SELECT COUNT(*) FROM registrations WHERE event_id = ? AND status = 'confirmed'`, id,
-			).Scan(&confirmed)
+			if err := tx.QueryRowContext(r.Context(),
+				`SELECT COUNT(*) FROM registrations WHERE event_id = ? AND status IN ('confirmed','offered')`, id,
+			).Scan(&confirmed); err != nil {
+				respondError(w, http.StatusInternalServerError, "database error")
+				return
+			}
 			if int64(*req.Capacity) < confirmed {
 				respondError(w, http.StatusBadRequest, "capacity cannot be less than current confirmed registrations")
 				return
 			}
 			newCap = sql.NullInt64{Int64: int64(*req.Capacity), Valid: true}
-			// Adjust slots_remaining proportionally.
-			if cap.Valid {
-				used := cap.Int64 - slots.Int64
-				newSlots = sql.NullInt64{Int64: int64(*req.Capacity) - used, Valid: true}
-				if newSlots.Int64 < 0 {
-					newSlots.Int64 = 0
-				}
-			} else {
-				newSlots = sql.NullInt64{Int64: int64(*req.Capacity), Valid: true}
-			}
+			// The seats in use stay in use, whether the event was limited
+			// before or not.
+			newSlots = sql.NullInt64{Int64: int64(*req.Capacity) - confirmed, Valid: true}
 		}
 	}
 
-	tx, err := s.DB.BeginTx(r.Context(), nil)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "database error")
-		return
-	}
-	defer tx.Rollback() //nolint:errcheck
-
 	_, err = tx.ExecContext(r.Context(),
 		`UPDATE events SET title=?, description=?, location=?, start_time=?, end_time=?,
-		  capacity=?, slots_remaining=?, updated_at=?
+		  capacity=?, slots_remaining=?, sync_deadline=?, checkin_rotation_seconds=?, manual_status=?, updated_at=?
 		 WHERE id=?`,
 		e.Title, e.Description, e.Location, e.StartTime, e.EndTime,
-		newCap, newSlots, now, id,
+		newCap, newSlots, e.SyncDeadline, e.CheckInRotationSeconds, e.ManualStatus, now, id,
 	)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "could not update event")
@@ -626,20 +830,30 @@ func (s *Server) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// More seats (or none left to count) let the waitlist move up.
+	promoted, err := s.settleWaitlist(r.Context(), tx, id, now)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not update waitlist")
+		return
+	}
+
 	if err := tx.Commit(); err != nil {
 		respondError(w, http.StatusInternalServerError, "database error")
 		return
 	}
+	s.notifyPromotions(r.Context(), promoted)
 
 	// Re-read the full event to return accurate capacity/slots values.
 	var updated models.Event
 	_ = s.DB.QueryRowContext(r.Context(),
 		`SELECT id, host_id, title, description, location, start_time, end_time, status,
-		        capacity, slots_remaining, created_at, updated_at
+		        capacity, slots_remaining, sync_deadline, checkin_rotation_seconds, created_at, updated_at, organisation_id,
+		        cancellation_reason, cancelled_at, manual_status
 		 FROM events WHERE id = ?`, id,
 	).Scan(&updated.ID, &updated.HostID, &updated.Title, &updated.Description,
 		&updated.Location, &updated.StartTime, &updated.EndTime, &updated.Status,
-		&updated.Capacity, &updated.SlotsRemaining, &updated.CreatedAt, &updated.UpdatedAt)
+		&updated.Capacity, &updated.SlotsRemaining, &updated.SyncDeadline, &updated.CheckInRotationSeconds, &updated.CreatedAt, &updated.UpdatedAt,
+		&updated.OrganisationID, &updated.CancellationReason, &updated.CancelledAt, &updated.ManualStatus)
 	updated.Skills = s.fetchEventSkills(r, id)
 	respond(w, http.StatusOK, updated)
 }
@@ -647,125 +861,99 @@ func (s *Server) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 // UnregisterFromEvent handles DELETE /api/events/{id}/register  (student only)
 //
 // Removes the student's registration from the event and restores the slot if
-// the registration was confirmed and the event has a capacity limit.
+// the registration held one (confirmed or offered) and the event has a
+// capacity limit; the slot goes to the next on the waitlist.  Unregistering
+// is also how a student declines an offered seat.
 func (s *Server) UnregisterFromEvent(w http.ResponseWriter, r *http.Request) {
 	eventID := r.PathValue("id")
 	studentID := middleware.GetUserID(r.Context())
 
-	// Find the registration.
-	var regID string
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// The status comes from the row actually deleted, so a double-click
+	// gives back one seat, not two, and a promotion made since the page
+	// loaded is accounted for.
 	var regStatus models.RegistrationStatus
-	err := s.DB.QueryRowContext(r.Context(),
-		`SELECT id, status FROM registrations WHERE event_id = ? AND student_id = ?`,
+	err = tx.QueryRowContext(r.Context(),
+		`DELETE FROM registrations WHERE event_id = ? AND student_id = ? RETURNING status`,
 		eventID, studentID,
-	).Scan(&regID, &regStatus)
+	).Scan(&regStatus)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			respondError(w, http.StatusNotFound, "registration not found")
 			return
 		}
-		respondError(w, http.StatusInternalServerError, "database error")
+		respondError(w, http.StatusInternalServerError, "could not remove registration")
 		return
 	}
 
-	tx, err := s.DB.BeginTx(r.Context(), nil)
+	// Restore slot if this registration held one for a capacity-limited
+	// event, and pass it on to the waitlist.
+	promoted, err := s.releaseSeat(r.Context(), tx, eventID, regStatus)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "database error")
+		respondError(w, http.StatusInternalServerError, "could not update waitlist")
 		return
 	}
-	defer tx.Rollback() //nolint:errcheck
-
-	if _, err = tx.ExecContext(r.Context(),
-		`DELETE FROM registrations WHERE id = ?`, regID,
-	); err != nil {
-		respondError(w, http.StatusInternalServerError, "could not remove registration")
-		return
-	}
-
-	// Restore slot if this was a confirmed registration for a capacity-limited event.
-	if regStatus == models.RegistrationConfirmed {
-		tx.ExecContext(r.Context(), //nolint:errcheck
-			`UPDATE events SET slots_remaining = slots_remaining + 1, updated_at = ?
-			 WHERE id = ? AND capacity IS NOT NULL`,
-			time.Now().UTC(), eventID,
-		)
-	}
 
 	if err := tx.Commit(); err != nil {
 		respondError(w, http.StatusInternalServerError, "database error")
 		return
 	}
+	s.notifyPromotions(r.Context(), promoted)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// KickRegistration handles DELETE /api/events/{id}/registrations/{reg_id}  (host only)
+// KickRegistration handles DELETE /api/events/{id}/registrations/{reg_id}  (organisation members)
 //
 // Allows the host to remove any registration (regardless of status) and
-// restore the slot if applicable.
+// restore the slot if applicable, for the next on the waitlist.
 func (s *Server) KickRegistration(w http.ResponseWriter, r *http.Request) {
 	eventID := r.PathValue("id")
 	regID := r.PathValue("reg_id")
-	hostID := middleware.GetUserID(r.Context())
 
-	// Verify host ownership.
-	var dbHostID string
-	err := s.DB.QueryRowContext(r.Context(),
-		`SELECT host_id FROM events WHERE id = ?`, eventID,
-	).Scan(&dbHostID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			respondError(w, http.StatusNotFound, "event not found")
-			return
-		}
-		respondError(w, http.StatusInternalServerError, "database error")
+	if !s.authorizeEvent(w, r, eventID, models.OrgStaff) {
 		return
 	}
-	if dbHostID != hostID {
-		respondError(w, http.StatusForbidden, "you are not the host of this event")
+
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
 		return
 	}
+	defer tx.Rollback() //nolint:errcheck
 
-	// Get the registration status before deleting.
+	// As in UnregisterFromEvent, the deleted row says whether a seat
+	// comes back.
 	var regStatus models.RegistrationStatus
-	err = s.DB.QueryRowContext(r.Context(),
-		`SELECT status FROM registrations WHERE id = ? AND event_id = ?`, regID, eventID,
+	err = tx.QueryRowContext(r.Context(),
+		`DELETE FROM registrations WHERE id = ? AND event_id = ? RETURNING status`, regID, eventID,
 	).Scan(&regStatus)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			respondError(w, http.StatusNotFound, "registration not found")
 			return
 		}
-		respondError(w, http.StatusInternalServerError, "database error")
+		respondError(w, http.StatusInternalServerError, "could not remove registration")
 		return
 	}
 
-	tx, err := s.DB.BeginTx(r.Context(), nil)
+	promoted, err := s.releaseSeat(r.Context(), tx, eventID, regStatus)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "database error")
-		return
-	}
-	defer tx.Rollback() //nolint:errcheck
-
-	if _, err = tx.ExecContext(r.Context(),
-		`DELETE FROM registrations WHERE id = ?`, regID,
-	); err != nil {
-		respondError(w, http.StatusInternalServerError, "could not remove registration")
+		respondError(w, http.StatusInternalServerError, "could not update waitlist")
 		return
 	}
 
-	if regStatus == models.RegistrationConfirmed {
-		tx.ExecContext(r.Context(), //nolint:errcheck
-			`UPDATE events SET slots_remaining = slots_remaining + 1, updated_at = ?
-			 WHERE id = ? AND capacity IS NOT NULL`,
-			time.Now().UTC(), eventID,
-		)
-	}
-
 	if err := tx.Commit(); err != nil {
 		respondError(w, http.StatusInternalServerError, "database error")
 		return
 	}
+	s.notifyPromotions(r.Context(), promoted)
 
 	w.WriteHeader(http.StatusNoContent)
 }
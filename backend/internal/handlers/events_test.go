@@ -8,6 +8,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/db"
 	"github.com/Elizabethomito/skillzone/backend/internal/models"
 	"github.com/google/uuid"
 )
@@ -17,7 +19,7 @@ func seedCompanyUser(t *testing.T, srv *Server) string {
 	t.Helper()
 	id := uuid.NewString()
 	_, err := srv.DB.Exec(
-		`INSERT INTO users (id, email, password_hash, name, role) VALUES (?, ?, ?, ?, 'company')`,
+		`INSERT INTO users (id, email, password_hash, name, role, email_verified_at) VALUES (?, ?, ?, ?, 'company', CURRENT_TIMESTAMP)`,
 		id, fmt.Sprintf("company-%s@test.com", id), "hash", "Test Company",
 	)
 	if err != nil {
@@ -31,7 +33,7 @@ func seedStudentUser(t *testing.T, srv *Server) string {
 	t.Helper()
 	id := uuid.NewString()
 	_, err := srv.DB.Exec(
-		`INSERT INTO users (id, email, password_hash, name, role) VALUES (?, ?, ?, ?, 'student')`,
+		`INSERT INTO users (id, email, password_hash, name, role, email_verified_at) VALUES (?, ?, ?, ?, 'student', CURRENT_TIMESTAMP)`,
 		id, fmt.Sprintf("student-%s@test.com", id), "hash", "Test Student",
 	)
 	if err != nil {
@@ -54,6 +56,15 @@ func seedSkill(t *testing.T, srv *Server, name string) string {
 	return id
 }
 
+// assignOrganisations puts events inserted directly into the personal
+// organisation of their host, as the start-up migration would.
+func assignOrganisations(t *testing.T, srv *Server) {
+	t.Helper()
+	if err := db.AssignEventOrganisations(t.Context(), srv.DB); err != nil {
+		t.Fatalf("assignOrganisations: %v", err)
+	}
+}
+
 // seedEvent inserts an event and returns its ID and check_in_code.
 func seedEvent(t *testing.T, srv *Server, hostID string) (eventID, checkInCode string) {
 	t.Helper()
@@ -70,6 +81,7 @@ func seedEvent(t *testing.T, srv *Server, hostID string) (eventID, checkInCode s
 	if err != nil {
 		t.Fatalf("seedEvent: %v", err)
 	}
+	assignOrganisations(t, srv)
 	return
 }
 
@@ -122,6 +134,126 @@ func TestCreateEvent_MissingTitle(t *testing.T) {
 	}
 }
 
+func TestCreateEvent_SyncDeadline(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	end := time.Now().Add(3 * time.Hour)
+
+	create := func(deadline time.Time) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/events", jsonBody(t, models.CreateEventRequest{
+			Title:        "Deadline Workshop",
+			StartTime:    time.Now().Add(1 * time.Hour),
+			EndTime:      end,
+			SyncDeadline: &deadline,
+		}))
+		req = ctxWithUser(req, companyID, "company")
+		rec := httptest.NewRecorder()
+		srv.CreateEvent(rec, req)
+		return rec
+	}
+
+	if rec := create(end.Add(-time.Minute)); rec.Code != http.StatusBadRequest {
+		t.Errorf("deadline before end_time: expected 400, got %d", rec.Code)
+	}
+
+	rec := create(end.Add(48 * time.Hour))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created models.Event
+	json.NewDecoder(rec.Body).Decode(&created)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/events/"+created.ID, nil)
+	req.SetPathValue("id", created.ID)
+	rec = httptest.NewRecorder()
+	srv.GetEvent(rec, req)
+	var got models.Event
+	json.NewDecoder(rec.Body).Decode(&got)
+	if got.SyncDeadline == nil || !got.SyncDeadline.Equal(*created.SyncDeadline) {
+		t.Errorf("sync_deadline: got %v, want %v", got.SyncDeadline, created.SyncDeadline)
+	}
+}
+
+func TestGetEventCheckInCode_Rotating(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+
+	create := func(rotation int) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/events", jsonBody(t, models.CreateEventRequest{
+			Title:                  "Rotating Workshop",
+			StartTime:              time.Now().Add(1 * time.Hour),
+			EndTime:                time.Now().Add(3 * time.Hour),
+			CheckInRotationSeconds: rotation,
+		}))
+		req = ctxWithUser(req, companyID, "company")
+		rec := httptest.NewRecorder()
+		srv.CreateEvent(rec, req)
+		return rec
+	}
+
+	if rec := create(3); rec.Code != http.StatusBadRequest {
+		t.Errorf("rotation below minimum: expected 400, got %d", rec.Code)
+	}
+
+	rec := create(30)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var e models.Event
+	json.NewDecoder(rec.Body).Decode(&e)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/events/"+e.ID+"/checkin-code", nil)
+	req.SetPathValue("id", e.ID)
+	req = ctxWithUser(req, companyID, "company")
+	rec = httptest.NewRecorder()
+	srv.GetEventCheckInCode(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Token           string `json:"token"`
+		ExpiresIn       int    `json:"expires_in_seconds"`
+		RotationSeconds int    `json:"rotation_seconds"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.RotationSeconds != 30 {
+		t.Errorf("rotation_seconds: got %d, want 30", resp.RotationSeconds)
+	}
+	if resp.ExpiresIn < 0 || resp.ExpiresIn > 30 {
+		t.Errorf("expires_in_seconds: got %d, want within one step", resp.ExpiresIn)
+	}
+	claims, err := auth.ParseCheckInToken(resp.Token, testKeys)
+	if err != nil {
+		t.Fatalf("ParseCheckInToken: %v", err)
+	}
+	if !claims.Rotating() || claims.Period != 30 {
+		t.Errorf("expected a rotating token with period 30, got period %d", claims.Period)
+	}
+}
+
+func TestRotateEventCheckInCode_OnlyHost(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	otherCompany := seedCompanyUser(t, srv)
+	eventID, checkInCode := seedEvent(t, srv, companyID)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/events/"+eventID+"/checkin-code/rotate", nil)
+	req.SetPathValue("id", eventID)
+	req = ctxWithUser(req, otherCompany, "company")
+	rec := httptest.NewRecorder()
+	srv.RotateEventCheckInCode(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", rec.Code)
+	}
+
+	var stored string
+	srv.DB.QueryRow(`SELECT check_in_code FROM events WHERE id = ?`, eventID).Scan(&stored)
+	if stored != checkInCode {
+		t.Error("check_in_code changed after a forbidden rotate")
+	}
+}
+
 func TestListEvents(t *testing.T) {
 	srv := newTestServer(t)
 	companyID := seedCompanyUser(t, srv)
@@ -194,6 +326,7 @@ func seedEventWithCapacity(t *testing.T, srv *Server, hostID string, capacity in
 	if err != nil {
 		t.Fatalf("seedEventWithCapacity: %v", err)
 	}
+	assignOrganisations(t, srv)
 	return
 }
 
@@ -254,6 +387,105 @@ func TestUpdateEventStatus(t *testing.T) {
 	}
 }
 
+func TestUpdateEventStatus_Cancel(t *testing.T) {
+	srv := newTestServer(t)
+	host := seedCompanyUser(t, srv)
+	staff := seedCompanyUser(t, srv)
+	eventID, _ := seedEventWithCapacity(t, srv, host, 5)
+	if _, err := srv.DB.Exec(
+		`INSERT INTO organisation_members (organisation_id, user_id, role, created_at) VALUES (?, ?, 'staff', ?)`,
+		dbString(t, srv, `SELECT organisation_id FROM events WHERE id = ?`, eventID), staff, time.Now().UTC()); err != nil {
+		t.Fatal(err)
+	}
+	student := seedStudentUser(t, srv)
+	asUser(t, srv.RegisterForEvent, http.MethodPost, student, "student", nil, "id", eventID)
+	setStatus := func(actor string, req models.UpdateEventStatusRequest) int {
+		return asCompany(t, srv.UpdateEventStatus, http.MethodPatch, actor, req, "id", eventID).Code
+	}
+	cancel := models.UpdateEventStatusRequest{Status: models.EventStatusCancelled, Reason: "  Venue flooded  "}
+
+	if code := setStatus(host, models.UpdateEventStatusRequest{Status: models.EventStatusCancelled, Reason: " "}); code != http.StatusBadRequest {
+		t.Errorf("cancel without a reason: expected 400, got %d", code)
+	}
+	if code := setStatus(staff, cancel); code != http.StatusForbidden {
+		t.Errorf("staff cancels: expected 403, got %d", code)
+	}
+	if code := setStatus(host, cancel); code != http.StatusOK {
+		t.Fatalf("cancel: expected 200, got %d", code)
+	}
+	if code := setStatus(host, models.UpdateEventStatusRequest{Status: models.EventStatusActive}); code != http.StatusConflict {
+		t.Errorf("reopen: expected 409, got %d", code)
+	}
+	if code := setStatus(host, cancel); code != http.StatusConflict {
+		t.Errorf("cancel twice: expected 409, got %d", code)
+	}
+
+	late := seedStudentUser(t, srv)
+	if rec := asUser(t, srv.RegisterForEvent, http.MethodPost, late, "student", nil, "id", eventID); rec.Code != http.StatusConflict {
+		t.Errorf("register: expected 409, got %d", rec.Code)
+	}
+	if rec := asCompany(t, srv.GetEventCheckInCode, http.MethodGet, host, nil, "id", eventID); rec.Code != http.StatusConflict {
+		t.Errorf("check-in code: expected 409, got %d", rec.Code)
+	}
+
+	// The registrant sees why.
+	rec := asUser(t, srv.GetMyRegistrations, http.MethodGet, student, "student", nil)
+	var mine []struct {
+		EventStatus        models.EventStatus `json:"event_status"`
+		CancellationReason string             `json:"cancellation_reason"`
+	}
+	json.NewDecoder(rec.Body).Decode(&mine)
+	if len(mine) != 1 || mine[0].EventStatus != models.EventStatusCancelled || mine[0].CancellationReason != "Venue flooded" {
+		t.Errorf("unexpected registrations %+v", mine)
+	}
+}
+
+func TestDeleteEvent(t *testing.T) {
+	srv := newTestServer(t)
+	host := seedCompanyUser(t, srv)
+	eventID, _ := seedEvent(t, srv, host)
+	student, scanner := seedStudentUser(t, srv), seedStudentUser(t, srv)
+	asUser(t, srv.RegisterForEvent, http.MethodPost, student, "student", nil, "id", eventID)
+	if _, err := srv.DB.Exec(
+		`INSERT INTO event_roles (event_id, user_id, role, granted_by) VALUES (?, ?, 'scanner', ?)`,
+		eventID, scanner, host); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := asCompany(t, srv.DeleteEvent, http.MethodDelete, seedCompanyUser(t, srv), nil, "id", eventID); rec.Code != http.StatusForbidden {
+		t.Errorf("outsider deletes: expected 403, got %d", rec.Code)
+	}
+	if rec := asCompany(t, srv.DeleteEvent, http.MethodDelete, host, nil, "id", eventID); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := asUser(t, srv.GetEvent, http.MethodGet, student, "student", nil, "id", eventID); rec.Code != http.StatusNotFound {
+		t.Errorf("get: expected 404, got %d", rec.Code)
+	}
+	var events []models.Event
+	json.NewDecoder(asUser(t, srv.ListEvents, http.MethodGet, student, "student", nil).Body).Decode(&events)
+	if len(events) != 0 {
+		t.Errorf("deleted event still listed")
+	}
+	if rec := asCompany(t, srv.DeleteEvent, http.MethodDelete, host, nil, "id", eventID); rec.Code != http.StatusNotFound {
+		t.Errorf("delete twice: expected 404, got %d", rec.Code)
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM event_roles WHERE event_id = ?`, eventID); n != 0 {
+		t.Errorf("event roles kept")
+	}
+
+	// The row stays, cancelled, so the registrant still finds it.
+	rec := asUser(t, srv.GetMyRegistrations, http.MethodGet, student, "student", nil)
+	var mine []struct {
+		EventStatus        models.EventStatus `json:"event_status"`
+		CancellationReason string             `json:"cancellation_reason"`
+	}
+	json.NewDecoder(rec.Body).Decode(&mine)
+	if len(mine) != 1 || mine[0].EventStatus != models.EventStatusCancelled || mine[0].CancellationReason == "" {
+		t.Errorf("unexpected registrations %+v", mine)
+	}
+}
+
 func TestResolveRegistrationConflict(t *testing.T) {
 	srv := newTestServer(t)
 	companyID := seedCompanyUser(t, srv)
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — institution affiliations
+// ────────────────────────────────────────────────────────────────────
+// Anyone can type "University of Nairobi" into a profile, so recruiters
+// cannot trust a claimed affiliation.  An email address they CAN trust,
+// once it is verified: only people the university gave an account to can
+// read mail at @students.uonbi.ac.ke.  So admins record each institution
+// together with the email domains they have checked belong to it, and a
+// student's affiliation is derived, never entered:
+//
+//	users.institution_id = the institution whose domain the student's
+//	                       verified email is on (longest match wins, so
+//	                       "cs.uni.ac.ke" can belong to another institution
+//	                       than "uni.ac.ke"), or NULL.
+//
+// It is recomputed (affiliationSQL) whenever one of its inputs changes: an
+// address is verified or replaced, a role changes, or an admin adds, edits
+// or deletes an institution.
+
+// maxInstitutionDomains bounds the domain list of one institution.
+const maxInstitutionDomains = 20
+
+// domainRE matches a lower-case DNS name with at least two labels.
+var domainRE = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// affiliationSQL sets institution_id from the email domain for verified
+// students, and clears it for everyone else.  Callers append a WHERE
+// clause.  Domains only hold [a-z0-9.-], so they cannot contain LIKE
+// wildcards, and the "." in the second pattern stops "uni.ac.ke" matching
+// "notuni.ac.ke".
+const affiliationSQL = `UPDATE users SET institution_id = CASE
+    WHEN role = 'student' AND email_verified_at IS NOT NULL THEN (
+        SELECT d.institution_id FROM institution_domains d
+        WHERE users.email LIKE '%@' || d.domain OR users.email LIKE '%.' || d.domain
+        ORDER BY length(d.domain) DESC LIMIT 1)
+    END`
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// updateAffiliation recomputes userID's institution after their email,
+// its verification or their role changed.
+func updateAffiliation(ctx context.Context, db execer, userID string) error {
+	_, err := db.ExecContext(ctx, affiliationSQL+` WHERE id = ?`, userID)
+	return err
+}
+
+// reaffiliateUsers recomputes every affiliation after the domains changed.
+func reaffiliateUsers(ctx context.Context, db execer) error {
+	_, err := db.ExecContext(ctx, affiliationSQL+` WHERE role = 'student' OR institution_id IS NOT NULL`)
+	return err
+}
+
+// normalizeDomains lower-cases, de-duplicates and validates domains.  A
+// leading "@" is dropped, so "@uni.ac.ke" is accepted.
+func normalizeDomains(domains []string) ([]string, error) {
+	if len(domains) == 0 {
+		return nil, errors.New("at least one domain is required")
+	}
+	var out []string
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "@")
+		if len(d) > 253 || !domainRE.MatchString(d) {
+			return nil, errors.New("invalid domain " + d)
+		}
+		if !slices.Contains(out, d) {
+			out = append(out, d)
+		}
+	}
+	if len(out) > maxInstitutionDomains {
+		return nil, errors.New("too many domains")
+	}
+	return out, nil
+}
+
+// loadInstitution reads one institution with its domains.
+func (s *Server) loadInstitution(ctx context.Context, id string) (models.Institution, error) {
+	inst := models.Institution{ID: id, Domains: []string{}}
+	if err := s.DB.QueryRowContext(ctx,
+		`SELECT name, created_at FROM institutions WHERE id = ?`, id,
+	).Scan(&inst.Name, &inst.CreatedAt); err != nil {
+		return models.Institution{}, err
+	}
+	rows, err := s.DB.QueryContext(ctx,
+		`SELECT domain FROM institution_domains WHERE institution_id = ? ORDER BY domain`, id)
+	if err != nil {
+		return models.Institution{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d string
+		if err := rows.Scan(&d); err != nil {
+			return models.Institution{}, err
+		}
+		inst.Domains = append(inst.Domains, d)
+	}
+	return inst, rows.Err()
+}
+
+// ListInstitutions handles GET /api/institutions  (public)
+// Returns every institution with its domains, by name — for the
+// affiliation filter of candidate search.
+func (s *Server) ListInstitutions(w http.ResponseWriter, r *http.Request) {
+	rows, err := s.DB.QueryContext(r.Context(),
+		`SELECT i.id, i.name, i.created_at, d.domain
+		 FROM institutions i
+		 LEFT JOIN institution_domains d ON d.institution_id = i.id
+		 ORDER BY i.name, d.domain`)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	institutions := []models.Institution{}
+	for rows.Next() {
+		var inst models.Institution
+		var domain sql.NullString
+		if err := rows.Scan(&inst.ID, &inst.Name, &inst.CreatedAt, &domain); err != nil {
+			respondError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		if n := len(institutions); n == 0 || institutions[n-1].ID != inst.ID {
+			inst.Domains = []string{}
+			institutions = append(institutions, inst)
+		}
+		if domain.Valid {
+			last := &institutions[len(institutions)-1]
+			last.Domains = append(last.Domains, domain.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		respondError(w, http.StatusInternalServerError, "rows error")
+		return
+	}
+
+	respond(w, http.StatusOK, institutions)
+}
+
+// CreateInstitution handles POST /api/admin/institutions  (admin only)
+//
+// Body: {"name": "University of Nairobi", "domains": ["uonbi.ac.ke"]}.
+// Students already verified on those domains are affiliated at once.
+func (s *Server) CreateInstitution(w http.ResponseWriter, r *http.Request) {
+	s.saveInstitution(w, r, "")
+}
+
+// UpdateInstitution handles PUT /api/admin/institutions/{id}  (admin only)
+// Replaces the name and the whole domain list; affiliations follow.
+func (s *Server) UpdateInstitution(w http.ResponseWriter, r *http.Request) {
+	s.saveInstitution(w, r, r.PathValue("id"))
+}
+
+// saveInstitution creates an institution (id == "") or replaces one.
+func (s *Server) saveInstitution(w http.ResponseWriter, r *http.Request, id string) {
+	var req models.InstitutionRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 255 {
+		respondError(w, http.StatusBadRequest, "name is required (at most 255 characters)")
+		return
+	}
+	domains, err := normalizeDomains(req.Domains)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	status := http.StatusOK
+	var result sql.Result
+	if id == "" {
+		id, status = uuid.NewString(), http.StatusCreated
+		result, err = tx.ExecContext(ctx,
+			`INSERT INTO institutions (id, name, created_at) VALUES (?, ?, ?)`, id, name, time.Now().UTC())
+	} else {
+		result, err = tx.ExecContext(ctx, `UPDATE institutions SET name = ? WHERE id = ?`, name, id)
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			respondError(w, http.StatusConflict, "an institution with that name already exists")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "could not save institution")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusNotFound, "institution not found")
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM institution_domains WHERE institution_id = ?`, id); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	for _, d := range domains {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO institution_domains (domain, institution_id) VALUES (?, ?)`, d, id,
+		); err != nil {
+			if strings.Contains(err.Error(), "UNIQUE") {
+				respondError(w, http.StatusConflict, "domain "+d+" belongs to another institution")
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+	}
+	if err := reaffiliateUsers(ctx, tx); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	inst, err := s.loadInstitution(ctx, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	respond(w, status, inst)
+}
+
+// DeleteInstitution handles DELETE /api/admin/institutions/{id}  (admin only)
+// Its students lose the affiliation, or fall back to another institution
+// that covers a shorter domain of their address.
+func (s *Server) DeleteInstitution(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// Deleted explicitly rather than by ON DELETE CASCADE, so the
+	// reaffiliation below is right even where foreign keys are off.
+	id := r.PathValue("id")
+	if _, err := tx.ExecContext(ctx, `DELETE FROM institution_domains WHERE institution_id = ?`, id); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM institutions WHERE id = ?`, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusNotFound, "institution not found")
+		return
+	}
+	if err := reaffiliateUsers(ctx, tx); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+)
+
+// saveInstitutionAs creates (id == "") or replaces an institution as an admin.
+func saveInstitutionAs(t *testing.T, srv *Server, id string, req models.InstitutionRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	method, handler := http.MethodPost, srv.CreateInstitution
+	if id != "" {
+		method, handler = http.MethodPut, srv.UpdateInstitution
+	}
+	r := httptest.NewRequest(method, "/api/admin/institutions/"+id, jsonBody(t, req))
+	r.SetPathValue("id", id)
+	rec := httptest.NewRecorder()
+	handler(rec, ctxWithUser(r, "admin", "admin"))
+	return rec
+}
+
+// searchByInstitution returns the students candidate search lists for
+// institutionID.
+func searchByInstitution(t *testing.T, srv *Server, institutionID string) []models.User {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/api/users/students?institution_id="+institutionID, nil)
+	rec := httptest.NewRecorder()
+	srv.SearchStudents(rec, ctxWithUser(r, "c", "company"))
+	var students []models.User
+	json.NewDecoder(rec.Body).Decode(&students)
+	return students
+}
+
+func TestInstitutions_AffiliationFollowsVerifiedEmail(t *testing.T) {
+	srv := newTestServer(t)
+	box := &outbox{}
+	srv.Mailer = box
+
+	// Verified before the institution exists: affiliated when it is added.
+	early := registerUser(t, srv, "early@students.uonbi.ac.ke", models.RoleStudent)
+	verifyEmail(t, srv, box.verifyToken(t))
+	lookalike := registerUser(t, srv, "x@notuonbi.ac.ke", models.RoleStudent)
+	verifyEmail(t, srv, box.verifyToken(t))
+
+	rec := saveInstitutionAs(t, srv, "", models.InstitutionRequest{
+		Name: "University of Nairobi", Domains: []string{" @UoNbi.ac.ke", "uonbi.ac.ke"},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var uon models.Institution
+	json.NewDecoder(rec.Body).Decode(&uon)
+	if len(uon.Domains) != 1 || uon.Domains[0] != "uonbi.ac.ke" {
+		t.Errorf("domains not normalised: %v", uon.Domains)
+	}
+
+	// Unverified: not affiliated until the link is opened.
+	late := registerUser(t, srv, "late@uonbi.ac.ke", models.RoleStudent)
+	if got := dbString(t, srv, `SELECT COALESCE(institution_id, '') FROM users WHERE id = ?`, late.User.ID); got != "" {
+		t.Error("unverified student affiliated")
+	}
+	verifyEmail(t, srv, box.verifyToken(t))
+
+	students := searchByInstitution(t, srv, uon.ID)
+	if len(students) != 2 {
+		t.Fatalf("expected the two uonbi students, got %+v", students)
+	}
+	for _, s := range students {
+		if s.ID == lookalike.User.ID {
+			t.Error("notuonbi.ac.ke matched uonbi.ac.ke")
+		}
+		if s.Institution == nil || s.Institution.Name != "University of Nairobi" {
+			t.Errorf("%s: institution missing from search result", s.Email)
+		}
+	}
+	profile, err := srv.loadProfile(t.Context(), early.User.ID)
+	if err != nil || profile.Institution == nil || profile.Institution.ID != uon.ID {
+		t.Errorf("profile: got %+v, %v", profile.Institution, err)
+	}
+}
+
+func TestInstitutions_DomainChanges(t *testing.T) {
+	srv := newTestServer(t)
+	box := &outbox{}
+	srv.Mailer = box
+	student := registerUser(t, srv, "amina@cs.uni.ac.ke", models.RoleStudent)
+	verifyEmail(t, srv, box.verifyToken(t))
+	affiliation := func() string {
+		return dbString(t, srv, `SELECT COALESCE(institution_id, '') FROM users WHERE id = ?`, student.User.ID)
+	}
+
+	var uni, cs models.Institution
+	json.NewDecoder(saveInstitutionAs(t, srv, "", models.InstitutionRequest{
+		Name: "Uni", Domains: []string{"uni.ac.ke"},
+	}).Body).Decode(&uni)
+	json.NewDecoder(saveInstitutionAs(t, srv, "", models.InstitutionRequest{
+		Name: "Uni School of Computing", Domains: []string{"cs.uni.ac.ke"},
+	}).Body).Decode(&cs)
+	if got := affiliation(); got != cs.ID {
+		t.Errorf("longest domain should win: got %q", got)
+	}
+
+	if rec := saveInstitutionAs(t, srv, "", models.InstitutionRequest{
+		Name: "Other", Domains: []string{"uni.ac.ke"},
+	}); rec.Code != http.StatusConflict {
+		t.Errorf("domain of another institution: expected 409, got %d", rec.Code)
+	}
+	if rec := saveInstitutionAs(t, srv, "", models.InstitutionRequest{
+		Name: "Bad", Domains: []string{"localhost"},
+	}); rec.Code != http.StatusBadRequest {
+		t.Errorf("single-label domain: expected 400, got %d", rec.Code)
+	}
+
+	// Removing the domain, or the institution, falls back to the shorter match.
+	if rec := saveInstitutionAs(t, srv, cs.ID, models.InstitutionRequest{
+		Name: cs.Name, Domains: []string{"cs.uni.example"},
+	}); rec.Code != http.StatusOK {
+		t.Fatalf("update: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := affiliation(); got != uni.ID {
+		t.Errorf("after domain removal: got %q, want %q", got, uni.ID)
+	}
+	r := httptest.NewRequest(http.MethodDelete, "/api/admin/institutions/"+uni.ID, nil)
+	r.SetPathValue("id", uni.ID)
+	rec := httptest.NewRecorder()
+	srv.DeleteInstitution(rec, ctxWithUser(r, "admin", "admin"))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", rec.Code)
+	}
+	if got := affiliation(); got != "" {
+		t.Errorf("after delete: still affiliated with %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ListInstitutions(rec, httptest.NewRequest(http.MethodGet, "/api/institutions", nil))
+	var list []models.Institution
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list) != 1 || list[0].ID != cs.ID || len(list[0].Domains) != 1 {
+		t.Errorf("unexpected list %+v", list)
+	}
+}
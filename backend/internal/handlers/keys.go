@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/google/uuid"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — where the keyring lives
+// ────────────────────────────────────────────────────────────────────
+// auth.Keyring is an in-memory structure; the signing_keys table is its
+// source of truth.  The admin CLI (`server keys …`, see cmd/server/keys.go)
+// edits the table directly, and every running server calls ReloadKeys on a
+// timer, so a key added or promoted from the CLI reaches all servers within
+// one reload interval without a restart.
+//
+// Keys are persisted rather than generated at start-up because students may
+// sync a check-in days after scanning it: a restart must not make the QR
+// tokens sitting in their phones' IndexedDB unverifiable.
+
+// Errors returned by the signing-key admin functions.
+var (
+	ErrKeyNotFound     = errors.New("signing key not found")
+	ErrKeyIsPrimary    = errors.New("cannot retire the primary key; promote another key first")
+	ErrKeyRetired      = errors.New("signing key is retired")
+	ErrKeyAlreadyFirst = errors.New("signing key is already primary")
+)
+
+// ExampleJWTSecret is the JWT_SECRET older releases fell back to when it was
+// unset.  It is published in this repository, so a session key made from it
+// lets anyone forge session tokens: LoadKeyring refuses it.
+const ExampleJWTSecret = "changeme-use-a-real-secret-in-production"
+
+// ErrExampleJWTSecret is returned by LoadKeyring for ExampleJWTSecret.
+var ErrExampleJWTSecret = errors.New("JWT_SECRET is the published example value; unset it to generate a random key")
+
+// LoadKeyring reads the signing keys from the database and returns them as a
+// keyring.  On first start it creates what is missing: the session key is
+// seeded from legacySecret (JWT_SECRET) when set, so existing deployments
+// keep their secret, and random otherwise; the check-in key is generated.
+// Once a session key exists legacySecret is ignored, with a warning if it
+// no longer matches the primary key.
+func LoadKeyring(ctx context.Context, db *sql.DB, legacySecret string) (*auth.Keyring, error) {
+	if legacySecret == ExampleJWTSecret {
+		return nil, ErrExampleJWTSecret
+	}
+	if err := ensurePrimaryKey(ctx, db, auth.PurposeSession, legacySecret); err != nil {
+		return nil, err
+	}
+	if err := ensurePrimaryKey(ctx, db, auth.PurposeCheckIn, ""); err != nil {
+		return nil, err
+	}
+	keys, err := ListSigningKeys(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if k.Purpose != auth.PurposeSession || k.Status != auth.KeyPrimary {
+			continue
+		}
+		switch {
+		case bytes.Equal(k.Material, []byte(ExampleJWTSecret)):
+			slog.Warn("the primary session key is the published example JWT_SECRET; "+
+				"anyone can forge sessions until it is replaced with `server keys add session` and `server keys promote`",
+				"kid", k.ID)
+		case legacySecret != "" && !bytes.Equal(k.Material, []byte(legacySecret)):
+			slog.Warn("JWT_SECRET differs from the stored primary session key and is ignored; "+
+				"manage keys with `server keys`", "kid", k.ID)
+		}
+	}
+	return auth.NewKeyring(keys)
+}
+
+// ReloadKeys re-reads signing_keys into s.Keys.  main calls it periodically.
+func (s *Server) ReloadKeys(ctx context.Context) error {
+	keys, err := ListSigningKeys(ctx, s.DB)
+	if err != nil {
+		return err
+	}
+	return s.Keys.Replace(keys)
+}
+
+// ensurePrimaryKey makes sure purpose has a primary key.  If it has keys but
+// none is primary (e.g. a database from before key rotation existed), the
+// newest one is promoted.  If it has none, one is created.
+func ensurePrimaryKey(ctx context.Context, db *sql.DB, purpose auth.KeyPurpose, legacySecret string) error {
+	keys, err := ListSigningKeys(ctx, db)
+	if err != nil {
+		return err
+	}
+	var newest *auth.Key
+	for i, k := range keys {
+		if k.Purpose != purpose || k.Status == auth.KeyRetired {
+			continue
+		}
+		if k.Status == auth.KeyPrimary {
+			return nil
+		}
+		if newest == nil || k.CreatedAt.After(newest.CreatedAt) {
+			newest = &keys[i]
+		}
+	}
+	if newest != nil {
+		return PromoteSigningKey(ctx, db, newest.ID)
+	}
+
+	key, err := newSigningKey(purpose)
+	if err != nil {
+		return err
+	}
+	if legacySecret != "" {
+		key.Material = []byte(legacySecret)
+	}
+	key.Status = auth.KeyPrimary
+	return insertSigningKey(ctx, db, key)
+}
+
+// newSigningKey generates fresh key material for purpose.
+func newSigningKey(purpose auth.KeyPurpose) (auth.Key, error) {
+	key := auth.Key{Purpose: purpose, Status: auth.KeyActive, CreatedAt: time.Now().UTC()}
+	switch purpose {
+	case auth.PurposeSession:
+		key.ID = uuid.NewString()
+		key.Material = make([]byte, 32)
+		if _, err := rand.Read(key.Material); err != nil {
+			return auth.Key{}, err
+		}
+	case auth.PurposeCheckIn:
+		ck, err := auth.NewCheckInKey()
+		if err != nil {
+			return auth.Key{}, err
+		}
+		key.ID = ck.ID
+		key.Material = ck.Seed()
+	default:
+		return auth.Key{}, fmt.Errorf("unknown key purpose %q", purpose)
+	}
+	return key, nil
+}
+
+func insertSigningKey(ctx context.Context, db *sql.DB, k auth.Key) error {
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO signing_keys (id, purpose, private_key, status, created_at) VALUES (?, ?, ?, ?, ?)`,
+		k.ID, k.Purpose, k.Material, k.Status, k.CreatedAt,
+	)
+	return err
+}
+
+// ListSigningKeys returns every stored key, oldest first.
+func ListSigningKeys(ctx context.Context, db *sql.DB) ([]auth.Key, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, purpose, private_key, status, created_at FROM signing_keys`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []auth.Key
+	for rows.Next() {
+		var k auth.Key
+		if err := rows.Scan(&k.ID, &k.Purpose, &k.Material, &k.Status, &k.CreatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// AddSigningKey generates a new key for purpose.  It starts ACTIVE (verify
+// only) so every server can learn it before it signs anything.
+func AddSigningKey(ctx context.Context, db *sql.DB, purpose auth.KeyPurpose) (auth.Key, error) {
+	key, err := newSigningKey(purpose)
+	if err != nil {
+		return auth.Key{}, err
+	}
+	if err := insertSigningKey(ctx, db, key); err != nil {
+		return auth.Key{}, err
+	}
+	return key, nil
+}
+
+// PromoteSigningKey makes id the primary key for its purpose.  The previous
+// primary drops to active so tokens it signed keep verifying.
+func PromoteSigningKey(ctx context.Context, db *sql.DB, id string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var purpose, status string
+	err = tx.QueryRowContext(ctx,
+		`SELECT purpose, status FROM signing_keys WHERE id = ?`, id,
+	).Scan(&purpose, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrKeyNotFound
+	}
+	if err != nil {
+		return err
+	}
+	switch auth.KeyStatus(status) {
+	case auth.KeyRetired:
+		return ErrKeyRetired
+	case auth.KeyPrimary:
+		return ErrKeyAlreadyFirst
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE signing_keys SET status = 'active' WHERE purpose = ? AND status = 'primary'`, purpose,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE signing_keys SET status = 'primary' WHERE id = ?`, id,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RetireSigningKey stops id from verifying tokens.  The primary key cannot
+// be retired.
+func RetireSigningKey(ctx context.Context, db *sql.DB, id string) error {
+	var status string
+	err := db.QueryRowContext(ctx,
+		`SELECT status FROM signing_keys WHERE id = ?`, id,
+	).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrKeyNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if auth.KeyStatus(status) == auth.KeyPrimary {
+		return ErrKeyIsPrimary
+	}
+	_, err = db.ExecContext(ctx,
+		`UPDATE signing_keys SET status = 'retired', retired_at = ? WHERE id = ?`,
+		time.Now().UTC(), id,
+	)
+	return err
+}
+
+// JWKS handles GET /api/.well-known/jwks.json  (public)
+//
+// Publishes the public halves of the check-in signing keys (primary and
+// active) as a JWK Set.  The PWA caches it while online and uses it to
+// verify a scanned QR code on the spot, before queuing it for sync.  Public
+// keys cannot sign, so this is safe to serve to anyone.
+func (s *Server) JWKS(w http.ResponseWriter, r *http.Request) {
+	// Let browsers and the service worker cache the set for a while; keys
+	// change rarely, and a token with an unknown kid makes the PWA refetch.
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	respond(w, http.StatusOK, s.Keys.JWKS())
+}
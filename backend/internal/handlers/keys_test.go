@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+)
+
+func TestLoadKeyring_BootstrapsAndPersists(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	first, err := LoadKeyring(ctx, srv.DB, "legacy-jwt-secret")
+	if err != nil {
+		t.Fatalf("first load: %v", err)
+	}
+	second, err := LoadKeyring(ctx, srv.DB, "a-different-secret")
+	if err != nil {
+		t.Fatalf("second load: %v", err)
+	}
+	if first.CheckInSigner().ID != second.CheckInSigner().ID {
+		t.Errorf("check-in key changed between loads: %q vs %q",
+			first.CheckInSigner().ID, second.CheckInSigner().ID)
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM signing_keys`); n != 2 {
+		t.Errorf("expected 2 stored keys, got %d", n)
+	}
+
+	// The session key was seeded from the first JWT_SECRET, and later
+	// values are ignored once a key exists.
+	token, err := auth.GenerateToken("user-1", "student", "session-1", second)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if _, err := auth.ParseToken(token, first); err != nil {
+		t.Errorf("token from reloaded keyring rejected: %v", err)
+	}
+	var material []byte
+	srv.DB.QueryRow(`SELECT private_key FROM signing_keys WHERE purpose = 'session'`).Scan(&material)
+	if string(material) != "legacy-jwt-secret" {
+		t.Errorf("session key not seeded from JWT_SECRET: %q", material)
+	}
+}
+
+func TestLoadKeyring_RefusesExampleSecret(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := LoadKeyring(context.Background(), srv.DB, ExampleJWTSecret); !errors.Is(err, ErrExampleJWTSecret) {
+		t.Fatalf("expected ErrExampleJWTSecret, got %v", err)
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM signing_keys`); n != 0 {
+		t.Errorf("keys were stored: %d", n)
+	}
+
+	// Unset, the session key is random.
+	if _, err := LoadKeyring(context.Background(), srv.DB, ""); err != nil {
+		t.Fatal(err)
+	}
+	var material []byte
+	srv.DB.QueryRow(`SELECT private_key FROM signing_keys WHERE purpose = 'session'`).Scan(&material)
+	if len(material) != 32 {
+		t.Errorf("expected a random 32-byte session key, got %d bytes", len(material))
+	}
+}
+
+// TestLoadKeyring_PromotesLegacyKey verifies that a check-in key stored
+// before keys had a status is promoted rather than replaced, so QR tokens
+// it already signed keep verifying.
+func TestLoadKeyring_PromotesLegacyKey(t *testing.T) {
+	srv := newTestServer(t)
+	legacy, _ := auth.NewCheckInKey()
+	srv.DB.Exec(`INSERT INTO signing_keys (id, purpose, private_key) VALUES (?, 'checkin', ?)`,
+		legacy.ID, legacy.Seed())
+
+	kr, err := LoadKeyring(context.Background(), srv.DB, "secret")
+	if err != nil {
+		t.Fatalf("LoadKeyring: %v", err)
+	}
+	if kr.CheckInSigner().ID != legacy.ID {
+		t.Errorf("check-in signer: got %q, want the legacy key %q", kr.CheckInSigner().ID, legacy.ID)
+	}
+}
+
+// TestSigningKeys_Rotation walks through add → promote → retire the way the
+// `server keys` command does and reloads the server's keyring after each
+// step.
+func TestSigningKeys_Rotation(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	kr, err := LoadKeyring(ctx, srv.DB, "old-secret")
+	if err != nil {
+		t.Fatalf("LoadKeyring: %v", err)
+	}
+	srv.Keys = kr
+	oldToken, _ := auth.GenerateToken("user-1", "student", "session-1", srv.Keys)
+	oldKid := dbString(t, srv, `SELECT id FROM signing_keys WHERE purpose = 'session'`)
+
+	added, err := AddSigningKey(ctx, srv.DB, auth.PurposeSession)
+	if err != nil {
+		t.Fatalf("AddSigningKey: %v", err)
+	}
+	if added.Status != auth.KeyActive {
+		t.Errorf("new key status: got %q, want active", added.Status)
+	}
+	if err := PromoteSigningKey(ctx, srv.DB, added.ID); err != nil {
+		t.Fatalf("PromoteSigningKey: %v", err)
+	}
+	if err := srv.ReloadKeys(ctx); err != nil {
+		t.Fatalf("ReloadKeys: %v", err)
+	}
+	if _, err := auth.ParseToken(oldToken, srv.Keys); err != nil {
+		t.Errorf("token signed before promote rejected: %v", err)
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM signing_keys WHERE purpose = 'session' AND status = 'primary'`); n != 1 {
+		t.Errorf("expected 1 primary session key, got %d", n)
+	}
+
+	if err := RetireSigningKey(ctx, srv.DB, added.ID); !errors.Is(err, ErrKeyIsPrimary) {
+		t.Errorf("retire primary: got %v, want ErrKeyIsPrimary", err)
+	}
+	if err := RetireSigningKey(ctx, srv.DB, oldKid); err != nil {
+		t.Fatalf("RetireSigningKey: %v", err)
+	}
+	if err := srv.ReloadKeys(ctx); err != nil {
+		t.Fatalf("ReloadKeys: %v", err)
+	}
+	if _, err := auth.ParseToken(oldToken, srv.Keys); !errors.Is(err, auth.ErrUnknownKey) {
+		t.Errorf("token of retired key: got %v, want ErrUnknownKey", err)
+	}
+	if err := PromoteSigningKey(ctx, srv.DB, oldKid); !errors.Is(err, ErrKeyRetired) {
+		t.Errorf("promote retired key: got %v, want ErrKeyRetired", err)
+	}
+	if err := PromoteSigningKey(ctx, srv.DB, "no-such-key"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("promote unknown key: got %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestJWKS_PublishesCheckInKey(t *testing.T) {
+	srv := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	srv.JWKS(rec, httptest.NewRequest(http.MethodGet, "/api/.well-known/jwks.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var set auth.JWKS
+	if err := json.NewDecoder(rec.Body).Decode(&set); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(set.Keys) != 1 {
+		t.Fatalf("expected 1 key, got %d", len(set.Keys))
+	}
+	if jwk := set.Keys[0]; jwk.Kid != testCheckInKey.ID || jwk.X != testCheckInKey.JWK().X {
+		t.Errorf("published key does not match the signing key: %+v", jwk)
+	}
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/mail"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — magic links
+// ────────────────────────────────────────────────────────────────────
+// A magic link is a password reset that skips the new password: opening it
+// logs the student straight in.  The token is handled exactly like a reset
+// token (hashed, single-use via compare-and-swap, cancelled by a newer
+// link), with two extra rules:
+//
+//   - It is short-lived (Server.MagicLinkTTL, default 15 minutes): it is a
+//     full login, not just a way to change a password.
+//   - It is bound to the email it was sent to.  If the account's email has
+//     changed since, the link no longer works — it proves control of the
+//     OLD address, which is no longer the account's.
+//
+// Only students can use it.  Company and admin accounts guard more data and
+// keep their password (and 2FA, which still applies to magic-link logins
+// through finishLogin).  Like /password/forgot, the request always answers
+// 202 so it cannot be used to find out who is registered, and at most one
+// link per magicLinkResendInterval is sent, so the endpoint cannot be used
+// to flood someone's inbox.
+
+const (
+	// defaultMagicLinkTTL is used when Server.MagicLinkTTL is zero.
+	defaultMagicLinkTTL = 15 * time.Minute
+	// magicLinkResendInterval is the minimum gap between two links to the
+	// same account.
+	magicLinkResendInterval = time.Minute
+)
+
+// RequestMagicLink handles POST /api/auth/magic-link
+//
+// Body: {"email": "..."}.  Always 202 Accepted for a well-formed request.
+func (s *Server) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var req models.MagicLinkRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	email := strings.TrimSpace(strings.ToLower(req.Email))
+	if email == "" {
+		respondError(w, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	if err := s.sendMagicLink(r.Context(), email); err != nil {
+		// Logged, not returned, for the same reason as in ForgotPassword.
+		slog.Error("magic link", "err", err)
+	}
+
+	respond(w, http.StatusAccepted, map[string]string{
+		"message": "if that email belongs to a student account, a login link is on its way",
+	})
+}
+
+// sendMagicLink issues a login token for the active student with email, if
+// there is one, and mails the link.
+func (s *Server) sendMagicLink(ctx context.Context, email string) error {
+	var userID, name string
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT id, name FROM users WHERE email = ? AND status = 'active' AND role = 'student'`, email,
+	).Scan(&userID, &name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ttl := s.MagicLinkTTL
+	if ttl <= 0 {
+		ttl = defaultMagicLinkTTL
+	}
+	now := time.Now().UTC()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// rowid order is insertion order.
+	var lastSent time.Time
+	err = tx.QueryRowContext(ctx,
+		`SELECT created_at FROM magic_links WHERE user_id = ? ORDER BY rowid DESC LIMIT 1`, userID,
+	).Scan(&lastSent)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil && now.Sub(lastSent) < magicLinkResendInterval {
+		return nil
+	}
+
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE magic_links SET used_at = ? WHERE user_id = ? AND used_at IS NULL`, now, userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO magic_links (id, user_id, email, token_hash, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		uuid.NewString(), userID, email, hash, now, now.Add(ttl),
+	); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if s.Mailer == nil {
+		return errors.New("no mailer configured")
+	}
+	return s.Mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Your Skillzone login link",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Open this link within %s to log in to Skillzone:\n\n%s\n\n"+
+			"The link works once.  If you did not ask for it, ignore this email.\n",
+			name, ttl, s.appLink("/magic-link", token)),
+	})
+}
+
+// VerifyMagicLink handles POST /api/auth/magic-link/verify
+//
+// Body: {"token": "..."} from the emailed link.  Answers like Login: a
+// LoginResponse, or a TwoFactorChallenge if the account uses 2FA.
+func (s *Server) VerifyMagicLink(w http.ResponseWriter, r *http.Request) {
+	var req models.MagicLinkVerifyRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.Token == "" {
+		respondError(w, http.StatusBadRequest, "invalid or expired login link")
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var linkID, linkEmail string
+	var expiresAt time.Time
+	var usedAt sql.NullTime
+	var user models.User
+	err = tx.QueryRowContext(ctx,
+		`SELECT m.id, m.email, m.expires_at, m.used_at,
+		        u.id, u.email, u.name, u.role, u.status, u.created_at, u.updated_at
+		 FROM magic_links m JOIN users u ON u.id = m.user_id
+		 WHERE m.token_hash = ?`, auth.HashOpaqueToken(req.Token),
+	).Scan(&linkID, &linkEmail, &expiresAt, &usedAt,
+		&user.ID, &user.Email, &user.Name, &user.Role, &user.Status, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	now := time.Now().UTC()
+	if err != nil || usedAt.Valid || isExpired(expiresAt, now) ||
+		user.Email != linkEmail || user.Role != models.RoleStudent || user.Status == models.UserDeleted {
+		respondError(w, http.StatusBadRequest, "invalid or expired login link")
+		return
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE magic_links SET used_at = ? WHERE id = ? AND used_at IS NULL`, now, linkID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusBadRequest, "invalid or expired login link")
+		return
+	}
+	// The link reached the student's inbox, which is all verification asks.
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET email_verified_at = COALESCE(email_verified_at, ?) WHERE id = ?`, now, user.ID,
+	); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := updateAffiliation(ctx, tx, user.ID); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if user.Status == models.UserSuspended {
+		respondError(w, http.StatusForbidden, "account suspended")
+		return
+	}
+	s.finishLogin(w, r, user, http.StatusOK)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+)
+
+var magicLinkRE = regexp.MustCompile(`/magic-link\?token=(\S+)`)
+
+func requestMagicLink(t *testing.T, srv *Server, email string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/magic-link",
+		jsonBody(t, models.MagicLinkRequest{Email: email}))
+	rec := httptest.NewRecorder()
+	srv.RequestMagicLink(rec, req)
+	return rec.Code
+}
+
+func verifyMagicLink(t *testing.T, srv *Server, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/magic-link/verify",
+		jsonBody(t, models.MagicLinkVerifyRequest{Token: token}))
+	rec := httptest.NewRecorder()
+	srv.VerifyMagicLink(rec, req)
+	return rec
+}
+
+// lastMagicToken returns the token from the most recent login email.
+func (o *outbox) lastMagicToken(t *testing.T) string {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.sent) == 0 {
+		t.Fatal("no email sent")
+	}
+	m := magicLinkRE.FindStringSubmatch(o.sent[len(o.sent)-1].Body)
+	if m == nil {
+		t.Fatalf("no login link in email:\n%s", o.sent[len(o.sent)-1].Body)
+	}
+	token, _ := url.QueryUnescape(m[1])
+	return token
+}
+
+func TestMagicLink_Flow(t *testing.T) {
+	srv := newTestServer(t)
+	box := &outbox{}
+	student := registerUser(t, srv, "forgetful@example.com", models.RoleStudent)
+	srv.Mailer = box
+
+	if code := requestMagicLink(t, srv, " Forgetful@Example.com "); code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", code)
+	}
+	if len(box.sent) != 1 || box.sent[0].To != "forgetful@example.com" {
+		t.Fatalf("expected one email to the student, got %+v", box.sent)
+	}
+	token := box.lastMagicToken(t)
+
+	rec := verifyMagicLink(t, srv, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("verify: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp models.LoginResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Token == "" || resp.User.ID != student.User.ID {
+		t.Errorf("unexpected login: %+v", resp)
+	}
+	if rec := verifyMagicLink(t, srv, token); rec.Code != http.StatusBadRequest {
+		t.Errorf("reused link: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestMagicLink_OnlyActiveStudents(t *testing.T) {
+	srv := newTestServer(t)
+	box := &outbox{}
+	registerUser(t, srv, "hr@example.com", models.RoleCompany)
+	srv.Mailer = box
+
+	for _, email := range []string{"hr@example.com", "nobody@example.com"} {
+		if code := requestMagicLink(t, srv, email); code != http.StatusAccepted {
+			t.Errorf("%s: expected 202, got %d", email, code)
+		}
+	}
+	if len(box.sent) != 0 {
+		t.Errorf("expected no email, got %+v", box.sent)
+	}
+}
+
+func TestMagicLink_BoundToEmailAndShortLived(t *testing.T) {
+	srv := newTestServer(t)
+	box := &outbox{}
+	student := registerUser(t, srv, "mover@example.com", models.RoleStudent)
+	srv.Mailer = box
+
+	requestMagicLink(t, srv, "mover@example.com")
+	token := box.lastMagicToken(t)
+	// A second request within the resend interval sends nothing.
+	requestMagicLink(t, srv, "mover@example.com")
+	if len(box.sent) != 1 {
+		t.Fatalf("expected one email, got %d", len(box.sent))
+	}
+
+	// The account moves to another address: the old link stops working.
+	srv.DB.Exec(`UPDATE users SET email = 'moved@example.com' WHERE id = ?`, student.User.ID)
+	if rec := verifyMagicLink(t, srv, token); rec.Code != http.StatusBadRequest {
+		t.Errorf("link for the old email: expected 400, got %d", rec.Code)
+	}
+
+	srv.DB.Exec(`DELETE FROM magic_links`)
+	requestMagicLink(t, srv, "moved@example.com")
+	token = box.lastMagicToken(t)
+	srv.DB.Exec(`UPDATE magic_links SET expires_at = ?`, time.Now().UTC().Add(-time.Second))
+	if rec := verifyMagicLink(t, srv, token); rec.Code != http.StatusBadRequest {
+		t.Errorf("expired link: expected 400, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,472 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/Elizabethomito/skillzone/backend/internal/oidc"
+	"github.com/google/uuid"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — single sign-on
+// ────────────────────────────────────────────────────────────────────
+// "Sign in with <university>" is three requests, all through the browser:
+//
+//	StartOIDCLogin   GET  /api/auth/oidc/{provider}/start
+//	                 → 302 to the identity provider (package oidc explains
+//	                   state, nonce and PKCE).
+//	OIDCCallback     GET  /api/auth/oidc/{provider}/callback
+//	                 ← the provider sends the browser back here.  We verify
+//	                   the ID token, find or create the account, and 302 to
+//	                   the frontend's /oidc/callback?token=<handoff>.
+//	OIDCExchange     POST /api/auth/oidc/exchange  {token}
+//	                 → the usual LoginResponse (or a 2FA challenge).
+//
+// Why the handoff token instead of putting the session in the redirect?
+// URLs end up in browser history and server logs.  The handoff works once
+// and only for oidcHandoffTTL, so a copied URL is worthless.
+//
+// Which account does an identity log in to?
+//  1. One already linked to (provider, sub) — sub is the provider's stable
+//     user ID; emails can change.
+//  2. Otherwise the account with the same email, but ONLY if the provider
+//     says it has verified that email.  Linking on an unverified email would
+//     let anyone who can register "amara@uni.test" at some provider take
+//     over Amara's account.  Even then only accounts whose role the provider
+//     could have given (its RoleMap and DefaultRole; see oidcMayLink) are
+//     linked: a university vouching for an address is no reason to hand it
+//     a company's or an admin's account.  Admin accounts never sign in
+//     through a provider at all.
+//  3. Otherwise a new account, with its role from the provider's role claim
+//     (Config.RoleClaim/RoleMap) or DefaultRole.  It has no password; the
+//     user can set one with "forgot password" if they want one.  Only
+//     student and company can be mapped: a group claim at the provider must
+//     never make a platform admin, who only come from `server admin`.
+//
+// The role mapping only applies when the account is created.  After that
+// the role is managed in Skillzone like any other account's.
+//
+// The state is also put in a cookie, and the callback checks that the two
+// match.  That ties the callback to the browser that started the login, so
+// nobody can trick a victim's browser into finishing an attacker's login.
+
+const (
+	// oidcLoginTTL is how long the user has to log in at the provider.
+	oidcLoginTTL = 10 * time.Minute
+	// oidcHandoffTTL is how long the frontend has to swap the handoff token.
+	oidcHandoffTTL  = time.Minute
+	oidcStateCookie = "sz_oidc_state"
+)
+
+// NewOIDCProviders builds the providers from their configs.  A config
+// without a redirect_url gets <apiURL>/api/auth/oidc/<name>/callback.
+func NewOIDCProviders(cfgs []oidc.Config, apiURL string) (map[string]*oidc.Provider, error) {
+	providers := make(map[string]*oidc.Provider, len(cfgs))
+	for _, c := range cfgs {
+		for value, role := range c.RoleMap {
+			if !oidcRoleAllowed(models.UserRole(role)) {
+				return nil, fmt.Errorf("OIDC provider %q: role_map[%q] must be student or company", c.Name, value)
+			}
+		}
+		if c.DefaultRole != "" && !oidcRoleAllowed(models.UserRole(c.DefaultRole)) {
+			return nil, fmt.Errorf("OIDC provider %q: default_role must be student or company", c.Name)
+		}
+		if c.RedirectURL == "" {
+			c.RedirectURL = strings.TrimRight(apiURL, "/") + "/api/auth/oidc/" + c.Name + "/callback"
+		}
+		providers[c.Name] = oidc.NewProvider(c)
+	}
+	return providers, nil
+}
+
+// oidcRoleAllowed reports whether an identity provider may give new
+// accounts role.  Admins are created with `server admin` only.
+func oidcRoleAllowed(role models.UserRole) bool {
+	return role == models.RoleStudent || role == models.RoleCompany
+}
+
+// oidcMayLink reports whether an existing account with role may be linked
+// to an identity from p on its email alone: only if p could have created an
+// account with that role, which is never admin.
+func oidcMayLink(p *oidc.Provider, role models.UserRole) bool {
+	if !oidcRoleAllowed(role) {
+		return false
+	}
+	if models.UserRole(p.DefaultRole) == role {
+		return true
+	}
+	for _, r := range p.RoleMap {
+		if models.UserRole(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+// rolePrecedence orders the roles an identity may map to when it maps to
+// several.
+var rolePrecedence = map[models.UserRole]int{
+	models.RoleStudent: 1,
+	models.RoleCompany: 2,
+}
+
+// oidcRole picks the role a new account from provider p gets.  If several
+// claim values map to roles, the most privileged wins.  Empty means none.
+func oidcRole(p *oidc.Provider, claims *oidc.Claims) models.UserRole {
+	var role models.UserRole
+	if p.RoleClaim != "" {
+		for _, v := range claims.Strings(p.RoleClaim) {
+			if r, ok := p.RoleMap[v]; ok && rolePrecedence[models.UserRole(r)] > rolePrecedence[role] {
+				role = models.UserRole(r)
+			}
+		}
+	}
+	if role == "" {
+		role = models.UserRole(p.DefaultRole)
+	}
+	// NewOIDCProviders rejects other roles; a provider built some other way
+	// still cannot create admins.
+	if !oidcRoleAllowed(role) {
+		return ""
+	}
+	return role
+}
+
+// ListOIDCProviders handles GET /api/auth/oidc/providers
+// The identity providers to show "Sign in with …" buttons for.
+func (s *Server) ListOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	list := []models.OIDCProvider{}
+	for name, p := range s.OIDC {
+		display := p.DisplayName
+		if display == "" {
+			display = name
+		}
+		list = append(list, models.OIDCProvider{Name: name, DisplayName: display})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	respond(w, http.StatusOK, list)
+}
+
+// StartOIDCLogin handles GET /api/auth/oidc/{provider}/start
+// Opened by the browser (a link, not fetch); redirects to the provider.
+func (s *Server) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("provider")
+	p, ok := s.OIDC[name]
+	if !ok {
+		respondError(w, http.StatusNotFound, "unknown identity provider")
+		return
+	}
+
+	state, stateHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not generate token")
+		return
+	}
+	nonce, _, err := auth.NewOpaqueToken()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not generate token")
+		return
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not generate token")
+		return
+	}
+
+	target, err := p.AuthCodeURL(r.Context(), state, nonce, challenge)
+	if err != nil {
+		slog.Error("oidc start", "provider", name, "err", err)
+		respondError(w, http.StatusBadGateway, "identity provider unavailable")
+		return
+	}
+	now := time.Now().UTC()
+	if _, err := s.DB.ExecContext(r.Context(),
+		`INSERT INTO oidc_logins (id, provider, state_hash, nonce, code_verifier, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		uuid.NewString(), name, stateHash, nonce, verifier, now, now.Add(oidcLoginTTL),
+	); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not start login")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc/",
+		MaxAge:   int(oidcLoginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil || strings.HasPrefix(p.RedirectURL, "https:"),
+		// Lax, not Strict: the callback is a cross-site navigation from the
+		// provider, which Strict would strip the cookie from.
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// oidcFail sends the browser back to the frontend with an error code it can
+// explain to the user.
+func (s *Server) oidcFail(w http.ResponseWriter, r *http.Request, code string) {
+	target := strings.TrimRight(s.AppURL, "/") + "/oidc/callback?error=" + url.QueryEscape(code)
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// Error codes OIDCCallback reports to the frontend.
+const (
+	oidcErrInvalidState     = "invalid_state"
+	oidcErrProvider         = "provider_error"
+	oidcErrEmailNotVerified = "email_not_verified"
+	oidcErrNoRole           = "no_role"
+	// oidcErrAccountDisabled: the account was deleted, or this provider may
+	// not sign in to it (oidcMayLink).
+	oidcErrAccountDisabled = "account_disabled"
+)
+
+// OIDCCallback handles GET /api/auth/oidc/{provider}/callback
+// The provider's redirect back to us.  Always answers with a redirect to
+// the frontend's /oidc/callback page, carrying either ?token= (for
+// OIDCExchange) or ?error=.
+func (s *Server) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name := r.PathValue("provider")
+	p, ok := s.OIDC[name]
+	if !ok {
+		respondError(w, http.StatusNotFound, "unknown identity provider")
+		return
+	}
+	q := r.URL.Query()
+	state := q.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || cookie.Value != state {
+		s.oidcFail(w, r, oidcErrInvalidState)
+		return
+	}
+	// The cookie has done its job.
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/api/auth/oidc/", MaxAge: -1})
+
+	var loginID, provider, nonce, verifier string
+	var expiresAt time.Time
+	var callbackAt sql.NullTime
+	err = s.DB.QueryRowContext(ctx,
+		`SELECT id, provider, nonce, code_verifier, expires_at, callback_at
+		 FROM oidc_logins WHERE state_hash = ?`, auth.HashOpaqueToken(state),
+	).Scan(&loginID, &provider, &nonce, &verifier, &expiresAt, &callbackAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	now := time.Now().UTC()
+	if err != nil || provider != name || callbackAt.Valid || isExpired(expiresAt, now) {
+		s.oidcFail(w, r, oidcErrInvalidState)
+		return
+	}
+	// Compare-and-swap so each state is used for one callback only.
+	result, err := s.DB.ExecContext(ctx,
+		`UPDATE oidc_logins SET callback_at = ? WHERE id = ? AND callback_at IS NULL`, now, loginID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		s.oidcFail(w, r, oidcErrInvalidState)
+		return
+	}
+
+	// The user cancelled, or the provider refused them.
+	if e := q.Get("error"); e != "" {
+		s.oidcFail(w, r, truncate(e, 64))
+		return
+	}
+	rawIDToken, err := p.Exchange(ctx, q.Get("code"), verifier)
+	if err != nil {
+		slog.Warn("oidc code exchange", "provider", name, "err", err)
+		s.oidcFail(w, r, oidcErrProvider)
+		return
+	}
+	claims, err := p.Verify(ctx, rawIDToken, nonce)
+	if err != nil {
+		slog.Warn("oidc id token", "provider", name, "err", err)
+		s.oidcFail(w, r, oidcErrProvider)
+		return
+	}
+
+	userID, code, err := s.oidcUser(ctx, p, claims)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if code != "" {
+		s.oidcFail(w, r, code)
+		return
+	}
+
+	handoff, handoffHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not generate token")
+		return
+	}
+	if _, err := s.DB.ExecContext(ctx,
+		`UPDATE oidc_logins SET user_id = ?, handoff_hash = ? WHERE id = ?`, userID, handoffHash, loginID,
+	); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	http.Redirect(w, r, s.appLink("/oidc/callback", handoff), http.StatusFound)
+}
+
+// oidcUser finds or creates the account for a verified identity, following
+// the rules in the note at the top of this file.  A non-empty code is an
+// error to report to the user; err is for database failures.
+func (s *Server) oidcUser(ctx context.Context, p *oidc.Provider, claims *oidc.Claims) (userID, code string, err error) {
+	now := time.Now().UTC()
+	email := strings.TrimSpace(strings.ToLower(claims.Email))
+
+	// 1. Already linked.
+	var status models.UserStatus
+	var role models.UserRole
+	err = s.DB.QueryRowContext(ctx,
+		`SELECT u.id, u.status, u.role FROM user_identities i JOIN users u ON u.id = i.user_id
+		 WHERE i.provider = ? AND i.subject = ?`, p.Name, claims.Subject,
+	).Scan(&userID, &status, &role)
+	if err == nil {
+		if status == models.UserDeleted || role == models.RoleAdmin {
+			return "", oidcErrAccountDisabled, nil
+		}
+		_, err = s.DB.ExecContext(ctx,
+			`UPDATE user_identities SET email = ?, last_login_at = ? WHERE provider = ? AND subject = ?`,
+			truncate(email, 255), now, p.Name, claims.Subject)
+		return userID, "", err
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", "", err
+	}
+
+	if email == "" || !claims.EmailVerified {
+		return "", oidcErrEmailNotVerified, nil
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", "", err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// 2. An existing account with the verified email.
+	err = tx.QueryRowContext(ctx,
+		`SELECT id, status, role FROM users WHERE email = ?`, email,
+	).Scan(&userID, &status, &role)
+	switch {
+	case err == nil:
+		if status == models.UserDeleted || !oidcMayLink(p, role) {
+			return "", oidcErrAccountDisabled, nil
+		}
+		// The provider vouches for the address.
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE users SET email_verified_at = COALESCE(email_verified_at, ?) WHERE id = ?`, now, userID,
+		); err != nil {
+			return "", "", err
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		// 3. A new account.
+		role = oidcRole(p, claims)
+		if role == "" {
+			return "", oidcErrNoRole, nil
+		}
+		name := truncate(strings.TrimSpace(claims.Name), 255)
+		if name == "" {
+			name = email
+		}
+		userID = uuid.NewString()
+		// An empty password_hash matches no password: SSO-only accounts
+		// cannot use POST /api/auth/login until they set one.
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO users (id, email, password_hash, name, role, created_at, updated_at, email_verified_at)
+			 VALUES (?, ?, '', ?, ?, ?, ?, ?)`,
+			userID, email, name, role, now, now, now,
+		); err != nil {
+			return "", "", err
+		}
+	default:
+		return "", "", err
+	}
+
+	if err := updateAffiliation(ctx, tx, userID); err != nil {
+		return "", "", err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO user_identities (id, user_id, provider, subject, email, created_at, last_login_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		uuid.NewString(), userID, p.Name, claims.Subject, truncate(email, 255), now, now,
+	); err != nil {
+		return "", "", err
+	}
+	return userID, "", tx.Commit()
+}
+
+// OIDCExchange handles POST /api/auth/oidc/exchange
+//
+// Body: {"token": "..."} from the frontend's /oidc/callback URL.  Answers
+// like Login: a LoginResponse, or a TwoFactorChallenge if the account uses
+// 2FA.
+func (s *Server) OIDCExchange(w http.ResponseWriter, r *http.Request) {
+	var req models.OIDCExchangeRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.Token == "" {
+		respondError(w, http.StatusUnauthorized, "invalid or expired sign-in token")
+		return
+	}
+	ctx := r.Context()
+
+	var loginID string
+	var callbackAt sql.NullTime
+	var user models.User
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT l.id, l.callback_at, u.id, u.email, u.name, u.role, u.status, u.created_at, u.updated_at
+		 FROM oidc_logins l JOIN users u ON u.id = l.user_id
+		 WHERE l.handoff_hash = ? AND l.used_at IS NULL`, auth.HashOpaqueToken(req.Token),
+	).Scan(&loginID, &callbackAt, &user.ID, &user.Email, &user.Name, &user.Role, &user.Status,
+		&user.CreatedAt, &user.UpdatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	now := time.Now().UTC()
+	if err != nil || !callbackAt.Valid || now.Sub(callbackAt.Time) > oidcHandoffTTL {
+		respondError(w, http.StatusUnauthorized, "invalid or expired sign-in token")
+		return
+	}
+	result, err := s.DB.ExecContext(ctx,
+		`UPDATE oidc_logins SET used_at = ? WHERE id = ? AND used_at IS NULL`, now, loginID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusUnauthorized, "invalid or expired sign-in token")
+		return
+	}
+
+	switch user.Status {
+	case models.UserActive:
+	case models.UserSuspended:
+		respondError(w, http.StatusForbidden, "account suspended")
+		return
+	default:
+		respondError(w, http.StatusUnauthorized, "invalid or expired sign-in token")
+		return
+	}
+	s.finishLogin(w, r, user, http.StatusOK)
+}
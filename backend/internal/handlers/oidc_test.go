@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/Elizabethomito/skillzone/backend/internal/oidc"
+	"github.com/Elizabethomito/skillzone/backend/internal/oidc/oidctest"
+)
+
+// newSSOServer returns a server with one provider, "campus", backed by a
+// stand-in IdP.  Members of the "staff" group become companies, everyone
+// else students.
+func newSSOServer(t *testing.T) (*Server, *oidctest.IdP) {
+	t.Helper()
+	srv := newTestServer(t)
+	srv.AppURL = "http://app.test"
+	idp := oidctest.New("skillzone", "client-secret")
+	t.Cleanup(idp.Close)
+
+	cfg := idp.Config("campus", "")
+	cfg.RoleClaim = "groups"
+	cfg.RoleMap = map[string]string{"staff": "company"}
+	cfg.DefaultRole = "student"
+	providers, err := NewOIDCProviders([]oidc.Config{cfg}, "http://api.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.OIDC = providers
+	return srv, idp
+}
+
+// ssoLogin plays the browser through start → IdP → callback and returns
+// where the callback finally sends it.
+func ssoLogin(t *testing.T, srv *Server, idp *oidctest.IdP, keepCookie bool) *url.URL {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/campus/start", nil)
+	req.SetPathValue("provider", "campus")
+	rec := httptest.NewRecorder()
+	srv.StartOIDCLogin(rec, req)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("start: expected 302, got %d: %s", rec.Code, rec.Body.String())
+	}
+	back, err := idp.Authorize(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if back.Path != "/api/auth/oidc/campus/callback" {
+		t.Fatalf("IdP redirected to %s", back)
+	}
+
+	cb := httptest.NewRequest(http.MethodGet, back.String(), nil)
+	cb.SetPathValue("provider", "campus")
+	if keepCookie {
+		for _, c := range rec.Result().Cookies() {
+			cb.AddCookie(c)
+		}
+	}
+	rec = httptest.NewRecorder()
+	srv.OIDCCallback(rec, cb)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("callback: expected 302, got %d: %s", rec.Code, rec.Body.String())
+	}
+	loc, _ := url.Parse(rec.Header().Get("Location"))
+	if loc.Host != "app.test" || loc.Path != "/oidc/callback" {
+		t.Fatalf("callback redirected to %s", loc)
+	}
+	return loc
+}
+
+func ssoExchange(t *testing.T, srv *Server, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/oidc/exchange",
+		jsonBody(t, models.OIDCExchangeRequest{Token: token}))
+	rec := httptest.NewRecorder()
+	srv.OIDCExchange(rec, req)
+	return rec
+}
+
+func TestOIDC_NewAndReturningUser(t *testing.T) {
+	srv, idp := newSSOServer(t)
+	idp.SetClaims(map[string]any{
+		"sub": "staff-7", "email": "Wanjiru@Uni.test", "email_verified": true,
+		"name": "Wanjiru K", "groups": []string{"staff", "alumni"},
+	})
+
+	loc := ssoLogin(t, srv, idp, true)
+	token := loc.Query().Get("token")
+	rec := ssoExchange(t, srv, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("exchange: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var first models.LoginResponse
+	json.NewDecoder(rec.Body).Decode(&first)
+	if first.Token == "" || first.User.Email != "wanjiru@uni.test" || first.User.Role != models.RoleCompany {
+		t.Errorf("new account: %+v", first.User)
+	}
+	if rec := ssoExchange(t, srv, token); rec.Code != http.StatusUnauthorized {
+		t.Errorf("reused handoff: expected 401, got %d", rec.Code)
+	}
+	// SSO-only accounts have no password to log in with.
+	if code := loginStatus(t, srv, "wanjiru@uni.test", ""); code != http.StatusUnauthorized {
+		t.Errorf("password login with empty password: expected 401, got %d", code)
+	}
+
+	// The same subject logs in to the same account even with a new email,
+	// and the role claim no longer matters.
+	idp.SetClaims(map[string]any{"sub": "staff-7", "email": "w.k@uni.test", "email_verified": true})
+	rec = ssoExchange(t, srv, ssoLogin(t, srv, idp, true).Query().Get("token"))
+	var second models.LoginResponse
+	json.NewDecoder(rec.Body).Decode(&second)
+	if second.User.ID != first.User.ID || second.User.Role != models.RoleCompany {
+		t.Errorf("returning user: got %+v, want id %s", second.User, first.User.ID)
+	}
+}
+
+func TestOIDC_LinksOnlyVerifiedEmail(t *testing.T) {
+	srv, idp := newSSOServer(t)
+	existing := registerUser(t, srv, "amara@uni.test", models.RoleStudent)
+
+	idp.SetClaims(map[string]any{"sub": "s-1", "email": "amara@uni.test", "email_verified": false})
+	loc := ssoLogin(t, srv, idp, true)
+	if got := loc.Query().Get("error"); got != oidcErrEmailNotVerified {
+		t.Fatalf("unverified email: got error %q", got)
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM user_identities`); n != 0 {
+		t.Errorf("identity linked on an unverified email")
+	}
+
+	idp.SetClaims(map[string]any{"sub": "s-1", "email": "amara@uni.test", "email_verified": true})
+	rec := ssoExchange(t, srv, ssoLogin(t, srv, idp, true).Query().Get("token"))
+	var resp models.LoginResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.User.ID != existing.User.ID {
+		t.Errorf("linked to %s, want existing account %s", resp.User.ID, existing.User.ID)
+	}
+	if code := loginStatus(t, srv, "amara@uni.test", "password123"); code != http.StatusOK {
+		t.Errorf("password login after linking: expected 200, got %d", code)
+	}
+}
+
+func TestOIDC_NeverLinksPrivilegedAccounts(t *testing.T) {
+	srv, idp := newSSOServer(t)
+	seedAdminUser(t, srv)
+	registerUser(t, srv, "hr@corp.test", models.RoleCompany)
+
+	// The IdP vouches for the admin's address: still no way in.
+	idp.SetClaims(map[string]any{"sub": "a-1", "email": "admin@example.com", "email_verified": true})
+	if got := ssoLogin(t, srv, idp, true).Query().Get("error"); got != oidcErrAccountDisabled {
+		t.Errorf("admin email: got error %q", got)
+	}
+
+	// A provider that only makes students cannot sign in to a company.
+	srv.OIDC["campus"].RoleMap = nil
+	idp.SetClaims(map[string]any{"sub": "c-1", "email": "hr@corp.test", "email_verified": true})
+	if got := ssoLogin(t, srv, idp, true).Query().Get("error"); got != oidcErrAccountDisabled {
+		t.Errorf("company email at a student-only provider: got error %q", got)
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM user_identities`); n != 0 {
+		t.Errorf("%d identities linked, want 0", n)
+	}
+}
+
+func TestOIDC_Refusals(t *testing.T) {
+	srv, idp := newSSOServer(t)
+	idp.SetClaims(map[string]any{"sub": "x", "email": "x@uni.test", "email_verified": true})
+
+	// Without the state cookie the callback could have been started by
+	// someone else's browser.
+	if got := ssoLogin(t, srv, idp, false).Query().Get("error"); got != oidcErrInvalidState {
+		t.Errorf("missing cookie: got error %q", got)
+	}
+
+	// The user cancelled at the IdP.
+	idp.SetClaims(nil)
+	if got := ssoLogin(t, srv, idp, true).Query().Get("error"); got != "access_denied" {
+		t.Errorf("cancelled: got error %q", got)
+	}
+
+	// No role for the user and no default.
+	srv.OIDC["campus"].DefaultRole = ""
+	idp.SetClaims(map[string]any{"sub": "y", "email": "y@uni.test", "email_verified": true, "groups": "visitors"})
+	if got := ssoLogin(t, srv, idp, true).Query().Get("error"); got != oidcErrNoRole {
+		t.Errorf("no role: got error %q", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/nope/start", nil)
+	req.SetPathValue("provider", "nope")
+	rec := httptest.NewRecorder()
+	srv.StartOIDCLogin(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown provider: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestNewOIDCProviders_ChecksRoles(t *testing.T) {
+	_, err := NewOIDCProviders([]oidc.Config{{Name: "a", Issuer: "i", ClientID: "c", DefaultRole: "superuser"}}, "")
+	if err == nil {
+		t.Error("accepted an unknown default_role")
+	}
+	_, err = NewOIDCProviders([]oidc.Config{{Name: "a", Issuer: "i", ClientID: "c", DefaultRole: "admin"}}, "")
+	if err == nil {
+		t.Error("accepted admin as default_role")
+	}
+	_, err = NewOIDCProviders([]oidc.Config{{Name: "a", Issuer: "i", ClientID: "c",
+		RoleClaim: "groups", RoleMap: map[string]string{"ops": "admin"}}}, "")
+	if err == nil {
+		t.Error("accepted admin in role_map")
+	}
+	p, err := NewOIDCProviders([]oidc.Config{{Name: "a", Issuer: "i", ClientID: "c"}}, "https://api.test/")
+	if err != nil || p["a"].RedirectURL != "https://api.test/api/auth/oidc/a/callback" {
+		t.Errorf("default redirect: %v %v", p, err)
+	}
+}
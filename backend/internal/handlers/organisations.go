@@ -0,0 +1,725 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/mail"
+	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — organisations
+// ────────────────────────────────────────────────────────────────────
+// An event used to belong to the one user who created it, so if that
+// person was off sick nobody else at the company could open check-in.
+// Now events belong to an ORGANISATION, and what a company account may do
+// with an event depends on its role there (organisation_members):
+//
+//	staff — run events: check-in codes, status, registrations, conflicts
+//	admin — staff, plus create and edit events, invite and manage staff
+//	        and admins
+//	owner — admin, plus manage owners; every organisation keeps one
+//
+// The role is looked up on every request (authorizeEvent), so removing
+// someone takes effect at once.  People join by invitation: the link is
+// emailed to the address and only works for the account with that address,
+// like a magic link.  A company that creates an event before joining or
+// creating any organisation gets one of its own.
+
+const (
+	// organisationInvitationTTL is how long an invitation link works.
+	organisationInvitationTTL = 7 * 24 * time.Hour
+	// maxOrganisationMembers bounds one organisation, invitations included.
+	maxOrganisationMembers = 200
+)
+
+// orgRoleRank orders roles so checks can ask for "at least admin".
+var orgRoleRank = map[models.OrganisationRole]int{
+	models.OrgStaff: 1,
+	models.OrgAdmin: 2,
+	models.OrgOwner: 3,
+}
+
+func validOrgRole(role models.OrganisationRole) bool {
+	_, ok := orgRoleRank[role]
+	return ok
+}
+
+// hasOrgRole reports whether role includes everything need may do.  The
+// empty role (not a member) has none.
+func hasOrgRole(role, need models.OrganisationRole) bool {
+	return role != "" && orgRoleRank[role] >= orgRoleRank[need]
+}
+
+// canManageMember reports whether a member with role actor may change
+// someone's role from "from" to "to".  from is "" for a newcomer (an
+// invitation) and to is "" for a removal.
+func canManageMember(actor, from, to models.OrganisationRole) bool {
+	switch actor {
+	case models.OrgOwner:
+		return true
+	case models.OrgAdmin:
+		return from != models.OrgOwner && to != models.OrgOwner
+	}
+	return false
+}
+
+// authorizeEvent checks that the caller has at least role need in the
+// organisation hosting eventID, or one of the event roles grants on it
+// (eventroles.go).  Otherwise it writes 404 (also for deleted events) or
+// 403 and returns false.
+func (s *Server) authorizeEvent(w http.ResponseWriter, r *http.Request, eventID string, need models.OrganisationRole, grants ...models.EventRole) bool {
+	userID := middleware.GetUserID(r.Context())
+	var role, granted sql.NullString
+	err := s.DB.QueryRowContext(r.Context(),
+		`SELECT m.role, g.role FROM events e
+		 LEFT JOIN organisation_members m ON m.organisation_id = e.organisation_id AND m.user_id = ?
+		 LEFT JOIN event_roles g ON g.event_id = e.id AND g.user_id = ?
+		 WHERE e.id = ? AND e.deleted_at IS NULL`, userID, userID, eventID,
+	).Scan(&role, &granted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "event not found")
+			return false
+		}
+		respondError(w, http.StatusInternalServerError, "database error")
+		return false
+	}
+	if granted.Valid && slices.Contains(grants, models.EventRole(granted.String)) {
+		return true
+	}
+	if !role.Valid {
+		respondError(w, http.StatusForbidden, "you are not a member of this event's organisation")
+		return false
+	}
+	if !hasOrgRole(models.OrganisationRole(role.String), need) {
+		respondError(w, http.StatusForbidden, "your role in this event's organisation does not allow this")
+		return false
+	}
+	return true
+}
+
+// authorizeOrganisation returns the caller's role in orgID if it is at
+// least need.  Otherwise it writes 404 (not a member: the organisation is
+// not theirs to see) or 403 and returns false.
+func (s *Server) authorizeOrganisation(w http.ResponseWriter, r *http.Request, orgID string, need models.OrganisationRole) (models.OrganisationRole, bool) {
+	var role models.OrganisationRole
+	err := s.DB.QueryRowContext(r.Context(),
+		`SELECT role FROM organisation_members WHERE organisation_id = ? AND user_id = ?`,
+		orgID, middleware.GetUserID(r.Context()),
+	).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "organisation not found")
+			return "", false
+		}
+		respondError(w, http.StatusInternalServerError, "database error")
+		return "", false
+	}
+	if !hasOrgRole(role, need) {
+		respondError(w, http.StatusForbidden, "your role in this organisation does not allow this")
+		return "", false
+	}
+	return role, true
+}
+
+// errNoHostingOrganisation and errSeveralOrganisations are returned by
+// hostingOrganisation when it cannot pick the organisation for a new event.
+var (
+	errNoHostingOrganisation = errors.New("your role in this organisation does not allow creating events")
+	errSeveralOrganisations  = errors.New("organisation_id is required: you manage several organisations")
+)
+
+// hostingOrganisation returns the organisation a new event of userID's
+// goes to: requested if they are an admin or owner there, otherwise the
+// only one they manage.  A user in no organisation at all gets a new one,
+// named after them, inside tx.
+func hostingOrganisation(ctx context.Context, tx *sql.Tx, userID, requested string) (string, error) {
+	if requested != "" {
+		var role models.OrganisationRole
+		err := tx.QueryRowContext(ctx,
+			`SELECT role FROM organisation_members WHERE organisation_id = ? AND user_id = ?`, requested, userID,
+		).Scan(&role)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && !hasOrgRole(role, models.OrgAdmin)) {
+			return "", errNoHostingOrganisation
+		}
+		return requested, err
+	}
+
+	var managed, memberships int
+	var orgID sql.NullString
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FILTER (WHERE role IN ('owner','admin')), COUNT(*),
+		        MAX(organisation_id) FILTER (WHERE role IN ('owner','admin'))
+		 FROM organisation_members WHERE user_id = ?`, userID,
+	).Scan(&managed, &memberships, &orgID); err != nil {
+		return "", err
+	}
+	switch {
+	case managed == 1:
+		return orgID.String, nil
+	case managed > 1:
+		return "", errSeveralOrganisations
+	case memberships > 0:
+		return "", errNoHostingOrganisation
+	}
+
+	var name string
+	if err := tx.QueryRowContext(ctx, `SELECT name FROM users WHERE id = ?`, userID).Scan(&name); err != nil {
+		return "", err
+	}
+	return createOrganisation(ctx, tx, userID, name)
+}
+
+// createOrganisation inserts an organisation with ownerID as its owner.
+func createOrganisation(ctx context.Context, tx *sql.Tx, ownerID, name string) (string, error) {
+	id := uuid.NewString()
+	now := time.Now().UTC()
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO organisations (id, name, created_at) VALUES (?, ?, ?)`, id, name, now,
+	); err != nil {
+		return "", err
+	}
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO organisation_members (organisation_id, user_id, role, created_at) VALUES (?, ?, 'owner', ?)`,
+		id, ownerID, now)
+	return id, err
+}
+
+// hasOwner reports whether orgID still has an owner, for the checks that
+// stop the last one leaving or being demoted.
+func hasOwner(ctx context.Context, tx *sql.Tx, orgID string) (bool, error) {
+	var owners int
+	err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM organisation_members WHERE organisation_id = ? AND role = 'owner'`, orgID,
+	).Scan(&owners)
+	return owners > 0, err
+}
+
+// CreateOrganisation handles POST /api/organisations  (company only)
+// Body: {"name": "TechCorp Africa"}.  The caller becomes its owner.
+func (s *Server) CreateOrganisation(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	var req models.CreateOrganisationRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 255 {
+		respondError(w, http.StatusBadRequest, "name is required (at most 255 characters)")
+		return
+	}
+
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	id, err := createOrganisation(r.Context(), tx, userID, name)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not create organisation")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	s.respondOrganisation(w, r, http.StatusCreated, id, models.OrgOwner)
+}
+
+// ListOrganisations handles GET /api/organisations  (company only)
+// Returns the organisations the caller belongs to, with their role in each.
+func (s *Server) ListOrganisations(w http.ResponseWriter, r *http.Request) {
+	rows, err := s.DB.QueryContext(r.Context(),
+		`SELECT o.id, o.name, o.created_at, m.role
+		 FROM organisation_members m
+		 JOIN organisations o ON o.id = m.organisation_id
+		 WHERE m.user_id = ?
+		 ORDER BY o.name`, middleware.GetUserID(r.Context()))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	orgs := []models.Organisation{}
+	for rows.Next() {
+		var o models.Organisation
+		if err := rows.Scan(&o.ID, &o.Name, &o.CreatedAt, &o.Role); err != nil {
+			respondError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		orgs = append(orgs, o)
+	}
+	if err := rows.Err(); err != nil {
+		respondError(w, http.StatusInternalServerError, "rows error")
+		return
+	}
+
+	respond(w, http.StatusOK, orgs)
+}
+
+// GetOrganisation handles GET /api/organisations/{id}  (members only)
+// Returns the organisation with its members.
+func (s *Server) GetOrganisation(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	role, ok := s.authorizeOrganisation(w, r, id, models.OrgStaff)
+	if !ok {
+		return
+	}
+	s.respondOrganisation(w, r, http.StatusOK, id, role)
+}
+
+// respondOrganisation writes organisation id with its members; role is
+// the caller's.
+func (s *Server) respondOrganisation(w http.ResponseWriter, r *http.Request, status int, id string, role models.OrganisationRole) {
+	org := models.Organisation{ID: id, Role: role, Members: []models.OrganisationMember{}}
+	if err := s.DB.QueryRowContext(r.Context(),
+		`SELECT name, created_at FROM organisations WHERE id = ?`, id,
+	).Scan(&org.Name, &org.CreatedAt); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	rows, err := s.DB.QueryContext(r.Context(),
+		`SELECT u.id, u.name, u.email, m.role, m.created_at
+		 FROM organisation_members m
+		 JOIN users u ON u.id = m.user_id
+		 WHERE m.organisation_id = ?
+		 ORDER BY m.created_at, u.name`, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m models.OrganisationMember
+		if err := rows.Scan(&m.UserID, &m.Name, &m.Email, &m.Role, &m.JoinedAt); err != nil {
+			respondError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		org.Members = append(org.Members, m)
+	}
+	if err := rows.Err(); err != nil {
+		respondError(w, http.StatusInternalServerError, "rows error")
+		return
+	}
+
+	respond(w, status, org)
+}
+
+// UpdateMemberRole handles PATCH /api/organisations/{id}/members/{user_id}
+//
+// Body: {"role": "staff" | "admin" | "owner"}.  Admins manage staff and
+// admins; only owners can touch owners.  The last owner cannot step down.
+func (s *Server) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
+	orgID, memberID := r.PathValue("id"), r.PathValue("user_id")
+	actor, ok := s.authorizeOrganisation(w, r, orgID, models.OrgAdmin)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateMemberRoleRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if !validOrgRole(req.Role) {
+		respondError(w, http.StatusBadRequest, "role must be 'owner', 'admin' or 'staff'")
+		return
+	}
+
+	s.changeMember(w, r, orgID, memberID, actor, req.Role)
+}
+
+// RemoveMember handles DELETE /api/organisations/{id}/members/{user_id}
+// Admins remove staff and admins, owners anyone; every member may leave.
+// The last owner cannot.
+func (s *Server) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	orgID, memberID := r.PathValue("id"), r.PathValue("user_id")
+	actor, ok := s.authorizeOrganisation(w, r, orgID, models.OrgStaff)
+	if !ok {
+		return
+	}
+	if memberID == middleware.GetUserID(r.Context()) {
+		// Leaving needs no management rights.
+		actor = models.OrgOwner
+	}
+
+	s.changeMember(w, r, orgID, memberID, actor, "")
+}
+
+// changeMember gives memberID role to, or removes them when to is "".
+func (s *Server) changeMember(w http.ResponseWriter, r *http.Request, orgID, memberID string, actor, to models.OrganisationRole) {
+	ctx := r.Context()
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var from models.OrganisationRole
+	err = tx.QueryRowContext(ctx,
+		`SELECT role FROM organisation_members WHERE organisation_id = ? AND user_id = ?`, orgID, memberID,
+	).Scan(&from)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "member not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if !canManageMember(actor, from, to) {
+		respondError(w, http.StatusForbidden, "only owners can manage owners")
+		return
+	}
+
+	if to == "" {
+		_, err = tx.ExecContext(ctx,
+			`DELETE FROM organisation_members WHERE organisation_id = ? AND user_id = ?`, orgID, memberID)
+	} else {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE organisation_members SET role = ? WHERE organisation_id = ? AND user_id = ?`, to, orgID, memberID)
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not update member")
+		return
+	}
+	owned, err := hasOwner(ctx, tx, orgID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if !owned {
+		respondError(w, http.StatusConflict, "an organisation needs at least one owner; make someone else owner first")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if to == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	respond(w, http.StatusOK, map[string]string{"user_id": memberID, "role": string(to)})
+}
+
+// InviteMember handles POST /api/organisations/{id}/invitations  (admins and owners)
+//
+// Body: {"email": "...", "role": "staff"}.  Emails a link that adds the
+// company account with that address to the organisation.  A new invitation
+// for the same address replaces the old one.
+func (s *Server) InviteMember(w http.ResponseWriter, r *http.Request) {
+	orgID := r.PathValue("id")
+	actor, ok := s.authorizeOrganisation(w, r, orgID, models.OrgAdmin)
+	if !ok {
+		return
+	}
+
+	var req models.InviteMemberRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if !strings.Contains(email, "@") || len(email) > 255 {
+		respondError(w, http.StatusBadRequest, "a valid email is required")
+		return
+	}
+	if !validOrgRole(req.Role) {
+		respondError(w, http.StatusBadRequest, "role must be 'owner', 'admin' or 'staff'")
+		return
+	}
+	if !canManageMember(actor, "", req.Role) {
+		respondError(w, http.StatusForbidden, "only owners can invite owners")
+		return
+	}
+
+	ctx := r.Context()
+	var inviteeRole models.UserRole
+	var member bool
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT role, EXISTS (SELECT 1 FROM organisation_members WHERE organisation_id = ? AND user_id = users.id)
+		 FROM users WHERE email = ?`, orgID, email,
+	).Scan(&inviteeRole, &member)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err == nil && inviteeRole != models.RoleCompany {
+		respondError(w, http.StatusBadRequest, "only company accounts can join an organisation")
+		return
+	}
+	if member {
+		respondError(w, http.StatusConflict, "already a member")
+		return
+	}
+
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not create invitation")
+		return
+	}
+	now := time.Now().UTC()
+	inv := models.OrganisationInvitation{
+		ID:             uuid.NewString(),
+		OrganisationID: orgID,
+		Email:          email,
+		Role:           req.Role,
+		InvitedBy:      middleware.GetUserID(ctx),
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(organisationInvitationTTL),
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE organisation_invitations SET revoked_at = ?
+		 WHERE organisation_id = ? AND email = ? AND accepted_at IS NULL AND revoked_at IS NULL`,
+		now, orgID, email,
+	); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	var size int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT (SELECT COUNT(*) FROM organisation_members WHERE organisation_id = ?) +
+		        (SELECT COUNT(*) FROM organisation_invitations
+		         WHERE organisation_id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?)`,
+		orgID, orgID, now,
+	).Scan(&size); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if size >= maxOrganisationMembers {
+		respondError(w, http.StatusConflict, "organisation is full")
+		return
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO organisation_invitations (id, organisation_id, email, role, invited_by, token_hash, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		inv.ID, orgID, email, inv.Role, inv.InvitedBy, hash, now, inv.ExpiresAt,
+	); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not create invitation")
+		return
+	}
+	var orgName, inviterName string
+	if err := tx.QueryRowContext(ctx,
+		`SELECT o.name, u.name FROM organisations o, users u WHERE o.id = ? AND u.id = ?`, orgID, inv.InvitedBy,
+	).Scan(&orgName, &inviterName); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if s.Mailer == nil {
+		respondError(w, http.StatusInternalServerError, "could not send invitation email")
+		return
+	}
+	if err := s.Mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: fmt.Sprintf("Join %s on Skillzone", orgName),
+		Body: fmt.Sprintf("Hi,\n\n"+
+			"%s invited you to join %s on Skillzone as %s.  Open this link to accept:\n\n%s\n\n"+
+			"Log in (or sign up as a company) with this email address first.  The link works for %s.\n",
+			inviterName, orgName, inv.Role, s.appLink("/invitations", token), organisationInvitationTTL),
+	}); err != nil {
+		slog.Error("invitation email", "err", err)
+		respondError(w, http.StatusInternalServerError, "could not send invitation email")
+		return
+	}
+
+	respond(w, http.StatusCreated, inv)
+}
+
+// ListInvitations handles GET /api/organisations/{id}/invitations  (admins and owners)
+// Returns the invitations that can still be accepted, newest first.
+func (s *Server) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	orgID := r.PathValue("id")
+	if _, ok := s.authorizeOrganisation(w, r, orgID, models.OrgAdmin); !ok {
+		return
+	}
+
+	rows, err := s.DB.QueryContext(r.Context(),
+		`SELECT id, email, role, invited_by, created_at, expires_at
+		 FROM organisation_invitations
+		 WHERE organisation_id = ? AND accepted_at IS NULL AND revoked_at IS NULL
+		 ORDER BY rowid DESC`, orgID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	invitations := []models.OrganisationInvitation{}
+	for rows.Next() {
+		inv := models.OrganisationInvitation{OrganisationID: orgID}
+		if err := rows.Scan(&inv.ID, &inv.Email, &inv.Role, &inv.InvitedBy, &inv.CreatedAt, &inv.ExpiresAt); err != nil {
+			respondError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		if !isExpired(inv.ExpiresAt, now) {
+			invitations = append(invitations, inv)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		respondError(w, http.StatusInternalServerError, "rows error")
+		return
+	}
+
+	respond(w, http.StatusOK, invitations)
+}
+
+// RevokeInvitation handles DELETE /api/organisations/{id}/invitations/{invitation_id}
+// The link stops working.  Only owners can revoke an invitation to be owner.
+func (s *Server) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	orgID, invID := r.PathValue("id"), r.PathValue("invitation_id")
+	actor, ok := s.authorizeOrganisation(w, r, orgID, models.OrgAdmin)
+	if !ok {
+		return
+	}
+
+	var role models.OrganisationRole
+	err := s.DB.QueryRowContext(r.Context(),
+		`SELECT role FROM organisation_invitations
+		 WHERE id = ? AND organisation_id = ? AND accepted_at IS NULL AND revoked_at IS NULL`, invID, orgID,
+	).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "invitation not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if !canManageMember(actor, "", role) {
+		respondError(w, http.StatusForbidden, "only owners can manage owners")
+		return
+	}
+	if _, err := s.DB.ExecContext(r.Context(),
+		`UPDATE organisation_invitations SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`,
+		time.Now().UTC(), invID,
+	); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptInvitation handles POST /api/organisations/invitations/accept  (company only)
+//
+// Body: {"token": "..."} from the emailed link.  The caller must be logged
+// in with the address the invitation was sent to.  Returns the organisation.
+func (s *Server) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	var req models.AcceptInvitationRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.Token == "" {
+		respondError(w, http.StatusBadRequest, "invalid or expired invitation")
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var invID, orgID, email, userEmail string
+	var role models.OrganisationRole
+	var expiresAt time.Time
+	var acceptedAt, revokedAt sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		`SELECT i.id, i.organisation_id, i.email, i.role, i.expires_at, i.accepted_at, i.revoked_at, u.email
+		 FROM organisation_invitations i, users u
+		 WHERE i.token_hash = ? AND u.id = ?`, auth.HashOpaqueToken(req.Token), userID,
+	).Scan(&invID, &orgID, &email, &role, &expiresAt, &acceptedAt, &revokedAt, &userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	now := time.Now().UTC()
+	if err != nil || acceptedAt.Valid || revokedAt.Valid || isExpired(expiresAt, now) {
+		respondError(w, http.StatusBadRequest, "invalid or expired invitation")
+		return
+	}
+	if email != userEmail {
+		respondError(w, http.StatusForbidden, "this invitation is for "+email+"; log in with that address")
+		return
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE organisation_invitations SET accepted_at = ? WHERE id = ? AND accepted_at IS NULL`, now, invID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusBadRequest, "invalid or expired invitation")
+		return
+	}
+	// An existing member keeps the role they have.
+	if _, err := tx.ExecContext(ctx,
+		`INSERT OR IGNORE INTO organisation_members (organisation_id, user_id, role, created_at) VALUES (?, ?, ?, ?)`,
+		orgID, userID, role, now,
+	); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not join organisation")
+		return
+	}
+	// The link reached the inbox, which is all verification asks.
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET email_verified_at = COALESCE(email_verified_at, ?) WHERE id = ?`, now, userID,
+	); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	var joined models.OrganisationRole
+	if err := tx.QueryRowContext(ctx,
+		`SELECT role FROM organisation_members WHERE organisation_id = ? AND user_id = ?`, orgID, userID,
+	).Scan(&joined); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	s.respondOrganisation(w, r, http.StatusOK, orgID, joined)
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+)
+
+var inviteLinkRE = regexp.MustCompile(`/invitations\?token=(\S+)`)
+
+// inviteToken returns the token from the most recent invitation email.
+func (o *outbox) inviteToken(t *testing.T) string {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := len(o.sent) - 1; i >= 0; i-- {
+		if m := inviteLinkRE.FindStringSubmatch(o.sent[i].Body); m != nil {
+			token, _ := url.QueryUnescape(m[1])
+			return token
+		}
+	}
+	t.Fatal("no invitation email sent")
+	return ""
+}
+
+// asCompany calls handler as company user userID.  pathValues are name,
+// value pairs.
+func asCompany(t *testing.T, handler http.HandlerFunc, method, userID string, body any, pathValues ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	return asUser(t, handler, method, userID, "company", body, pathValues...)
+}
+
+// asUser is asCompany for a user with any role.
+func asUser(t *testing.T, handler http.HandlerFunc, method, userID, role string, body any, pathValues ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/test", nil)
+	if body != nil {
+		req = httptest.NewRequest(method, "/api/test", jsonBody(t, body))
+	}
+	for i := 0; i+1 < len(pathValues); i += 2 {
+		req.SetPathValue(pathValues[i], pathValues[i+1])
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, ctxWithUser(req, userID, role))
+	return rec
+}
+
+func newEventRequest(orgID string) models.CreateEventRequest {
+	return models.CreateEventRequest{
+		Title:          "Careers Fair",
+		StartTime:      time.Now().Add(time.Hour),
+		EndTime:        time.Now().Add(2 * time.Hour),
+		OrganisationID: orgID,
+	}
+}
+
+func TestOrganisations_StaffRunEvents(t *testing.T) {
+	srv := newTestServer(t)
+	owner := seedCompanyUser(t, srv)
+	staff := registerUser(t, srv, "staff@acme.test", models.RoleCompany)
+	eve := registerUser(t, srv, "eve@acme.test", models.RoleCompany)
+	box := &outbox{}
+	srv.Mailer = box
+
+	// A company's first event gets an organisation of its own.
+	rec := asCompany(t, srv.CreateEvent, http.MethodPost, owner, newEventRequest(""))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create event: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var event models.Event
+	json.NewDecoder(rec.Body).Decode(&event)
+	if event.OrganisationID == "" {
+		t.Fatal("event has no organisation")
+	}
+	orgID := event.OrganisationID
+	checkInCode := func(userID string) int {
+		return asCompany(t, srv.GetEventCheckInCode, http.MethodGet, userID, nil, "id", event.ID).Code
+	}
+	if code := checkInCode(staff.User.ID); code != http.StatusForbidden {
+		t.Errorf("non-member check-in code: expected 403, got %d", code)
+	}
+
+	rec = asCompany(t, srv.InviteMember, http.MethodPost, owner,
+		models.InviteMemberRequest{Email: " Staff@Acme.test", Role: models.OrgStaff}, "id", orgID)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("invite: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	token := box.inviteToken(t)
+	accept := func(userID string) *httptest.ResponseRecorder {
+		return asCompany(t, srv.AcceptInvitation, http.MethodPost, userID, models.AcceptInvitationRequest{Token: token})
+	}
+	if rec := accept(eve.User.ID); rec.Code != http.StatusForbidden {
+		t.Errorf("accept with another account: expected 403, got %d", rec.Code)
+	}
+	if rec := accept(staff.User.ID); rec.Code != http.StatusOK {
+		t.Fatalf("accept: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := accept(staff.User.ID); rec.Code != http.StatusBadRequest {
+		t.Errorf("reused invitation: expected 400, got %d", rec.Code)
+	}
+
+	// Staff run the event, but do not edit it or manage the organisation.
+	if code := checkInCode(staff.User.ID); code != http.StatusOK {
+		t.Errorf("staff check-in code: expected 200, got %d", code)
+	}
+	if rec := asCompany(t, srv.UpdateEventStatus, http.MethodPatch, staff.User.ID,
+		models.UpdateEventStatusRequest{Status: models.EventStatusActive}, "id", event.ID); rec.Code != http.StatusOK {
+		t.Errorf("staff status change: expected 200, got %d", rec.Code)
+	}
+	if rec := asCompany(t, srv.UpdateEvent, http.MethodPut, staff.User.ID,
+		models.UpdateEventRequest{Title: "Renamed"}, "id", event.ID); rec.Code != http.StatusForbidden {
+		t.Errorf("staff edit: expected 403, got %d", rec.Code)
+	}
+	if rec := asCompany(t, srv.InviteMember, http.MethodPost, staff.User.ID,
+		models.InviteMemberRequest{Email: "x@acme.test", Role: models.OrgStaff}, "id", orgID); rec.Code != http.StatusForbidden {
+		t.Errorf("staff invite: expected 403, got %d", rec.Code)
+	}
+	rec = asCompany(t, srv.ListHostedEvents, http.MethodGet, staff.User.ID, nil)
+	var hosted []models.Event
+	json.NewDecoder(rec.Body).Decode(&hosted)
+	if len(hosted) != 1 || hosted[0].ID != event.ID {
+		t.Errorf("staff hosted events: got %+v", hosted)
+	}
+
+	// Removal takes effect at once.
+	if rec := asCompany(t, srv.RemoveMember, http.MethodDelete, owner, nil,
+		"id", orgID, "user_id", staff.User.ID); rec.Code != http.StatusNoContent {
+		t.Fatalf("remove: expected 204, got %d", rec.Code)
+	}
+	if code := checkInCode(staff.User.ID); code != http.StatusForbidden {
+		t.Errorf("removed staff check-in code: expected 403, got %d", code)
+	}
+}
+
+func TestOrganisations_Owners(t *testing.T) {
+	srv := newTestServer(t)
+	owner := seedCompanyUser(t, srv)
+	admin := seedCompanyUser(t, srv)
+
+	rec := asCompany(t, srv.CreateOrganisation, http.MethodPost, owner,
+		models.CreateOrganisationRequest{Name: "Acme"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var org models.Organisation
+	json.NewDecoder(rec.Body).Decode(&org)
+	if _, err := srv.DB.Exec(
+		`INSERT INTO organisation_members (organisation_id, user_id, role, created_at) VALUES (?, ?, 'admin', ?)`,
+		org.ID, admin, time.Now().UTC()); err != nil {
+		t.Fatal(err)
+	}
+	setRole := func(actor, member string, role models.OrganisationRole) int {
+		return asCompany(t, srv.UpdateMemberRole, http.MethodPatch, actor,
+			models.UpdateMemberRoleRequest{Role: role}, "id", org.ID, "user_id", member).Code
+	}
+	remove := func(actor, member string) int {
+		return asCompany(t, srv.RemoveMember, http.MethodDelete, actor, nil, "id", org.ID, "user_id", member).Code
+	}
+
+	// Admins cannot touch owners, nor make owners.
+	if code := remove(admin, owner); code != http.StatusForbidden {
+		t.Errorf("admin removes owner: expected 403, got %d", code)
+	}
+	if code := setRole(admin, admin, models.OrgOwner); code != http.StatusForbidden {
+		t.Errorf("admin promotes self: expected 403, got %d", code)
+	}
+	// The last owner cannot leave or step down.
+	if code := remove(owner, owner); code != http.StatusConflict {
+		t.Errorf("last owner leaves: expected 409, got %d", code)
+	}
+	if code := setRole(owner, owner, models.OrgAdmin); code != http.StatusConflict {
+		t.Errorf("last owner steps down: expected 409, got %d", code)
+	}
+	if code := setRole(owner, admin, models.OrgOwner); code != http.StatusOK {
+		t.Fatalf("promote: expected 200, got %d", code)
+	}
+	if code := remove(owner, owner); code != http.StatusNoContent {
+		t.Fatalf("owner leaves: expected 204, got %d", code)
+	}
+	if rec := asCompany(t, srv.GetOrganisation, http.MethodGet, owner, nil, "id", org.ID); rec.Code != http.StatusNotFound {
+		t.Errorf("former member reads organisation: expected 404, got %d", rec.Code)
+	}
+
+	// Someone managing two organisations must say which one hosts the event.
+	asCompany(t, srv.CreateOrganisation, http.MethodPost, admin, models.CreateOrganisationRequest{Name: "Acme Labs"})
+	if rec := asCompany(t, srv.CreateEvent, http.MethodPost, admin, newEventRequest("")); rec.Code != http.StatusBadRequest {
+		t.Errorf("ambiguous organisation: expected 400, got %d", rec.Code)
+	}
+	if rec := asCompany(t, srv.CreateEvent, http.MethodPost, owner, newEventRequest(org.ID)); rec.Code != http.StatusForbidden {
+		t.Errorf("event for another organisation: expected 403, got %d", rec.Code)
+	}
+	rec = asCompany(t, srv.CreateEvent, http.MethodPost, admin, newEventRequest(org.ID))
+	var event models.Event
+	json.NewDecoder(rec.Body).Decode(&event)
+	if rec.Code != http.StatusCreated || event.OrganisationID != org.ID {
+		t.Errorf("event for own organisation: got %d, organisation %q", rec.Code, event.OrganisationID)
+	}
+}
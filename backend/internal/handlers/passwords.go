@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/mail"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — password reset tokens
+// ────────────────────────────────────────────────────────────────────
+// "Forgot password" emails a link containing a random token.  Whoever holds
+// that link can set a new password, so it is treated like a password itself:
+//
+//   - Only its SHA-256 is stored (see auth.NewOpaqueToken), so a database
+//     leak does not hand out working links.
+//   - It is single-use: the reset marks the row used_at in the same
+//     transaction that changes the password, with a compare-and-swap on
+//     used_at IS NULL so two racing resets cannot both win.
+//   - It expires (Server.PasswordResetTTL, default one hour), and asking
+//     for a new link cancels any older ones.
+//
+// /forgot answers 202 whether or not the email is registered.  Anything
+// else would let strangers find out who has an account.
+
+// defaultPasswordResetTTL is used when Server.PasswordResetTTL is zero.
+const defaultPasswordResetTTL = time.Hour
+
+// minPasswordLength is enforced everywhere a password is chosen.
+const minPasswordLength = 8
+
+// ForgotPassword handles POST /api/auth/password/forgot
+//
+// Body: {"email": "..."}.  Always 202 Accepted for a well-formed request.
+func (s *Server) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ForgotPasswordRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	email := strings.TrimSpace(strings.ToLower(req.Email))
+	if email == "" {
+		respondError(w, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	if err := s.sendPasswordReset(r.Context(), email); err != nil {
+		// Logged, not returned: the response must not depend on whether
+		// the account exists or the mail server is up.
+		slog.Error("password reset", "err", err)
+	}
+
+	respond(w, http.StatusAccepted, map[string]string{
+		"message": "if that email is registered, a reset link is on its way",
+	})
+}
+
+// sendPasswordReset issues a reset token for the active account with email,
+// if there is one, and mails the link.
+func (s *Server) sendPasswordReset(ctx context.Context, email string) error {
+	var userID, name string
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT id, name FROM users WHERE email = ? AND status = 'active'`, email,
+	).Scan(&userID, &name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	ttl := s.PasswordResetTTL
+	if ttl <= 0 {
+		ttl = defaultPasswordResetTTL
+	}
+	now := time.Now().UTC()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE password_resets SET used_at = ? WHERE user_id = ? AND used_at IS NULL`, now, userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO password_resets (id, user_id, token_hash, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?)`,
+		uuid.NewString(), userID, hash, now, now.Add(ttl),
+	); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if s.Mailer == nil {
+		return errors.New("no mailer configured")
+	}
+	link := s.appLink("/reset-password", token)
+	return s.Mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Reset your Skillzone password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password for your Skillzone account.\n"+
+			"If it was you, open this link within %s:\n\n%s\n\n"+
+			"If it wasn't, ignore this email; your password has not changed.\n",
+			name, ttl, link),
+	})
+}
+
+// appLink builds a frontend URL carrying token, for links in emails.
+func (s *Server) appLink(path, token string) string {
+	return strings.TrimRight(s.AppURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// ResetPassword handles POST /api/auth/password/reset
+//
+// Body: {"token": "...", "password": "..."}.  On success the password is
+// changed, every session and API key of the user is revoked and the
+// response is 204.
+func (s *Server) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ResetPasswordRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if len(req.Password) < minPasswordLength {
+		respondError(w, http.StatusBadRequest, "password must be at least 8 characters")
+		return
+	}
+	if req.Token == "" {
+		respondError(w, http.StatusBadRequest, "invalid or expired reset token")
+		return
+	}
+
+	hash, err := s.Passwords.Hash(req.Password)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to hash password")
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var resetID, userID string
+	var expiresAt time.Time
+	var usedAt sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		`SELECT id, user_id, expires_at, used_at FROM password_resets WHERE token_hash = ?`,
+		auth.HashOpaqueToken(req.Token),
+	).Scan(&resetID, &userID, &expiresAt, &usedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	now := time.Now().UTC()
+	if err != nil || usedAt.Valid || isExpired(expiresAt, now) {
+		respondError(w, http.StatusBadRequest, "invalid or expired reset token")
+		return
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE password_resets SET used_at = ? WHERE id = ? AND used_at IS NULL`, now, resetID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusBadRequest, "invalid or expired reset token")
+		return
+	}
+	// The account may have been suspended or deleted since the link was sent.
+	result, err = tx.ExecContext(ctx,
+		`UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ? AND status = 'active'`,
+		string(hash), now, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not update password")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusBadRequest, "invalid or expired reset token")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	// Whoever knew the old password is logged out everywhere, and loses
+	// any API key they made with it.
+	if err := s.revokeUserSessions(ctx, userID); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not revoke sessions")
+		return
+	}
+	if err := s.revokeUserAPIKeys(ctx, userID); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not revoke API keys")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/mail"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+)
+
+// outbox is a mail.Mailer that keeps every message in memory.
+type outbox struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (o *outbox) Send(_ context.Context, msg mail.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.sent = append(o.sent, msg)
+	return nil
+}
+
+var resetLinkRE = regexp.MustCompile(`/reset-password\?token=(\S+)`)
+
+// lastResetToken returns the token from the most recent reset email.
+func (o *outbox) lastResetToken(t *testing.T) string {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.sent) == 0 {
+		t.Fatal("no email sent")
+	}
+	m := resetLinkRE.FindStringSubmatch(o.sent[len(o.sent)-1].Body)
+	if m == nil {
+		t.Fatalf("no reset link in email:\n%s", o.sent[len(o.sent)-1].Body)
+	}
+	token, _ := url.QueryUnescape(m[1])
+	return token
+}
+
+func forgot(t *testing.T, srv *Server, email string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/password/forgot",
+		jsonBody(t, models.ForgotPasswordRequest{Email: email}))
+	rec := httptest.NewRecorder()
+	srv.ForgotPassword(rec, req)
+	return rec.Code
+}
+
+func reset(t *testing.T, srv *Server, token, password string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/password/reset",
+		jsonBody(t, models.ResetPasswordRequest{Token: token, Password: password}))
+	rec := httptest.NewRecorder()
+	srv.ResetPassword(rec, req)
+	return rec.Code
+}
+
+func TestPasswordReset_Flow(t *testing.T) {
+	srv := newTestServer(t)
+	box := &outbox{}
+	srv.AppURL = "https://app.example.com/"
+	login := registerUser(t, srv, "forgetful@example.com", models.RoleStudent)
+	srv.Mailer = box
+
+	if code := forgot(t, srv, " Forgetful@Example.com "); code != http.StatusAccepted {
+		t.Fatalf("forgot: expected 202, got %d", code)
+	}
+	if len(box.sent) != 1 || box.sent[0].To != "forgetful@example.com" {
+		t.Fatalf("expected one email to forgetful@example.com, got %+v", box.sent)
+	}
+	token := box.lastResetToken(t)
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM password_resets WHERE token_hash = ?`, token); n != 0 {
+		t.Error("reset token stored in plain text")
+	}
+
+	if code := reset(t, srv, token, "short"); code != http.StatusBadRequest {
+		t.Errorf("short password: expected 400, got %d", code)
+	}
+	if code := reset(t, srv, token, "brand-new-password"); code != http.StatusNoContent {
+		t.Fatalf("reset: expected 204, got %d", code)
+	}
+	if code := loginStatus(t, srv, "forgetful@example.com", "password123"); code != http.StatusUnauthorized {
+		t.Errorf("old password: expected 401, got %d", code)
+	}
+	if code := loginStatus(t, srv, "forgetful@example.com", "brand-new-password"); code != http.StatusOK {
+		t.Errorf("new password: expected 200, got %d", code)
+	}
+	claims, _ := auth.ParseToken(login.Token, testKeys)
+	if state, _ := srv.SessionState(context.Background(), claims.SessionID); state.Active {
+		t.Error("existing session survived the reset")
+	}
+
+	// Single use.
+	if code := reset(t, srv, token, "another-password"); code != http.StatusBadRequest {
+		t.Errorf("reused token: expected 400, got %d", code)
+	}
+}
+
+func TestForgotPassword_UnknownEmailLooksTheSame(t *testing.T) {
+	srv := newTestServer(t)
+	box := &outbox{}
+	srv.Mailer = box
+
+	if code := forgot(t, srv, "nobody@example.com"); code != http.StatusAccepted {
+		t.Errorf("expected 202, got %d", code)
+	}
+	if len(box.sent) != 0 {
+		t.Errorf("expected no email, got %d", len(box.sent))
+	}
+}
+
+func TestPasswordReset_ExpiredAndSuperseded(t *testing.T) {
+	srv := newTestServer(t)
+	box := &outbox{}
+	registerUser(t, srv, "slow@example.com", models.RoleCompany)
+	srv.Mailer = box
+
+	forgot(t, srv, "slow@example.com")
+	first := box.lastResetToken(t)
+	forgot(t, srv, "slow@example.com")
+	second := box.lastResetToken(t)
+
+	if code := reset(t, srv, first, "new-password-1"); code != http.StatusBadRequest {
+		t.Errorf("superseded token: expected 400, got %d", code)
+	}
+
+	srv.DB.Exec(`UPDATE password_resets SET expires_at = ? WHERE token_hash = ?`,
+		time.Now().UTC().Add(-time.Minute), auth.HashOpaqueToken(second))
+	if code := reset(t, srv, second, "new-password-2"); code != http.StatusBadRequest {
+		t.Errorf("expired token: expected 400, got %d", code)
+	}
+	if code := reset(t, srv, "not-a-token", "new-password-3"); code != http.StatusBadRequest {
+		t.Errorf("unknown token: expected 400, got %d", code)
+	}
+}
@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	netmail "net/mail"
+	"strings"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/mail"
+	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — changing your own account
+// ────────────────────────────────────────────────────────────────────
+// The name can simply be overwritten.  The other two fields are
+// credentials, so changing them needs proof:
+//
+//   - Email: the new address only replaces the old one once the link sent
+//     TO IT is opened (ConfirmEmail).  Otherwise a typo — or someone at an
+//     unlocked laptop — could move the account to an address the owner
+//     does not control, and "forgot password" would then help the wrong
+//     person.  Until then the request shows up as pending_email.
+//   - Password: the current password must be supplied, and every OTHER
+//     session is revoked so a thief who was logged in elsewhere is kicked
+//     out.  The device that made the change stays logged in.
+
+// emailChangeTTL is how long an email confirmation link works.
+const emailChangeTTL = 24 * time.Hour
+
+// loadProfile reads the user's own profile, including any pending email.
+func (s *Server) loadProfile(ctx context.Context, userID string) (models.User, error) {
+	var user models.User
+	var verifiedAt sql.NullTime
+	var institutionID, institutionName sql.NullString
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT u.id, u.email, u.name, u.role, u.status, u.created_at, u.updated_at,
+		        u.totp_enabled_at IS NOT NULL, u.email_verified_at, i.id, i.name
+		 FROM users u LEFT JOIN institutions i ON i.id = u.institution_id
+		 WHERE u.id = ?`, userID,
+	).Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.Status,
+		&user.CreatedAt, &user.UpdatedAt, &user.TwoFactorEnabled, &verifiedAt,
+		&institutionID, &institutionName)
+	if err != nil {
+		return models.User{}, err
+	}
+	if verifiedAt.Valid {
+		user.EmailVerifiedAt = &verifiedAt.Time
+	}
+	if institutionID.Valid {
+		user.Institution = &models.InstitutionRef{ID: institutionID.String, Name: institutionName.String}
+	}
+
+	rows, err := s.DB.QueryContext(ctx,
+		`SELECT new_email, expires_at FROM email_changes
+		 WHERE user_id = ? AND used_at IS NULL`, userID)
+	if err != nil {
+		return models.User{}, err
+	}
+	defer rows.Close()
+	now := time.Now()
+	for rows.Next() {
+		var email string
+		var expiresAt time.Time
+		if err := rows.Scan(&email, &expiresAt); err != nil {
+			return models.User{}, err
+		}
+		if !isExpired(expiresAt, now) {
+			user.PendingEmail = email
+		}
+	}
+	return user, rows.Err()
+}
+
+// UpdateMe handles PATCH /api/auth/me
+//
+// Body: {"name"?: "...", "email"?: "..."}.  A name change applies at once.
+// An email change sends a confirmation link to the new address and is
+// reported back as pending_email until confirmed.  Every field is checked
+// before anything is written, and both changes are written together, so a
+// request that fails changes nothing.
+func (s *Server) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	var req models.UpdateProfileRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	var name, email string
+	if req.Name != nil {
+		name = truncate(strings.TrimSpace(*req.Name), 255)
+		if name == "" {
+			respondError(w, http.StatusBadRequest, "name cannot be empty")
+			return
+		}
+	}
+	if req.Email != nil {
+		email = strings.TrimSpace(strings.ToLower(*req.Email))
+		if addr, err := netmail.ParseAddress(email); err != nil || addr.Address != email {
+			respondError(w, http.StatusBadRequest, "invalid email address")
+			return
+		}
+		if status, err := s.checkNewEmail(r.Context(), userID, email); err != nil {
+			respondError(w, status, err.Error())
+			return
+		}
+	}
+
+	var token, hash string
+	if email != "" {
+		var err error
+		if token, hash, err = auth.NewOpaqueToken(); err != nil {
+			respondError(w, http.StatusInternalServerError, "could not generate token")
+			return
+		}
+	}
+	now := time.Now().UTC()
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+	if name != "" {
+		if _, err := tx.ExecContext(r.Context(),
+			`UPDATE users SET name = ?, updated_at = ? WHERE id = ? AND name != ?`,
+			name, now, userID, name,
+		); err != nil {
+			respondError(w, http.StatusInternalServerError, "could not update profile")
+			return
+		}
+	}
+	if email != "" {
+		if err := recordEmailChange(r.Context(), tx, userID, email, hash, now); err != nil {
+			respondError(w, http.StatusInternalServerError, "could not update profile")
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not update profile")
+		return
+	}
+
+	if email != "" {
+		if err := s.sendEmailChange(r.Context(), userID, email, token); err != nil {
+			respondError(w, http.StatusInternalServerError, "could not send confirmation email")
+			return
+		}
+	}
+
+	user, err := s.loadProfile(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	respond(w, http.StatusOK, user)
+}
+
+// checkNewEmail reports whether userID may switch to email.  On failure it
+// returns the HTTP status to answer with and an error whose text is safe to
+// show the user.
+func (s *Server) checkNewEmail(ctx context.Context, userID, email string) (int, error) {
+	var current string
+	if err := s.DB.QueryRowContext(ctx,
+		`SELECT email FROM users WHERE id = ?`, userID,
+	).Scan(&current); err != nil {
+		return http.StatusInternalServerError, errors.New("database error")
+	}
+	if email == current {
+		return http.StatusBadRequest, errors.New("that is already your email address")
+	}
+	var taken int
+	if err := s.DB.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM users WHERE email = ?`, email,
+	).Scan(&taken); err != nil {
+		return http.StatusInternalServerError, errors.New("database error")
+	}
+	if taken > 0 {
+		return http.StatusConflict, errors.New("email already registered")
+	}
+	return 0, nil
+}
+
+// recordEmailChange stores a pending change to email, replacing any earlier
+// one: only the latest request counts.
+func recordEmailChange(ctx context.Context, tx *sql.Tx, userID, email, tokenHash string, now time.Time) error {
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE email_changes SET used_at = ? WHERE user_id = ? AND used_at IS NULL`, now, userID,
+	); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO email_changes (id, user_id, new_email, token_hash, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		uuid.NewString(), userID, email, tokenHash, now, now.Add(emailChangeTTL))
+	return err
+}
+
+// sendEmailChange mails the confirmation link for a change to email.
+func (s *Server) sendEmailChange(ctx context.Context, userID, email, token string) error {
+	if s.Mailer == nil {
+		return errors.New("no mailer configured")
+	}
+	var current, name string
+	if err := s.DB.QueryRowContext(ctx,
+		`SELECT email, name FROM users WHERE id = ?`, userID,
+	).Scan(&current, &name); err != nil {
+		return err
+	}
+	return s.Mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Confirm your new Skillzone email address",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Open this link within %s to use this address for your Skillzone account:\n\n%s\n\n"+
+			"Until then you keep logging in with %s.  If you did not ask for this, ignore this email.\n",
+			name, emailChangeTTL, s.appLink("/confirm-email", token), current),
+	})
+}
+
+// ConfirmEmail handles POST /api/auth/email/confirm
+//
+// Body: {"token": "..."}.  Applies the pending email change the token was
+// issued for and returns the updated user.  Opening the link proves the new
+// address, so it is also marked verified.
+func (s *Server) ConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.ConfirmEmailRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.Token == "" {
+		respondError(w, http.StatusBadRequest, "invalid or expired confirmation token")
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var changeID, userID, email string
+	var expiresAt time.Time
+	var usedAt sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		`SELECT id, user_id, new_email, expires_at, used_at FROM email_changes WHERE token_hash = ?`,
+		auth.HashOpaqueToken(req.Token),
+	).Scan(&changeID, &userID, &email, &expiresAt, &usedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	now := time.Now().UTC()
+	if err != nil || usedAt.Valid || isExpired(expiresAt, now) {
+		respondError(w, http.StatusBadRequest, "invalid or expired confirmation token")
+		return
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE email_changes SET used_at = ? WHERE id = ? AND used_at IS NULL`, now, changeID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusBadRequest, "invalid or expired confirmation token")
+		return
+	}
+	result, err = tx.ExecContext(ctx,
+		`UPDATE users SET email = ?, email_verified_at = ?, updated_at = ? WHERE id = ? AND status = 'active'`,
+		email, now, now, userID)
+	if err != nil {
+		// Someone else registered the address after the change was requested.
+		if strings.Contains(err.Error(), "UNIQUE") {
+			respondError(w, http.StatusConflict, "email already registered")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "could not update email")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusBadRequest, "invalid or expired confirmation token")
+		return
+	}
+	if err := updateAffiliation(ctx, tx, userID); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	user, err := s.loadProfile(ctx, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	respond(w, http.StatusOK, user)
+}
+
+// ChangePassword handles POST /api/auth/me/password
+//
+// Body: {"current_password": "...", "new_password": "..."}.  Responds 204;
+// every session except the caller's, and every API key, is revoked.
+func (s *Server) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	sessionID := middleware.GetSessionID(r.Context())
+
+	var req models.ChangePasswordRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if len(req.NewPassword) < minPasswordLength {
+		respondError(w, http.StatusBadRequest, "password must be at least 8 characters")
+		return
+	}
+
+	var currentHash string
+	if err := s.DB.QueryRowContext(r.Context(),
+		`SELECT password_hash FROM users WHERE id = ?`, userID,
+	).Scan(&currentHash); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	// 403 rather than 401: the caller IS authenticated, and a 401 would make
+	// the frontend try to refresh its token.
+	if err := auth.CheckPassword(currentHash, req.CurrentPassword); err != nil {
+		respondError(w, http.StatusForbidden, "current password is incorrect")
+		return
+	}
+
+	hash, err := s.Passwords.Hash(req.NewPassword)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to hash password")
+		return
+	}
+
+	now := time.Now().UTC()
+	tx, err := s.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+	for _, stmt := range []struct {
+		query string
+		args  []any
+	}{
+		{`UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?`, []any{string(hash), now, userID}},
+		{`UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND id != ? AND revoked_at IS NULL`, []any{now, userID, sessionID}},
+		// API keys too: whoever knew the old password may have made one.
+		{`UPDATE api_keys SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`, []any{now, userID}},
+		// An outstanding reset link would undo the change.
+		{`UPDATE password_resets SET used_at = ? WHERE user_id = ? AND used_at IS NULL`, []any{now, userID}},
+	} {
+		if _, err := tx.ExecContext(r.Context(), stmt.query, stmt.args...); err != nil {
+			respondError(w, http.StatusInternalServerError, "could not update password")
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	s.authCache.forgetUser(userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
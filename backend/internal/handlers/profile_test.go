@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+)
+
+func TestMe_ReturnsProfile(t *testing.T) {
+	srv := newTestServer(t)
+	login := registerUser(t, srv, "me@example.com", models.RoleStudent)
+
+	req := ctxWithSession(t, httptest.NewRequest(http.MethodGet, "/api/auth/me", nil), login.Token)
+	rec := httptest.NewRecorder()
+	srv.Me(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var u models.User
+	json.NewDecoder(rec.Body).Decode(&u)
+	if u.ID != login.User.ID || u.Email != "me@example.com" || u.Status != models.UserActive {
+		t.Errorf("unexpected profile: %+v", u)
+	}
+}
+
+func patchMe(t *testing.T, srv *Server, token string, body models.UpdateProfileRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	req := ctxWithSession(t, httptest.NewRequest(http.MethodPatch, "/api/auth/me", jsonBody(t, body)), token)
+	rec := httptest.NewRecorder()
+	srv.UpdateMe(rec, req)
+	return rec
+}
+
+func TestUpdateMe_Name(t *testing.T) {
+	srv := newTestServer(t)
+	login := registerUser(t, srv, "rename@example.com", models.RoleCompany)
+	time.Sleep(10 * time.Millisecond)
+
+	name := "  New Name  "
+	rec := patchMe(t, srv, login.Token, models.UpdateProfileRequest{Name: &name})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var u models.User
+	json.NewDecoder(rec.Body).Decode(&u)
+	if u.Name != "New Name" {
+		t.Errorf("name: got %q", u.Name)
+	}
+	if !u.UpdatedAt.After(login.User.UpdatedAt) {
+		t.Errorf("updated_at not advanced: %v -> %v", login.User.UpdatedAt, u.UpdatedAt)
+	}
+
+	empty := " "
+	if rec := patchMe(t, srv, login.Token, models.UpdateProfileRequest{Name: &empty}); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty name: expected 400, got %d", rec.Code)
+	}
+
+	// A bad email fails the whole request: the name is not changed either.
+	other, bad := "Other Name", "not an email"
+	if rec := patchMe(t, srv, login.Token, models.UpdateProfileRequest{Name: &other, Email: &bad}); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad email with a name: expected 400, got %d", rec.Code)
+	}
+	if got := dbString(t, srv, `SELECT name FROM users WHERE id = ?`, login.User.ID); got != "New Name" {
+		t.Errorf("name changed by a failed request: %q", got)
+	}
+}
+
+var confirmLinkRE = regexp.MustCompile(`/confirm-email\?token=(\S+)`)
+
+func TestUpdateMe_EmailNeedsConfirmation(t *testing.T) {
+	srv := newTestServer(t)
+	box := &outbox{}
+	login := registerUser(t, srv, "old@example.com", models.RoleStudent)
+	registerUser(t, srv, "taken@example.com", models.RoleStudent)
+	srv.Mailer = box
+
+	for _, tc := range []struct {
+		email string
+		want  int
+	}{
+		{"not an email", http.StatusBadRequest},
+		{"old@example.com", http.StatusBadRequest},
+		{"taken@example.com", http.StatusConflict},
+	} {
+		email := tc.email
+		if rec := patchMe(t, srv, login.Token, models.UpdateProfileRequest{Email: &email}); rec.Code != tc.want {
+			t.Errorf("%q: expected %d, got %d", tc.email, tc.want, rec.Code)
+		}
+	}
+
+	email := "New@Example.com"
+	rec := patchMe(t, srv, login.Token, models.UpdateProfileRequest{Email: &email})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var u models.User
+	json.NewDecoder(rec.Body).Decode(&u)
+	if u.Email != "old@example.com" || u.PendingEmail != "new@example.com" {
+		t.Errorf("before confirmation: email=%q pending=%q", u.Email, u.PendingEmail)
+	}
+	if len(box.sent) != 1 || box.sent[0].To != "new@example.com" {
+		t.Fatalf("expected confirmation mail to the new address, got %+v", box.sent)
+	}
+	m := confirmLinkRE.FindStringSubmatch(box.sent[0].Body)
+	if m == nil {
+		t.Fatalf("no confirmation link in:\n%s", box.sent[0].Body)
+	}
+
+	confirm := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/email/confirm",
+			jsonBody(t, models.ConfirmEmailRequest{Token: token}))
+		rec := httptest.NewRecorder()
+		srv.ConfirmEmail(rec, req)
+		return rec
+	}
+	rec = confirm(m[1])
+	if rec.Code != http.StatusOK {
+		t.Fatalf("confirm: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var confirmed models.User
+	json.NewDecoder(rec.Body).Decode(&confirmed)
+	if confirmed.Email != "new@example.com" || confirmed.PendingEmail != "" {
+		t.Errorf("after confirmation: email=%q pending=%q", confirmed.Email, confirmed.PendingEmail)
+	}
+	if code := loginStatus(t, srv, "new@example.com", "password123"); code != http.StatusOK {
+		t.Errorf("login with new email: expected 200, got %d", code)
+	}
+	if rec := confirm(m[1]); rec.Code != http.StatusBadRequest {
+		t.Errorf("reused token: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	srv := newTestServer(t)
+	first := registerUser(t, srv, "changer@example.com", models.RoleStudent)
+	// Log the same account in on a second device.
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login",
+		jsonBody(t, models.LoginRequest{Email: "changer@example.com", Password: "password123"}))
+	rec := httptest.NewRecorder()
+	srv.Login(rec, req)
+	var other models.LoginResponse
+	json.NewDecoder(rec.Body).Decode(&other)
+
+	change := func(current, next string) int {
+		req := ctxWithSession(t, httptest.NewRequest(http.MethodPost, "/api/auth/me/password",
+			jsonBody(t, models.ChangePasswordRequest{CurrentPassword: current, NewPassword: next})), first.Token)
+		rec := httptest.NewRecorder()
+		srv.ChangePassword(rec, req)
+		return rec.Code
+	}
+
+	if code := change("wrong-password", "new-password-1"); code != http.StatusForbidden {
+		t.Errorf("wrong current password: expected 403, got %d", code)
+	}
+	if code := change("password123", "short"); code != http.StatusBadRequest {
+		t.Errorf("short password: expected 400, got %d", code)
+	}
+	if code := change("password123", "new-password-1"); code != http.StatusNoContent {
+		t.Fatalf("change: expected 204, got %d", code)
+	}
+
+	if code := loginStatus(t, srv, "changer@example.com", "new-password-1"); code != http.StatusOK {
+		t.Errorf("login with new password: expected 200, got %d", code)
+	}
+	mine, _ := auth.ParseToken(first.Token, testKeys)
+	theirs, _ := auth.ParseToken(other.Token, testKeys)
+	if state, _ := srv.SessionState(context.Background(), mine.SessionID); !state.Active {
+		t.Error("the session that changed the password was revoked")
+	}
+	if state, _ := srv.SessionState(context.Background(), theirs.SessionID); state.Active {
+		t.Error("other session survived the password change")
+	}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — moving events along on time
+// ────────────────────────────────────────────────────────────────────
+// Hosts often forget PATCH /api/events/{id}/status, leaving an event
+// upcoming after it started or active for days after it ended.
+// StatusScheduler runs inside the server process and, every Interval,
+// moves each event that does not have manual_status set:
+//
+//	upcoming          → active     once start_time has passed
+//	upcoming / active → completed  once end_time + CompleteGrace has passed
+//
+// Cancelled, completed and deleted events are left alone.  Each UPDATE is
+// conditional on the status the sweep read (… AND status = ?), so it never
+// overwrites a change a host made in between.
+//
+// With Waitlist set, each sweep also lets go of seats held by waitlist
+// offers that have expired (Server.SettleExpiredOffers), so the next in
+// line moves up even if nobody touches the event.
+//
+// The clock is a field, so tests move time by swapping Now instead of
+// sleeping, and Run returns when its context is cancelled, so main stops it
+// together with the HTTP server, before the database closes.
+
+const (
+	defaultStatusInterval = time.Minute
+	defaultCompleteGrace  = time.Hour
+)
+
+// StatusScheduler moves events to active and completed on time.
+type StatusScheduler struct {
+	DB *sql.DB
+	// Interval is the time between sweeps. Zero uses the default of one
+	// minute; negative turns the scheduler off.
+	Interval time.Duration
+	// CompleteGrace is how long after end_time an event is completed. Zero
+	// uses the default of one hour.
+	CompleteGrace time.Duration
+	// Now is the clock. Nil uses time.Now.
+	Now func() time.Time
+	// Waitlist, when set, settles expired waitlist offers on every sweep.
+	Waitlist *Server
+}
+
+// StatusChange is one move a sweep made.
+type StatusChange struct {
+	EventID string
+	From    models.EventStatus
+	To      models.EventStatus
+}
+
+// Run sweeps at once and then every Interval until ctx is cancelled.
+func (sc *StatusScheduler) Run(ctx context.Context) {
+	interval := sc.Interval
+	if interval < 0 {
+		return
+	}
+	if interval == 0 {
+		interval = defaultStatusInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		changes, err := sc.Sweep(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("event status sweep", "err", err)
+		}
+		for _, c := range changes {
+			slog.Info("event status changed", "event_id", c.EventID, "from", c.From, "to", c.To)
+		}
+		if sc.Waitlist != nil {
+			if _, err := sc.Waitlist.SettleExpiredOffers(ctx, sc.now()); err != nil && ctx.Err() == nil {
+				slog.Error("waitlist offer sweep", "err", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (sc *StatusScheduler) now() time.Time {
+	if sc.Now != nil {
+		return sc.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// Sweep makes every move that is due now, in one transaction.
+func (sc *StatusScheduler) Sweep(ctx context.Context) ([]StatusChange, error) {
+	at := sc.now()
+	grace := sc.CompleteGrace
+	if grace <= 0 {
+		grace = defaultCompleteGrace
+	}
+
+	tx, err := sc.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var due []StatusChange
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, status, start_time, end_time FROM events
+		 WHERE status IN ('upcoming','active') AND manual_status = 0 AND deleted_at IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var c StatusChange
+		var start, end time.Time
+		if err := rows.Scan(&c.EventID, &c.From, &start, &end); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		switch {
+		case !at.Before(end.Add(grace)):
+			c.To = models.EventStatusCompleted
+		case c.From == models.EventStatusUpcoming && !at.Before(start):
+			c.To = models.EventStatusActive
+		default:
+			continue
+		}
+		due = append(due, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var changes []StatusChange
+	for _, c := range due {
+		result, err := tx.ExecContext(ctx,
+			`UPDATE events SET status = ?, updated_at = ? WHERE id = ? AND status = ? AND manual_status = 0`,
+			c.To, at, c.EventID, c.From)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := result.RowsAffected(); n == 1 {
+			changes = append(changes, c)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
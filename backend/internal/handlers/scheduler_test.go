@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// seedTimedEvent inserts an event with the given status and times and
+// returns its ID.
+func seedTimedEvent(t *testing.T, srv *Server, hostID string, status models.EventStatus, start, end time.Time) string {
+	t.Helper()
+	id := uuid.NewString()
+	_, err := srv.DB.Exec(
+		`INSERT INTO events (id, host_id, title, start_time, end_time, status, check_in_code)
+		 VALUES (?, ?, 'Timed Event', ?, ?, ?, ?)`,
+		id, hostID, start.UTC(), end.UTC(), status, uuid.NewString(),
+	)
+	if err != nil {
+		t.Fatalf("seedTimedEvent: %v", err)
+	}
+	return id
+}
+
+func TestStatusScheduler_Sweep(t *testing.T) {
+	srv := newTestServer(t)
+	host := seedCompanyUser(t, srv)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := now
+	sc := &StatusScheduler{DB: srv.DB, CompleteGrace: time.Hour, Now: func() time.Time { return clock }}
+
+	later := seedTimedEvent(t, srv, host, models.EventStatusUpcoming, now.Add(time.Hour), now.Add(3*time.Hour))
+	started := seedTimedEvent(t, srv, host, models.EventStatusUpcoming, now.Add(-time.Minute), now.Add(2*time.Hour))
+	inGrace := seedTimedEvent(t, srv, host, models.EventStatusActive, now.Add(-3*time.Hour), now.Add(-30*time.Minute))
+	over := seedTimedEvent(t, srv, host, models.EventStatusActive, now.Add(-5*time.Hour), now.Add(-2*time.Hour))
+	missed := seedTimedEvent(t, srv, host, models.EventStatusUpcoming, now.Add(-5*time.Hour), now.Add(-2*time.Hour))
+	manual := seedTimedEvent(t, srv, host, models.EventStatusUpcoming, now.Add(-time.Minute), now.Add(2*time.Hour))
+	cancelled := seedTimedEvent(t, srv, host, models.EventStatusCancelled, now.Add(-5*time.Hour), now.Add(-2*time.Hour))
+	deleted := seedTimedEvent(t, srv, host, models.EventStatusUpcoming, now.Add(-time.Minute), now.Add(2*time.Hour))
+	srv.DB.Exec(`UPDATE events SET manual_status = 1 WHERE id = ?`, manual)
+	srv.DB.Exec(`UPDATE events SET deleted_at = ? WHERE id = ?`, now, deleted)
+
+	status := func(id string) models.EventStatus {
+		return models.EventStatus(dbString(t, srv, `SELECT status FROM events WHERE id = ?`, id))
+	}
+	sweep := func() int {
+		t.Helper()
+		changes, err := sc.Sweep(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(changes)
+	}
+
+	if n := sweep(); n != 3 {
+		t.Errorf("first sweep: expected 3 changes, got %d", n)
+	}
+	for id, want := range map[string]models.EventStatus{
+		later:     models.EventStatusUpcoming,
+		started:   models.EventStatusActive,
+		inGrace:   models.EventStatusActive,
+		over:      models.EventStatusCompleted,
+		missed:    models.EventStatusCompleted,
+		manual:    models.EventStatusUpcoming,
+		cancelled: models.EventStatusCancelled,
+		deleted:   models.EventStatusUpcoming,
+	} {
+		if got := status(id); got != want {
+			t.Errorf("event %s: expected %s, got %s", id, want, got)
+		}
+	}
+	if n := sweep(); n != 0 {
+		t.Errorf("second sweep at the same time: expected no changes, got %d", n)
+	}
+
+	// Half an hour on, the grace period of inGrace is over.
+	clock = now.Add(30 * time.Minute)
+	sweep()
+	if got := status(inGrace); got != models.EventStatusCompleted {
+		t.Errorf("after the grace period: expected completed, got %s", got)
+	}
+}
+
+func TestStatusScheduler_RunStopsWithContext(t *testing.T) {
+	srv := newTestServer(t)
+	now := time.Now().UTC()
+	eventID := seedTimedEvent(t, srv, seedCompanyUser(t, srv), models.EventStatusUpcoming, now.Add(-time.Minute), now.Add(time.Hour))
+	sc := &StatusScheduler{DB: srv.DB, Interval: 10 * time.Millisecond, Now: func() time.Time { return now }}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sc.Run(ctx)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for dbString(t, srv, `SELECT status FROM events WHERE id = ?`, eventID) != string(models.EventStatusActive) {
+		if time.Now().After(deadline) {
+			t.Fatal("the scheduler never activated the event")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after its context was cancelled")
+	}
+}
@@ -1,7 +1,7 @@
 // filepath: /home/ksilas/Documents/Projects/skillzone/backend/internal/handlers/seed.go
 package handlers
 
-// SeedDemo handles POST /api/admin/seed
+// SeedDemo handles POST /api/admin/seed  (admin only)
 //
 // Idempotent demo seeder. Calls INSERT OR IGNORE so re-seeding a live server
 // is harmless. All UUIDs are pre-determined constants.
@@ -24,10 +24,11 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/Elizabethomito/skillzone/backend/internal/auth"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/Elizabethomito/skillzone/backend/internal/db"
 )
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -147,22 +148,20 @@ const (
 	SeedMedWorkCheckInCode    = "DEMO-CHECKIN-CODE-MED-WORKSHOP-2026"
 )
 
-// SeedDemo handles POST /api/admin/seed
+// SeedDemo handles POST /api/admin/seed  (admin only)
 func (s *Server) SeedDemo(w http.ResponseWriter, r *http.Request) {
-	hash, err := bcrypt.GenerateFromPassword([]byte("demo1234"), bcrypt.DefaultCost)
+	pw, err := s.Passwords.Hash("demo1234")
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "bcrypt: "+err.Error())
+		respondError(w, http.StatusInternalServerError, "hash password: "+err.Error())
 		return
 	}
-	pw := string(hash)
 
 	// Filler accounts use a random-looking password — they are never logged in.
-	fillerHash, err := bcrypt.GenerateFromPassword([]byte("filler-no-login"), bcrypt.DefaultCost)
+	filler, err := s.Passwords.Hash("filler-no-login")
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "bcrypt filler: "+err.Error())
+		respondError(w, http.StatusInternalServerError, "hash filler password: "+err.Error())
 		return
 	}
-	filler := string(fillerHash)
 
 	now := time.Now().UTC()
 
@@ -196,9 +195,9 @@ func (s *Server) SeedDemo(w http.ResponseWriter, r *http.Request) {
 		{SeedNiaID, "nia@student.test", "Nia Kamau", "student", filler},
 	} {
 		s.DB.ExecContext(r.Context(),
-			`INSERT OR IGNORE INTO users (id, email, password_hash, name, role, created_at, updated_at)
- VALUES (?, ?, ?, ?, ?, ?, ?)`,
-			u.id, u.email, u.pw, u.name, u.role, now, now,
+			`INSERT OR IGNORE INTO users (id, email, password_hash, name, role, created_at, updated_at, email_verified_at)
+ VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			u.id, u.email, u.pw, u.name, u.role, now, now, now,
 		)
 	}
 
@@ -412,6 +411,11 @@ func (s *Server) SeedDemo(w http.ResponseWriter, r *http.Request) {
 			e.capacity, e.slotsRemaining, now, now,
 		)
 	}
+	// Each company hosts its events through an organisation of its own.
+	if err := db.AssignEventOrganisations(r.Context(), s.DB); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not seed organisations")
+		return
+	}
 
 	// ── 4. Event–skill links ──────────────────────────────────────────────────
 	for _, link := range [][2]string{
@@ -463,13 +467,13 @@ func (s *Server) SeedDemo(w http.ResponseWriter, r *http.Request) {
 				seedID(e.studentID+"-reg", i), e.eventID, e.studentID, e.when.Add(-24*time.Hour),
 			)
 			ciToken, _ := auth.GenerateCheckInTokenWithExpiry(
-				e.eventID, e.ciCode, s.Secret, e.when, e.when.Add(auth.CheckInTokenDuration),
+				e.eventID, e.ciCode, s.Keys.CheckInSigner(), e.when, e.when.Add(auth.CheckInTokenDuration),
 			)
 			s.DB.ExecContext(r.Context(),
 				`INSERT OR IGNORE INTO attendances (id, event_id, student_id, payload, status, created_at, updated_at)
  VALUES (?, ?, ?, ?, 'verified', ?, ?)`,
 				seedID(e.studentID+"-att", i), e.eventID, e.studentID,
-				`{"token":"`+ciToken+`"}`, e.when, e.when,
+				`{"token":"`+ciToken+`","timestamp":`+strconv.FormatInt(e.when.Unix(), 10)+`}`, e.when, e.when,
 			)
 			if e.skillID != "" {
 				s.DB.ExecContext(r.Context(),
@@ -583,6 +587,22 @@ func (s *Server) SeedDemo(w http.ResponseWriter, r *http.Request) {
 		{SeedNiaID, SeedEventMedWorkID, SeedSkillHealthcareAIID, SeedMedWorkCheckInCode, now.Add(-55 * time.Minute)},
 	})
 
+	// ── Internship seats already taken ────────────────────────────────────────
+	// Each internship opens one seat short so the last one is contested in
+	// the demo; these registrations hold the missing seats, which keeps
+	// slots_remaining consistent with them (see check.go).
+	for _, held := range []struct{ regID, eventID, studentID string }{
+		{"seed-chidi-reg-internship", SeedEventInternshipID, SeedChidiID},
+		{"seed-zara-reg-agriintern", SeedEventAgriInternID, SeedZaraID},
+		{"seed-muna-reg-medintern", SeedEventMedInternID, SeedMunaID},
+	} {
+		s.DB.ExecContext(r.Context(),
+			`INSERT OR IGNORE INTO registrations (id, event_id, student_id, registered_at, status)
+ VALUES (?, ?, ?, ?, 'confirmed')`,
+			held.regID, held.eventID, held.studentID, now.Add(-48*time.Hour),
+		)
+	}
+
 	// ── 6. Response ───────────────────────────────────────────────────────────
 	respond(w, http.StatusOK, map[string]any{
 		"seeded": true,
@@ -43,6 +43,16 @@ func dbInt(t *testing.T, srv *Server, query string, args ...any) int {
 	return n
 }
 
+// dbString is a helper to read a single string from the database.
+func dbString(t *testing.T, srv *Server, query string, args ...any) string {
+	t.Helper()
+	var v string
+	if err := srv.DB.QueryRow(query, args...).Scan(&v); err != nil {
+		t.Fatalf("dbString(%q): %v", query, err)
+	}
+	return v
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // Seed response shape
 // ─────────────────────────────────────────────────────────────────────────────
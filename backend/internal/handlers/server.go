@@ -8,7 +8,7 @@
 // split by domain (auth, events, skills, sync) purely for readability.
 //
 // The central type is Server. It holds the two things every handler
-// needs: a database connection and the JWT secret. Putting shared
+// needs: a database connection and the signing keyring. Putting shared
 // dependencies on a struct (instead of global variables) makes the code
 // easier to test — each test creates its own Server with its own
 // in-memory database and no test pollutes another.
@@ -17,7 +17,14 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"net"
 	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/mail"
+	"github.com/Elizabethomito/skillzone/backend/internal/oidc"
 )
 
 // respond writes v as JSON with the given HTTP status code.
@@ -45,6 +52,33 @@ func decode(r *http.Request, v any) error {
 	return json.NewDecoder(r.Body).Decode(v)
 }
 
+// clientIP returns the remote address of the request without the port.
+// We deliberately ignore X-Forwarded-For: the server is meant to be reached
+// directly on the venue LAN, and trusting the header would let any client
+// pick its own IP.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// truncate shortens s to at most n bytes so client-controlled strings
+// (user agents, names) can't bloat the database.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// isExpired reports whether something valid until expiresAt has run out by
+// now.  Expiry is always checked in Go, never in SQL (see db.Open).
+func isExpired(expiresAt, now time.Time) bool {
+	return !now.Before(expiresAt)
+}
+
 // Server holds shared dependencies for all handlers.
 // Using a struct instead of package-level globals means tests can spin
 // up many independent Server instances without state leaking between them.
@@ -52,6 +86,44 @@ type Server struct {
 	// DB is the SQLite connection pool. database/sql is safe for
 	// concurrent use — the pool manages multiple connections internally.
 	DB *sql.DB
-	// Secret is the HMAC key used to sign and verify JWTs.
-	Secret string
+	// Keys signs and verifies session JWTs and QR check-in tokens. The
+	// public halves of the check-in keys are published at
+	// GET /api/.well-known/jwks.json (see keys.go).
+	Keys *auth.Keyring
+	// CheckIn holds the clock-skew and sync-deadline tolerances for
+	// offline check-ins. The zero value uses the defaults.
+	CheckIn CheckInPolicy
+	// LoginThrottle holds the failed-login thresholds and lockout lengths (see
+	// throttle.go). The zero value uses the defaults.
+	LoginThrottle LoginThrottlePolicy
+	// AuthCacheTTL is how long SessionState answers are reused (see
+	// authcache.go). Zero uses the default; negative disables caching.
+	AuthCacheTTL time.Duration
+	// Mailer delivers password reset, confirmation and login emails.
+	Mailer mail.Mailer
+	// AppURL is the frontend's base URL, used to build links in emails.
+	AppURL string
+	// PasswordResetTTL is how long a reset link works. Zero uses the
+	// default of one hour.
+	PasswordResetTTL time.Duration
+	// MagicLinkTTL is how long an emailed login link works (see
+	// magiclink.go). Zero uses the default of 15 minutes.
+	MagicLinkTTL time.Duration
+	// WaitlistOfferTTL is how long a student who moves up from the
+	// waitlist has to accept the seat (see waitlist.go). Zero confirms
+	// them at once.
+	WaitlistOfferTTL time.Duration
+	// OIDC holds the single sign-on providers by name (see oidc.go).
+	OIDC map[string]*oidc.Provider
+	// EmailVerification decides what accounts with an unverified email may
+	// do (see verification.go). The zero value enforces verification.
+	EmailVerification EmailVerificationPolicy
+	// Passwords is the algorithm and cost new password hashes are made
+	// with; older hashes are upgraded at login. The zero value is argon2id
+	// at the auth package defaults.
+	Passwords auth.PasswordHasher
+
+	authCache     authCache
+	dummyHashOnce sync.Once
+	dummyHash     string
 }
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — access tokens vs refresh tokens
+// ────────────────────────────────────────────────────────────────────
+// A JWT cannot be "un-issued": once signed it is valid until exp.  So we
+// keep the JWT (the access token) short-lived — auth.AccessTokenDuration —
+// and pair it with a long-lived opaque refresh token stored (hashed) in the
+// sessions table.
+//
+//   - Every access token carries the session ID (sid claim).  The
+//     Authenticate middleware asks SessionState on each request, so revoking
+//     the session row locks the device out immediately.
+//   - Refresh tokens ROTATE: each call to /api/auth/refresh replaces the
+//     stored hash, so the old refresh token stops working.  If an old token
+//     is ever presented again it means two parties hold copies (e.g. it was
+//     stolen), and we revoke the whole session.
+//
+// The refresh token is "<session id>.<random secret>" so the server can find
+// the row without scanning every hash.
+
+// issueSession creates a sessions row for the user and returns a fresh
+// access + refresh token pair. It is called by every handler that logs a
+// user in (Register, Login).
+func (s *Server) issueSession(r *http.Request, user models.User) (models.LoginResponse, error) {
+	secret, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return models.LoginResponse{}, err
+	}
+
+	sessionID := uuid.NewString()
+	now := time.Now().UTC()
+	_, err = s.DB.ExecContext(r.Context(),
+		`INSERT INTO sessions (id, user_id, token_hash, user_agent, ip, created_at, last_used_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		sessionID, user.ID, hash, truncate(r.UserAgent(), 255), clientIP(r),
+		now, now, now.Add(auth.RefreshTokenDuration),
+	)
+	if err != nil {
+		return models.LoginResponse{}, err
+	}
+
+	token, err := auth.GenerateToken(user.ID, string(user.Role), sessionID, s.Keys)
+	if err != nil {
+		return models.LoginResponse{}, err
+	}
+
+	return models.LoginResponse{
+		Token:        token,
+		RefreshToken: sessionID + "." + secret,
+		ExpiresIn:    int(auth.AccessTokenDuration.Seconds()),
+		User:         user,
+	}, nil
+}
+
+// Refresh handles POST /api/auth/refresh
+//
+// Exchanges a refresh token for a new access token AND a new refresh token.
+// The presented refresh token is invalidated by the swap.
+func (s *Server) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	sessionID, secret, ok := strings.Cut(req.RefreshToken, ".")
+	if !ok || sessionID == "" || secret == "" {
+		respondError(w, http.StatusUnauthorized, "invalid refresh token")
+		return
+	}
+
+	var storedHash string
+	var expiresAt time.Time
+	var revokedAt sql.NullTime
+	var user models.User
+	err := s.DB.QueryRowContext(r.Context(),
+		`SELECT s.token_hash, s.expires_at, s.revoked_at,
+		        u.id, u.email, u.name, u.role, u.status, u.created_at, u.updated_at
+		 FROM sessions s
+		 JOIN users u ON u.id = s.user_id
+		 WHERE s.id = ?`, sessionID,
+	).Scan(&storedHash, &expiresAt, &revokedAt,
+		&user.ID, &user.Email, &user.Name, &user.Role, &user.Status, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusUnauthorized, "invalid refresh token")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if revokedAt.Valid || isExpired(expiresAt, time.Now()) || user.Status == models.UserDeleted {
+		respondError(w, http.StatusUnauthorized, "session has ended")
+		return
+	}
+	if user.Status == models.UserSuspended {
+		respondError(w, http.StatusForbidden, "account suspended")
+		return
+	}
+
+	now := time.Now().UTC()
+	presentedHash := auth.HashOpaqueToken(secret)
+	if subtle.ConstantTimeCompare([]byte(presentedHash), []byte(storedHash)) != 1 {
+		// An already-rotated token was replayed — assume it was stolen and
+		// end the session for everyone holding a copy.
+		s.DB.ExecContext(r.Context(), //nolint:errcheck
+			`UPDATE sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, now, sessionID)
+		s.authCache.forgetSession(sessionID)
+		respondError(w, http.StatusUnauthorized, "refresh token reuse detected; session revoked")
+		return
+	}
+
+	newSecret, newHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not generate token")
+		return
+	}
+
+	// Compare-and-swap on the old hash: if two refreshes race with the same
+	// token only one of them wins the rotation.
+	result, err := s.DB.ExecContext(r.Context(),
+		`UPDATE sessions SET token_hash = ?, last_used_at = ?, user_agent = ?, ip = ?
+		 WHERE id = ? AND token_hash = ? AND revoked_at IS NULL`,
+		newHash, now, truncate(r.UserAgent(), 255), clientIP(r), sessionID, storedHash,
+	)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusUnauthorized, "invalid refresh token")
+		return
+	}
+
+	token, err := auth.GenerateToken(user.ID, string(user.Role), sessionID, s.Keys)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not generate token")
+		return
+	}
+
+	respond(w, http.StatusOK, models.LoginResponse{
+		Token:        token,
+		RefreshToken: sessionID + "." + newSecret,
+		ExpiresIn:    int(auth.AccessTokenDuration.Seconds()),
+		User:         user,
+	})
+}
+
+// Logout handles POST /api/auth/logout
+// Revokes the session the request's access token belongs to.
+func (s *Server) Logout(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	sessionID := middleware.GetSessionID(r.Context())
+
+	_, err := s.DB.ExecContext(r.Context(),
+		`UPDATE sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`,
+		time.Now().UTC(), sessionID, userID,
+	)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not revoke session")
+		return
+	}
+	s.authCache.forgetSession(sessionID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll handles POST /api/auth/logout-all
+// "Log out everywhere" — revokes every session of the current user,
+// including the one making the request.
+func (s *Server) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	if err := s.revokeUserSessions(r.Context(), userID); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not revoke sessions")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeUserSessions ends every live session of the user.
+func (s *Server) revokeUserSessions(ctx context.Context, userID string) error {
+	_, err := s.DB.ExecContext(ctx,
+		`UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`,
+		time.Now().UTC(), userID,
+	)
+	s.authCache.forgetUser(userID)
+	return err
+}
+
+// ListSessions handles GET /api/auth/sessions
+// Returns the user's live sessions (one per logged-in device), most
+// recently used first, with the caller's own session flagged as current.
+func (s *Server) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	currentID := middleware.GetSessionID(r.Context())
+
+	rows, err := s.DB.QueryContext(r.Context(),
+		`SELECT id, user_agent, ip, created_at, last_used_at, expires_at
+		 FROM sessions
+		 WHERE user_id = ? AND revoked_at IS NULL
+		 ORDER BY last_used_at DESC`, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	sessions := []models.Session{}
+	for rows.Next() {
+		var sess models.Session
+		if err := rows.Scan(&sess.ID, &sess.UserAgent, &sess.IP,
+			&sess.CreatedAt, &sess.LastUsedAt, &sess.ExpiresAt); err != nil {
+			respondError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		if isExpired(sess.ExpiresAt, now) {
+			continue
+		}
+		sess.Current = sess.ID == currentID
+		sessions = append(sessions, sess)
+	}
+	if err := rows.Err(); err != nil {
+		respondError(w, http.StatusInternalServerError, "rows error")
+		return
+	}
+
+	respond(w, http.StatusOK, sessions)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+)
+
+// refresh calls the Refresh handler with the given refresh token.
+func refresh(t *testing.T, srv *Server, refreshToken string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/refresh",
+		jsonBody(t, models.RefreshRequest{RefreshToken: refreshToken}))
+	rec := httptest.NewRecorder()
+	srv.Refresh(rec, req)
+	return rec
+}
+
+// ctxWithSession simulates Authenticate for a real access token.
+func ctxWithSession(t *testing.T, r *http.Request, accessToken string) *http.Request {
+	t.Helper()
+	claims, err := auth.ParseToken(accessToken, testKeys)
+	if err != nil {
+		t.Fatalf("ctxWithSession: %v", err)
+	}
+	r = ctxWithUser(r, claims.UserID, claims.Role)
+	return r.WithContext(context.WithValue(r.Context(), middleware.ContextSessionID, claims.SessionID))
+}
+
+func TestRefresh_RotatesToken(t *testing.T) {
+	srv := newTestServer(t)
+	login := registerUser(t, srv, "rotate@example.com", models.RoleStudent)
+
+	rec := refresh(t, srv, login.RefreshToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp models.LoginResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Token == "" || resp.RefreshToken == "" || resp.RefreshToken == login.RefreshToken {
+		t.Fatalf("expected a new token pair, got %+v", resp)
+	}
+
+	// The new refresh token works…
+	if rec := refresh(t, srv, resp.RefreshToken); rec.Code != http.StatusOK {
+		t.Fatalf("second refresh: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestRefresh_ReuseRevokesSession(t *testing.T) {
+	srv := newTestServer(t)
+	login := registerUser(t, srv, "reuse@example.com", models.RoleStudent)
+
+	rec := refresh(t, srv, login.RefreshToken)
+	var rotated models.LoginResponse
+	json.NewDecoder(rec.Body).Decode(&rotated)
+
+	// Replaying the old token is treated as theft…
+	if rec := refresh(t, srv, login.RefreshToken); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("replay: expected 401, got %d", rec.Code)
+	}
+	// …which also kills the legitimately rotated token.
+	if rec := refresh(t, srv, rotated.RefreshToken); rec.Code != http.StatusUnauthorized {
+		t.Errorf("after reuse: expected 401, got %d", rec.Code)
+	}
+}
+
+func TestRefresh_Garbage(t *testing.T) {
+	srv := newTestServer(t)
+	for _, tok := range []string{"", "no-dot", "unknown-session.secret"} {
+		if rec := refresh(t, srv, tok); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%q: expected 401, got %d", tok, rec.Code)
+		}
+	}
+}
+
+func TestLogout_RevokesCurrentSession(t *testing.T) {
+	srv := newTestServer(t)
+	login := registerUser(t, srv, "logout@example.com", models.RoleStudent)
+	claims, _ := auth.ParseToken(login.Token, testKeys)
+
+	req := ctxWithSession(t, httptest.NewRequest(http.MethodPost, "/api/auth/logout", nil), login.Token)
+	rec := httptest.NewRecorder()
+	srv.Logout(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+
+	state, err := srv.SessionState(context.Background(), claims.SessionID)
+	if err != nil || state.Active {
+		t.Errorf("expected session to be revoked, active=%v err=%v", state.Active, err)
+	}
+	if rec := refresh(t, srv, login.RefreshToken); rec.Code != http.StatusUnauthorized {
+		t.Errorf("refresh after logout: expected 401, got %d", rec.Code)
+	}
+}
+
+func TestLogoutAll_RevokesEverySession(t *testing.T) {
+	srv := newTestServer(t)
+	first := registerUser(t, srv, "everywhere@example.com", models.RoleCompany)
+
+	// Log in on a second device.
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login",
+		jsonBody(t, models.LoginRequest{Email: "everywhere@example.com", Password: "password123"}))
+	rec := httptest.NewRecorder()
+	srv.Login(rec, req)
+	var second models.LoginResponse
+	json.NewDecoder(rec.Body).Decode(&second)
+
+	// Both devices show up in the session list.
+	req = ctxWithSession(t, httptest.NewRequest(http.MethodGet, "/api/auth/sessions", nil), second.Token)
+	rec = httptest.NewRecorder()
+	srv.ListSessions(rec, req)
+	var sessions []models.Session
+	json.NewDecoder(rec.Body).Decode(&sessions)
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(sessions))
+	}
+	current := 0
+	for _, sess := range sessions {
+		if sess.Current {
+			current++
+		}
+	}
+	if current != 1 {
+		t.Errorf("expected exactly one current session, got %d", current)
+	}
+
+	req = ctxWithSession(t, httptest.NewRequest(http.MethodPost, "/api/auth/logout-all", nil), second.Token)
+	rec = httptest.NewRecorder()
+	srv.LogoutAll(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+
+	for _, tok := range []string{first.Token, second.Token} {
+		claims, _ := auth.ParseToken(tok, testKeys)
+		if state, _ := srv.SessionState(context.Background(), claims.SessionID); state.Active {
+			t.Errorf("session %s still active after logout-all", claims.SessionID)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// getSetting reads one value from the settings table.  ok is false when the
+// key has never been set.
+func (s *Server) getSetting(ctx context.Context, key string) (value string, ok bool, err error) {
+	err = s.DB.QueryRowContext(ctx, `SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// putSetting creates or replaces one value in the settings table.
+func (s *Server) putSetting(ctx context.Context, key, value string) error {
+	_, err := s.DB.ExecContext(ctx,
+		`INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)
+		 ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		key, value, time.Now().UTC())
+	return err
+}
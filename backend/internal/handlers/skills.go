@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"database/sql"
 	"net/http"
 	"strings"
 	"time"
@@ -85,12 +86,13 @@ func (s *Server) ListSkills(w http.ResponseWriter, r *http.Request) {
 	respond(w, http.StatusOK, skills)
 }
 
-// SearchStudents handles GET /api/users/students?skill_id=<uuid>  (company only)
+// SearchStudents handles GET /api/users/students?skill_id=<uuid>&institution_id=<uuid>  (company only)
 //
 // Returns students who possess specific verified skill badges. Companies use
 // this as a "talent filter" — e.g. "show me all students with Python + Cloud badges".
 // Multiple skill_id query params are ANDed: the student must hold ALL listed skills.
-// Omitting skill_id returns all students on the platform.
+// Omitting skill_id returns all students on the platform.  institution_id
+// keeps only students affiliated with that institution (see institutions.go).
 func (s *Server) SearchStudents(w http.ResponseWriter, r *http.Request) {
 	skillIDs := r.URL.Query()["skill_id"]
 
@@ -99,38 +101,35 @@ func (s *Server) SearchStudents(w http.ResponseWriter, r *http.Request) {
 		Skills []models.UserSkill `json:"skills"`
 	}
 
-	var rows interface {
-		Next() bool
-		Scan(...interface{}) error
-		Close() error
-		Err() error
-	}
-	var err error
+	where := []string{"u.role = 'student'", "u.status = 'active'"}
+	var args []interface{}
 
-	if len(skillIDs) == 0 {
-		// Return all students.
-		rows, err = s.DB.QueryContext(r.Context(),
-			`SELECT id, email, name, role, created_at, updated_at
-			 FROM users WHERE role = 'student' ORDER BY name ASC`)
-	} else {
+	// Unverified students are left out unless the policy says otherwise
+	// (see verification.go).
+	if !s.EmailVerification.ListUnverifiedStudents {
+		where = append(where, "u.email_verified_at IS NOT NULL")
+	}
+	if institutionID := r.URL.Query().Get("institution_id"); institutionID != "" {
+		where = append(where, "u.institution_id = ?")
+		args = append(args, institutionID)
+	}
+	if len(skillIDs) > 0 {
 		// Only students who hold ALL requested skills.
 		placeholders := strings.Repeat("?,", len(skillIDs))
 		placeholders = placeholders[:len(placeholders)-1]
-		args := make([]interface{}, len(skillIDs)+1)
-		args[0] = len(skillIDs)
-		for i, id := range skillIDs {
-			args[i+1] = id
-		}
-		rows, err = s.DB.QueryContext(r.Context(),
-			`SELECT u.id, u.email, u.name, u.role, u.created_at, u.updated_at
-			 FROM users u
-			 WHERE u.role = 'student'
-			   AND (SELECT COUNT(DISTINCT skill_id) FROM user_skills
-			        WHERE user_id = u.id AND skill_id IN (`+placeholders+`)) = ?
-			 ORDER BY u.name ASC`,
-			append(skillIDsToInterfaces(skillIDs), args[0])...,
-		)
+		where = append(where, `(SELECT COUNT(DISTINCT skill_id) FROM user_skills
+		        WHERE user_id = u.id AND skill_id IN (`+placeholders+`)) = ?`)
+		args = append(append(args, skillIDsToInterfaces(skillIDs)...), len(skillIDs))
 	}
+
+	rows, err := s.DB.QueryContext(r.Context(),
+		`SELECT u.id, u.email, u.name, u.role, u.created_at, u.updated_at, i.id, i.name
+		 FROM users u
+		 LEFT JOIN institutions i ON i.id = u.institution_id
+		 WHERE `+strings.Join(where, " AND ")+`
+		 ORDER BY u.name ASC`,
+		args...,
+	)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "database error")
 		return
@@ -140,10 +139,15 @@ func (s *Server) SearchStudents(w http.ResponseWriter, r *http.Request) {
 	var students []StudentWithSkills
 	for rows.Next() {
 		var u models.User
-		if err := rows.Scan(&u.ID, &u.Email, &u.Name, &u.Role, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		var institutionID, institutionName sql.NullString
+		if err := rows.Scan(&u.ID, &u.Email, &u.Name, &u.Role, &u.CreatedAt, &u.UpdatedAt,
+			&institutionID, &institutionName); err != nil {
 			respondError(w, http.StatusInternalServerError, "scan error")
 			return
 		}
+		if institutionID.Valid {
+			u.Institution = &models.InstitutionRef{ID: institutionID.String, Name: institutionName.String}
+		}
 		// Fetch this student's skills.
 		skillRows, err := s.DB.QueryContext(r.Context(),
 			`SELECT us.id, us.user_id, us.skill_id, us.event_id, us.awarded_at,
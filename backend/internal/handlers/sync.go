@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"crypto/subtle"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -15,13 +17,12 @@ import (
 //
 //  1. HOST SIDE (online, at the event):
 //     Company calls GET /api/events/{id}/checkin-code to get the check_in_code.
-//     Their PWA builds a CheckInPayload JSON:
-//     { "event_id": "...", "host_sig": "<check_in_code>", "timestamp": <unix> }
-//     and displays it as a QR code on a screen.
+//     Their PWA displays { "token": "<signed JWT>" } as a QR code on a screen.
 //
 //  2. STUDENT SIDE (offline is fine):
-//     Student scans the QR with their PWA. The PWA stores the raw payload JSON
-//     in IndexedDB (via Dexie.js) as ATTENDANCE_PENDING.
+//     Student scans the QR with their PWA. The PWA stores the token plus the
+//     scan time — { "token": "...", "timestamp": <unix> } — in IndexedDB
+//     (via Dexie.js) as ATTENDANCE_PENDING.
 //
 //  3. SYNC (when student is back online):
 //     Student's PWA sends all PENDING records to this endpoint in one batch.
@@ -56,83 +57,200 @@ func (s *Server) SyncAttendance(w http.ResponseWriter, r *http.Request) {
 	respond(w, http.StatusOK, models.SyncAttendanceResponse{Results: results})
 }
 
+// CheckInPolicy holds the time tolerances applied to offline check-ins.
+// Zero values fall back to the defaults below, so tests and the zero Server
+// get sensible behaviour without configuring anything.
+type CheckInPolicy struct {
+	// ClockSkew widens the token's [iat, exp] window on both sides when
+	// checking the device-reported scan time, and is how far in the future
+	// a scan time may be. Phones at rural venues are often minutes off.
+	ClockSkew time.Duration
+	// SyncGrace is how long after an event's end_time offline check-ins
+	// are still accepted when the event has no explicit sync_deadline.
+	SyncGrace time.Duration
+	// StepTolerance is how many time steps a rotating code's step may differ
+	// from the step of the recorded scan time (see auth.CheckScanStep).
+	StepTolerance int64
+}
+
+const (
+	defaultClockSkew     = 5 * time.Minute
+	defaultSyncGrace     = 7 * 24 * time.Hour
+	defaultStepTolerance = 1
+)
+
+func (p CheckInPolicy) clockSkew() time.Duration {
+	if p.ClockSkew > 0 {
+		return p.ClockSkew
+	}
+	return defaultClockSkew
+}
+
+func (p CheckInPolicy) syncGrace() time.Duration {
+	if p.SyncGrace > 0 {
+		return p.SyncGrace
+	}
+	return defaultSyncGrace
+}
+
+func (p CheckInPolicy) stepTolerance() int64 {
+	if p.StepTolerance > 0 {
+		return p.StepTolerance
+	}
+	return defaultStepTolerance
+}
+
 // processAttendanceRecord validates and persists a single offline check-in.
 //
 // It is deliberately separated from SyncAttendance so it can be unit-tested
 // directly (see sync_test.go) and so the loop in SyncAttendance stays clean.
 //
 // ────────────────────────────────────────────────────────────────────
-// LEARNING NOTE — the new token-based verification model
+// LEARNING NOTE — the token-based verification model
 // ────────────────────────────────────────────────────────────────────
 // v1 (old): the QR code contained a raw check_in_code UUID + unix timestamp.
 //
 //	The server rejected syncs where time.Since(timestamp) > 24 h, which
 //	forced students with poor connectivity to sync quickly.
 //
-// v2 (new): the QR code contains a signed JWT (CheckInPayload.Token).
+// v2 (current): the QR code contains a signed JWT (CheckInPayload.Token)
+// and the device adds the time it scanned it (CheckInPayload.Timestamp).
 //
-//	The JWT has a 6-hour exp, enforcing the SCAN window — you must have
-//	scanned while the QR was live on screen.  But the JWT never expires
-//	for the SERVER: once a student holds a valid signed token we accept
-//	their sync at any point in the future.  There is no wall-clock check
-//	at sync time.
+//   - The JWT is signed with the server's Ed25519 check-in key — it cannot
+//     be forged, and the PWA can verify it offline with the public key.
+//   - The scan time must fall inside the JWT's [iat, exp] window (plus
+//     clock skew).  This is the SCAN window: you must have scanned while
+//     the QR was live on screen.
+//   - The sync must arrive before the event's sync deadline.  This is the
+//     SYNC window: generous (days) so poor connectivity is not punished,
+//     but finite so an old screenshot cannot be replayed forever.
+//   - The token's host_sig must equal the event's current check_in_code,
+//     so rotating the secret revokes every token issued before.
+//   - For events with rotating codes the scan window shrinks to the
+//     token's time step (± CheckInPolicy.StepTolerance steps), and static
+//     tokens are refused.
 //
-// Why is this safe?
-//   - The JWT is signed with the server secret — it cannot be forged.
-//   - The JWT's exp was set when the host generated the QR.  A student
-//     scanning after exp would get a JWT that fails ParseCheckInToken.
-//   - A student who scanned during the live window holds a token whose
-//     signature is permanently valid (we verify sig only, not exp at sync).
-//   - We achieve this by calling jwt.ParseWithClaims with a custom
-//     parser option (jwt.WithoutClaimsValidation) inside ParseCheckInToken
-//     — see auth/jwt.go.
+// Every rejection carries a models.SyncRejectReason so the PWA can react
+// differently to "never valid" and "try again later".
 func (s *Server) processAttendanceRecord(r *http.Request, studentID string, rec models.AttendanceSyncRecord) models.SyncResult {
 	// Helper to build a rejection result in one line.
-	fail := func(msg string) models.SyncResult {
-		return models.SyncResult{LocalID: rec.LocalID, Status: models.AttendanceRejected, Message: msg}
+	fail := func(reason models.SyncRejectReason, msg string) models.SyncResult {
+		return models.SyncResult{LocalID: rec.LocalID, Status: models.AttendanceRejected, Reason: reason, Message: msg}
 	}
 
 	// Step 1 — Parse the QR payload the student's PWA captured.
 	var payload models.CheckInPayload
 	if err := json.Unmarshal([]byte(rec.Payload), &payload); err != nil {
-		return fail("invalid payload JSON")
+		return fail(models.RejectInvalidPayload, "invalid payload JSON")
 	}
 
 	if payload.Token == "" {
-		return fail("payload missing token")
+		return fail(models.RejectMissingToken, "payload missing token")
 	}
 
 	// Step 2 — Verify the signed check-in token.
-	// ParseCheckInToken checks the JWT signature (was this signed by our server?)
-	// but NOT the expiry — the exp only controls the scan window, which already
-	// closed when the student's device stored the payload.
-	claims, err := auth.ParseCheckInToken(payload.Token, s.Secret)
+	// ParseCheckInToken checks the JWT signature (was this signed by our server?);
+	// the time checks follow in steps 5 and 6.
+	claims, err := auth.ParseCheckInToken(payload.Token, s.Keys)
 	if err != nil {
-		return fail("invalid check-in token: " + err.Error())
+		return fail(models.RejectInvalidToken, "invalid check-in token: "+err.Error())
 	}
 
 	// Step 3 — The token's event_id must match the outer record's event_id.
 	// This guards against a student copy-pasting the wrong QR payload.
 	if claims.EventID != rec.EventID {
-		return fail("token event_id does not match record event_id")
+		return fail(models.RejectEventMismatch, "token event_id does not match record event_id")
 	}
 
-	// Step 4 — Confirm the event still exists in the database.
-	// (The host_sig inside the token is already verified by the JWT signature,
-	// so we don't need a separate database lookup to confirm it.)
-	var exists bool
+	// Step 4 — Load the event; we need its current check-in secret, end
+	// time, sync deadline, whether it uses rotating codes and whether it
+	// was cancelled or deleted.  Deleted events are loaded too: a student
+	// who checked in before the delete still gets their attendance.
+	var checkInCode string
+	var endTime time.Time
+	var syncDeadline sql.NullTime
+	var rotation sql.NullInt64
+	var status models.EventStatus
+	var deletedAt sql.NullTime
 	err = s.DB.QueryRowContext(r.Context(),
-		`SELECT 1 FROM events WHERE id = ?`, rec.EventID,
-	).Scan(&exists)
-	if err != nil || !exists {
-		return fail("event not found")
+		`SELECT check_in_code, end_time, sync_deadline, checkin_rotation_seconds, status, deleted_at
+		 FROM events WHERE id = ?`, rec.EventID,
+	).Scan(&checkInCode, &endTime, &syncDeadline, &rotation, &status, &deletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fail(models.RejectEventNotFound, "event not found")
+		}
+		return fail(models.RejectInternalError, "database error loading event")
+	}
+
+	// A check-in we already verified is answered "verified" again even if it
+	// would now fail the host_sig or deadline checks below: the client may
+	// simply have lost our first response.
+	alreadyVerified := func() bool {
+		var ok bool
+		s.DB.QueryRowContext(r.Context(), //nolint:errcheck
+			`SELECT 1 FROM attendances WHERE event_id = ? AND student_id = ? AND status = 'verified'`,
+			rec.EventID, studentID,
+		).Scan(&ok)
+		return ok
+	}
+
+	// A deleted event that had finished keeps taking check-ins until its
+	// sync deadline; one that had not was cancelled by the delete.
+	if status == models.EventStatusCancelled && !alreadyVerified() {
+		if deletedAt.Valid {
+			return fail(models.RejectEventDeleted, "the event has been deleted")
+		}
+		return fail(models.RejectEventCancelled, "the event has been cancelled")
+	}
+
+	// The token's host_sig must still be the event's check-in secret.  The
+	// host rotates the secret (POST /checkin-code/rotate) to revoke every
+	// token issued before, e.g. after a QR photo leaked.
+	if subtle.ConstantTimeCompare([]byte(claims.HostSig), []byte(checkInCode)) != 1 && !alreadyVerified() {
+		return fail(models.RejectCodeRevoked, "check-in code has been rotated by the host")
+	}
+
+	// Step 5 — Scan window: the device-reported scan time must lie inside
+	// the token's validity window (or time step, for rotating codes), and
+	// cannot be in the future.
+	if payload.Timestamp == 0 {
+		return fail(models.RejectMissingScanTime, "payload missing scan timestamp")
+	}
+	now := time.Now().UTC()
+	skew := s.CheckIn.clockSkew()
+	scannedAt := time.Unix(payload.Timestamp, 0).UTC()
+	if scannedAt.After(now.Add(skew)) {
+		return fail(models.RejectScanInFuture, "scan timestamp is in the future")
+	}
+	switch {
+	case claims.Rotating():
+		if err := claims.CheckScanStep(scannedAt, s.CheckIn.stepTolerance()); err != nil {
+			return fail(models.RejectCodeRotated, err.Error())
+		}
+	case rotation.Valid:
+		// A static token for an event that now rotates was issued before
+		// rotation was switched on; it could have been forwarded anywhere.
+		return fail(models.RejectCodeRotated, "this event requires a rotating check-in code")
+	default:
+		if err := claims.CheckScanTime(scannedAt, skew); err != nil {
+			return fail(models.RejectScanOutsideWindow, err.Error())
+		}
+	}
+
+	// Step 6 — Sync deadline.
+	deadline := endTime.Add(s.CheckIn.syncGrace())
+	if syncDeadline.Valid {
+		deadline = syncDeadline.Time
+	}
+	if now.After(deadline) && !alreadyVerified() {
+		return fail(models.RejectSyncDeadlinePassed, "the sync deadline for this event has passed")
 	}
 
-	// Step 5 — Upsert the attendance record (idempotent).
+	// Step 7 — Upsert the attendance record (idempotent).
 	// ON CONFLICT ... DO UPDATE means a retry on bad network just refreshes
 	// the updated_at timestamp without creating a duplicate row.
 	attendanceID := uuid.NewString()
-	now := time.Now().UTC()
 	_, err = s.DB.ExecContext(r.Context(),
 		`INSERT INTO attendances (id, event_id, student_id, payload, status, created_at, updated_at)
 		 VALUES (?, ?, ?, ?, 'verified', ?, ?)
@@ -142,19 +260,19 @@ func (s *Server) processAttendanceRecord(r *http.Request, studentID string, rec
 		attendanceID, rec.EventID, studentID, rec.Payload, now, now,
 	)
 	if err != nil {
-		return fail("database error recording attendance")
+		return fail(models.RejectInternalError, "database error recording attendance")
 	}
 
-	// Step 6 — Auto-register the student if they haven't already.
+	// Step 8 — Auto-register the student if they haven't already.
 	// This handles the scenario where a student scans the QR without
 	// having pre-registered online.  Same slot-aware logic as RegisterForEvent.
 	if err := s.upsertRegistration(r, studentID, rec.EventID, now); err != nil {
-		return fail("could not record registration: " + err.Error())
+		return fail(models.RejectInternalError, "could not record registration: "+err.Error())
 	}
 
-	// Step 7 — Award badges (also idempotent via INSERT OR IGNORE).
+	// Step 9 — Award badges (also idempotent via INSERT OR IGNORE).
 	if err := s.awardSkills(r, studentID, rec.EventID); err != nil {
-		return fail("could not award skills: " + err.Error())
+		return fail(models.RejectInternalError, "could not award skills: "+err.Error())
 	}
 
 	return models.SyncResult{
@@ -168,44 +286,28 @@ func (s *Server) processAttendanceRecord(r *http.Request, studentID string, rec
 // Called from processAttendanceRecord so a QR scan auto-registers the student
 // even if they never pressed "Register" while online.
 //
-// Slot logic (mirrors RegisterForEvent):
+// Slot logic is RegisterForEvent's, shared through claimSeat:
 //   - If capacity is NULL or slots remain → confirmed.
 //   - If no slots remain → conflict_pending (host dashboard will show this).
-//   - If already registered → no change (INSERT OR IGNORE).
+//   - If already registered → no change.
 func (s *Server) upsertRegistration(r *http.Request, studentID, eventID string, now time.Time) error {
-	// Read capacity state.
-	var capacity, slotsRemaining sql.NullInt64
-	err := s.DB.QueryRowContext(r.Context(),
-		`SELECT capacity, slots_remaining FROM events WHERE id = ?`, eventID,
-	).Scan(&capacity, &slotsRemaining)
+	tx, err := s.DB.BeginTx(r.Context(), nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback() //nolint:errcheck
 
-	regStatus := "confirmed"
-	if capacity.Valid && capacity.Int64 > 0 && slotsRemaining.Valid && slotsRemaining.Int64 <= 0 {
-		regStatus = "conflict_pending"
-	}
-
-	result, err := s.DB.ExecContext(r.Context(),
-		`INSERT OR IGNORE INTO registrations (id, event_id, student_id, registered_at, status)
-		 VALUES (?, ?, ?, ?, ?)`,
-		uuid.NewString(), eventID, studentID, now, regStatus,
-	)
+	promoted, err := s.settleWaitlist(r.Context(), tx, eventID, now)
 	if err != nil {
 		return err
 	}
-
-	// Only decrement slots when a brand-new confirmed registration was inserted.
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected > 0 && regStatus == "confirmed" && capacity.Valid {
-		_, err = s.DB.ExecContext(r.Context(),
-			`UPDATE events SET slots_remaining = slots_remaining - 1, updated_at = ?
-			 WHERE id = ? AND slots_remaining > 0`,
-			now, eventID,
-		)
+	if _, _, err := s.claimSeat(r.Context(), tx, eventID, studentID, now); err != nil {
 		return err
 	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.notifyPromotions(r.Context(), promoted)
 	return nil
 }
 
@@ -290,14 +392,30 @@ func (s *Server) GetMySkills(w http.ResponseWriter, r *http.Request) {
 // Returns the student's registered events with event details embedded,
 // avoiding a second round-trip from the client. The anonymous struct
 // RegWithEvent is defined inline because it's only used here.
+//
+// Waiting registrations carry waitlist_position: 1 is next in line, in the
+// same registered_at order settleWaitlist promotes in.  Registrations for
+// cancelled events stay, with the event's cancellation_reason, even after
+// the host deletes the event, so the student learns why.
 func (s *Server) GetMyRegistrations(w http.ResponseWriter, r *http.Request) {
 	studentID := middleware.GetUserID(r.Context())
 
+	// An offer of the student's that ran out is dropped before they see it.
+	if _, err := s.settleExpiredOffers(r.Context(), studentID, time.Now().UTC()); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not update waitlist")
+		return
+	}
+
 	rows, err := s.DB.QueryContext(r.Context(),
-		`SELECT r.id, r.event_id, r.student_id, r.registered_at,
-        e.title, e.start_time, e.end_time, e.status, e.location
+		`SELECT r.id, r.event_id, r.student_id, r.registered_at, r.status, r.offer_expires_at,
+        e.title, e.start_time, e.end_time, e.status, e.cancellation_reason, e.location, q.position
  FROM registrations r
  JOIN events e ON e.id = r.event_id
+ LEFT JOIN (
+   SELECT id, ROW_NUMBER() OVER (PARTITION BY event_id ORDER BY registered_at, rowid) AS position
+   FROM registrations
+   WHERE status IN ('waitlisted','conflict_pending')
+ ) q ON q.id = r.id
  WHERE r.student_id = ?
  ORDER BY e.start_time ASC`, studentID)
 	if err != nil {
@@ -314,19 +432,31 @@ func (s *Server) GetMyRegistrations(w http.ResponseWriter, r *http.Request) {
 		StartTime   time.Time          `json:"start_time"`
 		EndTime     time.Time          `json:"end_time"`
 		EventStatus models.EventStatus `json:"event_status"`
-		Location    string             `json:"location"`
+		// CancellationReason is set once the event is cancelled.
+		CancellationReason string `json:"cancellation_reason,omitempty"`
+		Location           string `json:"location"`
+		// WaitlistPosition is set only while waiting for a seat.
+		WaitlistPosition *int64 `json:"waitlist_position,omitempty"`
 	}
 
 	var regs []RegWithEvent
 	for rows.Next() {
 		var reg RegWithEvent
+		var offerExpiresAt sql.NullTime
+		var position sql.NullInt64
 		if err := rows.Scan(
-			&reg.ID, &reg.EventID, &reg.StudentID, &reg.RegisteredAt,
-			&reg.EventTitle, &reg.StartTime, &reg.EndTime, &reg.EventStatus, &reg.Location,
+			&reg.ID, &reg.EventID, &reg.StudentID, &reg.RegisteredAt, &reg.Status, &offerExpiresAt,
+			&reg.EventTitle, &reg.StartTime, &reg.EndTime, &reg.EventStatus, &reg.CancellationReason, &reg.Location, &position,
 		); err != nil {
 			respondError(w, http.StatusInternalServerError, "scan error")
 			return
 		}
+		if offerExpiresAt.Valid {
+			reg.OfferExpiresAt = &offerExpiresAt.Time
+		}
+		if position.Valid {
+			reg.WaitlistPosition = &position.Int64
+		}
 		regs = append(regs, reg)
 	}
 	if err := rows.Err(); err != nil {
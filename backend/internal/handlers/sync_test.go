@@ -10,17 +10,43 @@ import (
 
 	"github.com/Elizabethomito/skillzone/backend/internal/auth"
 	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 // makeCheckInPayload builds a valid CheckInPayload JSON string for testing.
-// It generates a signed check-in token using the test server's secret.
-func makeCheckInPayload(t *testing.T, eventID, checkInCode, secret string) string {
+// It generates a check-in token signed with key and stamps it with the
+// current time as the scan time.
+func makeCheckInPayload(t *testing.T, eventID, checkInCode string, key *auth.CheckInKey) string {
 	t.Helper()
-	token, err := auth.GenerateCheckInToken(eventID, checkInCode, secret)
+	token, err := auth.GenerateCheckInToken(eventID, checkInCode, key)
 	if err != nil {
 		t.Fatalf("makeCheckInPayload: %v", err)
 	}
-	return fmt.Sprintf(`{"token":%q}`, token)
+	return fmt.Sprintf(`{"token":%q,"timestamp":%d}`, token, time.Now().Unix())
+}
+
+// syncOne posts a single attendance record and returns its result.
+func syncOne(t *testing.T, srv *Server, studentID, eventID, payload string) models.SyncResult {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/sync/attendance", jsonBody(t, models.SyncAttendanceRequest{
+		Records: []models.AttendanceSyncRecord{
+			{LocalID: "local-x", EventID: eventID, Payload: payload},
+		},
+	}))
+	req = ctxWithUser(req, studentID, "student")
+	rec := httptest.NewRecorder()
+	srv.SyncAttendance(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp models.SyncAttendanceResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(resp.Results))
+	}
+	return resp.Results[0]
 }
 
 func TestSyncAttendance_Success(t *testing.T) {
@@ -36,7 +62,7 @@ func TestSyncAttendance_Success(t *testing.T) {
 		t.Fatalf("link skill: %v", err)
 	}
 
-	payload := makeCheckInPayload(t, eventID, checkInCode, testSecret)
+	payload := makeCheckInPayload(t, eventID, checkInCode, testCheckInKey)
 	localID := "local-001"
 
 	req := httptest.NewRequest(http.MethodPost, "/api/sync/attendance", jsonBody(t, models.SyncAttendanceRequest{
@@ -71,15 +97,76 @@ func TestSyncAttendance_Success(t *testing.T) {
 	}
 }
 
+func TestSyncAttendance_EventCancelled(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	eventID, checkInCode := seedEvent(t, srv, companyID)
+	verified, late := seedStudentUser(t, srv), seedStudentUser(t, srv)
+	first := makeCheckInPayload(t, eventID, checkInCode, testCheckInKey)
+	if res := syncOne(t, srv, verified, eventID, first); res.Status != models.AttendanceVerified {
+		t.Fatalf("before cancelling: got %q (%s)", res.Status, res.Message)
+	}
+
+	if _, err := srv.DB.Exec(`UPDATE events SET status = 'cancelled', cancellation_reason = 'Storm' WHERE id = ?`, eventID); err != nil {
+		t.Fatal(err)
+	}
+	res := syncOne(t, srv, late, eventID, makeCheckInPayload(t, eventID, checkInCode, testCheckInKey))
+	if res.Status != models.AttendanceRejected || res.Reason != models.RejectEventCancelled {
+		t.Errorf("after cancelling: got %q/%q, want rejected/event_cancelled", res.Status, res.Reason)
+	}
+	// A retry of a check-in verified before the cancellation still succeeds.
+	if res := syncOne(t, srv, verified, eventID, first); res.Status != models.AttendanceVerified {
+		t.Errorf("retry: got %q (%s)", res.Status, res.Message)
+	}
+}
+
+func TestSyncAttendance_EventDeleted(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	del := func(eventID string) {
+		t.Helper()
+		if rec := asCompany(t, srv.DeleteEvent, http.MethodDelete, companyID, nil, "id", eventID); rec.Code != http.StatusNoContent {
+			t.Fatalf("delete: expected 204, got %d", rec.Code)
+		}
+	}
+
+	// Deleted while active: the delete cancelled it.
+	eventID, checkInCode := seedEvent(t, srv, companyID)
+	verified, late := seedStudentUser(t, srv), seedStudentUser(t, srv)
+	first := makeCheckInPayload(t, eventID, checkInCode, testCheckInKey)
+	if res := syncOne(t, srv, verified, eventID, first); res.Status != models.AttendanceVerified {
+		t.Fatalf("before deleting: got %q (%s)", res.Status, res.Message)
+	}
+	del(eventID)
+	if res := syncOne(t, srv, verified, eventID, first); res.Status != models.AttendanceVerified {
+		t.Errorf("retry after deleting: got %q (%s)", res.Status, res.Message)
+	}
+	res := syncOne(t, srv, late, eventID, makeCheckInPayload(t, eventID, checkInCode, testCheckInKey))
+	if res.Status != models.AttendanceRejected || res.Reason != models.RejectEventDeleted {
+		t.Errorf("new check-in after deleting: got %q/%q, want rejected/event_deleted", res.Status, res.Reason)
+	}
+
+	// Deleted after it finished: offline check-ins still sync before the
+	// deadline.
+	later := time.Now().Add(24 * time.Hour).UTC()
+	pastID, payload := seedPastEvent(t, srv, companyID, &later)
+	srv.DB.Exec(`UPDATE events SET status = 'completed' WHERE id = ?`, pastID)
+	del(pastID)
+	if res := syncOne(t, srv, late, pastID, payload); res.Status != models.AttendanceVerified {
+		t.Errorf("completed event deleted before sync: got %q/%q (%s)", res.Status, res.Reason, res.Message)
+	}
+}
+
 func TestSyncAttendance_InvalidSignature(t *testing.T) {
 	srv := newTestServer(t)
 	companyID := seedCompanyUser(t, srv)
 	studentID := seedStudentUser(t, srv)
 	eventID, checkInCode := seedEvent(t, srv, companyID)
 
-	// Build a token signed with the WRONG secret — simulates a forged QR.
-	badToken, _ := auth.GenerateCheckInToken(eventID, checkInCode, "wrong-secret")
-	payload := fmt.Sprintf(`{"token":%q}`, badToken)
+	// Build a token signed with the WRONG key — simulates a forged QR.
+	wrongKey, _ := auth.NewCheckInKey()
+	badToken, _ := auth.GenerateCheckInToken(eventID, checkInCode, wrongKey)
+	payload := fmt.Sprintf(`{"token":%q,"timestamp":%d}`, badToken, time.Now().Unix())
 
 	req := httptest.NewRequest(http.MethodPost, "/api/sync/attendance", jsonBody(t, models.SyncAttendanceRequest{
 		Records: []models.AttendanceSyncRecord{
@@ -106,7 +193,7 @@ func TestSyncAttendance_Idempotent(t *testing.T) {
 	studentID := seedStudentUser(t, srv)
 	eventID, checkInCode := seedEvent(t, srv, companyID)
 
-	payload := makeCheckInPayload(t, eventID, checkInCode, testSecret)
+	payload := makeCheckInPayload(t, eventID, checkInCode, testCheckInKey)
 	syncReq := models.SyncAttendanceRequest{
 		Records: []models.AttendanceSyncRecord{
 			{LocalID: "local-003", EventID: eventID, Payload: payload},
@@ -132,9 +219,9 @@ func TestSyncAttendance_Idempotent(t *testing.T) {
 }
 
 // TestSyncAttendance_TokenExpiredButSyncedLate verifies the core design:
-// a token whose exp has passed is still accepted at sync time because the
-// server only checks the JWT signature, not the expiry, during sync.
-// The exp only matters at scan time (i.e. when the host's QR was live).
+// a token whose exp has passed is still accepted at sync time as long as the
+// device scanned it while it was live and the event's sync deadline has not
+// passed.  The exp only matters at scan time (i.e. when the host's QR was live).
 func TestSyncAttendance_TokenExpiredButSyncedLate(t *testing.T) {
 	srv := newTestServer(t)
 	companyID := seedCompanyUser(t, srv)
@@ -144,16 +231,18 @@ func TestSyncAttendance_TokenExpiredButSyncedLate(t *testing.T) {
 	// Manually build a token that expired 1 week ago — simulates a student
 	// who scanned the QR during the live window but only connects now.
 	// We sign it with the correct secret so the signature is valid.
+	iat := time.Now().Add(-7 * 24 * time.Hour)
 	expiredToken, err := auth.GenerateCheckInTokenWithExpiry(
-		eventID, checkInCode, testSecret,
-		time.Now().Add(-7*24*time.Hour),                                // iat in the past
-		time.Now().Add(-7*24*time.Hour).Add(auth.CheckInTokenDuration), // exp also in the past
+		eventID, checkInCode, testCheckInKey,
+		iat,                                // iat in the past
+		iat.Add(auth.CheckInTokenDuration), // exp also in the past
 	)
 	if err != nil {
 		t.Fatalf("GenerateCheckInTokenWithExpiry: %v", err)
 	}
 
-	payload := fmt.Sprintf(`{"token":%q}`, expiredToken)
+	// The device scanned it an hour after it was issued.
+	payload := fmt.Sprintf(`{"token":%q,"timestamp":%d}`, expiredToken, iat.Add(time.Hour).Unix())
 	req := httptest.NewRequest(http.MethodPost, "/api/sync/attendance", jsonBody(t, models.SyncAttendanceRequest{
 		Records: []models.AttendanceSyncRecord{
 			{LocalID: "local-late", EventID: eventID, Payload: payload},
@@ -174,17 +263,18 @@ func TestSyncAttendance_TokenExpiredButSyncedLate(t *testing.T) {
 	}
 }
 
-// TestSyncAttendance_WrongSecret verifies that a token signed by a different
+// TestSyncAttendance_WrongKey verifies that a token signed by a different
 // server (or a tampered token) is rejected even if the exp hasn't passed.
-func TestSyncAttendance_WrongSecret(t *testing.T) {
+func TestSyncAttendance_WrongKey(t *testing.T) {
 	srv := newTestServer(t)
 	companyID := seedCompanyUser(t, srv)
 	studentID := seedStudentUser(t, srv)
 	eventID, checkInCode := seedEvent(t, srv, companyID)
 
-	// Token issued by a "different server" — wrong signing secret.
-	foreignToken, _ := auth.GenerateCheckInToken(eventID, checkInCode, "different-server-secret")
-	payload := fmt.Sprintf(`{"token":%q}`, foreignToken)
+	// Token issued by a "different server" — wrong signing key.
+	foreignKey, _ := auth.NewCheckInKey()
+	foreignToken, _ := auth.GenerateCheckInToken(eventID, checkInCode, foreignKey)
+	payload := fmt.Sprintf(`{"token":%q,"timestamp":%d}`, foreignToken, time.Now().Unix())
 
 	req := httptest.NewRequest(http.MethodPost, "/api/sync/attendance", jsonBody(t, models.SyncAttendanceRequest{
 		Records: []models.AttendanceSyncRecord{
@@ -202,6 +292,305 @@ func TestSyncAttendance_WrongSecret(t *testing.T) {
 	}
 }
 
+// TestSyncAttendance_SessionSecretCannotMintCheckIns verifies that a token
+// HMAC-signed with the session secret is rejected: check-in tokens must be
+// signed with the Ed25519 check-in key.
+func TestSyncAttendance_SessionSecretCannotMintCheckIns(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	studentID := seedStudentUser(t, srv)
+	eventID, checkInCode := seedEvent(t, srv, companyID)
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.CheckInClaims{
+		EventID: eventID,
+		HostSig: checkInCode,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	forged.Header["kid"] = testCheckInKey.ID
+	token, err := forged.SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	payload := fmt.Sprintf(`{"token":%q,"timestamp":%d}`, token, time.Now().Unix())
+	res := syncOne(t, srv, studentID, eventID, payload)
+	if res.Status != models.AttendanceRejected || res.Reason != models.RejectInvalidToken {
+		t.Errorf("got %q/%q, want rejected/%q", res.Status, res.Reason, models.RejectInvalidToken)
+	}
+}
+
+func TestSyncAttendance_MissingScanTime(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	studentID := seedStudentUser(t, srv)
+	eventID, checkInCode := seedEvent(t, srv, companyID)
+
+	token, _ := auth.GenerateCheckInToken(eventID, checkInCode, testCheckInKey)
+	res := syncOne(t, srv, studentID, eventID, fmt.Sprintf(`{"token":%q}`, token))
+	if res.Status != models.AttendanceRejected || res.Reason != models.RejectMissingScanTime {
+		t.Errorf("got %q/%q, want rejected/%q", res.Status, res.Reason, models.RejectMissingScanTime)
+	}
+}
+
+// TestSyncAttendance_ScanOutsideWindow simulates a screenshot of the QR being
+// "scanned" hours after the token expired.
+func TestSyncAttendance_ScanOutsideWindow(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	studentID := seedStudentUser(t, srv)
+	eventID, checkInCode := seedEvent(t, srv, companyID)
+
+	iat := time.Now().Add(-12 * time.Hour)
+	token, err := auth.GenerateCheckInTokenWithExpiry(eventID, checkInCode, testCheckInKey,
+		iat, iat.Add(auth.CheckInTokenDuration))
+	if err != nil {
+		t.Fatalf("GenerateCheckInTokenWithExpiry: %v", err)
+	}
+
+	cases := map[string]time.Time{
+		"after expiry": iat.Add(auth.CheckInTokenDuration + time.Hour),
+		"before issue": iat.Add(-time.Hour),
+	}
+	for name, scannedAt := range cases {
+		t.Run(name, func(t *testing.T) {
+			payload := fmt.Sprintf(`{"token":%q,"timestamp":%d}`, token, scannedAt.Unix())
+			res := syncOne(t, srv, studentID, eventID, payload)
+			if res.Status != models.AttendanceRejected || res.Reason != models.RejectScanOutsideWindow {
+				t.Errorf("got %q/%q, want rejected/%q", res.Status, res.Reason, models.RejectScanOutsideWindow)
+			}
+		})
+	}
+}
+
+// TestSyncAttendance_ScanWithinClockSkew verifies that a phone clock a
+// couple of minutes behind the server is tolerated.
+func TestSyncAttendance_ScanWithinClockSkew(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	studentID := seedStudentUser(t, srv)
+	eventID, checkInCode := seedEvent(t, srv, companyID)
+
+	token, _ := auth.GenerateCheckInToken(eventID, checkInCode, testCheckInKey)
+	payload := fmt.Sprintf(`{"token":%q,"timestamp":%d}`, token, time.Now().Add(-2*time.Minute).Unix())
+	res := syncOne(t, srv, studentID, eventID, payload)
+	if res.Status != models.AttendanceVerified {
+		t.Errorf("expected verified, got %q: %s", res.Status, res.Message)
+	}
+}
+
+func TestSyncAttendance_ScanInFuture(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	studentID := seedStudentUser(t, srv)
+	eventID, checkInCode := seedEvent(t, srv, companyID)
+
+	token, _ := auth.GenerateCheckInToken(eventID, checkInCode, testCheckInKey)
+	payload := fmt.Sprintf(`{"token":%q,"timestamp":%d}`, token, time.Now().Add(time.Hour).Unix())
+	res := syncOne(t, srv, studentID, eventID, payload)
+	if res.Status != models.AttendanceRejected || res.Reason != models.RejectScanInFuture {
+		t.Errorf("got %q/%q, want rejected/%q", res.Status, res.Reason, models.RejectScanInFuture)
+	}
+}
+
+// rotateCheckInCode calls the host's rotate endpoint and returns the new token.
+func rotateCheckInCode(t *testing.T, srv *Server, hostID, eventID string) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/events/"+eventID+"/checkin-code/rotate", nil)
+	req.SetPathValue("id", eventID)
+	req = ctxWithUser(req, hostID, "company")
+	rec := httptest.NewRecorder()
+	srv.RotateEventCheckInCode(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rotate: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	return resp.Token
+}
+
+func TestSyncAttendance_HostSigRevoked(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	studentID := seedStudentUser(t, srv)
+	eventID, checkInCode := seedEvent(t, srv, companyID)
+
+	oldPayload := makeCheckInPayload(t, eventID, checkInCode, testCheckInKey)
+	newToken := rotateCheckInCode(t, srv, companyID, eventID)
+
+	res := syncOne(t, srv, studentID, eventID, oldPayload)
+	if res.Status != models.AttendanceRejected || res.Reason != models.RejectCodeRevoked {
+		t.Errorf("old token: got %q/%q, want rejected/%q", res.Status, res.Reason, models.RejectCodeRevoked)
+	}
+
+	newPayload := fmt.Sprintf(`{"token":%q,"timestamp":%d}`, newToken, time.Now().Unix())
+	if res := syncOne(t, srv, studentID, eventID, newPayload); res.Status != models.AttendanceVerified {
+		t.Errorf("new token: got %q/%q: %s", res.Status, res.Reason, res.Message)
+	}
+}
+
+// TestSyncAttendance_HostSigMismatch verifies that host_sig is
+// checked even when the signature is valid — e.g. a token minted for a
+// different event's code.
+func TestSyncAttendance_HostSigMismatch(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	studentID := seedStudentUser(t, srv)
+	eventID, _ := seedEvent(t, srv, companyID)
+
+	res := syncOne(t, srv, studentID, eventID, makeCheckInPayload(t, eventID, "not-the-code", testCheckInKey))
+	if res.Reason != models.RejectCodeRevoked {
+		t.Errorf("got %q/%q, want rejected/%q", res.Status, res.Reason, models.RejectCodeRevoked)
+	}
+}
+
+// TestSyncAttendance_RetryAfterRotation verifies a verified check-in stays
+// verified when the client retries after the host rotated the code.
+func TestSyncAttendance_RetryAfterRotation(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	studentID := seedStudentUser(t, srv)
+	eventID, checkInCode := seedEvent(t, srv, companyID)
+
+	payload := makeCheckInPayload(t, eventID, checkInCode, testCheckInKey)
+	if res := syncOne(t, srv, studentID, eventID, payload); res.Status != models.AttendanceVerified {
+		t.Fatalf("first sync: got %q: %s", res.Status, res.Message)
+	}
+	rotateCheckInCode(t, srv, companyID, eventID)
+	if res := syncOne(t, srv, studentID, eventID, payload); res.Status != models.AttendanceVerified {
+		t.Errorf("retry after rotation: got %q/%q: %s", res.Status, res.Reason, res.Message)
+	}
+}
+
+// seedRotatingEvent switches an event to rotating codes with the given period.
+func seedRotatingEvent(t *testing.T, srv *Server, hostID string, periodSeconds int) (eventID, checkInCode string) {
+	t.Helper()
+	eventID, checkInCode = seedEvent(t, srv, hostID)
+	if _, err := srv.DB.Exec(`UPDATE events SET checkin_rotation_seconds = ? WHERE id = ?`,
+		periodSeconds, eventID); err != nil {
+		t.Fatalf("seedRotatingEvent: %v", err)
+	}
+	return
+}
+
+func TestSyncAttendance_RotatingCode(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	studentID := seedStudentUser(t, srv)
+	eventID, checkInCode := seedRotatingEvent(t, srv, companyID, 30)
+
+	// The code that was on screen two minutes ago.
+	shownAt := time.Now().Add(-2 * time.Minute)
+	token, _, err := auth.GenerateRotatingCheckInToken(eventID, checkInCode, testCheckInKey, 30*time.Second, shownAt)
+	if err != nil {
+		t.Fatalf("GenerateRotatingCheckInToken: %v", err)
+	}
+
+	// Forwarded to a friend who "scanned" the photo a minute later.
+	forwarded := fmt.Sprintf(`{"token":%q,"timestamp":%d}`, token, shownAt.Add(time.Minute).Unix())
+	res := syncOne(t, srv, studentID, eventID, forwarded)
+	if res.Status != models.AttendanceRejected || res.Reason != models.RejectCodeRotated {
+		t.Errorf("forwarded code: got %q/%q, want rejected/%q", res.Status, res.Reason, models.RejectCodeRotated)
+	}
+
+	// Scanned from the screen while it was showing.
+	payload := fmt.Sprintf(`{"token":%q,"timestamp":%d}`, token, shownAt.Unix())
+	if res := syncOne(t, srv, studentID, eventID, payload); res.Status != models.AttendanceVerified {
+		t.Errorf("live code: got %q/%q: %s", res.Status, res.Reason, res.Message)
+	}
+}
+
+// TestSyncAttendance_StaticCodeForRotatingEvent verifies that once an event
+// rotates its codes, a long-lived static token is no longer accepted.
+func TestSyncAttendance_StaticCodeForRotatingEvent(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	studentID := seedStudentUser(t, srv)
+	eventID, checkInCode := seedRotatingEvent(t, srv, companyID, 30)
+
+	res := syncOne(t, srv, studentID, eventID, makeCheckInPayload(t, eventID, checkInCode, testCheckInKey))
+	if res.Status != models.AttendanceRejected || res.Reason != models.RejectCodeRotated {
+		t.Errorf("got %q/%q, want rejected/%q", res.Status, res.Reason, models.RejectCodeRotated)
+	}
+}
+
+// seedPastEvent inserts an event that ended 30 days ago, optionally with an
+// explicit sync deadline, and returns a payload scanned while it was live.
+func seedPastEvent(t *testing.T, srv *Server, hostID string, syncDeadline *time.Time) (eventID, payload string) {
+	t.Helper()
+	eventID, checkInCode := seedEvent(t, srv, hostID)
+	start := time.Now().Add(-30 * 24 * time.Hour).UTC()
+	end := start.Add(8 * time.Hour)
+	if _, err := srv.DB.Exec(`UPDATE events SET start_time = ?, end_time = ?, sync_deadline = ? WHERE id = ?`,
+		start, end, syncDeadline, eventID); err != nil {
+		t.Fatalf("seedPastEvent: %v", err)
+	}
+	token, err := auth.GenerateCheckInTokenWithExpiry(eventID, checkInCode, testCheckInKey,
+		start, start.Add(auth.CheckInTokenDuration))
+	if err != nil {
+		t.Fatalf("GenerateCheckInTokenWithExpiry: %v", err)
+	}
+	return eventID, fmt.Sprintf(`{"token":%q,"timestamp":%d}`, token, start.Add(time.Hour).Unix())
+}
+
+func TestSyncAttendance_DefaultDeadlinePassed(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	studentID := seedStudentUser(t, srv)
+	eventID, payload := seedPastEvent(t, srv, companyID, nil)
+
+	res := syncOne(t, srv, studentID, eventID, payload)
+	if res.Status != models.AttendanceRejected || res.Reason != models.RejectSyncDeadlinePassed {
+		t.Errorf("got %q/%q, want rejected/%q", res.Status, res.Reason, models.RejectSyncDeadlinePassed)
+	}
+}
+
+func TestSyncAttendance_ExplicitDeadline(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	studentID := seedStudentUser(t, srv)
+
+	// A long deadline set by the host keeps the old event open for syncs.
+	later := time.Now().Add(24 * time.Hour).UTC()
+	eventID, payload := seedPastEvent(t, srv, companyID, &later)
+	if res := syncOne(t, srv, studentID, eventID, payload); res.Status != models.AttendanceVerified {
+		t.Errorf("expected verified before explicit deadline, got %q: %s", res.Status, res.Message)
+	}
+
+	// A configured grace period does not override an explicit deadline.
+	srv.CheckIn.SyncGrace = 365 * 24 * time.Hour
+	earlier := time.Now().Add(-time.Hour).UTC()
+	eventID, payload = seedPastEvent(t, srv, companyID, &earlier)
+	res := syncOne(t, srv, studentID, eventID, payload)
+	if res.Reason != models.RejectSyncDeadlinePassed {
+		t.Errorf("got %q/%q, want rejected/%q", res.Status, res.Reason, models.RejectSyncDeadlinePassed)
+	}
+}
+
+// TestSyncAttendance_RetryAfterDeadline verifies that a client that lost the
+// response to a successful sync still gets "verified" when it retries after
+// the deadline.
+func TestSyncAttendance_RetryAfterDeadline(t *testing.T) {
+	srv := newTestServer(t)
+	companyID := seedCompanyUser(t, srv)
+	studentID := seedStudentUser(t, srv)
+	later := time.Now().Add(time.Hour).UTC()
+	eventID, payload := seedPastEvent(t, srv, companyID, &later)
+
+	if res := syncOne(t, srv, studentID, eventID, payload); res.Status != models.AttendanceVerified {
+		t.Fatalf("first sync: got %q: %s", res.Status, res.Message)
+	}
+
+	srv.DB.Exec(`UPDATE events SET sync_deadline = ? WHERE id = ?`, time.Now().Add(-time.Minute).UTC(), eventID)
+	if res := syncOne(t, srv, studentID, eventID, payload); res.Status != models.AttendanceVerified {
+		t.Errorf("retry after deadline: got %q/%q: %s", res.Status, res.Reason, res.Message)
+	}
+}
+
 func TestGetMySkills(t *testing.T) {
 	srv := newTestServer(t)
 	companyID := seedCompanyUser(t, srv)
@@ -211,7 +600,7 @@ func TestGetMySkills(t *testing.T) {
 	srv.DB.Exec(`INSERT INTO event_skills (event_id, skill_id) VALUES (?, ?)`, eventID, skillID)
 
 	// Sync attendance to award skill
-	payload := makeCheckInPayload(t, eventID, checkInCode, testSecret)
+	payload := makeCheckInPayload(t, eventID, checkInCode, testCheckInKey)
 	syncReq := httptest.NewRequest(http.MethodPost, "/api/sync/attendance", jsonBody(t, models.SyncAttendanceRequest{
 		Records: []models.AttendanceSyncRecord{{LocalID: "local-005", EventID: eventID, Payload: payload}},
 	}))
@@ -248,7 +637,7 @@ func TestSyncAutoRegisters(t *testing.T) {
 		t.Fatal("expected no prior registration")
 	}
 
-	payload := makeCheckInPayload(t, eventID, checkInCode, testSecret)
+	payload := makeCheckInPayload(t, eventID, checkInCode, testCheckInKey)
 	req := httptest.NewRequest(http.MethodPost, "/api/sync/attendance", jsonBody(t, models.SyncAttendanceRequest{
 		Records: []models.AttendanceSyncRecord{{LocalID: "local-auto", EventID: eventID, Payload: payload}},
 	}))
@@ -289,7 +678,7 @@ func TestSyncAutoRegisters_ConflictWhenFull(t *testing.T) {
 	srv.RegisterForEvent(httptest.NewRecorder(), req1)
 
 	// Student2 syncs an offline QR scan.
-	payload := makeCheckInPayload(t, eventID, checkInCode, testSecret)
+	payload := makeCheckInPayload(t, eventID, checkInCode, testCheckInKey)
 	req2 := httptest.NewRequest(http.MethodPost, "/api/sync/attendance", jsonBody(t, models.SyncAttendanceRequest{
 		Records: []models.AttendanceSyncRecord{{LocalID: "local-conflict", EventID: eventID, Payload: payload}},
 	}))
@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — slowing down password guessing
+// ────────────────────────────────────────────────────────────────────
+// Password hashing makes each guess cost tens of milliseconds, which is not
+// much when the demo emails are printed in the README.  So failed logins are
+// counted twice:
+//
+//   - per ACCOUNT (the email typed, whether or not it exists — otherwise the
+//     lockout itself would reveal which emails are registered), which stops
+//     a slow, distributed guess at one password;
+//   - per client IP, which stops one machine spraying many accounts.
+//
+// The first few failures are free.  After that each failure locks the key
+// for twice as long as the previous one (exponential backoff), up to a cap.
+// While locked, Login answers 429 with Retry-After WITHOUT checking the
+// password, so guesses made during the lockout tell the attacker nothing.
+// The lockout check and the bump happen together, before the password is
+// checked: every attempt counts as a failure until the password proves
+// right, so a burst of parallel guesses cannot all get through the check
+// while the counter still reads zero.
+// A successful login clears the account's counter; counters also reset
+// after a quiet period.  Every failure is written to login_attempts, which
+// admins can read, and an admin can lift an account's lockout early.
+//
+// The IP counter has a much higher threshold than the account one because a
+// whole venue may share one address behind the Wi-Fi router.
+
+// LoginThrottlePolicy configures login throttling.  Zero values use the
+// defaults below.
+type LoginThrottlePolicy struct {
+	// AccountThreshold is how many failures in a row an email is allowed
+	// before it is locked.
+	AccountThreshold int
+	// IPThreshold is the same for a client IP.
+	IPThreshold int
+	// BaseLockout is the first lockout; each further failure doubles it.
+	BaseLockout time.Duration
+	// MaxLockout caps the doubling.
+	MaxLockout time.Duration
+	// Window is how long without a failure before a counter starts over.
+	Window time.Duration
+}
+
+const (
+	defaultAccountThreshold = 5
+	defaultIPThreshold      = 50
+	defaultBaseLockout      = 30 * time.Second
+	defaultMaxLockout       = 15 * time.Minute
+	defaultThrottleWindow   = time.Hour
+)
+
+func (p LoginThrottlePolicy) accountThreshold() int {
+	if p.AccountThreshold > 0 {
+		return p.AccountThreshold
+	}
+	return defaultAccountThreshold
+}
+
+func (p LoginThrottlePolicy) ipThreshold() int {
+	if p.IPThreshold > 0 {
+		return p.IPThreshold
+	}
+	return defaultIPThreshold
+}
+
+func (p LoginThrottlePolicy) baseLockout() time.Duration {
+	if p.BaseLockout > 0 {
+		return p.BaseLockout
+	}
+	return defaultBaseLockout
+}
+
+func (p LoginThrottlePolicy) maxLockout() time.Duration {
+	if p.MaxLockout > 0 {
+		return p.MaxLockout
+	}
+	return defaultMaxLockout
+}
+
+func (p LoginThrottlePolicy) window() time.Duration {
+	if p.Window > 0 {
+		return p.Window
+	}
+	return defaultThrottleWindow
+}
+
+// lockoutFor returns how long the key is locked after its n-th failure in
+// a row, given the threshold for that kind of key.
+func (p LoginThrottlePolicy) lockoutFor(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+	// Cap the exponent before shifting so a long attack cannot overflow.
+	exp := min(failures-threshold, 30)
+	d := p.baseLockout() * time.Duration(1<<exp)
+	return min(d, p.maxLockout())
+}
+
+// Reasons stored in login_attempts.reason.
+const (
+	attemptUnknownEmail = "unknown_email"
+	attemptBadPassword  = "bad_password"
+	attemptLocked       = "locked"
+	// attemptBadSecondFactor is a wrong authenticator or recovery code after
+	// a correct password; see twofactor.go.
+	attemptBadSecondFactor = "bad_2fa_code"
+)
+
+func accountKey(email string) string { return "account:" + truncate(email, 255) }
+func ipKey(ip string) string         { return "ip:" + ip }
+
+// claimLoginAttempt checks whether login is locked for email or ip and, if
+// not, counts this attempt as a failure against both, in one transaction.
+// Counting before the credential is checked means a burst of concurrent
+// guesses cannot all slip past the check before any of them is recorded;
+// refundLoginAttempt takes the count back once the credential checks out.
+// A refused attempt is audited as locked and not counted; the returned wait
+// says how much longer login is refused, zero meaning go ahead.
+func (s *Server) claimLoginAttempt(r *http.Request, email, userID string) (time.Duration, error) {
+	ctx := r.Context()
+	ip := clientIP(r)
+	now := time.Now().UTC()
+
+	// db.Open makes every transaction take the write lock at BEGIN, so the
+	// check and the bump below cannot interleave with another attempt's.
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var wait time.Duration
+	for _, key := range []string{accountKey(email), ipKey(ip)} {
+		var lockedUntil sql.NullTime
+		err := tx.QueryRowContext(ctx,
+			`SELECT locked_until FROM login_throttle WHERE key = ?`, key,
+		).Scan(&lockedUntil)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return 0, err
+		}
+		if lockedUntil.Valid && !isExpired(lockedUntil.Time, now) {
+			wait = max(wait, lockedUntil.Time.Sub(now))
+		}
+	}
+	if wait > 0 {
+		if err := insertLoginAttempt(ctx, tx, r, email, userID, attemptLocked, now); err != nil {
+			return 0, err
+		}
+		return wait, tx.Commit()
+	}
+
+	for _, k := range s.throttleKeys(email, ip) {
+		if err := s.bumpThrottle(ctx, tx, k.key, k.threshold, now); err != nil {
+			return 0, err
+		}
+	}
+	return 0, tx.Commit()
+}
+
+// refundLoginAttempt takes back the failure claimLoginAttempt counted for
+// email and ip, once the credential turned out to be right.
+func (s *Server) refundLoginAttempt(ctx context.Context, email, ip string) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, k := range s.throttleKeys(email, ip) {
+		var failures int
+		var lastFailure time.Time
+		err := tx.QueryRowContext(ctx,
+			`SELECT failures, last_failure_at FROM login_throttle WHERE key = ?`, k.key,
+		).Scan(&failures, &lastFailure)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // cleared in the meantime
+		}
+		if err != nil {
+			return err
+		}
+		failures = max(failures-1, 0)
+		var lockedUntil any
+		if d := s.LoginThrottle.lockoutFor(failures, k.threshold); d > 0 {
+			lockedUntil = lastFailure.Add(d)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE login_throttle SET failures = ?, locked_until = ? WHERE key = ?`,
+			failures, lockedUntil, k.key,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// recordLoginFailure writes the audit row for an attempt whose credential
+// was wrong.  claimLoginAttempt has already counted it.
+func (s *Server) recordLoginFailure(r *http.Request, email, userID, reason string) error {
+	return insertLoginAttempt(r.Context(), s.DB, r, email, userID, reason, time.Now().UTC())
+}
+
+// insertLoginAttempt adds a row to the login_attempts audit trail.
+func insertLoginAttempt(ctx context.Context, db execer, r *http.Request, email, userID, reason string, now time.Time) error {
+	var uid any
+	if userID != "" {
+		uid = userID
+	}
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO login_attempts (id, email, user_id, ip, user_agent, reason, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		uuid.NewString(), truncate(email, 255), uid, clientIP(r), truncate(r.UserAgent(), 255), reason, now)
+	return err
+}
+
+// throttleKey is one login_throttle counter and the failures it allows.
+type throttleKey struct {
+	key       string
+	threshold int
+}
+
+// throttleKeys lists the counters a login attempt is charged to.
+func (s *Server) throttleKeys(email, ip string) []throttleKey {
+	return []throttleKey{
+		{accountKey(email), s.LoginThrottle.accountThreshold()},
+		{ipKey(ip), s.LoginThrottle.ipThreshold()},
+	}
+}
+
+// bumpThrottle counts one more failure against key and sets its lockout.
+func (s *Server) bumpThrottle(ctx context.Context, tx *sql.Tx, key string, threshold int, now time.Time) error {
+	var failures int
+	var lastFailure time.Time
+	err := tx.QueryRowContext(ctx,
+		`SELECT failures, last_failure_at FROM login_throttle WHERE key = ?`, key,
+	).Scan(&failures, &lastFailure)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err != nil || now.Sub(lastFailure) > s.LoginThrottle.window() {
+		failures = 0
+	}
+	failures++
+
+	var lockedUntil any
+	if d := s.LoginThrottle.lockoutFor(failures, threshold); d > 0 {
+		lockedUntil = now.Add(d)
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO login_throttle (key, failures, last_failure_at, locked_until)
+		 VALUES (?, ?, ?, ?)
+		 ON CONFLICT(key) DO UPDATE SET
+		     failures = excluded.failures,
+		     last_failure_at = excluded.last_failure_at,
+		     locked_until = excluded.locked_until`,
+		key, failures, now, lockedUntil)
+	return err
+}
+
+// clearLoginFailures forgets the account's failures after a good login.
+func (s *Server) clearLoginFailures(ctx context.Context, email string) error {
+	_, err := s.DB.ExecContext(ctx, `DELETE FROM login_throttle WHERE key = ?`, accountKey(email))
+	return err
+}
+
+// respondTooManyAttempts writes a 429 with Retry-After in whole seconds.
+func respondTooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	secs := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(secs, 1)))
+	respondError(w, http.StatusTooManyRequests, "too many failed login attempts; try again later")
+}
+
+// UnlockUser handles POST /api/admin/users/{id}/unlock  (admin only)
+// Clears the user's failed-login counter and lockout.  IP lockouts are left
+// alone; they expire on their own.
+func (s *Server) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	var email string
+	err := s.DB.QueryRowContext(r.Context(),
+		`SELECT email FROM users WHERE id = ?`, r.PathValue("id"),
+	).Scan(&email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := s.clearLoginFailures(r.Context(), email); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not unlock account")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListLoginAttempts handles GET /api/admin/login-attempts  (admin only)
+//
+// The audit trail of failed logins, newest first.  Optional filters:
+// email=, ip=, user_id=; paging with limit/offset as for ListUsers.
+func (s *Server) ListLoginAttempts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	where := []string{"1 = 1"}
+	var args []any
+	for _, f := range []struct{ param, column string }{
+		{"email", "email"}, {"ip", "ip"}, {"user_id", "user_id"},
+	} {
+		if v := strings.TrimSpace(q.Get(f.param)); v != "" {
+			if f.param == "email" {
+				v = strings.ToLower(v)
+			}
+			where = append(where, f.column+" = ?")
+			args = append(args, v)
+		}
+	}
+	limit, offset, ok := pageParams(q.Get("limit"), q.Get("offset"))
+	if !ok {
+		respondError(w, http.StatusBadRequest, "limit and offset must be non-negative integers")
+		return
+	}
+	cond := strings.Join(where, " AND ")
+
+	var list models.LoginAttemptList
+	if err := s.DB.QueryRowContext(r.Context(),
+		`SELECT COUNT(*) FROM login_attempts WHERE `+cond, args...,
+	).Scan(&list.Total); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	// rowid follows insertion order, which is also time order; sorting on
+	// the text created_at column would not be reliable.
+	rows, err := s.DB.QueryContext(r.Context(),
+		`SELECT id, email, COALESCE(user_id, ''), ip, user_agent, reason, created_at
+		 FROM login_attempts WHERE `+cond+`
+		 ORDER BY rowid DESC
+		 LIMIT ? OFFSET ?`,
+		append(args, limit, offset)...,
+	)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	list.Attempts = []models.LoginAttempt{}
+	for rows.Next() {
+		var a models.LoginAttempt
+		if err := rows.Scan(&a.ID, &a.Email, &a.UserID, &a.IP, &a.UserAgent, &a.Reason, &a.CreatedAt); err != nil {
+			respondError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		list.Attempts = append(list.Attempts, a)
+	}
+	if err := rows.Err(); err != nil {
+		respondError(w, http.StatusInternalServerError, "rows error")
+		return
+	}
+
+	respond(w, http.StatusOK, list)
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+)
+
+// loginFrom attempts a login from the given remote address.
+func loginFrom(t *testing.T, srv *Server, addr, email, password string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login",
+		jsonBody(t, models.LoginRequest{Email: email, Password: password}))
+	req.RemoteAddr = addr
+	rec := httptest.NewRecorder()
+	srv.Login(rec, req)
+	return rec
+}
+
+func TestLoginThrottlePolicy_Backoff(t *testing.T) {
+	p := LoginThrottlePolicy{BaseLockout: time.Second, MaxLockout: 10 * time.Second}
+	for _, tc := range []struct {
+		failures int
+		want     time.Duration
+	}{
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{6, 8 * time.Second},
+		{7, 10 * time.Second},
+		{1000, 10 * time.Second},
+	} {
+		if got := p.lockoutFor(tc.failures, 3); got != tc.want {
+			t.Errorf("lockoutFor(%d): got %v, want %v", tc.failures, got, tc.want)
+		}
+	}
+}
+
+func TestLogin_LocksAccountAfterFailures(t *testing.T) {
+	srv := newTestServer(t)
+	srv.LoginThrottle = LoginThrottlePolicy{AccountThreshold: 3}
+	adminID := seedAdminUser(t, srv)
+	registerUser(t, srv, "target@example.com", models.RoleStudent)
+
+	for i := range 3 {
+		if rec := loginFrom(t, srv, "198.51.100.1:1000", "target@example.com", "guess"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: expected 401, got %d", i+1, rec.Code)
+		}
+	}
+
+	// Locked: even the right password is refused, from any address.
+	rec := loginFrom(t, srv, "203.0.113.9:1000", "target@example.com", "password123")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("while locked: expected 429, got %d", rec.Code)
+	}
+	if secs, err := strconv.Atoi(rec.Header().Get("Retry-After")); err != nil || secs < 1 || secs > 30 {
+		t.Errorf("Retry-After: got %q", rec.Header().Get("Retry-After"))
+	}
+
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM login_attempts WHERE email = 'target@example.com'`); n != 4 {
+		t.Errorf("audit rows: got %d, want 4 (3 bad passwords + 1 locked)", n)
+	}
+
+	var userID string
+	srv.DB.QueryRow(`SELECT id FROM users WHERE email = 'target@example.com'`).Scan(&userID)
+	req := ctxWithUser(httptest.NewRequest(http.MethodPost, "/", nil), adminID, "admin")
+	req.SetPathValue("id", userID)
+	rec = httptest.NewRecorder()
+	srv.UnlockUser(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("unlock: expected 204, got %d", rec.Code)
+	}
+	if rec := loginFrom(t, srv, "203.0.113.9:1000", "target@example.com", "password123"); rec.Code != http.StatusOK {
+		t.Errorf("after unlock: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestLogin_SuccessResetsAccountCounter(t *testing.T) {
+	srv := newTestServer(t)
+	srv.LoginThrottle = LoginThrottlePolicy{AccountThreshold: 3}
+	registerUser(t, srv, "typo@example.com", models.RoleStudent)
+
+	for range 2 {
+		loginFrom(t, srv, "198.51.100.1:1000", "typo@example.com", "typo")
+		if rec := loginFrom(t, srv, "198.51.100.1:1000", "typo@example.com", "password123"); rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+	}
+}
+
+// TestLogin_ParallelGuessesAreCounted fires a burst of wrong passwords at
+// one account at once.  Only the first AccountThreshold may be checked; the
+// rest must be refused, even though none had failed when they arrived.
+func TestLogin_ParallelGuessesAreCounted(t *testing.T) {
+	const threshold, guesses = 3, 30
+	srv := newFileTestServer(t)
+	srv.LoginThrottle = LoginThrottlePolicy{AccountThreshold: threshold}
+	registerUser(t, srv, "burst@example.com", models.RoleStudent)
+
+	var wg sync.WaitGroup
+	codes := make(chan int, guesses)
+	for i := range guesses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes <- loginFrom(t, srv, "198.51.100."+strconv.Itoa(i+1)+":1000", "burst@example.com", "guess").Code
+		}(i)
+	}
+	wg.Wait()
+	close(codes)
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	if counts[http.StatusUnauthorized] != threshold || counts[http.StatusTooManyRequests] != guesses-threshold {
+		t.Errorf("expected %d checked and %d refused, got %v", threshold, guesses-threshold, counts)
+	}
+}
+
+func TestLogin_RightPasswordIsNotCounted(t *testing.T) {
+	srv := newTestServer(t)
+	srv.LoginThrottle = LoginThrottlePolicy{IPThreshold: 3}
+	registerUser(t, srv, "regular@example.com", models.RoleStudent)
+
+	for i := range 5 {
+		if rec := loginFrom(t, srv, "198.51.100.9:1000", "regular@example.com", "password123"); rec.Code != http.StatusOK {
+			t.Fatalf("login %d: expected 200, got %d", i+1, rec.Code)
+		}
+	}
+	if n := dbInt(t, srv, `SELECT COALESCE(MAX(failures), 0) FROM login_throttle WHERE key = ?`, ipKey("198.51.100.9")); n != 0 {
+		t.Errorf("IP counter after good logins: got %d, want 0", n)
+	}
+}
+
+func TestLogin_LocksIPAcrossAccounts(t *testing.T) {
+	srv := newTestServer(t)
+	srv.LoginThrottle = LoginThrottlePolicy{IPThreshold: 4}
+	registerUser(t, srv, "bystander@example.com", models.RoleStudent)
+
+	for i := range 4 {
+		email := "victim" + strconv.Itoa(i) + "@example.com"
+		loginFrom(t, srv, "198.51.100.7:1000", email, "guess")
+	}
+	if rec := loginFrom(t, srv, "198.51.100.7:2000", "bystander@example.com", "password123"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("same IP: expected 429, got %d", rec.Code)
+	}
+	if rec := loginFrom(t, srv, "198.51.100.8:1000", "bystander@example.com", "password123"); rec.Code != http.StatusOK {
+		t.Errorf("other IP: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestListLoginAttempts(t *testing.T) {
+	srv := newTestServer(t)
+	adminID := seedAdminUser(t, srv)
+	loginFrom(t, srv, "198.51.100.1:1000", "a@example.com", "x")
+	loginFrom(t, srv, "198.51.100.2:1000", "b@example.com", "x")
+	loginFrom(t, srv, "198.51.100.2:1000", "admin@example.com", "wrong-password")
+
+	list := func(query string) models.LoginAttemptList {
+		t.Helper()
+		req := ctxWithUser(httptest.NewRequest(http.MethodGet, "/api/admin/login-attempts?"+query, nil), adminID, "admin")
+		rec := httptest.NewRecorder()
+		srv.ListLoginAttempts(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		var out models.LoginAttemptList
+		json.NewDecoder(rec.Body).Decode(&out)
+		return out
+	}
+
+	all := list("")
+	if all.Total != 3 || all.Attempts[0].Email != "admin@example.com" {
+		t.Fatalf("expected 3 attempts newest first, got %+v", all)
+	}
+	if all.Attempts[0].Reason != "bad_password" || all.Attempts[0].UserID != adminID {
+		t.Errorf("known account: got %+v", all.Attempts[0])
+	}
+	if all.Attempts[1].Reason != "unknown_email" || all.Attempts[1].UserID != "" {
+		t.Errorf("unknown email: got %+v", all.Attempts[1])
+	}
+	if got := list("ip=198.51.100.2"); got.Total != 2 {
+		t.Errorf("ip filter: total=%d, want 2", got.Total)
+	}
+	if got := list("email=A@example.com"); got.Total != 1 {
+		t.Errorf("email filter: total=%d, want 1", got.Total)
+	}
+}
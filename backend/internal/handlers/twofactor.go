@@ -0,0 +1,774 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — two-step login
+// ────────────────────────────────────────────────────────────────────
+// With 2FA on, a correct password is no longer enough to get a session.
+// Login answers with a TwoFactorChallenge instead: an opaque token that
+// proves "this person knew the password a moment ago" and nothing else.  It
+// cannot call the API; it can only be exchanged, together with a code from
+// the authenticator app (auth/totp.go) or a recovery code, for a real
+// session at POST /api/auth/login/2fa.
+//
+// The challenge lives in login_challenges (hashed, like every token we
+// hand out), expires after loginChallengeTTL and allows maxChallengeAttempts
+// wrong codes.  Wrong codes also count as failed logins in throttle.go, and
+// the account's failure counter is only cleared once the whole login
+// succeeds — otherwise re-entering the password would reset it and give an
+// attacker who knows the password unlimited guesses at the code.
+//
+// Admins can require 2FA for a role (settings table).  A member of that
+// role without 2FA still gets a challenge, flagged enrollment_required; they
+// set up their authenticator with POST /api/auth/login/2fa/enroll and their
+// first code both finishes enrollment and logs them in.
+
+const (
+	loginChallengeTTL    = 5 * time.Minute
+	maxChallengeAttempts = 5
+	recoveryCodeCount    = 10
+	// totpIssuer is the account label shown in authenticator apps.
+	totpIssuer = "Skillzone"
+	// totpSkew accepts codes one step (30 s) either side of now.
+	totpSkew = 1
+	// settingTwoFactorRoles holds a comma-separated list of roles that must
+	// use 2FA.
+	settingTwoFactorRoles = "two_factor_required_roles"
+)
+
+// twoFactorState is a user's 2FA columns.
+type twoFactorState struct {
+	// secret is the TOTP secret, set while enrollment is pending or once
+	// enabled; empty when 2FA has never been set up.
+	secret   string
+	enabled  bool
+	lastStep int64
+}
+
+func (s *Server) loadTwoFactor(ctx context.Context, userID string) (twoFactorState, error) {
+	var secret sql.NullString
+	var enabledAt sql.NullTime
+	var tf twoFactorState
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT totp_secret, totp_enabled_at, totp_last_step FROM users WHERE id = ?`, userID,
+	).Scan(&secret, &enabledAt, &tf.lastStep)
+	tf.secret, tf.enabled = secret.String, enabledAt.Valid
+	return tf, err
+}
+
+// twoFactorRequiredRoles returns the roles an admin has made 2FA mandatory
+// for.
+func (s *Server) twoFactorRequiredRoles(ctx context.Context) ([]models.UserRole, error) {
+	value, _, err := s.getSetting(ctx, settingTwoFactorRoles)
+	if err != nil {
+		return nil, err
+	}
+	roles := []models.UserRole{}
+	for _, r := range strings.Split(value, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			roles = append(roles, models.UserRole(r))
+		}
+	}
+	return roles, nil
+}
+
+func (s *Server) roleRequiresTwoFactor(ctx context.Context, role models.UserRole) (bool, error) {
+	roles, err := s.twoFactorRequiredRoles(ctx)
+	return slices.Contains(roles, role), err
+}
+
+// finishLogin is the last step of every handler that has just checked a
+// password (Register, Login): it either starts a session or, when 2FA is
+// on or required, hands out a challenge instead.
+func (s *Server) finishLogin(w http.ResponseWriter, r *http.Request, user models.User, status int) {
+	ctx := r.Context()
+	tf, err := s.loadTwoFactor(ctx, user.ID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	required, err := s.roleRequiresTwoFactor(ctx, user.Role)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if tf.enabled || required {
+		token, hash, err := auth.NewOpaqueToken()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "could not generate token")
+			return
+		}
+		now := time.Now().UTC()
+		if _, err := s.DB.ExecContext(ctx,
+			`INSERT INTO login_challenges (id, user_id, token_hash, created_at, expires_at)
+			 VALUES (?, ?, ?, ?, ?)`,
+			uuid.NewString(), user.ID, hash, now, now.Add(loginChallengeTTL),
+		); err != nil {
+			respondError(w, http.StatusInternalServerError, "could not start login")
+			return
+		}
+		respond(w, status, models.TwoFactorChallenge{
+			TwoFactorRequired:  true,
+			EnrollmentRequired: !tf.enabled,
+			ChallengeToken:     token,
+			ExpiresIn:          int(loginChallengeTTL.Seconds()),
+		})
+		return
+	}
+
+	if err := s.clearLoginFailures(ctx, user.Email); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	resp, err := s.issueSession(r, user)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not start session")
+		return
+	}
+	respond(w, status, resp)
+}
+
+var errBadChallenge = errors.New("invalid or expired challenge")
+
+// loadChallenge returns the challenge and user behind token, or
+// errBadChallenge if it is unknown, used, expired or out of attempts.
+func (s *Server) loadChallenge(ctx context.Context, token string) (challengeID, userID string, err error) {
+	var expiresAt time.Time
+	var attempts int
+	var usedAt sql.NullTime
+	err = s.DB.QueryRowContext(ctx,
+		`SELECT id, user_id, expires_at, attempts, used_at FROM login_challenges WHERE token_hash = ?`,
+		auth.HashOpaqueToken(token),
+	).Scan(&challengeID, &userID, &expiresAt, &attempts, &usedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", errBadChallenge
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if usedAt.Valid || isExpired(expiresAt, time.Now()) || attempts >= maxChallengeAttempts {
+		return "", "", errBadChallenge
+	}
+	return challengeID, userID, nil
+}
+
+// verifySecondFactor checks code against the user's authenticator and, if
+// allowRecovery, their unused recovery codes.  A matching code is consumed:
+// the TOTP step is recorded so the same six digits cannot be replayed, or
+// the recovery code is marked used.
+func (s *Server) verifySecondFactor(ctx context.Context, userID string, tf twoFactorState, code string, allowRecovery bool) (bool, error) {
+	if tf.secret == "" {
+		return false, nil
+	}
+	if step, ok := auth.VerifyTOTP(tf.secret, code, time.Now(), totpSkew); ok {
+		result, err := s.DB.ExecContext(ctx,
+			`UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?`, step, userID, step)
+		if err != nil {
+			return false, err
+		}
+		n, _ := result.RowsAffected()
+		return n == 1, nil
+	}
+	if !allowRecovery || !tf.enabled {
+		return false, nil
+	}
+	result, err := s.DB.ExecContext(ctx,
+		`UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`,
+		time.Now().UTC(), userID, auth.HashOpaqueToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// recoveryCodeEncoding renders recovery codes in lowercase base32, which
+// has no easily confused characters like 0/O or 1/l.
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// normalizeRecoveryCode accepts codes typed with any case, spaces or dashes.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores a fresh
+// set, returning them in the "xxxxx-xxxxx" form shown to the user.
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string) ([]string, error) {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := recoveryCodeEncoding.EncodeToString(buf)[:10]
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO recovery_codes (id, user_id, code_hash, created_at) VALUES (?, ?, ?, ?)`,
+			uuid.NewString(), userID, auth.HashOpaqueToken(raw), now,
+		); err != nil {
+			return nil, err
+		}
+		codes[i] = raw[:5] + "-" + raw[5:]
+	}
+	return codes, nil
+}
+
+// enableTwoFactor switches 2FA on for a user whose pending secret has just
+// been confirmed, and returns their first recovery codes.
+func (s *Server) enableTwoFactor(ctx context.Context, userID string) ([]string, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	now := time.Now().UTC()
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET totp_enabled_at = ?, updated_at = ? WHERE id = ?`, now, now, userID,
+	); err != nil {
+		return nil, err
+	}
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit()
+}
+
+// clearTwoFactor switches 2FA off and forgets the secret and recovery codes.
+func (s *Server) clearTwoFactor(ctx context.Context, userID string) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, updated_at = ?
+		 WHERE id = ?`, time.Now().UTC(), userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// startEnrollment stores a new pending TOTP secret for a user who does not
+// have 2FA on yet.
+func (s *Server) startEnrollment(ctx context.Context, userID, email string) (models.TwoFactorEnrollment, error) {
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return models.TwoFactorEnrollment{}, err
+	}
+	if _, err := s.DB.ExecContext(ctx,
+		`UPDATE users SET totp_secret = ?, totp_last_step = 0 WHERE id = ? AND totp_enabled_at IS NULL`,
+		secret, userID,
+	); err != nil {
+		return models.TwoFactorEnrollment{}, err
+	}
+	return models.TwoFactorEnrollment{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(totpIssuer, email, secret),
+	}, nil
+}
+
+// LoginTwoFactor handles POST /api/auth/login/2fa
+//
+// Body: {"challenge_token": "...", "code": "123456"}.  The code may also be
+// a recovery code.  If the challenge was flagged enrollment_required, the
+// code must come from the secret handed out by LoginTwoFactorEnroll; 2FA is
+// then switched on and the response carries the new recovery_codes.
+func (s *Server) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginTwoFactorRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	ctx := r.Context()
+
+	challengeID, userID, err := s.loadChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		if errors.Is(err, errBadChallenge) {
+			respondError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	var user models.User
+	err = s.DB.QueryRowContext(ctx,
+		`SELECT id, email, name, role, status, created_at, updated_at FROM users WHERE id = ?`, userID,
+	).Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.Status, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	switch user.Status {
+	case models.UserActive:
+	case models.UserSuspended:
+		respondError(w, http.StatusForbidden, "account suspended")
+		return
+	default:
+		respondError(w, http.StatusUnauthorized, errBadChallenge.Error())
+		return
+	}
+
+	tf, err := s.loadTwoFactor(ctx, user.ID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if tf.secret == "" {
+		respondError(w, http.StatusBadRequest, "set up two-factor authentication first")
+		return
+	}
+
+	wait, err := s.claimLoginAttempt(r, user.Email, user.ID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if wait > 0 {
+		respondTooManyAttempts(w, wait)
+		return
+	}
+	ok, err := s.verifySecondFactor(ctx, user.ID, tf, req.Code, tf.enabled)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if !ok {
+		s.DB.ExecContext(ctx, //nolint:errcheck
+			`UPDATE login_challenges SET attempts = attempts + 1 WHERE id = ?`, challengeID)
+		if err := s.recordLoginFailure(r, user.Email, user.ID, attemptBadSecondFactor); err != nil {
+			respondError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		respondError(w, http.StatusUnauthorized, "invalid code")
+		return
+	}
+
+	if err := s.refundLoginAttempt(ctx, user.Email, clientIP(r)); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	// Compare-and-swap so one challenge yields at most one session.
+	result, err := s.DB.ExecContext(ctx,
+		`UPDATE login_challenges SET used_at = ? WHERE id = ? AND used_at IS NULL`, time.Now().UTC(), challengeID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusUnauthorized, errBadChallenge.Error())
+		return
+	}
+
+	var codes []string
+	if !tf.enabled {
+		if codes, err = s.enableTwoFactor(ctx, user.ID); err != nil {
+			respondError(w, http.StatusInternalServerError, "could not enable two-factor authentication")
+			return
+		}
+	}
+	if err := s.clearLoginFailures(ctx, user.Email); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	resp, err := s.issueSession(r, user)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not start session")
+		return
+	}
+	resp.RecoveryCodes = codes
+	respond(w, http.StatusOK, resp)
+}
+
+// LoginTwoFactorEnroll handles POST /api/auth/login/2fa/enroll
+//
+// Body: {"challenge_token": "..."}.  For a challenge flagged
+// enrollment_required: returns a new TOTP secret to add to an authenticator
+// app.  The challenge stays valid for the code that follows.
+func (s *Server) LoginTwoFactorEnroll(w http.ResponseWriter, r *http.Request) {
+	var req models.ChallengeRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	ctx := r.Context()
+
+	_, userID, err := s.loadChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		if errors.Is(err, errBadChallenge) {
+			respondError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	s.respondEnrollment(w, r, userID)
+}
+
+// EnrollTwoFactor handles POST /api/auth/2fa/enroll
+// Starts turning on 2FA for the logged-in user: returns a new secret, which
+// only takes effect once a code from it is sent to ConfirmTwoFactor.
+// Calling it again replaces the pending secret.
+func (s *Server) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	s.respondEnrollment(w, r, middleware.GetUserID(r.Context()))
+}
+
+func (s *Server) respondEnrollment(w http.ResponseWriter, r *http.Request, userID string) {
+	ctx := r.Context()
+	tf, err := s.loadTwoFactor(ctx, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if tf.enabled {
+		respondError(w, http.StatusConflict, "two-factor authentication is already on")
+		return
+	}
+	var email string
+	if err := s.DB.QueryRowContext(ctx, `SELECT email FROM users WHERE id = ?`, userID).Scan(&email); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	enrollment, err := s.startEnrollment(ctx, userID, email)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not start enrollment")
+		return
+	}
+	respond(w, http.StatusOK, enrollment)
+}
+
+// claimSessionAttempt counts a code or password check made from a logged-in
+// session against the user's login lockout (throttle.go), exactly as a
+// login would.  Without it, a stolen session alone could try every code.
+// When the account or IP is locked it answers 429 and returns false;
+// otherwise it returns the user's email, the key the attempt was counted
+// under.
+func (s *Server) claimSessionAttempt(w http.ResponseWriter, r *http.Request, userID string) (string, bool) {
+	var email string
+	if err := s.DB.QueryRowContext(r.Context(),
+		`SELECT email FROM users WHERE id = ?`, userID,
+	).Scan(&email); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return "", false
+	}
+	wait, err := s.claimLoginAttempt(r, email, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return "", false
+	}
+	if wait > 0 {
+		respondTooManyAttempts(w, wait)
+		return "", false
+	}
+	return email, true
+}
+
+// recordSessionFailure audits a wrong code or password checked after
+// claimSessionAttempt.  It reports whether the caller should go on to
+// answer; on a database error it has already answered 500.
+func (s *Server) recordSessionFailure(w http.ResponseWriter, r *http.Request, email, userID, reason string) bool {
+	if err := s.recordLoginFailure(r, email, userID, reason); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return false
+	}
+	return true
+}
+
+// ConfirmTwoFactor handles POST /api/auth/2fa/confirm
+//
+// Body: {"code": "123456"} from the secret EnrollTwoFactor returned.
+// Switches 2FA on and returns the recovery codes — the only time they are
+// shown.  Wrong codes count towards the login lockout (claimSessionAttempt).
+func (s *Server) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	var req models.TwoFactorCodeRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	tf, err := s.loadTwoFactor(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if tf.enabled {
+		respondError(w, http.StatusConflict, "two-factor authentication is already on")
+		return
+	}
+	if tf.secret == "" {
+		respondError(w, http.StatusBadRequest, "start enrollment first")
+		return
+	}
+	email, claimed := s.claimSessionAttempt(w, r, userID)
+	if !claimed {
+		return
+	}
+	ok, err := s.verifySecondFactor(r.Context(), userID, tf, req.Code, false)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if !ok {
+		if s.recordSessionFailure(w, r, email, userID, attemptBadSecondFactor) {
+			respondError(w, http.StatusBadRequest, "invalid code")
+		}
+		return
+	}
+	if err := s.refundLoginAttempt(r.Context(), email, clientIP(r)); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	codes, err := s.enableTwoFactor(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "could not enable two-factor authentication")
+		return
+	}
+	respond(w, http.StatusOK, models.RecoveryCodes{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor handles POST /api/auth/2fa/disable
+//
+// Body: {"password": "...", "code": "..."}.  Both factors are needed, so a
+// stolen session alone cannot turn 2FA off, and wrong guesses at either
+// count towards the login lockout.  Refused with 403 when the user's role
+// requires 2FA.
+func (s *Server) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := middleware.GetUserID(ctx)
+	var req models.DisableTwoFactorRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	if required, err := s.roleRequiresTwoFactor(ctx, models.UserRole(middleware.GetRole(ctx))); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	} else if required {
+		respondError(w, http.StatusForbidden, "two-factor authentication is required for your role")
+		return
+	}
+	tf, err := s.loadTwoFactor(ctx, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if !tf.enabled {
+		respondError(w, http.StatusConflict, "two-factor authentication is not on")
+		return
+	}
+	email, claimed := s.claimSessionAttempt(w, r, userID)
+	if !claimed {
+		return
+	}
+
+	var hash string
+	if err := s.DB.QueryRowContext(ctx, `SELECT password_hash FROM users WHERE id = ?`, userID).Scan(&hash); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := auth.CheckPassword(hash, req.Password); err != nil {
+		if s.recordSessionFailure(w, r, email, userID, attemptBadPassword) {
+			respondError(w, http.StatusForbidden, "password is incorrect")
+		}
+		return
+	}
+	ok, err := s.verifySecondFactor(ctx, userID, tf, req.Code, true)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if !ok {
+		if s.recordSessionFailure(w, r, email, userID, attemptBadSecondFactor) {
+			respondError(w, http.StatusForbidden, "invalid code")
+		}
+		return
+	}
+	if err := s.refundLoginAttempt(ctx, email, clientIP(r)); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if err := s.clearTwoFactor(ctx, userID); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not disable two-factor authentication")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes handles POST /api/auth/2fa/recovery-codes
+//
+// Body: {"code": "123456"} — an authenticator code, not a recovery code.
+// Replaces every recovery code with a fresh set.  Wrong codes count towards
+// the login lockout, as for DisableTwoFactor.
+func (s *Server) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := middleware.GetUserID(ctx)
+	var req models.TwoFactorCodeRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	tf, err := s.loadTwoFactor(ctx, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if !tf.enabled {
+		respondError(w, http.StatusConflict, "two-factor authentication is not on")
+		return
+	}
+	email, claimed := s.claimSessionAttempt(w, r, userID)
+	if !claimed {
+		return
+	}
+	ok, err := s.verifySecondFactor(ctx, userID, tf, req.Code, false)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if !ok {
+		if s.recordSessionFailure(w, r, email, userID, attemptBadSecondFactor) {
+			respondError(w, http.StatusForbidden, "invalid code")
+		}
+		return
+	}
+	if err := s.refundLoginAttempt(ctx, email, clientIP(r)); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil || tx.Commit() != nil {
+		respondError(w, http.StatusInternalServerError, "could not create recovery codes")
+		return
+	}
+	respond(w, http.StatusOK, models.RecoveryCodes{RecoveryCodes: codes})
+}
+
+// GetTwoFactorPolicy handles GET /api/admin/two-factor-policy  (admin only)
+func (s *Server) GetTwoFactorPolicy(w http.ResponseWriter, r *http.Request) {
+	roles, err := s.twoFactorRequiredRoles(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	respond(w, http.StatusOK, models.TwoFactorPolicy{RequiredRoles: roles})
+}
+
+// UpdateTwoFactorPolicy handles PUT /api/admin/two-factor-policy  (admin only)
+//
+// Body: {"required_roles": ["company"]}.  Members of a newly required role
+// who have no 2FA yet are logged out, and must enroll at their next login.
+func (s *Server) UpdateTwoFactorPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req models.TwoFactorPolicy
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	roles := []models.UserRole{}
+	for _, role := range req.RequiredRoles {
+		if !validRole(role) {
+			respondError(w, http.StatusBadRequest, "role must be 'student', 'company' or 'admin'")
+			return
+		}
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+
+	previous, err := s.twoFactorRequiredRoles(ctx)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role)
+	}
+	if err := s.putSetting(ctx, settingTwoFactorRoles, strings.Join(names, ",")); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not save policy")
+		return
+	}
+
+	for _, role := range roles {
+		if slices.Contains(previous, role) {
+			continue
+		}
+		if _, err := s.DB.ExecContext(ctx,
+			`UPDATE sessions SET revoked_at = ?
+			 WHERE revoked_at IS NULL
+			   AND user_id IN (SELECT id FROM users WHERE role = ? AND totp_enabled_at IS NULL)`,
+			time.Now().UTC(), role,
+		); err != nil {
+			respondError(w, http.StatusInternalServerError, "could not revoke sessions")
+			return
+		}
+		s.authCache.forgetAll()
+	}
+
+	respond(w, http.StatusOK, models.TwoFactorPolicy{RequiredRoles: roles})
+}
+
+// ResetUserTwoFactor handles DELETE /api/admin/users/{id}/2fa  (admin only)
+// For a user who lost both their phone and their recovery codes: switches
+// 2FA off and logs them out everywhere.  If their role requires 2FA they
+// set it up again at their next login.
+func (s *Server) ResetUserTwoFactor(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var exists int
+	if err := s.DB.QueryRowContext(r.Context(), `SELECT COUNT(*) FROM users WHERE id = ?`, id).Scan(&exists); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if exists == 0 {
+		respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	if err := s.clearTwoFactor(r.Context(), id); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not reset two-factor authentication")
+		return
+	}
+	if err := s.revokeUserSessions(r.Context(), id); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not revoke sessions")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
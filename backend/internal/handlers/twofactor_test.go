@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+)
+
+// totpNow returns the authenticator code for secret, offset steps from now.
+func totpNow(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now())+offset)
+	if err != nil {
+		t.Fatalf("TOTPCode: %v", err)
+	}
+	return code
+}
+
+// turnOnTwoFactor enrolls the session's user and returns the secret and
+// recovery codes.  The code for the current step is used up.
+func turnOnTwoFactor(t *testing.T, srv *Server, token string) (string, []string) {
+	t.Helper()
+	req := ctxWithSession(t, httptest.NewRequest(http.MethodPost, "/api/auth/2fa/enroll", nil), token)
+	rec := httptest.NewRecorder()
+	srv.EnrollTwoFactor(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("enroll: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var enrollment models.TwoFactorEnrollment
+	json.NewDecoder(rec.Body).Decode(&enrollment)
+
+	req = ctxWithSession(t, httptest.NewRequest(http.MethodPost, "/api/auth/2fa/confirm",
+		jsonBody(t, models.TwoFactorCodeRequest{Code: totpNow(t, enrollment.Secret, 0)})), token)
+	rec = httptest.NewRecorder()
+	srv.ConfirmTwoFactor(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("confirm: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var codes models.RecoveryCodes
+	json.NewDecoder(rec.Body).Decode(&codes)
+	return enrollment.Secret, codes.RecoveryCodes
+}
+
+// startLogin logs in with the password and expects a 2FA challenge.
+func startLogin(t *testing.T, srv *Server, email, password string) models.TwoFactorChallenge {
+	t.Helper()
+	rec := loginFrom(t, srv, "192.0.2.1:1234", email, password)
+	var ch models.TwoFactorChallenge
+	json.NewDecoder(rec.Body).Decode(&ch)
+	if rec.Code != http.StatusOK || !ch.TwoFactorRequired || ch.ChallengeToken == "" {
+		t.Fatalf("expected a 2FA challenge, got %d %+v", rec.Code, ch)
+	}
+	return ch
+}
+
+func loginSecondStep(t *testing.T, srv *Server, challenge, code string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login/2fa",
+		jsonBody(t, models.LoginTwoFactorRequest{ChallengeToken: challenge, Code: code}))
+	req.RemoteAddr = "192.0.2.1:1234"
+	rec := httptest.NewRecorder()
+	srv.LoginTwoFactor(rec, req)
+	return rec
+}
+
+func TestTwoFactor_EnrollAndLogin(t *testing.T) {
+	srv := newTestServer(t)
+	login := registerUser(t, srv, "totp@example.com", models.RoleStudent)
+	secret, recovery := turnOnTwoFactor(t, srv, login.Token)
+	if len(recovery) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %v", recoveryCodeCount, recovery)
+	}
+
+	me := httptest.NewRecorder()
+	srv.Me(me, ctxWithSession(t, httptest.NewRequest(http.MethodGet, "/api/auth/me", nil), login.Token))
+	var u models.User
+	json.NewDecoder(me.Body).Decode(&u)
+	if !u.TwoFactorEnabled {
+		t.Error("profile does not report two_factor_enabled")
+	}
+
+	ch := startLogin(t, srv, "totp@example.com", "password123")
+	if ch.EnrollmentRequired {
+		t.Error("enrolled user asked to enroll")
+	}
+	// The current code was spent confirming enrollment.
+	if rec := loginSecondStep(t, srv, ch.ChallengeToken, totpNow(t, secret, 0)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("replayed code: expected 401, got %d", rec.Code)
+	}
+	rec := loginSecondStep(t, srv, ch.ChallengeToken, totpNow(t, secret, 1))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("second step: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp models.LoginResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Token == "" || resp.RefreshToken == "" {
+		t.Errorf("no session issued: %+v", resp)
+	}
+	if rec := loginSecondStep(t, srv, ch.ChallengeToken, totpNow(t, secret, -1)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("reused challenge: expected 401, got %d", rec.Code)
+	}
+
+	// A recovery code works once, typed in any case.
+	ch = startLogin(t, srv, "totp@example.com", "password123")
+	if rec := loginSecondStep(t, srv, ch.ChallengeToken, " "+recovery[0]+" "); rec.Code != http.StatusOK {
+		t.Fatalf("recovery code: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	ch = startLogin(t, srv, "totp@example.com", "password123")
+	if rec := loginSecondStep(t, srv, ch.ChallengeToken, recovery[0]); rec.Code != http.StatusUnauthorized {
+		t.Errorf("reused recovery code: expected 401, got %d", rec.Code)
+	}
+}
+
+func TestTwoFactor_WrongCodesAreLimited(t *testing.T) {
+	srv := newTestServer(t)
+	srv.LoginThrottle.AccountThreshold = 100
+	login := registerUser(t, srv, "guess@example.com", models.RoleStudent)
+	secret, _ := turnOnTwoFactor(t, srv, login.Token)
+
+	ch := startLogin(t, srv, "guess@example.com", "password123")
+	for i := 0; i < maxChallengeAttempts; i++ {
+		if rec := loginSecondStep(t, srv, ch.ChallengeToken, "000000"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("wrong code %d: expected 401, got %d", i, rec.Code)
+		}
+	}
+	if rec := loginSecondStep(t, srv, ch.ChallengeToken, totpNow(t, secret, 1)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("exhausted challenge: expected 401, got %d", rec.Code)
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM login_attempts WHERE reason = ?`, attemptBadSecondFactor); n != maxChallengeAttempts {
+		t.Errorf("expected %d audited failures, got %d", maxChallengeAttempts, n)
+	}
+	// Getting the password right again must not reset the failure counter.
+	startLogin(t, srv, "guess@example.com", "password123")
+	if n := dbInt(t, srv, `SELECT failures FROM login_throttle WHERE key = ?`, accountKey("guess@example.com")); n != maxChallengeAttempts {
+		t.Errorf("failure counter: got %d, want %d", n, maxChallengeAttempts)
+	}
+}
+
+func TestTwoFactor_SessionCodeChecksAreLimited(t *testing.T) {
+	srv := newTestServer(t)
+	srv.LoginThrottle.AccountThreshold = 3
+	login := registerUser(t, srv, "stolen@example.com", models.RoleStudent)
+	secret, _ := turnOnTwoFactor(t, srv, login.Token)
+
+	post := func(handler http.HandlerFunc, path string, body any) *httptest.ResponseRecorder {
+		req := ctxWithSession(t, httptest.NewRequest(http.MethodPost, path, jsonBody(t, body)), login.Token)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	regenerate := func(code string) *httptest.ResponseRecorder {
+		return post(srv.RegenerateRecoveryCodes, "/api/auth/2fa/recovery-codes", models.TwoFactorCodeRequest{Code: code})
+	}
+	for i := range 3 {
+		if rec := regenerate("000000"); rec.Code != http.StatusForbidden {
+			t.Fatalf("wrong code %d: expected 403, got %d", i+1, rec.Code)
+		}
+	}
+	// Locked now: not even the right code is checked.
+	rec := regenerate(totpNow(t, secret, 1))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("4th attempt: expected 429 with Retry-After, got %d", rec.Code)
+	}
+	rec = post(srv.DisableTwoFactor, "/api/auth/2fa/disable",
+		models.DisableTwoFactorRequest{Password: "password123", Code: totpNow(t, secret, 1)})
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("disable while locked: expected 429, got %d", rec.Code)
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM login_attempts WHERE reason = ?`, attemptBadSecondFactor); n != 3 {
+		t.Errorf("expected 3 audited failures, got %d", n)
+	}
+
+	// Enrollment is limited the same way.
+	other := registerUser(t, srv, "enrolling@example.com", models.RoleStudent)
+	req := ctxWithSession(t, httptest.NewRequest(http.MethodPost, "/api/auth/2fa/enroll", nil), other.Token)
+	srv.EnrollTwoFactor(httptest.NewRecorder(), req)
+	for i := range 4 {
+		req := ctxWithSession(t, httptest.NewRequest(http.MethodPost, "/api/auth/2fa/confirm",
+			jsonBody(t, models.TwoFactorCodeRequest{Code: "000000"})), other.Token)
+		rec := httptest.NewRecorder()
+		srv.ConfirmTwoFactor(rec, req)
+		want := http.StatusBadRequest
+		if i == 3 {
+			want = http.StatusTooManyRequests
+		}
+		if rec.Code != want {
+			t.Errorf("confirm %d: expected %d, got %d", i+1, want, rec.Code)
+		}
+	}
+}
+
+func TestTwoFactor_RequiredForRole(t *testing.T) {
+	srv := newTestServer(t)
+	adminID := seedAdminUser(t, srv)
+	company := registerUser(t, srv, "corp@example.com", models.RoleCompany)
+
+	req := ctxWithUser(httptest.NewRequest(http.MethodPut, "/api/admin/two-factor-policy",
+		jsonBody(t, models.TwoFactorPolicy{RequiredRoles: []models.UserRole{"company", "company"}})), adminID, "admin")
+	rec := httptest.NewRecorder()
+	srv.UpdateTwoFactorPolicy(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("policy: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var policy models.TwoFactorPolicy
+	json.NewDecoder(rec.Body).Decode(&policy)
+	if len(policy.RequiredRoles) != 1 || policy.RequiredRoles[0] != models.RoleCompany {
+		t.Errorf("policy: got %+v", policy.RequiredRoles)
+	}
+
+	claims, _ := auth.ParseToken(company.Token, testKeys)
+	if state, _ := srv.SessionState(context.Background(), claims.SessionID); state.Active {
+		t.Error("un-enrolled company session survived the new policy")
+	}
+
+	ch := startLogin(t, srv, "corp@example.com", "password123")
+	if !ch.EnrollmentRequired {
+		t.Fatal("expected enrollment_required")
+	}
+	if rec := loginSecondStep(t, srv, ch.ChallengeToken, "123456"); rec.Code != http.StatusBadRequest {
+		t.Errorf("code before enrolling: expected 400, got %d", rec.Code)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/auth/login/2fa/enroll",
+		jsonBody(t, models.ChallengeRequest{ChallengeToken: ch.ChallengeToken}))
+	rec = httptest.NewRecorder()
+	srv.LoginTwoFactorEnroll(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("enroll: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var enrollment models.TwoFactorEnrollment
+	json.NewDecoder(rec.Body).Decode(&enrollment)
+
+	rec = loginSecondStep(t, srv, ch.ChallengeToken, totpNow(t, enrollment.Secret, 0))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("second step: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp models.LoginResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.RecoveryCodes) != recoveryCodeCount {
+		t.Errorf("expected recovery codes with the enrolling login, got %v", resp.RecoveryCodes)
+	}
+
+	req = ctxWithSession(t, httptest.NewRequest(http.MethodPost, "/api/auth/2fa/disable",
+		jsonBody(t, models.DisableTwoFactorRequest{Password: "password123", Code: resp.RecoveryCodes[0]})), resp.Token)
+	rec = httptest.NewRecorder()
+	srv.DisableTwoFactor(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("disable under policy: expected 403, got %d", rec.Code)
+	}
+}
+
+func TestTwoFactor_DisableAndAdminReset(t *testing.T) {
+	srv := newTestServer(t)
+	adminID := seedAdminUser(t, srv)
+	login := registerUser(t, srv, "off@example.com", models.RoleStudent)
+	secret, recovery := turnOnTwoFactor(t, srv, login.Token)
+
+	disable := func(password, code string) int {
+		req := ctxWithSession(t, httptest.NewRequest(http.MethodPost, "/api/auth/2fa/disable",
+			jsonBody(t, models.DisableTwoFactorRequest{Password: password, Code: code})), login.Token)
+		rec := httptest.NewRecorder()
+		srv.DisableTwoFactor(rec, req)
+		return rec.Code
+	}
+	if code := disable("wrong-password", recovery[0]); code != http.StatusForbidden {
+		t.Errorf("wrong password: expected 403, got %d", code)
+	}
+	if code := disable("password123", "000000"); code != http.StatusForbidden {
+		t.Errorf("wrong code: expected 403, got %d", code)
+	}
+	if code := disable("password123", totpNow(t, secret, 1)); code != http.StatusNoContent {
+		t.Fatalf("disable: expected 204, got %d", code)
+	}
+	if code := loginStatus(t, srv, "off@example.com", "password123"); code != http.StatusOK {
+		t.Fatalf("login after disable: expected 200, got %d", code)
+	}
+	var challenge models.TwoFactorChallenge
+	rec := loginFrom(t, srv, "192.0.2.1:1234", "off@example.com", "password123")
+	json.NewDecoder(rec.Body).Decode(&challenge)
+	if challenge.TwoFactorRequired {
+		t.Error("still challenged after disabling 2FA")
+	}
+
+	// An admin reset switches 2FA off and logs the user out.
+	fresh := registerUser(t, srv, "lost@example.com", models.RoleStudent)
+	turnOnTwoFactor(t, srv, fresh.Token)
+	req := ctxWithUser(httptest.NewRequest(http.MethodDelete, "/api/admin/users/x/2fa", nil), adminID, "admin")
+	req.SetPathValue("id", fresh.User.ID)
+	rec = httptest.NewRecorder()
+	srv.ResetUserTwoFactor(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("reset: expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM recovery_codes WHERE user_id = ?`, fresh.User.ID); n != 0 {
+		t.Errorf("recovery codes left after reset: %d", n)
+	}
+	claims, _ := auth.ParseToken(fresh.Token, testKeys)
+	if state, _ := srv.SessionState(context.Background(), claims.SessionID); state.Active {
+		t.Error("session survived the 2FA reset")
+	}
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/auth"
+	"github.com/Elizabethomito/skillzone/backend/internal/mail"
+	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — verifying email addresses
+// ────────────────────────────────────────────────────────────────────
+// Register accepts any address, so on its own an account proves nothing
+// about who owns it: anyone can sign up as hr@some-famous-firm.com.  So
+// Register also mails a verification link, and users.email_verified_at is
+// set when it is opened.  Until then the account works, but
+// (EmailVerificationPolicy):
+//
+//   - a company cannot create events, so it cannot advertise under a name
+//     it does not own, and
+//   - a student does not show up in candidate search, so companies only
+//     find people who can be reached at the address shown.
+//
+// The token is handled like a magic-link token (hashed, single-use,
+// bound to the address it was sent to).  Anything else that proves control
+// of the address also marks it verified: confirming an email change, a
+// magic-link login, and single sign-on with a provider-verified email.
+// Accounts that existed before this column was added are treated as
+// verified (see columnBackfills in package db).  A verified student address
+// on a known institution's domain also sets the affiliation
+// (institutions.go).
+
+const (
+	// emailVerificationTTL is how long a verification link works.
+	emailVerificationTTL = 7 * 24 * time.Hour
+	// verificationResendInterval is the minimum gap between two links to
+	// the same account.
+	verificationResendInterval = time.Minute
+)
+
+// EmailVerificationPolicy decides what accounts with an unverified email
+// may do.  The zero value enforces both restrictions.
+type EmailVerificationPolicy struct {
+	// AllowUnverifiedHosts lets unverified companies create events.
+	AllowUnverifiedHosts bool
+	// ListUnverifiedStudents shows unverified students in candidate search.
+	ListUnverifiedStudents bool
+}
+
+// errAlreadyVerified is returned by sendVerificationEmail when there is
+// nothing to verify.
+var errAlreadyVerified = errors.New("email address already verified")
+
+// emailVerified reports whether userID's current email is verified.
+func (s *Server) emailVerified(ctx context.Context, userID string) (bool, error) {
+	var verified bool
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT email_verified_at IS NOT NULL FROM users WHERE id = ?`, userID,
+	).Scan(&verified)
+	return verified, err
+}
+
+// sendVerificationEmail mails userID a link that verifies their current
+// email.  It sends nothing (and returns nil) if a link went out less than
+// verificationResendInterval ago.
+func (s *Server) sendVerificationEmail(ctx context.Context, userID string) error {
+	var email, name string
+	var verifiedAt sql.NullTime
+	if err := s.DB.QueryRowContext(ctx,
+		`SELECT email, name, email_verified_at FROM users WHERE id = ?`, userID,
+	).Scan(&email, &name, &verifiedAt); err != nil {
+		return err
+	}
+	if verifiedAt.Valid {
+		return errAlreadyVerified
+	}
+	now := time.Now().UTC()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var lastSent time.Time
+	err = tx.QueryRowContext(ctx,
+		`SELECT created_at FROM email_verifications WHERE user_id = ? ORDER BY rowid DESC LIMIT 1`, userID,
+	).Scan(&lastSent)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil && now.Sub(lastSent) < verificationResendInterval {
+		return nil
+	}
+
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE email_verifications SET used_at = ? WHERE user_id = ? AND used_at IS NULL`, now, userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO email_verifications (id, user_id, email, token_hash, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		uuid.NewString(), userID, email, hash, now, now.Add(emailVerificationTTL),
+	); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if s.Mailer == nil {
+		return errors.New("no mailer configured")
+	}
+	return s.Mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Verify your Skillzone email address",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Open this link to confirm that this address belongs to you:\n\n%s\n\n"+
+			"The link works for %s.  If you did not create a Skillzone account, ignore this email.\n",
+			name, s.appLink("/verify-email", token), emailVerificationTTL),
+	})
+}
+
+// VerifyEmail handles POST /api/auth/email/verify
+//
+// Body: {"token": "..."} from the emailed link.  Marks the address verified
+// and returns the user's profile.  No login is needed: the token is the
+// proof, and the link may well be opened on another device.
+func (s *Server) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.VerifyEmailRequest
+	if err := decode(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.Token == "" {
+		respondError(w, http.StatusBadRequest, "invalid or expired verification link")
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var linkID, userID, linkEmail, userEmail string
+	var expiresAt time.Time
+	var usedAt sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		`SELECT v.id, v.user_id, v.email, v.expires_at, v.used_at, u.email
+		 FROM email_verifications v JOIN users u ON u.id = v.user_id
+		 WHERE v.token_hash = ?`, auth.HashOpaqueToken(req.Token),
+	).Scan(&linkID, &userID, &linkEmail, &expiresAt, &usedAt, &userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	now := time.Now().UTC()
+	// A link for an address the account no longer has proves nothing.
+	if err != nil || usedAt.Valid || isExpired(expiresAt, now) || linkEmail != userEmail {
+		respondError(w, http.StatusBadRequest, "invalid or expired verification link")
+		return
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE email_verifications SET used_at = ? WHERE id = ? AND used_at IS NULL`, now, linkID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(w, http.StatusBadRequest, "invalid or expired verification link")
+		return
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET email_verified_at = COALESCE(email_verified_at, ?) WHERE id = ?`, now, userID,
+	); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := updateAffiliation(ctx, tx, userID); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	user, err := s.loadProfile(ctx, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	respond(w, http.StatusOK, user)
+}
+
+// ResendVerificationEmail handles POST /api/auth/email/verify/resend
+// Responds 202 (at most one email per minute is actually sent), or 409 if
+// the address is already verified.
+func (s *Server) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	err := s.sendVerificationEmail(r.Context(), middleware.GetUserID(r.Context()))
+	if errors.Is(err, errAlreadyVerified) {
+		respondError(w, http.StatusConflict, "email address already verified")
+		return
+	}
+	if err != nil {
+		slog.Error("verification email", "err", err)
+		respondError(w, http.StatusInternalServerError, "could not send verification email")
+		return
+	}
+	respond(w, http.StatusAccepted, map[string]string{"message": "verification email sent"})
+}
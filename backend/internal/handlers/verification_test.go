@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+)
+
+var verifyLinkRE = regexp.MustCompile(`/verify-email\?token=(\S+)`)
+
+// verifyToken returns the token from the most recent verification email.
+func (o *outbox) verifyToken(t *testing.T) string {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := len(o.sent) - 1; i >= 0; i-- {
+		if m := verifyLinkRE.FindStringSubmatch(o.sent[i].Body); m != nil {
+			token, _ := url.QueryUnescape(m[1])
+			return token
+		}
+	}
+	t.Fatal("no verification email sent")
+	return ""
+}
+
+func verifyEmail(t *testing.T, srv *Server, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/email/verify",
+		jsonBody(t, models.VerifyEmailRequest{Token: token}))
+	rec := httptest.NewRecorder()
+	srv.VerifyEmail(rec, req)
+	return rec
+}
+
+func createEventAs(t *testing.T, srv *Server, companyID string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/events", jsonBody(t, models.CreateEventRequest{
+		Title:     "Careers Fair",
+		StartTime: time.Now().Add(time.Hour),
+		EndTime:   time.Now().Add(2 * time.Hour),
+	}))
+	req = ctxWithUser(req, companyID, "company")
+	rec := httptest.NewRecorder()
+	srv.CreateEvent(rec, req)
+	return rec.Code
+}
+
+func TestEmailVerification_UnlocksEvents(t *testing.T) {
+	srv := newTestServer(t)
+	box := &outbox{}
+	srv.Mailer = box
+	company := registerUser(t, srv, "hr@acme.test", models.RoleCompany)
+
+	if len(box.sent) != 1 || box.sent[0].To != "hr@acme.test" {
+		t.Fatalf("expected a verification email at sign-up, got %+v", box.sent)
+	}
+	if code := createEventAs(t, srv, company.User.ID); code != http.StatusForbidden {
+		t.Fatalf("unverified company: expected 403, got %d", code)
+	}
+
+	token := box.verifyToken(t)
+	rec := verifyEmail(t, srv, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("verify: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var user models.User
+	json.NewDecoder(rec.Body).Decode(&user)
+	if user.EmailVerifiedAt == nil {
+		t.Error("email_verified_at not set in the response")
+	}
+	if code := createEventAs(t, srv, company.User.ID); code != http.StatusCreated {
+		t.Errorf("verified company: expected 201, got %d", code)
+	}
+	if rec := verifyEmail(t, srv, token); rec.Code != http.StatusBadRequest {
+		t.Errorf("reused link: expected 400, got %d", rec.Code)
+	}
+
+	// Nothing left to verify.
+	req := ctxWithUser(httptest.NewRequest(http.MethodPost, "/api/auth/email/verify/resend", nil),
+		company.User.ID, "company")
+	rec = httptest.NewRecorder()
+	srv.ResendVerificationEmail(rec, req)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("resend when verified: expected 409, got %d", rec.Code)
+	}
+}
+
+func TestEmailVerification_HidesUnverifiedStudents(t *testing.T) {
+	srv := newTestServer(t)
+	box := &outbox{}
+	srv.Mailer = box
+	registerUser(t, srv, "new@uni.test", models.RoleStudent)
+	seedStudentUser(t, srv) // verified
+
+	search := func() int {
+		req := ctxWithUser(httptest.NewRequest(http.MethodGet, "/api/users/students", nil), "c", "company")
+		rec := httptest.NewRecorder()
+		srv.SearchStudents(rec, req)
+		var students []models.User
+		json.NewDecoder(rec.Body).Decode(&students)
+		return len(students)
+	}
+	if n := search(); n != 1 {
+		t.Errorf("expected only the verified student, got %d", n)
+	}
+	srv.EmailVerification.ListUnverifiedStudents = true
+	if n := search(); n != 2 {
+		t.Errorf("policy off: expected both students, got %d", n)
+	}
+	srv.EmailVerification.ListUnverifiedStudents = false
+
+	verifyEmail(t, srv, box.verifyToken(t))
+	if n := search(); n != 2 {
+		t.Errorf("after verifying: expected both students, got %d", n)
+	}
+}
+
+func TestEmailVerification_LinkBoundToAddress(t *testing.T) {
+	srv := newTestServer(t)
+	box := &outbox{}
+	srv.Mailer = box
+	student := registerUser(t, srv, "typo@uni.test", models.RoleStudent)
+	token := box.verifyToken(t)
+
+	// A second request within the resend interval sends nothing.
+	req := ctxWithUser(httptest.NewRequest(http.MethodPost, "/api/auth/email/verify/resend", nil),
+		student.User.ID, "student")
+	rec := httptest.NewRecorder()
+	srv.ResendVerificationEmail(rec, req)
+	if rec.Code != http.StatusAccepted || len(box.sent) != 1 {
+		t.Fatalf("resend: got %d with %d emails", rec.Code, len(box.sent))
+	}
+
+	srv.DB.Exec(`UPDATE users SET email = 'fixed@uni.test' WHERE id = ?`, student.User.ID)
+	if rec := verifyEmail(t, srv, token); rec.Code != http.StatusBadRequest {
+		t.Errorf("link for the old address: expected 400, got %d", rec.Code)
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM users WHERE id = ? AND email_verified_at IS NOT NULL`, student.User.ID); n != 0 {
+		t.Error("address verified by a link sent to another one")
+	}
+}
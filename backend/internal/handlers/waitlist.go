@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/mail"
+	"github.com/Elizabethomito/skillzone/backend/internal/middleware"
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — the waitlist
+// ────────────────────────────────────────────────────────────────────
+// A full event still takes registrations: they queue as waitlisted or
+// conflict_pending.  Whenever a seat frees up (someone unregisters or is
+// removed, an offer runs out, the capacity goes up) settleWaitlist hands it
+// to the registration that has waited longest (FIFO by registered_at), in
+// the SAME transaction that released the seat, so no one else can slip in
+// between.
+//
+// With Server.WaitlistOfferTTL set, the student does not get the seat
+// outright but an OFFER: status offered, seat held (slots_remaining already
+// counts it) until offer_expires_at.  They accept with
+// POST /api/events/{id}/register/accept, or decline by unregistering.  An
+// offer that runs out is dropped like an unregistration and the seat moves
+// on.  Expiry is checked whenever the event's registrations change or are
+// read, by the host or by a student listing their own; accepting also
+// checks it, so a late click never wins.  So that a seat does not sit empty
+// when nobody touches the event, the StatusScheduler (scheduler.go) also
+// calls SettleExpiredOffers on every sweep.
+//
+// Students who move up are emailed after the commit.
+//
+// LEARNING NOTE — counting seats without races
+// slots_remaining is only ever changed relative to itself, inside the
+// transaction that creates or removes the registration: claimSeat takes a
+// seat with one conditional UPDATE (… WHERE slots_remaining > 0) and lets
+// the rows it affected decide the status, and releaseSeat gives one back.
+// Reading the count first and deciding in Go would let two requests both see
+// the last seat.  SQLite runs one writer at a time, so the UPDATE cannot
+// interleave; db.Open's _txlock=immediate makes each transaction take the
+// write lock at BEGIN, so a burst of sign-ups queues on busy_timeout instead
+// of failing with SQLITE_BUSY.
+
+// promotion is a registration settleWaitlist moved up.
+type promotion struct {
+	RegistrationID string
+	StudentID      string
+	EventID        string
+	Status         models.RegistrationStatus
+	OfferExpiresAt *time.Time
+}
+
+// settleWaitlist drops the event's expired offers, then gives each free
+// seat to the longest-waiting registration.  Run it inside the transaction
+// that freed the seats, and pass what it returns to notifyPromotions after
+// the commit.
+func (s *Server) settleWaitlist(ctx context.Context, tx *sql.Tx, eventID string, now time.Time) ([]promotion, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, offer_expires_at FROM registrations WHERE event_id = ? AND status = 'offered'`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	var expired []string
+	for rows.Next() {
+		var id string
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&id, &expiresAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if expiresAt.Valid && isExpired(expiresAt.Time, now) {
+			expired = append(expired, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, id := range expired {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM registrations WHERE id = ?`, id); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE events SET slots_remaining = slots_remaining + 1, updated_at = ?
+			 WHERE id = ? AND capacity IS NOT NULL`, now, eventID,
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	var promoted []promotion
+	for {
+		var slots sql.NullInt64
+		var status models.EventStatus
+		if err := tx.QueryRowContext(ctx,
+			`SELECT slots_remaining, status FROM events WHERE id = ?`, eventID,
+		).Scan(&slots, &status); err != nil {
+			return nil, err
+		}
+		// NULL is unlimited: everyone queued moves up.  A cancelled event
+		// promotes no one.
+		if (slots.Valid && slots.Int64 <= 0) || status == models.EventStatusCancelled {
+			return promoted, nil
+		}
+
+		p := promotion{EventID: eventID, Status: models.RegistrationConfirmed}
+		err := tx.QueryRowContext(ctx,
+			`SELECT id, student_id FROM registrations
+			 WHERE event_id = ? AND status IN ('waitlisted','conflict_pending')
+			 ORDER BY registered_at, rowid LIMIT 1`, eventID,
+		).Scan(&p.RegistrationID, &p.StudentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return promoted, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if s.WaitlistOfferTTL > 0 {
+			expiresAt := now.Add(s.WaitlistOfferTTL)
+			p.Status, p.OfferExpiresAt = models.RegistrationOffered, &expiresAt
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE registrations SET status = ?, offer_expires_at = ? WHERE id = ?`,
+			p.Status, p.OfferExpiresAt, p.RegistrationID,
+		); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE events SET slots_remaining = slots_remaining - 1, updated_at = ? WHERE id = ?`, now, eventID,
+		); err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, p)
+	}
+}
+
+// SettleExpiredOffers settles the waitlist of every event holding an offer
+// that has expired by now, one transaction per event, and emails whoever
+// moves up.  It returns how many events it settled.
+func (s *Server) SettleExpiredOffers(ctx context.Context, now time.Time) (int, error) {
+	return s.settleExpiredOffers(ctx, "", now)
+}
+
+// settleExpiredOffers is SettleExpiredOffers, limited to the events where
+// studentID holds an offer unless studentID is empty.
+func (s *Server) settleExpiredOffers(ctx context.Context, studentID string, now time.Time) (int, error) {
+	rows, err := s.DB.QueryContext(ctx,
+		`SELECT event_id, offer_expires_at FROM registrations
+		 WHERE status = 'offered' AND offer_expires_at IS NOT NULL AND (? = '' OR student_id = ?)`,
+		studentID, studentID)
+	if err != nil {
+		return 0, err
+	}
+	var events []string
+	for rows.Next() {
+		var eventID string
+		var expiresAt time.Time
+		if err := rows.Scan(&eventID, &expiresAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if isExpired(expiresAt, now) && !slices.Contains(events, eventID) {
+			events = append(events, eventID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, eventID := range events {
+		tx, err := s.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return 0, err
+		}
+		promoted, err := s.settleWaitlist(ctx, tx, eventID, now)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			tx.Rollback() //nolint:errcheck
+			return 0, err
+		}
+		s.notifyPromotions(ctx, promoted)
+	}
+	return len(events), nil
+}
+
+// claimSeat registers studentID for the event, taking a seat if one is
+// free; otherwise the registration queues as conflict_pending.  If the
+// student is already registered it returns that registration and false.
+// Settle the waitlist first in the same transaction, so a newcomer never
+// takes a seat someone queued for.
+func (s *Server) claimSeat(ctx context.Context, tx *sql.Tx, eventID, studentID string, now time.Time) (models.Registration, bool, error) {
+	reg := models.Registration{EventID: eventID, StudentID: studentID}
+	var expiresAt sql.NullTime
+	err := tx.QueryRowContext(ctx,
+		`SELECT id, registered_at, status, offer_expires_at FROM registrations
+		 WHERE event_id = ? AND student_id = ?`, eventID, studentID,
+	).Scan(&reg.ID, &reg.RegisteredAt, &reg.Status, &expiresAt)
+	if err == nil {
+		if expiresAt.Valid {
+			reg.OfferExpiresAt = &expiresAt.Time
+		}
+		return reg, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return reg, false, err
+	}
+
+	// NULL slots_remaining is an unlimited event: the row matches and stays
+	// NULL.
+	result, err := tx.ExecContext(ctx,
+		`UPDATE events SET slots_remaining = slots_remaining - 1, updated_at = ?
+		 WHERE id = ? AND (slots_remaining IS NULL OR slots_remaining > 0)`, now, eventID)
+	if err != nil {
+		return reg, false, err
+	}
+	reg.Status = models.RegistrationConflictPending
+	if n, _ := result.RowsAffected(); n == 1 {
+		reg.Status = models.RegistrationConfirmed
+	}
+
+	reg.ID, reg.RegisteredAt = uuid.NewString(), now
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO registrations (id, event_id, student_id, registered_at, status) VALUES (?, ?, ?, ?, ?)`,
+		reg.ID, reg.EventID, reg.StudentID, reg.RegisteredAt, reg.Status,
+	); err != nil {
+		return reg, false, err
+	}
+	return reg, true, nil
+}
+
+// releaseSeat gives back the seat of a registration that has just been
+// deleted, if it held one, and settles the waitlist.  status must come from
+// the deleted row itself (DELETE … RETURNING status in the same
+// transaction), never from an earlier read.
+func (s *Server) releaseSeat(ctx context.Context, tx *sql.Tx, eventID string, status models.RegistrationStatus) ([]promotion, error) {
+	now := time.Now().UTC()
+	if status == models.RegistrationConfirmed || status == models.RegistrationOffered {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE events SET slots_remaining = slots_remaining + 1, updated_at = ?
+			 WHERE id = ? AND capacity IS NOT NULL`, now, eventID,
+		); err != nil {
+			return nil, err
+		}
+	}
+	return s.settleWaitlist(ctx, tx, eventID, now)
+}
+
+// notifyPromotions emails the students settleWaitlist moved up.  Failures
+// are only logged: the seat is theirs either way, and the registration
+// list shows it.
+func (s *Server) notifyPromotions(ctx context.Context, promoted []promotion) {
+	if s.Mailer == nil {
+		return
+	}
+	for _, p := range promoted {
+		var email, name, title string
+		if err := s.DB.QueryRowContext(ctx,
+			`SELECT u.email, u.name, e.title FROM users u, events e WHERE u.id = ? AND e.id = ?`,
+			p.StudentID, p.EventID,
+		).Scan(&email, &name, &title); err != nil {
+			slog.Error("waitlist email", "registration_id", p.RegistrationID, "err", err)
+			continue
+		}
+		link := strings.TrimRight(s.AppURL, "/") + "/events/" + p.EventID
+		body := fmt.Sprintf("Hi %s,\n\nA place opened up at %s and it is yours.\n\n%s\n", name, title, link)
+		if p.OfferExpiresAt != nil {
+			body = fmt.Sprintf("Hi %s,\n\n"+
+				"A place opened up at %s.  It is held for you until %s; accept it here:\n\n%s\n\n"+
+				"If you do not accept by then it goes to the next person on the waitlist.\n",
+				name, title, p.OfferExpiresAt.Format(time.RFC1123), link)
+		}
+		if err := s.Mailer.Send(ctx, mail.Message{
+			To:      email,
+			Subject: "A place opened up: " + title,
+			Body:    body,
+		}); err != nil {
+			slog.Error("waitlist email", "registration_id", p.RegistrationID, "err", err)
+		}
+	}
+}
+
+// AcceptWaitlistOffer handles POST /api/events/{id}/register/accept  (student only)
+//
+// Confirms the caller's offered seat.  409 if they hold no offer, 410 if
+// it has run out (the seat has then moved on).
+func (s *Server) AcceptWaitlistOffer(w http.ResponseWriter, r *http.Request) {
+	eventID := r.PathValue("id")
+	studentID := middleware.GetUserID(r.Context())
+	ctx := r.Context()
+	now := time.Now().UTC()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var reg models.Registration
+	var expiresAt sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		`SELECT id, event_id, student_id, registered_at, status, offer_expires_at
+		 FROM registrations WHERE event_id = ? AND student_id = ?`, eventID, studentID,
+	).Scan(&reg.ID, &reg.EventID, &reg.StudentID, &reg.RegisteredAt, &reg.Status, &expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "registration not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if reg.Status != models.RegistrationOffered {
+		respondError(w, http.StatusConflict, "you have no open offer for this event")
+		return
+	}
+	var status models.EventStatus
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM events WHERE id = ?`, eventID).Scan(&status); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if status == models.EventStatusCancelled {
+		respondError(w, http.StatusConflict, "the event is cancelled")
+		return
+	}
+
+	if expiresAt.Valid && isExpired(expiresAt.Time, now) {
+		promoted, err := s.settleWaitlist(ctx, tx, eventID, now)
+		if err != nil || tx.Commit() != nil {
+			respondError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		s.notifyPromotions(ctx, promoted)
+		respondError(w, http.StatusGone, "the offer has expired")
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE registrations SET status = 'confirmed', offer_expires_at = NULL WHERE id = ?`, reg.ID,
+	); err != nil {
+		respondError(w, http.StatusInternalServerError, "could not accept offer")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	reg.Status = models.RegistrationConfirmed
+	respond(w, http.StatusOK, reg)
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/models"
+)
+
+func TestWaitlist_PromotesInRegistrationOrder(t *testing.T) {
+	srv := newTestServer(t)
+	host := seedCompanyUser(t, srv)
+	eventID, _ := seedEventWithCapacity(t, srv, host, 1)
+	first, second, third := seedStudentUser(t, srv), seedStudentUser(t, srv), seedStudentUser(t, srv)
+	for _, student := range []string{first, second, third} {
+		asUser(t, srv.RegisterForEvent, http.MethodPost, student, "student", nil, "id", eventID)
+	}
+	box := &outbox{}
+	srv.Mailer = box
+	status := func(student string) string {
+		return dbString(t, srv, `SELECT status FROM registrations WHERE event_id = ? AND student_id = ?`, eventID, student)
+	}
+
+	// The third student sees they are second in line.
+	rec := asUser(t, srv.GetMyRegistrations, http.MethodGet, third, "student", nil)
+	var mine []struct {
+		Status           models.RegistrationStatus `json:"status"`
+		WaitlistPosition *int                      `json:"waitlist_position"`
+	}
+	json.NewDecoder(rec.Body).Decode(&mine)
+	if len(mine) != 1 || mine[0].Status != models.RegistrationConflictPending ||
+		mine[0].WaitlistPosition == nil || *mine[0].WaitlistPosition != 2 {
+		t.Fatalf("unexpected registrations %+v", mine)
+	}
+
+	if rec := asUser(t, srv.UnregisterFromEvent, http.MethodDelete, first, "student", nil, "id", eventID); rec.Code != http.StatusNoContent {
+		t.Fatalf("unregister: expected 204, got %d", rec.Code)
+	}
+	if s := status(second); s != string(models.RegistrationConfirmed) {
+		t.Errorf("second in line: expected confirmed, got %s", s)
+	}
+	if s := status(third); s != string(models.RegistrationConflictPending) {
+		t.Errorf("third in line: expected conflict_pending, got %s", s)
+	}
+	if n := dbInt(t, srv, `SELECT slots_remaining FROM events WHERE id = ?`, eventID); n != 0 {
+		t.Errorf("slots_remaining: expected 0, got %d", n)
+	}
+	if len(box.sent) != 1 {
+		t.Errorf("expected 1 email, got %d", len(box.sent))
+	}
+
+	// A host removing someone frees the seat the same way.
+	regID := dbString(t, srv, `SELECT id FROM registrations WHERE event_id = ? AND student_id = ?`, eventID, second)
+	if rec := asCompany(t, srv.KickRegistration, http.MethodDelete, host, nil, "id", eventID, "reg_id", regID); rec.Code != http.StatusNoContent {
+		t.Fatalf("kick: expected 204, got %d", rec.Code)
+	}
+	if s := status(third); s != string(models.RegistrationConfirmed) {
+		t.Errorf("after kick: expected confirmed, got %s", s)
+	}
+}
+
+func TestWaitlist_Offers(t *testing.T) {
+	srv := newTestServer(t)
+	srv.WaitlistOfferTTL = time.Hour
+	host := seedCompanyUser(t, srv)
+	eventID, _ := seedEventWithCapacity(t, srv, host, 1)
+	first, second, third := seedStudentUser(t, srv), seedStudentUser(t, srv), seedStudentUser(t, srv)
+	for _, student := range []string{first, second, third} {
+		asUser(t, srv.RegisterForEvent, http.MethodPost, student, "student", nil, "id", eventID)
+	}
+	status := func(student string) string {
+		return dbString(t, srv, `SELECT status FROM registrations WHERE event_id = ? AND student_id = ?`, eventID, student)
+	}
+	accept := func(student string) int {
+		return asUser(t, srv.AcceptWaitlistOffer, http.MethodPost, student, "student", nil, "id", eventID).Code
+	}
+
+	if code := accept(second); code != http.StatusConflict {
+		t.Errorf("accept without an offer: expected 409, got %d", code)
+	}
+	asUser(t, srv.UnregisterFromEvent, http.MethodDelete, first, "student", nil, "id", eventID)
+	if s := status(second); s != string(models.RegistrationOffered) {
+		t.Fatalf("expected offered, got %s", s)
+	}
+	// The offer holds the seat: a newcomer still queues.
+	late := seedStudentUser(t, srv)
+	rec := asUser(t, srv.RegisterForEvent, http.MethodPost, late, "student", nil, "id", eventID)
+	var reg models.Registration
+	json.NewDecoder(rec.Body).Decode(&reg)
+	if reg.Status != models.RegistrationConflictPending {
+		t.Errorf("newcomer while an offer is open: expected conflict_pending, got %s", reg.Status)
+	}
+
+	// Let the offer run out: the seat moves on to the third student.
+	if _, err := srv.DB.Exec(`UPDATE registrations SET offer_expires_at = ? WHERE student_id = ?`,
+		time.Now().UTC().Add(-time.Minute), second); err != nil {
+		t.Fatal(err)
+	}
+	if code := accept(second); code != http.StatusGone {
+		t.Errorf("late accept: expected 410, got %d", code)
+	}
+	if n := dbInt(t, srv, `SELECT COUNT(*) FROM registrations WHERE student_id = ?`, second); n != 0 {
+		t.Errorf("expired offer kept its registration")
+	}
+	if s := status(third); s != string(models.RegistrationOffered) {
+		t.Fatalf("next in line: expected offered, got %s", s)
+	}
+	if code := accept(third); code != http.StatusOK {
+		t.Fatalf("accept: expected 200, got %d", code)
+	}
+	if s := status(third); s != string(models.RegistrationConfirmed) {
+		t.Errorf("after accept: expected confirmed, got %s", s)
+	}
+	if n := dbInt(t, srv, `SELECT slots_remaining FROM events WHERE id = ?`, eventID); n != 0 {
+		t.Errorf("slots_remaining: expected 0, got %d", n)
+	}
+}
+
+func TestWaitlist_ExpiredOffersSettleUntouched(t *testing.T) {
+	srv := newTestServer(t)
+	srv.WaitlistOfferTTL = time.Hour
+	host := seedCompanyUser(t, srv)
+	eventID, _ := seedEventWithCapacity(t, srv, host, 1)
+	first, second, third, fourth := seedStudentUser(t, srv), seedStudentUser(t, srv), seedStudentUser(t, srv), seedStudentUser(t, srv)
+	for _, student := range []string{first, second, third, fourth} {
+		asUser(t, srv.RegisterForEvent, http.MethodPost, student, "student", nil, "id", eventID)
+	}
+	status := func(student string) string {
+		return dbString(t, srv, `SELECT COALESCE(MAX(status), '') FROM registrations WHERE event_id = ? AND student_id = ?`, eventID, student)
+	}
+	expire := func(student string) {
+		t.Helper()
+		if _, err := srv.DB.Exec(`UPDATE registrations SET offer_expires_at = ? WHERE student_id = ?`,
+			time.Now().UTC().Add(-time.Minute), student); err != nil {
+			t.Fatal(err)
+		}
+	}
+	asUser(t, srv.UnregisterFromEvent, http.MethodDelete, first, "student", nil, "id", eventID)
+	if s := status(second); s != string(models.RegistrationOffered) {
+		t.Fatalf("expected offered, got %s", s)
+	}
+
+	// The scheduler's sweep hands the seat on without anyone asking.
+	expire(second)
+	sc := &StatusScheduler{DB: srv.DB, Interval: time.Hour, Waitlist: srv}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sc.Run(ctx)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for status(third) != string(models.RegistrationOffered) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if s := status(second); s != "" {
+		t.Errorf("expired offer kept its registration: %s", s)
+	}
+	if s := status(third); s != string(models.RegistrationOffered) {
+		t.Fatalf("next in line: expected offered, got %s", s)
+	}
+
+	// The student whose offer ran out never sees it as still open.
+	expire(third)
+	rec := asUser(t, srv.GetMyRegistrations, http.MethodGet, third, "student", nil)
+	var mine []models.Registration
+	json.NewDecoder(rec.Body).Decode(&mine)
+	if len(mine) != 0 {
+		t.Errorf("expected no registrations after the offer ran out, got %+v", mine)
+	}
+	if s := status(fourth); s != string(models.RegistrationOffered) {
+		t.Errorf("after listing: expected the fourth student offered, got %s", s)
+	}
+}
+
+// TestClaimSeat_ConcurrentRegistrations fires a burst of sign-ups, online and
+// through attendance sync, at an event with few seats.
+func TestClaimSeat_ConcurrentRegistrations(t *testing.T) {
+	const seats, online, synced = 25, 200, 100
+	srv := newFileTestServer(t)
+	eventID, _ := seedEventWithCapacity(t, srv, seedCompanyUser(t, srv), seats)
+
+	students := make([]string, online+synced)
+	for i := range students {
+		students[i] = seedStudentUser(t, srv)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan string, len(students))
+	for i, student := range students {
+		wg.Add(1)
+		go func(i int, student string) {
+			defer wg.Done()
+			if i < online {
+				req := httptest.NewRequest(http.MethodPost, "/api/test", nil)
+				req.SetPathValue("id", eventID)
+				rec := httptest.NewRecorder()
+				srv.RegisterForEvent(rec, ctxWithUser(req, student, "student"))
+				if rec.Code != http.StatusCreated {
+					errs <- rec.Body.String()
+				}
+				return
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/sync/attendance", nil)
+			if err := srv.upsertRegistration(req, student, eventID, time.Now().UTC()); err != nil {
+				errs <- err.Error()
+			}
+		}(i, student)
+	}
+	wg.Wait()
+	close(errs)
+	for msg := range errs {
+		t.Errorf("registration failed: %s", msg)
+	}
+
+	confirmed := dbInt(t, srv, `SELECT COUNT(*) FROM registrations WHERE event_id = ? AND status = 'confirmed'`, eventID)
+	total := dbInt(t, srv, `SELECT COUNT(*) FROM registrations WHERE event_id = ?`, eventID)
+	slots := dbInt(t, srv, `SELECT slots_remaining FROM events WHERE id = ?`, eventID)
+	if confirmed != seats || slots != 0 || total != len(students) {
+		t.Errorf("confirmed %d (want %d), slots_remaining %d (want 0), registrations %d (want %d)",
+			confirmed, seats, slots, total, len(students))
+	}
+}
+
+// TestReleaseSeat_ConcurrentRemovals repeats each removal of a confirmed
+// registration many times at once — a student double-clicking unregister,
+// two hosts kicking the same person.  Each must give back one seat.
+func TestReleaseSeat_ConcurrentRemovals(t *testing.T) {
+	const repeats = 10
+	srv := newFileTestServer(t)
+	host := seedCompanyUser(t, srv)
+	eventID, _ := seedEventWithCapacity(t, srv, host, 2)
+	leaving, kicked := seedStudentUser(t, srv), seedStudentUser(t, srv)
+	waiting := []string{seedStudentUser(t, srv), seedStudentUser(t, srv), seedStudentUser(t, srv)}
+	for _, student := range append([]string{leaving, kicked}, waiting...) {
+		asUser(t, srv.RegisterForEvent, http.MethodPost, student, "student", nil, "id", eventID)
+	}
+	kickedReg := dbString(t, srv, `SELECT id FROM registrations WHERE event_id = ? AND student_id = ?`, eventID, kicked)
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 2*repeats)
+	for range repeats {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodDelete, "/api/test", nil)
+			req.SetPathValue("id", eventID)
+			rec := httptest.NewRecorder()
+			srv.UnregisterFromEvent(rec, ctxWithUser(req, leaving, "student"))
+			codes <- rec.Code
+		}()
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodDelete, "/api/test", nil)
+			req.SetPathValue("id", eventID)
+			req.SetPathValue("reg_id", kickedReg)
+			rec := httptest.NewRecorder()
+			srv.KickRegistration(rec, ctxWithUser(req, host, "company"))
+			codes <- rec.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	if counts[http.StatusNoContent] != 2 || counts[http.StatusNotFound] != 2*repeats-2 {
+		t.Errorf("expected 2 removals and %d not found, got %v", 2*repeats-2, counts)
+	}
+
+	// Two seats came back: the first two in line got them, the third waits.
+	confirmed := dbInt(t, srv, `SELECT COUNT(*) FROM registrations WHERE event_id = ? AND status = 'confirmed'`, eventID)
+	slots := dbInt(t, srv, `SELECT slots_remaining FROM events WHERE id = ?`, eventID)
+	if confirmed != 2 || slots != 0 {
+		t.Errorf("confirmed %d (want 2), slots_remaining %d (want 0)", confirmed, slots)
+	}
+	if s := dbString(t, srv, `SELECT status FROM registrations WHERE student_id = ?`, waiting[2]); s != string(models.RegistrationConflictPending) {
+		t.Errorf("third in line: expected conflict_pending, got %s", s)
+	}
+}
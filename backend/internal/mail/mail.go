@@ -0,0 +1,111 @@
+// Package mail sends the few emails the server needs (password resets,
+// confirmations).
+//
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — why an interface?
+// ────────────────────────────────────────────────────────────────────
+// Handlers only ever see the Mailer interface.  In production main wires in
+// SMTPMailer; on a laptop or in tests a LogMailer writes each message to a
+// file or the terminal instead, so the reset link can be copied from there.
+// Tests can also supply their own fake that records messages in memory.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages.  Send should return once the message has been
+// handed off (to an SMTP server, a file, …), not when it is read.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPMailer sends through an SMTP server using STARTTLS when the server
+// offers it.  Username may be empty for servers that need no login.
+type SMTPMailer struct {
+	Addr     string // host:port, e.g. "smtp.example.com:587"
+	From     string
+	Username string
+	Password string
+}
+
+// Send implements Mailer.  net/smtp has no context support, so ctx is only
+// checked before dialing.
+func (m SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return fmt.Errorf("smtp addr: %w", err)
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	data, err := format(m.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, data); err != nil {
+		return fmt.Errorf("smtp send: %w", err)
+	}
+	return nil
+}
+
+// LogMailer writes every message, headers and all, to W.  Use it in
+// development (W = os.Stderr or a file) and in tests.
+type LogMailer struct {
+	From string
+	W    io.Writer
+
+	mu sync.Mutex
+}
+
+// Send implements Mailer.
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	data, err := format(m.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := fmt.Fprintf(m.W, "%s\n-- end of message --\n\n", data); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+	return nil
+}
+
+// format renders msg as an RFC 5322 message.  Header values are rejected if
+// they contain a line break, which would let a caller inject headers.
+func format(from string, msg Message, now time.Time) ([]byte, error) {
+	for _, v := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, fmt.Errorf("mail header contains a line break: %q", v)
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String()), nil
+}
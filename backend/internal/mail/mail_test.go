@@ -0,0 +1,43 @@
+package mail
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLogMailer_WritesMessage(t *testing.T) {
+	var out strings.Builder
+	m := &LogMailer{From: "noreply@skillzone.test", W: &out}
+
+	err := m.Send(context.Background(), Message{
+		To:      "student@example.com",
+		Subject: "Reset your password",
+		Body:    "line one\nline two",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"From: noreply@skillzone.test\r\n",
+		"To: student@example.com\r\n",
+		"Subject: Reset your password\r\n",
+		"\r\n\r\nline one\r\nline two",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestFormat_RejectsHeaderInjection(t *testing.T) {
+	m := &LogMailer{From: "noreply@skillzone.test", W: &strings.Builder{}}
+	err := m.Send(context.Background(), Message{
+		To:      "victim@example.com\r\nBcc: everyone@example.com",
+		Subject: "hi",
+	})
+	if err == nil {
+		t.Error("expected an error for a header containing a line break")
+	}
+}
@@ -20,7 +20,9 @@ package middleware
 
 import (
 	"context"
+	"net"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/Elizabethomito/skillzone/backend/internal/auth"
@@ -35,22 +37,87 @@ const (
 	// ContextUserID is the key under which the authenticated user's ID
 	// is stored in the request context after Authenticate runs.
 	ContextUserID contextKey = "user_id"
-	// ContextRole is the key for the user's role ("student"/"company").
+	// ContextRole is the key for the user's role ("student"/"company"/"admin").
 	ContextRole contextKey = "role"
+	// ContextSessionID is the key for the session the access token belongs to.
+	ContextSessionID contextKey = "session_id"
+	// ContextAPIKeyID is the key for the API key the request was made with.
+	// It is only set for API-key requests.
+	ContextAPIKeyID contextKey = "api_key_id"
+	// ContextScopes is the key for the API key's scopes ([]string).
+	ContextScopes contextKey = "scopes"
 )
 
+// APIKeyPrefix starts every API key, so Authenticate can tell one from a
+// JWT without trying to parse it.
+const APIKeyPrefix = "szk_"
+
+// SessionState is what the store knows about the session behind a token
+// and the user who owns it, as of now rather than as of token issue.
+type SessionState struct {
+	// Active is false when the session does not exist, was revoked or has
+	// reached its absolute expiry.
+	Active bool
+	UserID string
+	// Role is the user's current role; it overrides the token's role claim.
+	Role string
+	// Status is the account status: "active", "suspended" or "deleted".
+	Status string
+}
+
+// APIKeyState is what the store knows about an API key and the user who
+// owns it.
+type APIKeyState struct {
+	// Active is false when the key does not exist, was revoked or has
+	// expired.
+	Active bool
+	KeyID  string
+	UserID string
+	// Role and Status are the owner's current role and account status.
+	Role   string
+	Status string
+	Scopes []string
+	// TwoFactorMissing is set when the owner's role requires 2FA and the
+	// owner has not enabled it; the key is refused until they do.
+	TwoFactorMissing bool
+}
+
+// Store is the database-backed lookup Authenticate uses to decide whether a
+// cryptographically valid token is still honoured. The handlers.Server type
+// implements it; keeping it an interface here means this package never
+// imports handlers (which would be an import cycle).
+type Store interface {
+	// SessionState returns the current state of the session and its user.
+	// Implementations may cache, but must forget an entry when they change
+	// the session, role or status it describes.
+	SessionState(ctx context.Context, sessionID string) (SessionState, error)
+	// APIKeyState returns the current state of the API key whose SHA-256
+	// is keyHash, and records that it was used from ip.
+	APIKeyState(ctx context.Context, keyHash, ip string) (APIKeyState, error)
+}
+
 // Authenticate is a middleware factory — it returns a middleware function
-// configured with the JWT secret. This lets us pass the secret once at
-// startup rather than on every request.
+// configured with the signing keyring and session store. This lets us pass them
+// once at startup rather than on every request.
 //
 // Flow:
 //  1. Read the "Authorization: Bearer <token>" header.
 //  2. Parse and validate the JWT.
-//  3. Store user_id and role in the request context.
-//  4. Call the next handler.
+//  3. Ask the store for the token's session and user, so a logout, a
+//     suspension or a role change takes effect immediately rather than
+//     when the access token expires.
+//  4. Store user_id, role (from the store, not the token) and session_id
+//     in the request context.
+//  5. Call the next handler.
+//
+// If the token is missing, invalid or revoked, or the account was deleted,
+// it responds with 401 and stops; a suspended account gets 403.
+// A nil store skips step 3 and trusts the token's role; only tests should
+// pass nil.
 //
-// If the token is missing or invalid, it responds with 401 and stops.
-func Authenticate(secret string) func(http.Handler) http.Handler {
+// A bearer value starting with APIKeyPrefix is an API key instead of a JWT;
+// see authenticateAPIKey.
+func Authenticate(keys *auth.Keyring, store Store) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			header := r.Header.Get("Authorization")
@@ -59,22 +126,138 @@ func Authenticate(secret string) func(http.Handler) http.Handler {
 				return
 			}
 			tokenStr := strings.TrimPrefix(header, "Bearer ")
+			if strings.HasPrefix(tokenStr, APIKeyPrefix) {
+				authenticateAPIKey(w, r, tokenStr, store, next)
+				return
+			}
 
-			claims, err := auth.ParseToken(tokenStr, secret)
+			claims, err := auth.ParseToken(tokenStr, keys)
 			if err != nil {
 				http.Error(w, `{"error":"invalid token"}`, http.StatusUnauthorized)
 				return
 			}
 
+			role := claims.Role
+			if store != nil {
+				if claims.SessionID == "" {
+					http.Error(w, `{"error":"invalid token"}`, http.StatusUnauthorized)
+					return
+				}
+				state, err := store.SessionState(r.Context(), claims.SessionID)
+				if err != nil {
+					http.Error(w, `{"error":"database error"}`, http.StatusInternalServerError)
+					return
+				}
+				if !state.Active || state.UserID != claims.UserID {
+					http.Error(w, `{"error":"session has been revoked"}`, http.StatusUnauthorized)
+					return
+				}
+				switch state.Status {
+				case "active":
+				case "suspended":
+					http.Error(w, `{"error":"account suspended"}`, http.StatusForbidden)
+					return
+				default:
+					http.Error(w, `{"error":"account deleted"}`, http.StatusUnauthorized)
+					return
+				}
+				role = state.Role
+			}
+
 			// Store the claims in the context so downstream handlers can
 			// retrieve them without re-parsing the token.
 			ctx := context.WithValue(r.Context(), ContextUserID, claims.UserID)
-			ctx = context.WithValue(ctx, ContextRole, claims.Role)
+			ctx = context.WithValue(ctx, ContextRole, role)
+			ctx = context.WithValue(ctx, ContextSessionID, claims.SessionID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// authenticateAPIKey is the API-key half of Authenticate.
+//
+// LEARNING NOTE — default deny for API keys
+// An API key is a long-lived secret that sits in a script or a CI variable,
+// so it should be able to do much less than a logged-in user.  Each route
+// that a key may call says so by wrapping its handler in RequireScope, and
+// the key must carry that scope.  Every other route refuses keys outright:
+// adding a new company endpoint does not silently make it reachable by
+// every key ever issued.  Authenticate spots scoped routes by checking
+// whether the handler it wraps is a RequireScope handler, so RequireScope
+// must come directly inside Authenticate: auth(RequireScope(…)(…)).
+//
+// Unlike sessions, key lookups are not cached: the store also records when
+// and from where the key was used, and a revoked key stops working at once.
+func authenticateAPIKey(w http.ResponseWriter, r *http.Request, key string, store Store, next http.Handler) {
+	if store == nil {
+		http.Error(w, `{"error":"invalid token"}`, http.StatusUnauthorized)
+		return
+	}
+	if _, scoped := next.(scopeGuard); !scoped {
+		http.Error(w, `{"error":"API keys cannot be used on this route"}`, http.StatusForbidden)
+		return
+	}
+
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	state, err := store.APIKeyState(r.Context(), auth.HashOpaqueToken(key), host)
+	if err != nil {
+		http.Error(w, `{"error":"database error"}`, http.StatusInternalServerError)
+		return
+	}
+	if !state.Active {
+		http.Error(w, `{"error":"invalid API key"}`, http.StatusUnauthorized)
+		return
+	}
+	switch state.Status {
+	case "active":
+	case "suspended":
+		http.Error(w, `{"error":"account suspended"}`, http.StatusForbidden)
+		return
+	default:
+		http.Error(w, `{"error":"account deleted"}`, http.StatusUnauthorized)
+		return
+	}
+	if state.TwoFactorMissing {
+		http.Error(w, `{"error":"the key's owner must enable two-factor authentication"}`, http.StatusForbidden)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), ContextUserID, state.UserID)
+	ctx = context.WithValue(ctx, ContextRole, state.Role)
+	ctx = context.WithValue(ctx, ContextAPIKeyID, state.KeyID)
+	ctx = context.WithValue(ctx, ContextScopes, state.Scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireScope returns a middleware that lets API keys with scope through
+// to the route.  Requests made with a session are not affected.  It must
+// wrap the handler directly inside Authenticate (see authenticateAPIKey).
+//
+// Example: auth(RequireScope("events:read")(onlyCompany(handler)))
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return scopeGuard{scope: scope, next: next}
+	}
+}
+
+// scopeGuard is the handler RequireScope returns.  It is a named type, not
+// an http.HandlerFunc, so that Authenticate can recognise it.
+type scopeGuard struct {
+	scope string
+	next  http.Handler
+}
+
+func (g scopeGuard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if scopes, isKey := r.Context().Value(ContextScopes).([]string); isKey && !slices.Contains(scopes, g.scope) {
+		http.Error(w, `{"error":"API key lacks the `+g.scope+` scope"}`, http.StatusForbidden)
+		return
+	}
+	g.next.ServeHTTP(w, r)
+}
+
 // RequireRole returns a middleware that only allows requests whose context
 // role matches one of the given roles. Must be used after Authenticate.
 //
@@ -136,3 +319,16 @@ func GetRole(ctx context.Context) string {
 	role, _ := ctx.Value(ContextRole).(string)
 	return role
 }
+
+// GetSessionID retrieves the session ID of the access token used for the request.
+func GetSessionID(ctx context.Context) string {
+	id, _ := ctx.Value(ContextSessionID).(string)
+	return id
+}
+
+// GetAPIKeyID retrieves the ID of the API key used for the request, or ""
+// when the request was made with a session.
+func GetAPIKeyID(ctx context.Context) string {
+	id, _ := ctx.Value(ContextAPIKeyID).(string)
+	return id
+}
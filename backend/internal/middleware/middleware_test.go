@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -8,7 +9,21 @@ import (
 	"github.com/Elizabethomito/skillzone/backend/internal/auth"
 )
 
-const testSecret = "middleware-test-secret"
+// testKeys signs and verifies every token in this file.
+var testKeys = func() *auth.Keyring {
+	ck, err := auth.NewCheckInKey()
+	if err != nil {
+		panic(err)
+	}
+	kr, err := auth.NewKeyring([]auth.Key{
+		{ID: "session-key", Purpose: auth.PurposeSession, Status: auth.KeyPrimary, Material: []byte("middleware-test-secret")},
+		{ID: ck.ID, Purpose: auth.PurposeCheckIn, Status: auth.KeyPrimary, Material: ck.Seed()},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return kr
+}()
 
 func TestCORS_SetsHeaders(t *testing.T) {
 	handler := CORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -39,7 +54,7 @@ func TestCORS_PreflightReturns204(t *testing.T) {
 }
 
 func TestAuthenticate_MissingHeader(t *testing.T) {
-	handler := Authenticate(testSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := Authenticate(testKeys, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -53,13 +68,13 @@ func TestAuthenticate_MissingHeader(t *testing.T) {
 }
 
 func TestAuthenticate_ValidToken(t *testing.T) {
-	token, err := auth.GenerateToken("user-1", "student", testSecret)
+	token, err := auth.GenerateToken("user-1", "student", "session-1", testKeys)
 	if err != nil {
 		t.Fatalf("GenerateToken: %v", err)
 	}
 
 	var capturedID string
-	handler := Authenticate(testSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := Authenticate(testKeys, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		capturedID = GetUserID(r.Context())
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -78,9 +93,9 @@ func TestAuthenticate_ValidToken(t *testing.T) {
 }
 
 func TestRequireRole_Forbidden(t *testing.T) {
-	token, _ := auth.GenerateToken("user-2", "student", testSecret)
+	token, _ := auth.GenerateToken("user-2", "student", "session-2", testKeys)
 
-	handler := Authenticate(testSecret)(
+	handler := Authenticate(testKeys, nil)(
 		RequireRole("company")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 		})),
@@ -95,3 +110,133 @@ func TestRequireRole_Forbidden(t *testing.T) {
 		t.Errorf("expected 403, got %d", rec.Code)
 	}
 }
+
+// fakeStore is an in-memory middleware.Store for tests.
+type fakeStore map[string]SessionState
+
+func (f fakeStore) SessionState(_ context.Context, sessionID string) (SessionState, error) {
+	return f[sessionID], nil
+}
+
+func (f fakeStore) APIKeyState(context.Context, string, string) (APIKeyState, error) {
+	return APIKeyState{}, nil
+}
+
+// keyStore adds API keys, by hash, to a fakeStore.
+type keyStore struct {
+	fakeStore
+	keys map[string]APIKeyState
+}
+
+func (k keyStore) APIKeyState(_ context.Context, keyHash, _ string) (APIKeyState, error) {
+	return k.keys[keyHash], nil
+}
+
+func TestAuthenticate_ChecksSessionState(t *testing.T) {
+	store := fakeStore{
+		"live":      {Active: true, UserID: "user-3", Role: "student", Status: "active"},
+		"revoked":   {Active: false, UserID: "user-3", Role: "student", Status: "active"},
+		"other":     {Active: true, UserID: "someone-else", Role: "student", Status: "active"},
+		"suspended": {Active: true, UserID: "user-3", Role: "student", Status: "suspended"},
+		"deleted":   {Active: true, UserID: "user-3", Role: "student", Status: "deleted"},
+	}
+	handler := Authenticate(testKeys, store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tc := range []struct {
+		sessionID string
+		want      int
+	}{
+		{"live", http.StatusOK},
+		{"revoked", http.StatusUnauthorized},
+		{"unknown", http.StatusUnauthorized},
+		{"other", http.StatusUnauthorized},
+		{"suspended", http.StatusForbidden},
+		{"deleted", http.StatusUnauthorized},
+		{"", http.StatusUnauthorized}, // tokens without a session are not honoured
+	} {
+		token, _ := auth.GenerateToken("user-3", "student", tc.sessionID, testKeys)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tc.want {
+			t.Errorf("session %q: expected %d, got %d", tc.sessionID, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestAuthenticate_RoleComesFromStore(t *testing.T) {
+	// The token still says "student", but the user has since been promoted.
+	store := fakeStore{"s": {Active: true, UserID: "user-4", Role: "company", Status: "active"}}
+	token, _ := auth.GenerateToken("user-4", "student", "s", testKeys)
+
+	handler := Authenticate(testKeys, store)(
+		RequireRole("company")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})),
+	)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rec.Code)
+	}
+}
+
+func TestAuthenticate_APIKeys(t *testing.T) {
+	store := keyStore{keys: map[string]APIKeyState{
+		auth.HashOpaqueToken("szk_reader"): {Active: true, KeyID: "k1", UserID: "co-1", Role: "company",
+			Status: "active", Scopes: []string{"registrations:read"}},
+		auth.HashOpaqueToken("szk_suspended"): {Active: true, KeyID: "k2", UserID: "co-2", Role: "company",
+			Status: "suspended", Scopes: []string{"registrations:read"}},
+	}}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if GetAPIKeyID(r.Context()) == "" || GetUserID(r.Context()) == "" {
+			t.Error("key and owner not in context")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	authn := Authenticate(testKeys, store)
+	scoped := authn(RequireScope("registrations:read")(RequireRole("company")(ok)))
+	otherScope := authn(RequireScope("candidates:read")(ok))
+	unscoped := authn(RequireRole("company")(ok))
+
+	for _, tc := range []struct {
+		name    string
+		handler http.Handler
+		key     string
+		want    int
+	}{
+		{"scoped route", scoped, "szk_reader", http.StatusOK},
+		{"missing scope", otherScope, "szk_reader", http.StatusForbidden},
+		{"route without a scope", unscoped, "szk_reader", http.StatusForbidden},
+		{"unknown key", scoped, "szk_unknown", http.StatusUnauthorized},
+		{"suspended owner", scoped, "szk_suspended", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.key)
+		rec := httptest.NewRecorder()
+		tc.handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, rec.Code)
+		}
+	}
+
+	// Sessions are not affected by RequireScope.
+	store.fakeStore = fakeStore{"s": {Active: true, UserID: "co-1", Role: "company", Status: "active"}}
+	token, _ := auth.GenerateToken("co-1", "company", "s", testKeys)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	Authenticate(testKeys, store)(RequireScope("candidates:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("session on a scoped route: expected 200, got %d", rec.Code)
+	}
+}
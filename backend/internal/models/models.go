@@ -16,6 +16,24 @@ type UserRole string
 const (
 	RoleStudent UserRole = "student"
 	RoleCompany UserRole = "company"
+	// RoleAdmin operates the platform. Admins cannot self-register; they
+	// are created with `server admin` or the ADMIN_EMAIL bootstrap.
+	RoleAdmin UserRole = "admin"
+)
+
+// UserStatus says whether an account may be used.  Only admins change it.
+type UserStatus string
+
+const (
+	UserActive UserStatus = "active"
+	// UserSuspended blocks login and every authenticated request until an
+	// admin sets the account back to active.  Sessions survive, so the
+	// user's devices work again after reinstatement.
+	UserSuspended UserStatus = "suspended"
+	// UserDeleted is a soft delete: the row stays (events, badges and
+	// attendance keep their references) but the account is gone for good
+	// from the user's point of view — its sessions are revoked.
+	UserDeleted UserStatus = "deleted"
 )
 
 // EventStatus represents the lifecycle state of an event.
@@ -27,6 +45,9 @@ const (
 	EventStatusUpcoming  EventStatus = "upcoming"
 	EventStatusActive    EventStatus = "active"
 	EventStatusCompleted EventStatus = "completed"
+	// EventStatusCancelled is final: the event takes no registrations or
+	// check-ins and its status cannot change again.
+	EventStatusCancelled EventStatus = "cancelled"
 )
 
 // AttendanceStatus tracks a pending check-in's sync state on the server.
@@ -47,19 +68,176 @@ const (
 	RegistrationConfirmed       RegistrationStatus = "confirmed"
 	RegistrationConflictPending RegistrationStatus = "conflict_pending"
 	RegistrationWaitlisted      RegistrationStatus = "waitlisted"
+	// RegistrationOffered holds a freed seat for a student who moved up
+	// from the waitlist, until they accept or the offer expires.
+	RegistrationOffered RegistrationStatus = "offered"
+)
+
+// OrganisationRole is what a member may do in an organisation.  Each role
+// can do everything the ones below it can.
+type OrganisationRole string
+
+const (
+	// OrgStaff runs events: check-in codes, status, registrations.
+	OrgStaff OrganisationRole = "staff"
+	// OrgAdmin also creates and edits events and manages staff and admins.
+	OrgAdmin OrganisationRole = "admin"
+	// OrgOwner also manages owners.  Every organisation keeps at least one.
+	OrgOwner OrganisationRole = "owner"
+)
+
+// EventRole is a job on one event given to someone outside its
+// organisation, such as a volunteer.
+type EventRole string
+
+const (
+	// EventScanner shows the check-in QR code.
+	EventScanner EventRole = "scanner"
+	// EventCoordinator also sees the registrations.
+	EventCoordinator EventRole = "coordinator"
 )
 
 // User represents both student and company accounts.
 // The json:"-" tag on PasswordHash tells encoding/json to NEVER include it
 // in a JSON response — even if you forget to filter it manually.
 type User struct {
-	ID           string    `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"` // NEVER serialised to JSON
-	Name         string    `json:"name"`
-	Role         UserRole  `json:"role"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           string     `json:"id"`
+	Email        string     `json:"email"`
+	PasswordHash string     `json:"-"` // NEVER serialised to JSON
+	Name         string     `json:"name"`
+	Role         UserRole   `json:"role"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Status       UserStatus `json:"status,omitempty"`
+	// SuspendedAt is when the account was suspended, while it is.
+	SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+	// EmailVerifiedAt is when the owner proved they control Email.  Only
+	// set on the user's own profile and in the admin user list; unset
+	// there means not verified yet.
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	// PendingEmail is a requested new address still awaiting confirmation.
+	// Only set on the user's own profile (GET/PATCH /api/auth/me).
+	PendingEmail string `json:"pending_email,omitempty"`
+	// TwoFactorEnabled reports whether TOTP 2FA is on.  Only set on the
+	// user's own profile.
+	TwoFactorEnabled bool `json:"two_factor_enabled,omitempty"`
+	// Institution is the student's affiliation: the institution whose
+	// domain their verified email is on.  Set on the own profile and in
+	// candidate search.
+	Institution *InstitutionRef `json:"institution,omitempty"`
+}
+
+// Organisation is a company that hosts events, with the people who work
+// for it.  Role is the caller's own role; Members is only set by
+// GET /api/organisations/{id}.
+type Organisation struct {
+	ID        string               `json:"id"`
+	Name      string               `json:"name"`
+	Role      OrganisationRole     `json:"role,omitempty"`
+	CreatedAt time.Time            `json:"created_at"`
+	Members   []OrganisationMember `json:"members,omitempty"`
+}
+
+// OrganisationMember is one person in an organisation.
+type OrganisationMember struct {
+	UserID   string           `json:"user_id"`
+	Name     string           `json:"name"`
+	Email    string           `json:"email"`
+	Role     OrganisationRole `json:"role"`
+	JoinedAt time.Time        `json:"joined_at"`
+}
+
+// OrganisationInvitation is a pending emailed invitation to join.  The
+// token is only in the email.
+type OrganisationInvitation struct {
+	ID             string           `json:"id"`
+	OrganisationID string           `json:"organisation_id"`
+	Email          string           `json:"email"`
+	Role           OrganisationRole `json:"role"`
+	InvitedBy      string           `json:"invited_by"`
+	CreatedAt      time.Time        `json:"created_at"`
+	ExpiresAt      time.Time        `json:"expires_at"`
+}
+
+// EventRoleGrant is one person's role on an event.
+type EventRoleGrant struct {
+	EventID   string    `json:"event_id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Role      EventRole `json:"role"`
+	GrantedBy string    `json:"granted_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Institution is a university or college.  Domains are the email domains
+// an admin has checked belong to it; students who verify an address on one
+// of them (or a subdomain) are affiliated with it.
+type Institution struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Domains   []string  `json:"domains"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InstitutionRef names the institution a student is affiliated with.
+type InstitutionRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Session is one logged-in device, as listed by GET /api/auth/sessions.
+// The refresh token hash is never serialised.
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current is true for the session the request was made with.
+	Current bool `json:"current"`
+}
+
+// API key scopes.  A key can only call the routes that require one of its
+// scopes; everything else needs a logged-in session.
+const (
+	// ScopeEventsRead lists the company's own events.
+	ScopeEventsRead = "events:read"
+	// ScopeRegistrationsRead reads the registrations (and attendance
+	// status) of the company's events.
+	ScopeRegistrationsRead = "registrations:read"
+	// ScopeCandidatesRead searches student profiles.
+	ScopeCandidatesRead = "candidates:read"
+)
+
+// APIKey is a company's API key as listed by GET /api/api-keys.  The key
+// itself is only ever returned once, by POST /api/api-keys; Prefix is its
+// first characters, to tell keys apart.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+}
+
+// CreateAPIKeyRequest is used by POST /api/api-keys.  ExpiresAt is
+// optional; without it the key works until revoked.
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreatedAPIKey is returned by POST /api/api-keys.  Key is the secret to
+// send as "Authorization: Bearer <key>"; it cannot be retrieved again.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
 }
 
 // Skill is a badge that can be awarded to a student upon event completion.
@@ -81,6 +259,18 @@ type Event struct {
 	EndTime     time.Time   `json:"end_time"`
 	Status      EventStatus `json:"status"`
 
+	// OrganisationID is the organisation whose members manage the event.
+	// HostID is only the member who created it.
+	OrganisationID string `json:"organisation_id"`
+
+	// CancellationReason and CancelledAt are set once Status is cancelled.
+	CancellationReason string     `json:"cancellation_reason,omitempty"`
+	CancelledAt        *time.Time `json:"cancelled_at,omitempty"`
+
+	// ManualStatus opts the event out of the status scheduler: its hosts
+	// move it to active and completed themselves.
+	ManualStatus bool `json:"manual_status"`
+
 	// CheckInCode is a short-lived secret embedded in the host's QR code.
 	// omitempty means it is omitted from JSON when empty — the list endpoint
 	// never populates it; only the host's /checkin-code endpoint does.
@@ -92,6 +282,15 @@ type Event struct {
 	Capacity       *int `json:"capacity,omitempty"`
 	SlotsRemaining *int `json:"slots_remaining,omitempty"`
 
+	// SyncDeadline is the last moment an offline check-in for this event may
+	// be synced. nil means the server default (end_time + a grace period).
+	SyncDeadline *time.Time `json:"sync_deadline,omitempty"`
+
+	// CheckInRotationSeconds turns on rotating QR codes: the host screen
+	// shows a new check-in token every N seconds. nil means one static
+	// token valid for the whole scan window.
+	CheckInRotationSeconds *int `json:"checkin_rotation_seconds,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
@@ -112,6 +311,8 @@ type Registration struct {
 	StudentID    string             `json:"student_id"`
 	RegisteredAt time.Time          `json:"registered_at"`
 	Status       RegistrationStatus `json:"status"`
+	// OfferExpiresAt is when an offered seat goes to the next in line.
+	OfferExpiresAt *time.Time `json:"offer_expires_at,omitempty"`
 } // Attendance is the cryptographic check-in proof submitted by a student.
 // Local-first flow:
 //  1. Host's device shows a QR code containing a CheckInPayload JSON.
@@ -162,9 +363,237 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// LoginResponse is returned by every endpoint that starts or renews a session.
+// Token is the short-lived access token sent as "Authorization: Bearer …";
+// RefreshToken is exchanged at POST /api/auth/refresh for a new pair once
+// the access token expires (ExpiresIn seconds after issue).
 type LoginResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	User         User   `json:"user"`
+	// RecoveryCodes is only set when the login also finished 2FA enrollment;
+	// it is the one time the codes are shown.
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// TwoFactorChallenge is returned by login (and register) instead of a
+// LoginResponse when a second factor is needed.  ChallengeToken is sent to
+// POST /api/auth/login/2fa with a code; it expires after ExpiresIn seconds.
+// EnrollmentRequired means the role requires 2FA but the account has none
+// yet: call POST /api/auth/login/2fa/enroll first.
+type TwoFactorChallenge struct {
+	TwoFactorRequired  bool   `json:"two_factor_required"`
+	EnrollmentRequired bool   `json:"enrollment_required,omitempty"`
+	ChallengeToken     string `json:"challenge_token"`
+	ExpiresIn          int    `json:"expires_in"`
+}
+
+// LoginTwoFactorRequest is used by POST /api/auth/login/2fa.  Code is a
+// six-digit authenticator code or a recovery code.
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// ChallengeRequest is used by POST /api/auth/login/2fa/enroll.
+type ChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+// TwoFactorEnrollment is the new TOTP secret, both raw (for typing in) and
+// as an otpauth:// URI (for a QR code).
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// TwoFactorCodeRequest is used by POST /api/auth/2fa/confirm and
+// POST /api/auth/2fa/recovery-codes.
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// DisableTwoFactorRequest is used by POST /api/auth/2fa/disable.  Code may
+// be an authenticator code or a recovery code.
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// RecoveryCodes is a fresh set of single-use 2FA recovery codes.
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorPolicy is read and written at /api/admin/two-factor-policy.
+type TwoFactorPolicy struct {
+	RequiredRoles []UserRole `json:"required_roles"`
+}
+
+// VerifyEmailRequest is used by POST /api/auth/email/verify.  Token is the
+// value from the emailed link.
+type VerifyEmailRequest struct {
 	Token string `json:"token"`
-	User  User   `json:"user"`
+}
+
+// MagicLinkRequest is used by POST /api/auth/magic-link.
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// MagicLinkVerifyRequest is used by POST /api/auth/magic-link/verify.
+type MagicLinkVerifyRequest struct {
+	Token string `json:"token"`
+}
+
+// OIDCProvider is one entry of GET /api/auth/oidc/providers.
+type OIDCProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// OIDCExchangeRequest is used by POST /api/auth/oidc/exchange.  Token is the
+// handoff token from the frontend's /oidc/callback URL.
+type OIDCExchangeRequest struct {
+	Token string `json:"token"`
+}
+
+// RefreshRequest is used by POST /api/auth/refresh.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// ForgotPasswordRequest is used by POST /api/auth/password/forgot.
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest is used by POST /api/auth/password/reset.  Token is
+// the value from the emailed link.
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// UpdateProfileRequest is used by PATCH /api/auth/me.  Omitted fields are
+// left unchanged.  A new email only takes effect once confirmed.
+type UpdateProfileRequest struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+}
+
+// ChangePasswordRequest is used by POST /api/auth/me/password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ConfirmEmailRequest is used by POST /api/auth/email/confirm.  Token is
+// the value from the emailed link.
+type ConfirmEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// UserList is returned by GET /api/admin/users.  Total counts every match,
+// not just the page in Users.
+type UserList struct {
+	Users []User `json:"users"`
+	Total int    `json:"total"`
+}
+
+// LoginAttempt is one failed login in the audit trail.  Reason is
+// "unknown_email", "bad_password" or "locked" (refused during a lockout
+// without checking the password).  UserID is empty for unknown emails.
+type LoginAttempt struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	UserID    string    `json:"user_id,omitempty"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LoginAttemptList is returned by GET /api/admin/login-attempts.
+type LoginAttemptList struct {
+	Attempts []LoginAttempt `json:"attempts"`
+	Total    int            `json:"total"`
+}
+
+// Invariant names a rule the database should always satisfy; see
+// handlers/check.go.
+type Invariant string
+
+const (
+	InvariantSlots                  Invariant = "slots_remaining"
+	InvariantAttendanceRegistration Invariant = "attendance_without_registration"
+	InvariantSkillAttendance        Invariant = "skill_without_attendance"
+	InvariantOrphanedEventSkill     Invariant = "orphaned_event_skill"
+)
+
+// InvariantViolation is one row breaking an invariant.  Subject is the id
+// of the offending row (for event_skills, "event_id/skill_id").
+type InvariantViolation struct {
+	Invariant Invariant `json:"invariant"`
+	Subject   string    `json:"subject"`
+	Detail    string    `json:"detail"`
+}
+
+// CheckReport is returned by GET /api/admin/check and
+// POST /api/admin/check/repair.  Repaired is true when the violations were
+// fixed.
+type CheckReport struct {
+	Violations []InvariantViolation `json:"violations"`
+	Repaired   bool                 `json:"repaired"`
+	CheckedAt  time.Time            `json:"checked_at"`
+}
+
+// UpdateUserRoleRequest is used by PATCH /api/admin/users/{id}/role
+type UpdateUserRoleRequest struct {
+	Role UserRole `json:"role"`
+}
+
+// UpdateUserStatusRequest is used by PATCH /api/admin/users/{id}/status
+type UpdateUserStatusRequest struct {
+	Status UserStatus `json:"status"`
+}
+
+// CreateOrganisationRequest is used by POST /api/organisations.
+type CreateOrganisationRequest struct {
+	Name string `json:"name"`
+}
+
+// InviteMemberRequest is used by POST /api/organisations/{id}/invitations.
+type InviteMemberRequest struct {
+	Email string           `json:"email"`
+	Role  OrganisationRole `json:"role"`
+}
+
+// UpdateMemberRoleRequest is used by
+// PATCH /api/organisations/{id}/members/{user_id}.
+type UpdateMemberRoleRequest struct {
+	Role OrganisationRole `json:"role"`
+}
+
+// AcceptInvitationRequest is used by POST /api/organisations/invitations/accept.
+// Token is the value from the emailed link.
+type AcceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+// GrantEventRoleRequest is used by POST /api/events/{id}/roles.  Email
+// names an existing account; a second grant replaces the first.
+type GrantEventRoleRequest struct {
+	Email string    `json:"email"`
+	Role  EventRole `json:"role"`
+}
+
+// InstitutionRequest is used by POST /api/admin/institutions and
+// PUT /api/admin/institutions/{id}.  Domains replaces the full list.
+type InstitutionRequest struct {
+	Name    string   `json:"name"`
+	Domains []string `json:"domains"`
 }
 
 type CreateEventRequest struct {
@@ -179,11 +608,24 @@ type CreateEventRequest struct {
 	// Capacity, if > 0, caps the number of confirmed registrations.
 	// Leave 0 or omit for unlimited. Used for internships with limited slots.
 	Capacity int `json:"capacity,omitempty"`
+	// SyncDeadline, if set, must be after EndTime. Omit for the server default.
+	SyncDeadline *time.Time `json:"sync_deadline,omitempty"`
+	// CheckInRotationSeconds, if > 0, enables rotating QR codes with this
+	// period. Leave 0 or omit for a single static code.
+	CheckInRotationSeconds int `json:"checkin_rotation_seconds,omitempty"`
+	// OrganisationID is the organisation hosting the event; the caller must
+	// be one of its owners or admins.  It may be omitted when the caller
+	// manages exactly one organisation (or none yet: one is created).
+	OrganisationID string `json:"organisation_id,omitempty"`
+	// ManualStatus keeps the status scheduler away from the event.
+	ManualStatus bool `json:"manual_status,omitempty"`
 }
 
 // UpdateEventStatusRequest is used by PATCH /api/events/{id}/status
+// Reason is required when Status is cancelled, and shown to registrants.
 type UpdateEventStatusRequest struct {
 	Status EventStatus `json:"status"`
+	Reason string      `json:"reason,omitempty"`
 }
 
 // UpdateEventRequest is used by PUT /api/events/{id}
@@ -198,6 +640,13 @@ type UpdateEventRequest struct {
 	SkillIDs *[]string `json:"skill_ids"`
 	// Capacity: set to 0 or negative to make unlimited; nil = no change.
 	Capacity *int `json:"capacity"`
+	// SyncDeadline: nil = no change. Must be after the (new) end_time.
+	SyncDeadline *time.Time `json:"sync_deadline"`
+	// CheckInRotationSeconds: set to 0 to switch back to a static code;
+	// nil = no change.
+	CheckInRotationSeconds *int `json:"checkin_rotation_seconds"`
+	// ManualStatus: nil = no change.
+	ManualStatus *bool `json:"manual_status"`
 }
 
 // ResolveConflictRequest is used by PATCH /api/events/{id}/registrations/{reg_id}
@@ -228,38 +677,70 @@ type SyncAttendanceResponse struct {
 	Results []SyncResult `json:"results"`
 }
 
+// SyncRejectReason is a machine-readable code explaining why a sync record
+// was rejected. The PWA switches on it (e.g. to tell the student "this QR
+// was scanned too late" vs "try again later"); Message stays human-readable.
+type SyncRejectReason string
+
+const (
+	RejectInvalidPayload     SyncRejectReason = "invalid_payload"
+	RejectMissingToken       SyncRejectReason = "missing_token"
+	RejectInvalidToken       SyncRejectReason = "invalid_token"
+	RejectEventMismatch      SyncRejectReason = "event_mismatch"
+	RejectEventNotFound      SyncRejectReason = "event_not_found"
+	RejectMissingScanTime    SyncRejectReason = "missing_scan_time"
+	RejectScanInFuture       SyncRejectReason = "scan_in_future"
+	RejectScanOutsideWindow  SyncRejectReason = "scan_outside_window"
+	RejectSyncDeadlinePassed SyncRejectReason = "sync_deadline_passed"
+	// RejectCodeRotated means the token was not the one on the host's
+	// screen at the recorded scan time (rotating-code events only).
+	RejectCodeRotated SyncRejectReason = "code_rotated"
+	// RejectCodeRevoked means the host rotated the event's check-in secret
+	// after the token was issued, so its host_sig no longer matches.
+	RejectCodeRevoked SyncRejectReason = "code_revoked"
+	// RejectEventCancelled means the host cancelled the event.
+	RejectEventCancelled SyncRejectReason = "event_cancelled"
+	// RejectEventDeleted means the host deleted the event before it finished.
+	RejectEventDeleted SyncRejectReason = "event_deleted"
+	// RejectInternalError means the server failed, not the record — the
+	// client should keep it queued and retry.
+	RejectInternalError SyncRejectReason = "internal_error"
+)
+
 // SyncResult tells the client whether each record was accepted or rejected,
-// with a human-readable message for any rejection reason.
+// with a machine-readable reason and a human-readable message for rejections.
 type SyncResult struct {
 	LocalID string           `json:"local_id"`
 	Status  AttendanceStatus `json:"status"`
+	Reason  SyncRejectReason `json:"reason,omitempty"`
 	Message string           `json:"message,omitempty"`
 }
 
-// CheckInPayload is the structure embedded in the host's QR code.
+// CheckInPayload is the structure the student's device stores after scanning
+// the host's QR code.
 //
 // Security model (v2 — signed token):
 //   - Token is a short-lived JWT (6 h) signed with the server secret.
 //   - The JWT carries event_id and host_sig claims inside its payload.
-//   - A student can only produce a valid payload if they scanned the host's
-//     QR while it was live — the JWT's exp enforces the scan window.
-//   - The student's sync can happen at ANY time after scanning; the server
-//     verifies the JWT signature (was it signed by us?) but does NOT
-//     re-check whether the token is still "fresh" at sync time.
-//     This lets students with poor connectivity sync days later.
+//   - Timestamp is added by the student's device at scan time. The server
+//     requires it to fall inside the token's iat/exp window (plus clock
+//     skew), so a token photographed off the projector can't be "scanned"
+//     hours later.
+//   - The sync itself may happen later, but only until the event's sync
+//     deadline.
 //
-// Migration note: the legacy fields EventID, HostSig, and Timestamp are
-// retained as omitempty so that clients on the old format get a clear
-// rejection rather than a panic.
+// Migration note: the legacy fields EventID and HostSig are retained as
+// omitempty so that clients on the old format get a clear rejection rather
+// than a panic.
 type CheckInPayload struct {
 	// Token is the signed JWT produced by GET /api/events/{id}/checkin-code.
-	// This is the only field the server validates. Presence of the other
-	// fields is optional and only used by legacy clients.
 	Token string `json:"token"`
 
+	// Timestamp is when the student's device scanned the QR, in Unix seconds.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
 	// Deprecated legacy fields — ignored by the server but kept so old
 	// clients fail gracefully with "missing token" rather than a crash.
-	EventID   string `json:"event_id,omitempty"`
-	HostSig   string `json:"host_sig,omitempty"`
-	Timestamp int64  `json:"timestamp,omitempty"`
+	EventID string `json:"event_id,omitempty"`
+	HostSig string `json:"host_sig,omitempty"`
 }
@@ -0,0 +1,442 @@
+// Package oidc is the relying-party half of OpenID Connect: it sends users
+// to an identity provider (IdP) and verifies who the IdP says came back.
+//
+// ────────────────────────────────────────────────────────────────────
+// LEARNING NOTE — the authorization code flow with PKCE
+// ────────────────────────────────────────────────────────────────────
+//  1. We redirect the browser to the IdP's authorization endpoint with a
+//     random `state`, a random `nonce` and a code_challenge — the SHA-256
+//     of a random code_verifier we keep to ourselves (PKCE, RFC 7636).
+//  2. The user logs in at the IdP, which redirects back to our callback
+//     with a one-time `code` and the same `state`.  Checking state proves
+//     the callback answers a login WE started.
+//  3. We POST the code and the code_verifier to the token endpoint.  The IdP
+//     only answers if the verifier hashes to the challenge from step 1, so a
+//     code stolen from a URL or log is useless on its own.
+//  4. The answer contains an ID token: a JWT signed by the IdP.  We check
+//     the signature against the IdP's published keys (jwks_uri), that it was
+//     issued by the IdP we asked (iss), for us (aud), is not expired, and
+//     carries the nonce from step 1 so it cannot be replayed from another
+//     login.  Only then do we trust its claims (sub, email, …).
+//
+// The endpoints and key URL are read from the IdP's discovery document
+// (<issuer>/.well-known/openid-configuration) on first use, so configuring a
+// provider only needs its issuer URL and our client credentials.
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config describes one identity provider.  It is usually loaded from JSON
+// (see ParseConfigs).
+type Config struct {
+	// Name identifies the provider in URLs (/api/auth/oidc/{name}/start)
+	// and in stored identities.  Lowercase letters, digits and dashes.
+	Name string `json:"name"`
+	// DisplayName is shown on the login button.
+	DisplayName string `json:"display_name"`
+	Issuer      string `json:"issuer"`
+	ClientID    string `json:"client_id"`
+	// ClientSecret may be empty for public clients, which rely on PKCE alone.
+	ClientSecret string `json:"client_secret"`
+	// RedirectURL is our callback as registered with the IdP.
+	RedirectURL string `json:"redirect_url"`
+	// Scopes default to openid, email and profile.
+	Scopes []string `json:"scopes"`
+	// RoleClaim names the ID token claim (a string or list of strings, e.g.
+	// "groups") whose values RoleMap translates to Skillzone roles: student
+	// or company only, as admins are never created from an identity provider.
+	RoleClaim string            `json:"role_claim"`
+	RoleMap   map[string]string `json:"role_map"`
+	// DefaultRole is used when no claim value is in RoleMap.  Empty means
+	// such users may not sign up.
+	DefaultRole string `json:"default_role"`
+}
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// ParseConfigs reads a JSON array of provider configs and checks the
+// fields every provider needs.
+func ParseConfigs(data []byte) ([]Config, error) {
+	var cfgs []Config
+	if err := json.Unmarshal(data, &cfgs); err != nil {
+		return nil, fmt.Errorf("parse OIDC providers: %w", err)
+	}
+	seen := map[string]bool{}
+	for i, c := range cfgs {
+		switch {
+		case !validName.MatchString(c.Name):
+			return nil, fmt.Errorf("OIDC provider %d: name %q must be lowercase letters, digits and dashes", i, c.Name)
+		case seen[c.Name]:
+			return nil, fmt.Errorf("OIDC provider %q: duplicate name", c.Name)
+		case c.Issuer == "" || c.ClientID == "":
+			return nil, fmt.Errorf("OIDC provider %q: issuer and client_id are required", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return cfgs, nil
+}
+
+// Provider talks to one IdP.  Discovery and keys are fetched lazily and
+// cached, so an IdP that is down at startup does not stop the server.
+type Provider struct {
+	Config
+	// Client makes the requests to the IdP; nil uses a client with a 10 s
+	// timeout.
+	Client *http.Client
+
+	mu         sync.Mutex
+	meta       *metadata
+	keys       map[string]any
+	keysLoaded time.Time
+}
+
+// NewProvider returns a Provider for cfg.
+func NewProvider(cfg Config) *Provider {
+	return &Provider{Config: cfg}
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+func (p *Provider) client() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return defaultClient
+}
+
+func (p *Provider) scopes() []string {
+	if len(p.Scopes) > 0 {
+		return p.Scopes
+	}
+	return []string{"openid", "email", "profile"}
+}
+
+// getJSON fetches url and decodes a JSON body into v.
+func (p *Provider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// discover returns the provider's metadata, fetching it on first use.
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+	var m metadata
+	if err := p.getJSON(ctx, strings.TrimRight(p.Issuer, "/")+"/.well-known/openid-configuration", &m); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	// The issuer in the document must be the one we were configured with,
+	// or a compromised document could point us at someone else's tokens.
+	if m.Issuer != p.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", m.Issuer, p.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: document is missing endpoints")
+	}
+	p.meta = &m
+	return p.meta, nil
+}
+
+// NewPKCE returns a random code_verifier and its S256 code_challenge.
+func NewPKCE() (verifier, challenge string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(buf)
+	return verifier, CodeChallenge(verifier), nil
+}
+
+// CodeChallenge is the S256 transformation of a PKCE code_verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the IdP URL to send the browser to.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.ClientID)
+	q.Set("redirect_uri", p.RedirectURL)
+	q.Set("scope", strings.Join(p.scopes(), " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+	sep := "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return m.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange trades an authorization code for the raw ID token.
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", p.ClientID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.ClientSecret != "" {
+		// client_secret_basic, the default client authentication method.
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oidc token request: %w", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("oidc token response: %s: %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("oidc token response: %s: %s %s", resp.Status, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("oidc token response: no id_token")
+	}
+	return body.IDToken, nil
+}
+
+// Claims are the verified claims of an ID token.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+
+	raw jwt.MapClaims
+}
+
+// Strings returns claim name as a list: a string claim gives one value, a
+// list claim its string members, anything else none.
+func (c *Claims) Strings(name string) []string {
+	switch v := c.raw[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var out []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// clockLeeway allows for small clock differences with the IdP.
+const clockLeeway = time.Minute
+
+// Verify checks an ID token's signature, issuer, audience, expiry and
+// nonce, and returns its claims.
+func (p *Provider) Verify(ctx context.Context, rawIDToken, nonce string) (*Claims, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mc := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, mc,
+		func(t *jwt.Token) (any, error) {
+			kid, _ := t.Header["kid"].(string)
+			return p.key(ctx, m.JWKSURI, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384"}),
+		jwt.WithIssuer(m.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockLeeway),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("oidc id token: %w", err)
+	}
+	if got, _ := mc["nonce"].(string); got == "" || got != nonce {
+		return nil, errors.New("oidc id token: nonce mismatch")
+	}
+	// With several audiences, azp must name us (OIDC Core 3.1.3.7).
+	if azp, ok := mc["azp"].(string); ok && azp != p.ClientID {
+		return nil, errors.New("oidc id token: issued to another client")
+	}
+
+	c := &Claims{raw: mc}
+	c.Subject, _ = mc["sub"].(string)
+	c.Email, _ = mc["email"].(string)
+	c.Name, _ = mc["name"].(string)
+	switch v := mc["email_verified"].(type) {
+	case bool:
+		c.EmailVerified = v
+	case string: // some IdPs send "true"
+		c.EmailVerified = v == "true"
+	}
+	if c.Subject == "" {
+		return nil, errors.New("oidc id token: no sub claim")
+	}
+	return c, nil
+}
+
+// keysRefetchInterval limits how often an unknown kid makes us download the
+// key set again, so forged tokens cannot make us hammer the IdP.
+const keysRefetchInterval = time.Minute
+
+// key returns the IdP's public key with the given kid, refetching the key
+// set once if the IdP has rotated keys since we last looked.
+func (p *Provider) key(ctx context.Context, jwksURI, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if k, ok := p.lookupKey(kid); ok {
+		return k, nil
+	}
+	if time.Since(p.keysLoaded) < keysRefetchInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("fetch signing keys: %w", err)
+	}
+	p.keys = map[string]any{}
+	for _, j := range set.Keys {
+		if j.Use != "" && j.Use != "sig" {
+			continue
+		}
+		if k, err := j.publicKey(); err == nil {
+			p.keys[j.Kid] = k
+		}
+	}
+	p.keysLoaded = time.Now()
+	if k, ok := p.lookupKey(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds kid in the cached set.  A token without a kid is accepted
+// only when the IdP publishes exactly one key.
+func (p *Provider) lookupKey(kid string) (any, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+	k, ok := p.keys[kid]
+	return k, ok
+}
+
+// jwk is the subset of RFC 7517 needed for RSA and EC signing keys.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (j jwk) publicKey() (any, error) {
+	num := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return nil, errors.New("bad key parameter")
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+	switch j.Kty {
+	case "RSA":
+		n, err := num(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := num(j.E)
+		if err != nil || !e.IsInt64() {
+			return nil, errors.New("bad RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384()}
+		curve, ok := curves[j.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, err := num(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := num(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) { //nolint:staticcheck // ecdh cannot express ecdsa.PublicKey
+			return nil, errors.New("EC point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+}
@@ -0,0 +1,141 @@
+package oidc_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Elizabethomito/skillzone/backend/internal/oidc"
+	"github.com/Elizabethomito/skillzone/backend/internal/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const redirectURL = "http://localhost:8080/api/auth/oidc/test/callback"
+
+// login runs the code flow against idp and returns the code and the PKCE
+// verifier that goes with it.
+func login(t *testing.T, p *oidc.Provider, idp *oidctest.IdP, nonce string) (code, verifier string) {
+	t.Helper()
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := p.AuthCodeURL(context.Background(), "state-1", nonce, challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	back, err := idp.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if got := back.Query().Get("state"); got != "state-1" {
+		t.Fatalf("state: got %q", got)
+	}
+	if !strings.HasPrefix(back.String(), redirectURL) {
+		t.Fatalf("redirected to %s", back)
+	}
+	return back.Query().Get("code"), verifier
+}
+
+func TestProvider_CodeFlow(t *testing.T) {
+	idp := oidctest.New("skillzone", "s3cret")
+	defer idp.Close()
+	idp.SetClaims(map[string]any{
+		"sub": "u-1", "email": "amara@uni.test", "email_verified": true,
+		"name": "Amara Osei", "groups": []string{"students", "chess-club"},
+	})
+	p := oidc.NewProvider(idp.Config("test", redirectURL))
+	ctx := context.Background()
+
+	code, verifier := login(t, p, idp, "nonce-1")
+	raw, err := p.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	claims, err := p.Verify(ctx, raw, "nonce-1")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.Subject != "u-1" || claims.Email != "amara@uni.test" || !claims.EmailVerified || claims.Name != "Amara Osei" {
+		t.Errorf("claims: %+v", claims)
+	}
+	if got := claims.Strings("groups"); len(got) != 2 || got[0] != "students" {
+		t.Errorf("groups: %v", got)
+	}
+
+	if _, err := p.Verify(ctx, raw, "another-nonce"); err == nil {
+		t.Error("token accepted with the wrong nonce")
+	}
+	if _, err := p.Exchange(ctx, code, verifier); err == nil {
+		t.Error("code accepted twice")
+	}
+}
+
+func TestProvider_PKCEVerifierRequired(t *testing.T) {
+	idp := oidctest.New("skillzone", "")
+	defer idp.Close()
+	idp.SetClaims(map[string]any{"sub": "u-1"})
+	p := oidc.NewProvider(idp.Config("test", redirectURL))
+
+	code, _ := login(t, p, idp, "n")
+	other, _, _ := oidc.NewPKCE()
+	if _, err := p.Exchange(context.Background(), code, other); err == nil {
+		t.Error("code exchanged with the wrong verifier")
+	}
+}
+
+func TestProvider_VerifyRejects(t *testing.T) {
+	idp := oidctest.New("skillzone", "")
+	defer idp.Close()
+	p := oidc.NewProvider(idp.Config("test", redirectURL))
+	now := time.Now()
+	base := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": idp.URL, "aud": "skillzone", "sub": "u-1", "nonce": "n",
+			"iat": now.Unix(), "exp": now.Add(time.Minute).Unix(),
+		}
+	}
+	if _, err := p.Verify(context.Background(), idp.Sign(base()), "n"); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+
+	for name, mutate := range map[string]func(jwt.MapClaims){
+		"wrong audience": func(c jwt.MapClaims) { c["aud"] = "someone-else" },
+		"wrong issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.test" },
+		"expired":        func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Hour).Unix() },
+		"no expiry":      func(c jwt.MapClaims) { delete(c, "exp") },
+		"other azp":      func(c jwt.MapClaims) { c["aud"] = []string{"skillzone", "x"}; c["azp"] = "x" },
+	} {
+		c := base()
+		mutate(c)
+		if _, err := p.Verify(context.Background(), idp.Sign(c), "n"); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+
+	// Only the IdP's asymmetric algorithms are allowed; accepting HS256 is
+	// the classic algorithm-confusion hole.
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, base()).SignedString([]byte("anything"))
+	if _, err := p.Verify(context.Background(), forged, "n"); err == nil {
+		t.Error("HS256 token accepted")
+	}
+}
+
+func TestParseConfigs(t *testing.T) {
+	cfgs, err := oidc.ParseConfigs([]byte(`[{"name":"uon","issuer":"https://idp.test","client_id":"c",
+		"role_claim":"groups","role_map":{"staff":"company"},"default_role":"student"}]`))
+	if err != nil || len(cfgs) != 1 || cfgs[0].RoleMap["staff"] != "company" {
+		t.Fatalf("got %+v, %v", cfgs, err)
+	}
+	for _, bad := range []string{
+		`[{"name":"Bad Name","issuer":"https://idp.test","client_id":"c"}]`,
+		`[{"name":"a","client_id":"c"}]`,
+		`[{"name":"a","issuer":"i","client_id":"c"},{"name":"a","issuer":"i","client_id":"c"}]`,
+		`{`,
+	} {
+		if _, err := oidc.ParseConfigs([]byte(bad)); err == nil {
+			t.Errorf("accepted %s", bad)
+		}
+	}
+}
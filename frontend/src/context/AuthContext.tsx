@@ -2,7 +2,8 @@
  * AuthContext.tsx — Authentication state using the Skillzone REST API.
  *
  * Key design decisions:
- * - Tokens stored in localStorage keyed by "sz_token"; user profile stored as
+ * - Tokens stored in localStorage keyed by "sz_token" / "sz_refresh_token"
+ *   (api.ts refreshes the short-lived access token on 401); user profile stored as
  *   "sz_user" so the UI can bootstrap offline without hitting the server.
  * - Multi-user safety: AuthContext stores the current user's ID so the Dexie
  *   sync_queue can tag actions with user_id, preventing data mixing between
@@ -24,12 +25,20 @@ import {
 import {
   apiLogin,
   apiRegister,
+  isTwoFactorChallenge,
   apiMe,
+  apiLogout,
   setToken,
+  setRefreshToken,
   getToken,
   type User,
 } from "../lib/api";
 import { initSyncListener, runSync } from "../lib/sync";
+import { refreshCheckInKeys } from "../lib/checkin";
+
+// The app has no second-step screen yet; accounts with 2FA sign in elsewhere.
+const twoFactorUnsupported =
+  "This account uses two-factor authentication, which this app does not support yet.";
 
 // ─── Context shape ────────────────────────────────────────────────────────────
 
@@ -85,6 +94,12 @@ export function AuthProvider({ children }: { children: ReactNode }) {
     initSyncListener(() => user?.id ?? null);
   }, [user]);
 
+  // Cache the check-in public keys while online, so QR codes scanned later
+  // at a venue without signal can still be verified on the device.
+  useEffect(() => {
+    if (user && navigator.onLine) refreshCheckInKeys().catch(() => {});
+  }, [user]);
+
   const signUp = useCallback(
     async (
       email: string,
@@ -93,7 +108,9 @@ export function AuthProvider({ children }: { children: ReactNode }) {
       role: "student" | "company"
     ) => {
       const res = await apiRegister(email, password, name, role);
+      if (isTwoFactorChallenge(res)) throw new Error(twoFactorUnsupported);
       setToken(res.token);
+      setRefreshToken(res.refresh_token);
       setUser(res.user);
       localStorage.setItem("sz_user", JSON.stringify(res.user));
     },
@@ -102,7 +119,9 @@ export function AuthProvider({ children }: { children: ReactNode }) {
 
   const signIn = useCallback(async (email: string, password: string) => {
     const res = await apiLogin(email, password);
+    if (isTwoFactorChallenge(res)) throw new Error(twoFactorUnsupported);
     setToken(res.token);
+    setRefreshToken(res.refresh_token);
     setUser(res.user);
     localStorage.setItem("sz_user", JSON.stringify(res.user));
     // Drain any queued offline actions for this user
@@ -110,7 +129,10 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   }, []);
 
   const signOut = useCallback(() => {
+    // Best effort — if we're offline the session simply expires server-side.
+    apiLogout().catch(() => {});
     setToken(null);
+    setRefreshToken(null);
     setUser(null);
     localStorage.removeItem("sz_user");
     localStorage.removeItem("sz_token");
@@ -177,7 +177,9 @@ export async function enqueueCheckIn(
     user_id: userId,
     action: "ATTENDANCE_CHECK_IN",
     event_id: eventId,
-    payload: JSON.stringify({ token }),
+    // The scan time (Unix seconds) must fall inside the token's validity
+    // window; the server rejects scans it cannot place in time.
+    payload: JSON.stringify({ token, timestamp: Math.floor(Date.now() / 1000) }),
     status: "PENDING",
     queued_at: Date.now(),
   });
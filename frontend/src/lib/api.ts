@@ -11,6 +11,7 @@ const BASE = import.meta.env.VITE_API_URL ?? "http://localhost:8080";
 // ─── Token storage ────────────────────────────────────────────────────────────
 
 let _token: string | null = localStorage.getItem("sz_token");
+let _refreshToken: string | null = localStorage.getItem("sz_refresh_token");
 
 export function setToken(token: string | null) {
   _token = token;
@@ -22,11 +23,49 @@ export function getToken(): string | null {
   return _token;
 }
 
+export function setRefreshToken(token: string | null) {
+  _refreshToken = token;
+  if (token) localStorage.setItem("sz_refresh_token", token);
+  else localStorage.removeItem("sz_refresh_token");
+}
+
+// Access tokens live 15 minutes. When one expires we swap the refresh token
+// for a new pair. Refresh tokens are single-use, so concurrent 401s must share
+// one in-flight refresh instead of each spending the token.
+let _refreshing: Promise<boolean> | null = null;
+
+function refreshSession(): Promise<boolean> {
+  if (!_refreshToken) return Promise.resolve(false);
+  if (!_refreshing) {
+    _refreshing = fetch(`${BASE}/api/auth/refresh`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ refresh_token: _refreshToken }),
+    })
+      .then(async (res) => {
+        if (!res.ok) {
+          if (res.status === 401) setRefreshToken(null);
+          return false;
+        }
+        const body = (await res.json()) as LoginResponse;
+        setToken(body.token);
+        setRefreshToken(body.refresh_token);
+        return true;
+      })
+      .catch(() => false)
+      .finally(() => {
+        _refreshing = null;
+      });
+  }
+  return _refreshing;
+}
+
 // ─── Core fetch wrapper ───────────────────────────────────────────────────────
 
 async function apiFetch<T>(
   path: string,
-  options: RequestInit = {}
+  options: RequestInit = {},
+  retried = false
 ): Promise<T> {
   const headers: Record<string, string> = {
     "Content-Type": "application/json",
@@ -36,6 +75,10 @@ async function apiFetch<T>(
 
   const res = await fetch(`${BASE}${path}`, { ...options, headers });
 
+  if (res.status === 401 && _token && !retried && (await refreshSession())) {
+    return apiFetch<T>(path, options, true);
+  }
+
   if (!res.ok) {
     let msg = `HTTP ${res.status}`;
     try {
@@ -56,14 +99,46 @@ export interface User {
   id: string;
   email: string;
   name: string;
-  role: "student" | "company";
+  role: "student" | "company" | "admin";
+  status?: "active" | "suspended" | "deleted";
   created_at: string;
   updated_at: string;
+  pending_email?: string; // requested new email awaiting confirmation
+  two_factor_enabled?: boolean; // own profile only
+  email_verified_at?: string; // own profile only; absent until verified
+  institution?: InstitutionRef; // students: set from their verified email's domain
+}
+
+export interface InstitutionRef {
+  id: string;
+  name: string;
 }
 
 export interface LoginResponse {
   token: string;
+  refresh_token: string;
+  expires_in: number;
   user: User;
+  recovery_codes?: string[]; // only when this login finished 2FA enrollment
+}
+
+/** Returned by login/register instead of tokens when a 2FA code is needed. */
+export interface TwoFactorChallenge {
+  two_factor_required: true;
+  enrollment_required?: boolean; // call apiLoginTwoFactorEnroll first
+  challenge_token: string;
+  expires_in: number;
+}
+
+export interface TwoFactorEnrollment {
+  secret: string;
+  otpauth_uri: string; // render as a QR code
+}
+
+export function isTwoFactorChallenge(
+  res: LoginResponse | TwoFactorChallenge
+): res is TwoFactorChallenge {
+  return "two_factor_required" in res && res.two_factor_required === true;
 }
 
 export async function apiRegister(
@@ -71,7 +146,7 @@ export async function apiRegister(
   password: string,
   name: string,
   role: "student" | "company"
-): Promise<LoginResponse> {
+): Promise<LoginResponse | TwoFactorChallenge> {
   return apiFetch("/api/auth/register", {
     method: "POST",
     body: JSON.stringify({ email, password, name, role }),
@@ -81,17 +156,187 @@ export async function apiRegister(
 export async function apiLogin(
   email: string,
   password: string
-): Promise<LoginResponse> {
+): Promise<LoginResponse | TwoFactorChallenge> {
   return apiFetch("/api/auth/login", {
     method: "POST",
     body: JSON.stringify({ email, password }),
   });
 }
 
+export interface OIDCProvider {
+  name: string;
+  display_name: string;
+}
+
+/** Identity providers to offer "Sign in with …" buttons for. */
+export async function apiListOIDCProviders(): Promise<OIDCProvider[]> {
+  return apiFetch("/api/auth/oidc/providers");
+}
+
+/** Where to navigate the window to start single sign-on. */
+export function oidcStartURL(provider: string): string {
+  return `${BASE}/api/auth/oidc/${encodeURIComponent(provider)}/start`;
+}
+
+/** Swap the token from /oidc/callback?token=… for a session. */
+export async function apiOIDCExchange(
+  token: string
+): Promise<LoginResponse | TwoFactorChallenge> {
+  return apiFetch("/api/auth/oidc/exchange", {
+    method: "POST",
+    body: JSON.stringify({ token }),
+  });
+}
+
+/** Second login step: the challenge from apiLogin plus an authenticator or
+ * recovery code. */
+export async function apiLoginTwoFactor(
+  challengeToken: string,
+  code: string
+): Promise<LoginResponse> {
+  return apiFetch("/api/auth/login/2fa", {
+    method: "POST",
+    body: JSON.stringify({ challenge_token: challengeToken, code }),
+  });
+}
+
+/** For a challenge with enrollment_required: get the secret to set up. */
+export async function apiLoginTwoFactorEnroll(
+  challengeToken: string
+): Promise<TwoFactorEnrollment> {
+  return apiFetch("/api/auth/login/2fa/enroll", {
+    method: "POST",
+    body: JSON.stringify({ challenge_token: challengeToken }),
+  });
+}
+
+/** Start turning on 2FA; takes effect after apiConfirmTwoFactor. */
+export async function apiEnrollTwoFactor(): Promise<TwoFactorEnrollment> {
+  return apiFetch("/api/auth/2fa/enroll", { method: "POST" });
+}
+
+/** Turn 2FA on; returns the recovery codes, shown only this once. */
+export async function apiConfirmTwoFactor(
+  code: string
+): Promise<{ recovery_codes: string[] }> {
+  return apiFetch("/api/auth/2fa/confirm", {
+    method: "POST",
+    body: JSON.stringify({ code }),
+  });
+}
+
+export async function apiDisableTwoFactor(
+  password: string,
+  code: string
+): Promise<void> {
+  return apiFetch("/api/auth/2fa/disable", {
+    method: "POST",
+    body: JSON.stringify({ password, code }),
+  });
+}
+
+export async function apiRegenerateRecoveryCodes(
+  code: string
+): Promise<{ recovery_codes: string[] }> {
+  return apiFetch("/api/auth/2fa/recovery-codes", {
+    method: "POST",
+    body: JSON.stringify({ code }),
+  });
+}
+
 export async function apiMe(): Promise<User> {
   return apiFetch("/api/auth/me");
 }
 
+/** Update the caller's name and/or request an email change. */
+export async function apiUpdateMe(
+  fields: { name?: string; email?: string }
+): Promise<User> {
+  return apiFetch("/api/auth/me", {
+    method: "PATCH",
+    body: JSON.stringify(fields),
+  });
+}
+
+/** Change the password; other devices are logged out. */
+export async function apiChangePassword(
+  currentPassword: string,
+  newPassword: string
+): Promise<void> {
+  return apiFetch("/api/auth/me/password", {
+    method: "POST",
+    body: JSON.stringify({
+      current_password: currentPassword,
+      new_password: newPassword,
+    }),
+  });
+}
+
+/** Confirm an email change with the token from the emailed link. */
+export async function apiConfirmEmail(token: string): Promise<User> {
+  return apiFetch("/api/auth/email/confirm", {
+    method: "POST",
+    body: JSON.stringify({ token }),
+  });
+}
+
+/** Verify the account's email with the token from the emailed link. */
+export async function apiVerifyEmail(token: string): Promise<User> {
+  return apiFetch("/api/auth/email/verify", {
+    method: "POST",
+    body: JSON.stringify({ token }),
+  });
+}
+
+/** Email the logged-in user a new verification link. */
+export async function apiResendVerification(): Promise<void> {
+  await apiFetch("/api/auth/email/verify/resend", { method: "POST" });
+}
+
+/** Ask for a password reset email. Resolves the same whether or not the
+ * address is registered. */
+export async function apiForgotPassword(email: string): Promise<void> {
+  await apiFetch("/api/auth/password/forgot", {
+    method: "POST",
+    body: JSON.stringify({ email }),
+  });
+}
+
+/** Set a new password with the token from the reset email. */
+export async function apiResetPassword(
+  token: string,
+  password: string
+): Promise<void> {
+  return apiFetch("/api/auth/password/reset", {
+    method: "POST",
+    body: JSON.stringify({ token, password }),
+  });
+}
+
+/** Ask for a passwordless login link (students only). Resolves the same
+ * whether or not the address is registered. */
+export async function apiRequestMagicLink(email: string): Promise<void> {
+  await apiFetch("/api/auth/magic-link", {
+    method: "POST",
+    body: JSON.stringify({ email }),
+  });
+}
+
+/** Log in with the token from a magic-link email. */
+export async function apiVerifyMagicLink(
+  token: string
+): Promise<LoginResponse | TwoFactorChallenge> {
+  return apiFetch("/api/auth/magic-link/verify", {
+    method: "POST",
+    body: JSON.stringify({ token }),
+  });
+}
+
+/** Revoke the current session on the server. */
+export async function apiLogout(): Promise<void> {
+  return apiFetch("/api/auth/logout", { method: "POST" });
+}
+
 // ─── Events ───────────────────────────────────────────────────────────────────
 
 export interface Skill {
@@ -109,9 +354,16 @@ export interface ApiEvent {
   location: string;
   start_time: string;
   end_time: string;
-  status: "upcoming" | "active" | "completed";
+  status: "upcoming" | "active" | "completed" | "cancelled";
+  organisation_id: string;
+  cancellation_reason?: string;
+  cancelled_at?: string;
+  // true = the server never moves the status on its own.
+  manual_status: boolean;
   capacity?: number;
   slots_remaining?: number;
+  sync_deadline?: string;
+  checkin_rotation_seconds?: number;
   created_at: string;
   updated_at: string;
   skills?: Skill[];
@@ -133,6 +385,10 @@ export interface CreateEventPayload {
   end_time: string;
   skill_ids: string[];
   capacity?: number;
+  // Required only when the caller manages several organisations.
+  organisation_id?: string;
+  // Opt out of automatic status changes at start_time / end_time.
+  manual_status?: boolean;
 }
 
 export async function apiCreateEvent(
@@ -153,18 +409,29 @@ export async function apiUpdateEvent(
 
 export async function apiUpdateEventStatus(
   id: string,
-  status: "upcoming" | "active" | "completed"
-): Promise<{ event_id: string; status: string }> {
+  status: "upcoming" | "active" | "completed" | "cancelled",
+  // Required when cancelling.
+  reason?: string
+): Promise<{ event_id: string; status: string; cancellation_reason?: string }> {
   return apiFetch(`/api/events/${id}/status`, {
     method: "PATCH",
-    body: JSON.stringify({ status }),
+    body: JSON.stringify({ status, reason }),
   });
 }
 
+// Soft delete: the event disappears from listings, and is cancelled if it
+// had not finished.
+export async function apiDeleteEvent(id: string): Promise<void> {
+  return apiFetch(`/api/events/${id}`, { method: "DELETE" });
+}
+
 export interface CheckinCodeResponse {
   event_id: string;
   token: string;
   expires_in_seconds: number;
+  // Present for rotating codes: fetch a new token when expires_in_seconds
+  // runs out.
+  rotation_seconds?: number;
 }
 
 export async function apiGetCheckinCode(
@@ -173,6 +440,64 @@ export async function apiGetCheckinCode(
   return apiFetch(`/api/events/${eventId}/checkin-code`);
 }
 
+/** One public key from GET /api/.well-known/jwks.json (RFC 8037 OKP key). */
+export interface CheckInJwk {
+  kty: "OKP";
+  crv: "Ed25519";
+  x: string;
+  kid: string;
+  alg?: string;
+  use?: string;
+}
+
+/** Public keys the PWA uses to verify check-in QR codes offline. */
+export async function apiGetCheckInKeys(): Promise<{ keys: CheckInJwk[] }> {
+  return apiFetch("/api/.well-known/jwks.json");
+}
+
+/**
+ * Issue a new check-in secret for the event. Every QR code shown before
+ * stops working — use it when a code has leaked.
+ */
+export async function apiRotateCheckinCode(
+  eventId: string
+): Promise<CheckinCodeResponse> {
+  return apiFetch(`/api/events/${eventId}/checkin-code/rotate`, { method: "POST" });
+}
+
+// Per-event roles for volunteers: scanners show the check-in QR,
+// coordinators also see registrations.
+export type EventRole = "scanner" | "coordinator";
+
+export interface EventRoleGrant {
+  event_id: string;
+  user_id: string;
+  name: string;
+  email: string;
+  role: EventRole;
+  granted_by: string;
+  created_at: string;
+}
+
+export async function apiListEventRoles(eventId: string): Promise<EventRoleGrant[]> {
+  return apiFetch(`/api/events/${eventId}/roles`);
+}
+
+export async function apiGrantEventRole(
+  eventId: string,
+  email: string,
+  role: EventRole
+): Promise<EventRoleGrant> {
+  return apiFetch(`/api/events/${eventId}/roles`, {
+    method: "POST",
+    body: JSON.stringify({ email, role }),
+  });
+}
+
+export async function apiRevokeEventRole(eventId: string, userId: string): Promise<void> {
+  return apiFetch(`/api/events/${eventId}/roles/${userId}`, { method: "DELETE" });
+}
+
 // ─── Registrations ────────────────────────────────────────────────────────────
 
 export interface Registration {
@@ -180,7 +505,8 @@ export interface Registration {
   event_id: string;
   student_id: string;
   registered_at: string;
-  status: "confirmed" | "conflict_pending" | "waitlisted";
+  status: "confirmed" | "offered" | "conflict_pending" | "waitlisted";
+  offer_expires_at?: string;
 }
 
 export interface RegistrationWithEvent extends Registration {
@@ -188,7 +514,9 @@ export interface RegistrationWithEvent extends Registration {
   start_time: string;
   end_time: string;
   event_status: string;
+  cancellation_reason?: string;
   location: string;
+  waitlist_position?: number;
 }
 
 export interface RegistrationWithStudent extends Registration {
@@ -206,6 +534,12 @@ export async function apiUnregisterFromEvent(eventId: string): Promise<void> {
   return apiFetch(`/api/events/${eventId}/register`, { method: "DELETE" });
 }
 
+export async function apiAcceptWaitlistOffer(
+  eventId: string
+): Promise<Registration> {
+  return apiFetch(`/api/events/${eventId}/register/accept`, { method: "POST" });
+}
+
 export async function apiGetEventRegistrations(
   eventId: string
 ): Promise<RegistrationWithStudent[]> {
@@ -276,12 +610,158 @@ export interface StudentWithSkills extends User {
 }
 
 export async function apiSearchStudents(
-  skillId?: string
+  skillId?: string,
+  institutionId?: string
 ): Promise<StudentWithSkills[]> {
-  const qs = skillId ? `?skill_id=${encodeURIComponent(skillId)}` : "";
+  const params = new URLSearchParams();
+  if (skillId) params.set("skill_id", skillId);
+  if (institutionId) params.set("institution_id", institutionId);
+  const qs = params.toString() ? `?${params}` : "";
   return apiFetch(`/api/users/students${qs}`);
 }
 
+export interface Institution extends InstitutionRef {
+  domains: string[];
+  created_at: string;
+}
+
+/** Institutions students can be affiliated with, for the search filter. */
+export async function apiListInstitutions(): Promise<Institution[]> {
+  return apiFetch("/api/institutions");
+}
+
+// ─── API keys (company) ───────────────────────────────────────────────────────
+
+export type ApiKeyScope =
+  | "events:read"
+  | "registrations:read"
+  | "candidates:read";
+
+export interface ApiKey {
+  id: string;
+  name: string;
+  prefix: string;
+  scopes: ApiKeyScope[];
+  created_at: string;
+  expires_at?: string;
+  last_used_at?: string;
+  last_used_ip?: string;
+}
+
+/** Returned once, on creation: `key` cannot be fetched again. */
+export interface CreatedApiKey extends ApiKey {
+  key: string;
+}
+
+export async function apiListApiKeys(): Promise<ApiKey[]> {
+  return apiFetch("/api/api-keys");
+}
+
+export async function apiCreateApiKey(payload: {
+  name: string;
+  scopes: ApiKeyScope[];
+  expires_at?: string;
+}): Promise<CreatedApiKey> {
+  return apiFetch("/api/api-keys", {
+    method: "POST",
+    body: JSON.stringify(payload),
+  });
+}
+
+export async function apiRevokeApiKey(id: string): Promise<void> {
+  return apiFetch(`/api/api-keys/${id}`, { method: "DELETE" });
+}
+
+// ─── Organisations (company) ──────────────────────────────────────────────────
+
+export type OrganisationRole = "staff" | "admin" | "owner";
+
+export interface OrganisationMember {
+  user_id: string;
+  name: string;
+  email: string;
+  role: OrganisationRole;
+  joined_at: string;
+}
+
+export interface Organisation {
+  id: string;
+  name: string;
+  role?: OrganisationRole; // the caller's
+  created_at: string;
+  members?: OrganisationMember[];
+}
+
+export interface OrganisationInvitation {
+  id: string;
+  organisation_id: string;
+  email: string;
+  role: OrganisationRole;
+  invited_by: string;
+  created_at: string;
+  expires_at: string;
+}
+
+export async function apiListOrganisations(): Promise<Organisation[]> {
+  return apiFetch("/api/organisations");
+}
+
+export async function apiCreateOrganisation(name: string): Promise<Organisation> {
+  return apiFetch("/api/organisations", {
+    method: "POST",
+    body: JSON.stringify({ name }),
+  });
+}
+
+export async function apiGetOrganisation(id: string): Promise<Organisation> {
+  return apiFetch(`/api/organisations/${id}`);
+}
+
+export async function apiUpdateMemberRole(
+  orgId: string,
+  userId: string,
+  role: OrganisationRole
+): Promise<{ user_id: string; role: OrganisationRole }> {
+  return apiFetch(`/api/organisations/${orgId}/members/${userId}`, {
+    method: "PATCH",
+    body: JSON.stringify({ role }),
+  });
+}
+
+/** Removes a member; pass your own id to leave the organisation. */
+export async function apiRemoveMember(orgId: string, userId: string): Promise<void> {
+  return apiFetch(`/api/organisations/${orgId}/members/${userId}`, { method: "DELETE" });
+}
+
+export async function apiInviteMember(
+  orgId: string,
+  email: string,
+  role: OrganisationRole
+): Promise<OrganisationInvitation> {
+  return apiFetch(`/api/organisations/${orgId}/invitations`, {
+    method: "POST",
+    body: JSON.stringify({ email, role }),
+  });
+}
+
+export async function apiListInvitations(orgId: string): Promise<OrganisationInvitation[]> {
+  return apiFetch(`/api/organisations/${orgId}/invitations`);
+}
+
+export async function apiRevokeInvitation(orgId: string, invitationId: string): Promise<void> {
+  return apiFetch(`/api/organisations/${orgId}/invitations/${invitationId}`, {
+    method: "DELETE",
+  });
+}
+
+/** Accepts the invitation from an emailed /invitations?token=… link. */
+export async function apiAcceptInvitation(token: string): Promise<Organisation> {
+  return apiFetch("/api/organisations/invitations/accept", {
+    method: "POST",
+    body: JSON.stringify({ token }),
+  });
+}
+
 // ─── Attendance sync ──────────────────────────────────────────────────────────
 
 export interface SyncAttendanceRecord {
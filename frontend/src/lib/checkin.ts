@@ -0,0 +1,111 @@
+/**
+ * checkin.ts — Offline verification of scanned check-in QR codes.
+ *
+ * Check-in tokens are Ed25519-signed JWTs. The server publishes the public
+ * keys at GET /api/.well-known/jwks.json; we cache them in localStorage
+ * whenever we are online so a student at a venue with no signal still gets
+ * an immediate "genuine / not genuine" answer after scanning.
+ *
+ * This check is a UX convenience only — the server verifies every token
+ * again at sync time.
+ */
+
+import { apiGetCheckInKeys, type CheckInJwk } from "./api";
+
+const KEYS_STORAGE = "sz_checkin_jwks";
+
+// Same tolerance the server applies to device clocks by default.
+const CLOCK_SKEW_SECONDS = 5 * 60;
+
+export type CheckInVerdict =
+  | "valid"      // signature verifies and the code is live right now
+  | "invalid"    // signature does not verify — forged or corrupted QR
+  | "expired"    // genuine, but not live at the device's current time
+  | "unverified"; // no cached keys, or the browser lacks Ed25519 support
+
+export interface CheckInClaims {
+  event_id: string;
+  iat?: number;
+  exp?: number;
+  step?: number;
+  period?: number;
+}
+
+/** Fetch and cache the current public keys. Safe to call on every launch. */
+export async function refreshCheckInKeys(): Promise<void> {
+  const { keys } = await apiGetCheckInKeys();
+  localStorage.setItem(KEYS_STORAGE, JSON.stringify(keys));
+}
+
+function cachedKeys(): CheckInJwk[] {
+  try {
+    return JSON.parse(localStorage.getItem(KEYS_STORAGE) ?? "[]");
+  } catch {
+    return [];
+  }
+}
+
+function base64UrlDecode(s: string): Uint8Array {
+  const b64 = s.replace(/-/g, "+").replace(/_/g, "/").padEnd(Math.ceil(s.length / 4) * 4, "=");
+  return Uint8Array.from(atob(b64), (c) => c.charCodeAt(0));
+}
+
+/** Decode the claims of a check-in JWT without verifying it. */
+export function decodeCheckInClaims(token: string): CheckInClaims {
+  const parts = token.split(".");
+  if (parts.length !== 3) throw new Error("not a JWT");
+  return JSON.parse(new TextDecoder().decode(base64UrlDecode(parts[1])));
+}
+
+/**
+ * Verify a check-in token against the cached public keys and check that it
+ * is live at the device's current time.
+ */
+export async function verifyCheckInToken(token: string): Promise<CheckInVerdict> {
+  const [headerB64, payloadB64, sigB64] = token.split(".");
+  if (!headerB64 || !payloadB64 || !sigB64) return "invalid";
+
+  let header: { alg?: string; kid?: string };
+  try {
+    header = JSON.parse(new TextDecoder().decode(base64UrlDecode(headerB64)));
+  } catch {
+    return "invalid";
+  }
+  if (header.alg !== "EdDSA") return "invalid";
+
+  let keys = cachedKeys();
+  if (!keys.some((k) => k.kid === header.kid) && navigator.onLine) {
+    // Unknown kid — the server may have a key we have not cached yet.
+    await refreshCheckInKeys().catch(() => {});
+    keys = cachedKeys();
+  }
+  const jwk = keys.find((k) => k.kid === header.kid);
+  if (!jwk) return keys.length > 0 && navigator.onLine ? "invalid" : "unverified";
+
+  let ok: boolean;
+  try {
+    const key = await crypto.subtle.importKey(
+      "jwk",
+      { kty: jwk.kty, crv: jwk.crv, x: jwk.x },
+      { name: "Ed25519" },
+      false,
+      ["verify"]
+    );
+    ok = await crypto.subtle.verify(
+      { name: "Ed25519" },
+      key,
+      base64UrlDecode(sigB64),
+      new TextEncoder().encode(`${headerB64}.${payloadB64}`)
+    );
+  } catch {
+    // Older browsers without Ed25519 in WebCrypto — let the server decide.
+    return "unverified";
+  }
+  if (!ok) return "invalid";
+
+  const claims = decodeCheckInClaims(token);
+  const now = Date.now() / 1000;
+  if (claims.iat && now < claims.iat - CLOCK_SKEW_SECONDS) return "expired";
+  if (claims.exp && now > claims.exp + CLOCK_SKEW_SECONDS) return "expired";
+  return "valid";
+}
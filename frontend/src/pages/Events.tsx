@@ -20,10 +20,11 @@ import { useAuth } from "../context/AuthContext";
 import { useOnlineStatus } from "../hooks/useOnlineStatus";
 import {
   apiListEvents, apiCreateEvent, apiUpdateEvent, apiUpdateEventStatus,
-  apiGetCheckinCode, apiRegisterForEvent, apiUnregisterFromEvent,
+  apiGetCheckinCode, apiRotateCheckinCode, apiRegisterForEvent, apiUnregisterFromEvent,
   apiGetEventRegistrations, apiListSkills, apiResolveConflict, apiKickRegistration,
   type ApiEvent, type Skill, type RegistrationWithStudent,
 } from "../lib/api";
+import { decodeCheckInClaims, refreshCheckInKeys, verifyCheckInToken } from "../lib/checkin";
 import {
   db, cacheEvents, enqueueCheckIn, enqueueRegister, enqueueUnregister,
   type CachedEvent,
@@ -175,21 +176,57 @@ function QRModal({ event, onClose }: { event: ApiEvent; onClose: () => void }) {
   const canvasRef = useRef<HTMLCanvasElement>(null);
   const [token, setToken] = useState("");
   const [expiresIn, setExpiresIn] = useState(0);
+  const [rotation, setRotation] = useState(0);
   const [loading, setLoading] = useState(true);
+  // Bumped after the secret is rotated so the effect below redraws the QR.
+  const [generation, setGeneration] = useState(0);
+  const [revoking, setRevoking] = useState(false);
+
+  const revokeCode = async () => {
+    if (!confirm("Issue a new code? Every QR shown so far will stop working.")) return;
+    setRevoking(true);
+    try {
+      await apiRotateCheckinCode(event.id);
+      setGeneration((g) => g + 1);
+      toast.success("New check-in code issued");
+    } catch {
+      toast.error("Could not issue a new code");
+    } finally {
+      setRevoking(false);
+    }
+  };
 
   useEffect(() => {
-    apiGetCheckinCode(event.id)
-      .then(async (res) => {
-        setToken(res.token);
-        setExpiresIn(res.expires_in_seconds);
-        const payload = JSON.stringify({ token: res.token });
-        if (canvasRef.current) {
-          await QRCode.toCanvas(canvasRef.current, payload, { width: 280, margin: 2 });
-        }
-      })
-      .catch(() => toast.error("Could not generate QR code"))
-      .finally(() => setLoading(false));
-  }, [event.id]);
+    let cancelled = false;
+    let timer: ReturnType<typeof setTimeout> | undefined;
+
+    // Rotating codes expire every few seconds, so fetch the next one as
+    // soon as the current step ends.
+    const load = () => {
+      apiGetCheckinCode(event.id)
+        .then(async (res) => {
+          if (cancelled) return;
+          setToken(res.token);
+          setExpiresIn(res.expires_in_seconds);
+          setRotation(res.rotation_seconds ?? 0);
+          const payload = JSON.stringify({ token: res.token });
+          if (canvasRef.current) {
+            await QRCode.toCanvas(canvasRef.current, payload, { width: 280, margin: 2 });
+          }
+          if (res.rotation_seconds) {
+            timer = setTimeout(load, Math.max(res.expires_in_seconds, 1) * 1000);
+          }
+        })
+        .catch(() => toast.error("Could not generate QR code"))
+        .finally(() => setLoading(false));
+    };
+    load();
+
+    return () => {
+      cancelled = true;
+      if (timer) clearTimeout(timer);
+    };
+  }, [event.id, generation]);
 
   return (
     <div className="fixed inset-0 z-50 flex items-center justify-center bg-black/50 p-4">
@@ -206,7 +243,12 @@ function QRModal({ event, onClose }: { event: ApiEvent; onClose: () => void }) {
         ) : (
           <canvas ref={canvasRef} className="mx-auto rounded-lg" />
         )}
-        {expiresIn > 0 && (
+        {rotation > 0 ? (
+          <p className="mt-3 text-xs text-muted-foreground">
+            <Clock className="mr-1 inline h-3 w-3" />
+            Code changes every {rotation} seconds — keep this screen open
+          </p>
+        ) : expiresIn > 0 && (
           <p className="mt-3 text-xs text-muted-foreground">
             <Clock className="mr-1 inline h-3 w-3" />
             Valid for {Math.floor(expiresIn / 3600)} hours — display on projector screen
@@ -215,6 +257,10 @@ function QRModal({ event, onClose }: { event: ApiEvent; onClose: () => void }) {
         <p className="mt-2 text-xs text-green-600 font-medium">
           Students scan while offline — they sync later ✓
         </p>
+        <button onClick={revokeCode} disabled={revoking || loading}
+          className="mt-4 text-xs text-muted-foreground underline hover:text-foreground disabled:opacity-50">
+          {revoking ? "Issuing…" : "Code leaked? Issue a new one"}
+        </button>
       </div>
     </div>
   );
@@ -228,6 +274,9 @@ function QRScannerModal({ userId, onClose }: { userId: string; onClose: () => vo
   const [result, setResult] = useState<string | null>(null);
 
   useEffect(() => {
+    // Keep the offline verification keys fresh while we have a connection.
+    if (navigator.onLine) refreshCheckInKeys().catch(() => {});
+
     const scanner = new Html5QrcodeScanner(
       "qr-reader",
       { fps: 10, qrbox: { width: 250, height: 250 } },
@@ -244,13 +293,20 @@ function QRScannerModal({ userId, onClose }: { userId: string; onClose: () => vo
           const payload = JSON.parse(decodedText) as { token: string };
           if (!payload.token) throw new Error("missing token");
 
-          // Decode JWT payload (no verification — server verifies)
-          const parts = payload.token.split(".");
-          const claims = JSON.parse(atob(parts[1]));
-          const eventId: string = claims.event_id;
+          // Check the signature against the cached public keys so a fake
+          // or stale QR is caught now rather than at sync time.
+          const verdict = await verifyCheckInToken(payload.token);
+          if (verdict === "invalid") throw new Error("this QR was not issued by SkillZone");
+          if (verdict === "expired") throw new Error("this QR code is no longer live");
+
+          const eventId = decodeCheckInClaims(payload.token).event_id;
 
           await enqueueCheckIn(userId, eventId, payload.token);
-          setResult(`✓ Check-in queued for event. Badges will appear when you reconnect.`);
+          setResult(
+            verdict === "valid"
+              ? `✓ Genuine check-in code. Badges will appear when you reconnect.`
+              : `✓ Check-in queued for event. Badges will appear when you reconnect.`
+          );
           toast.success("QR scanned! Check-in queued offline.");
 
           // Attempt immediate sync if online